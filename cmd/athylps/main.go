@@ -5,9 +5,15 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
+	_ "time/tzdata" // The production image has no timezone database, digests are scheduled in local time
 
 	"athylps/internal/app"
 	"athylps/internal/config"
+	"athylps/internal/logging"
+	"athylps/internal/tracing"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 	"go.uber.org/zap"
@@ -30,24 +36,53 @@ func main() {
 	if err != nil {
 		log.Fatalf("can't initialize zap logger: %v", err)
 	}
-	defer logger.Sync()
+	logger = logging.Redact(logger)
 
-	// Configure db connection pool
-	dbpool, err := pgxpool.New(context.Background(), cfg.Database.ConnectionUrl())
+	// Cancel the app context on SIGINT/SIGTERM to shut down gracefully
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	shutdownTracing, err := tracing.Setup(ctx, &cfg.Tracing, cfg.Server.Env)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Unable to create connection pool: %v\n", err)
+		logger.Fatal("can't initialize tracing", zap.Error(err))
+	}
+
+	err = run(ctx, cfg, logger)
+	// Export spans of the last requests, the app context is already cancelled
+	flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if flushErr := shutdownTracing(flushCtx); flushErr != nil {
+		logger.Warn("failed to flush traces", zap.Error(flushErr))
+	}
+	if err != nil {
+		logger.Error("app failed", zap.Error(err))
+		_ = logger.Sync()
 		os.Exit(1)
 	}
+	_ = logger.Sync()
+}
+
+// run owns the database pool, it's closed only after the app has drained requests and stopped workers.
+func run(ctx context.Context, cfg *config.Config, logger *zap.Logger) error {
+	// Configure db connection pool
+	poolConfig, err := pgxpool.ParseConfig(cfg.Database.ConnectionUrl())
+	if err != nil {
+		return fmt.Errorf("invalid database config: %w", err)
+	}
+	poolConfig.ConnConfig.Tracer = tracing.QueryTracer{}
+	dbpool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	if err != nil {
+		return fmt.Errorf("unable to create connection pool: %w", err)
+	}
 	defer dbpool.Close()
 
 	// Check that we can connect to the database
 	var greeting string
-	err = dbpool.QueryRow(context.Background(), "select 'Hello, world!'").Scan(&greeting)
+	err = dbpool.QueryRow(ctx, "select 'Hello, world!'").Scan(&greeting)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "QueryRow failed: %v\n", err)
-		os.Exit(1)
+		return fmt.Errorf("QueryRow failed: %w", err)
 	}
 
 	// Run our app
-	app.Run(cfg, logger, dbpool)
+	return app.Run(ctx, cfg, logger, dbpool)
 }
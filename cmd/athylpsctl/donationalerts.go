@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"net/http"
+	"time"
+
+	"athylps/internal/repositories"
+	"athylps/internal/services"
+	"athylps/internal/usecases"
+)
+
+var donationAlertsAuthorizeCommand = &command{
+	name:    "donationalerts authorize",
+	usage:   "",
+	summary: "Print the link connecting the DonationAlerts account donations are polled from",
+	run:     runDonationAlertsAuthorize,
+}
+
+type donationAlertsAuthorizeResult struct {
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func runDonationAlertsAuthorize(ctx context.Context, env *env, args []string) error {
+	fs := flag.NewFlagSet("donationalerts authorize", flag.ContinueOnError)
+	if _, err := parseArgs(fs, args, 0); err != nil {
+		return err
+	}
+
+	pool, err := env.db(ctx)
+	if err != nil {
+		return err
+	}
+
+	// The server completes the authorization when the owner is redirected back to it
+	usecase := usecases.NewConnectDonationAlertsUsecase(
+		env.cfg.DonationAlerts.ClientSecret,
+		services.NewDonationAlertsClient(&env.cfg.DonationAlerts, http.DefaultClient),
+		repositories.NewDonationAlertsTokenRepository(pool),
+		env.logger,
+	)
+	authorization, err := usecase.Authorize()
+	if err != nil {
+		return err
+	}
+
+	result := &donationAlertsAuthorizeResult{URL: authorization.URL, ExpiresAt: authorization.ExpiresAt.UTC()}
+	return env.out.write(result, []string{"URL", "EXPIRES AT"}, [][]string{{result.URL, result.ExpiresAt.Format(time.RFC3339)}})
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"flag"
+
+	"athylps/internal/repositories"
+	"athylps/internal/usecases"
+)
+
+var entitlementsGrantCommand = &command{
+	name:    "entitlements grant",
+	usage:   "-user ID -entitlement ID [-until TIME]",
+	summary: "Grant an entitlement to a user by hand, forever unless -until is set",
+	run:     runEntitlementsGrant,
+}
+
+var entitlementsRevokeCommand = &command{
+	name:    "entitlements revoke",
+	usage:   "-user ID -entitlement ID",
+	summary: "Revoke an entitlement granted by hand",
+	run:     runEntitlementsRevoke,
+}
+
+var subscriptionHeader = []string{"APP USER ID", "PRODUCT", "STORE", "STATUS", "EXPIRES AT", "LAST EVENT", "LAST EVENT AT"}
+
+func writeSubscription(env *env, s *subscriptionView) error {
+	row := []string{s.AppUserID, s.ProductID, s.Store, s.Status, cell(s.ExpiresAt), s.LastEventType, cell(s.LastEventAt)}
+	return env.out.write(s, subscriptionHeader, [][]string{row})
+}
+
+func runEntitlementsGrant(ctx context.Context, env *env, args []string) error {
+	fs := flag.NewFlagSet("entitlements grant", flag.ContinueOnError)
+	userID := fs.String("user", "", "id of the user")
+	entitlementID := fs.String("entitlement", "", "entitlement to grant, e.g. premium")
+	until := fs.String("until", "", "time the entitlement expires at, RFC 3339 or YYYY-MM-DD")
+	if _, err := parseArgs(fs, args, 0); err != nil {
+		return err
+	}
+	if *userID == "" || *entitlementID == "" {
+		return errUsage
+	}
+	expiresAt, err := parseTime(*until)
+	if err != nil {
+		return err
+	}
+
+	db, err := env.db(ctx)
+	if err != nil {
+		return err
+	}
+	usecase := usecases.NewGrantEntitlementUsecase(repositories.NewUserRepository(db), repositories.NewSubscriptionRepository(db), env.logger)
+	granted, err := usecase.Perform(ctx, &usecases.GrantEntitlementParams{
+		UserID:        *userID,
+		EntitlementID: *entitlementID,
+		ExpiresAt:     expiresAt,
+		GrantedBy:     actor(),
+	})
+	if err != nil {
+		return err
+	}
+
+	return writeSubscription(env, toSubscriptionView(granted))
+}
+
+func runEntitlementsRevoke(ctx context.Context, env *env, args []string) error {
+	fs := flag.NewFlagSet("entitlements revoke", flag.ContinueOnError)
+	userID := fs.String("user", "", "id of the user")
+	entitlementID := fs.String("entitlement", "", "entitlement to revoke, e.g. premium")
+	if _, err := parseArgs(fs, args, 0); err != nil {
+		return err
+	}
+	if *userID == "" || *entitlementID == "" {
+		return errUsage
+	}
+
+	db, err := env.db(ctx)
+	if err != nil {
+		return err
+	}
+	usecase := usecases.NewRevokeEntitlementUsecase(repositories.NewSubscriptionRepository(db), env.logger)
+	revoked, err := usecase.Perform(ctx, &usecases.RevokeEntitlementParams{
+		UserID:        *userID,
+		EntitlementID: *entitlementID,
+		RevokedBy:     actor(),
+	})
+	if err != nil {
+		return err
+	}
+
+	return writeSubscription(env, toSubscriptionView(revoked))
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"os/user"
+	"slices"
+	"strings"
+	"syscall"
+	_ "time/tzdata"
+
+	"athylps/internal/config"
+	"athylps/internal/logging"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+// errUsage is returned by commands called with wrong arguments, the usage of the command is printed instead.
+var errUsage = errors.New("usage")
+
+var (
+	flags  = flag.NewFlagSet("athylpsctl", flag.ExitOnError)
+	format = flags.String("o", formatTable, "output format, table or json")
+)
+
+// command is a subcommand of athylpsctl, it parses its own flags from args.
+// Names of commands operating the same thing start with the same word, e.g. users list.
+type command struct {
+	name    string
+	usage   string
+	summary string
+	run     func(ctx context.Context, env *env, args []string) error
+}
+
+var commands = []*command{
+	usersLookupCommand,
+	usersListCommand,
+	usersDeleteCommand,
+	usersRestoreCommand,
+	entitlementsGrantCommand,
+	entitlementsRevokeCommand,
+	notificationsListCommand,
+	notificationsResendCommand,
+	telegramTestCommand,
+	revenueCommand,
+	replayCommand,
+	donationAlertsAuthorizeCommand,
+}
+
+// env is what commands operate the service with, the database is connected on first use.
+type env struct {
+	cfg    *config.Config
+	logger *zap.Logger
+	out    *output
+	pool   *pgxpool.Pool
+}
+
+func (e *env) db(ctx context.Context) (*pgxpool.Pool, error) {
+	if e.pool != nil {
+		return e.pool, nil
+	}
+
+	pool, err := pgxpool.New(ctx, e.cfg.Database.ConnectionUrl())
+	if err != nil {
+		return nil, fmt.Errorf("unable to create connection pool: %w", err)
+	}
+	e.pool = pool
+
+	return pool, nil
+}
+
+func main() {
+	flags.Usage = usage
+	flags.Parse(os.Args[1:])
+
+	args := flags.Args()
+	if len(args) == 0 || args[0] == "help" {
+		flags.Usage()
+		return
+	}
+
+	cmd, cmdArgs := findCommand(args)
+	if cmd == nil {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", strings.Join(args, " "))
+		flags.Usage()
+		os.Exit(2)
+	}
+	if *format != formatTable && *format != formatJSON {
+		log.Fatalf("unknown output format %q", *format)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+	logger, err := zap.NewDevelopment()
+	if err != nil {
+		log.Fatalf("can't initialize zap logger: %v", err)
+	}
+	logger = logging.Redact(logger)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	e := &env{cfg: cfg, logger: logger, out: &output{format: *format, w: os.Stdout}}
+	err = cmd.run(ctx, e, cmdArgs)
+	if e.pool != nil {
+		e.pool.Close()
+	}
+	_ = logger.Sync()
+	if errors.Is(err, errUsage) {
+		fmt.Fprintf(os.Stderr, "Usage: athylpsctl [-o table|json] %s %s\n", cmd.name, cmd.usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", cmd.name, err)
+		os.Exit(1)
+	}
+}
+
+// findCommand returns the command named by the first args and the rest of args.
+func findCommand(args []string) (*command, []string) {
+	for _, c := range commands {
+		words := strings.Fields(c.name)
+		if len(args) >= len(words) && slices.Equal(args[:len(words)], words) {
+			return c, args[len(words):]
+		}
+	}
+
+	return nil, nil
+}
+
+// actor is who operates the service, it's recorded with changes made by commands.
+func actor() string {
+	name := "unknown"
+	if u, err := user.Current(); err == nil {
+		name = u.Username
+	}
+
+	return "athylpsctl:" + name
+}
+
+func usage() {
+	fmt.Println(`Usage: athylpsctl [-o table|json] COMMAND [ARGS]
+Operates the service with the configuration from the environment, like the server does.`)
+	flags.PrintDefaults()
+	fmt.Println("\nCommands:")
+	for _, c := range commands {
+		fmt.Printf("    %-22s %s\n", c.name, c.summary)
+	}
+	fmt.Println("\nRun athylpsctl COMMAND -h to see arguments of the command.")
+}
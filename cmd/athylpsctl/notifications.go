@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"strconv"
+	"strings"
+	"time"
+
+	"athylps/internal/models"
+	"athylps/internal/repositories"
+
+	"go.uber.org/zap"
+)
+
+var notificationsListCommand = &command{
+	name:    "notifications list",
+	usage:   "[-status pending|sent|failed|coalesced] [-limit N]",
+	summary: "List notifications of the outbox, the newest first",
+	run:     runNotificationsList,
+}
+
+var notificationsResendCommand = &command{
+	name:    "notifications resend",
+	usage:   "ID",
+	summary: "Send a notification again, the running server delivers it",
+	run:     runNotificationsResend,
+}
+
+type notificationView struct {
+	ID        string     `json:"id"`
+	Channel   string     `json:"channel"`
+	Recipient string     `json:"recipient"`
+	Status    string     `json:"status"`
+	Attempts  int        `json:"attempts"`
+	LastError *string    `json:"last_error"`
+	Text      string     `json:"text"`
+	CreatedAt *time.Time `json:"created_at"`
+	SentAt    *time.Time `json:"sent_at"`
+}
+
+func writeNotifications(env *env, notifications []*models.Notification) error {
+	views := make([]*notificationView, 0, len(notifications))
+	rows := make([][]string, 0, len(notifications))
+	for _, n := range notifications {
+		view := &notificationView{
+			ID:        n.ID,
+			Channel:   n.Target.Channel,
+			Recipient: n.Target.Recipient,
+			Status:    string(n.Status),
+			Attempts:  n.Attempts,
+			LastError: n.LastError,
+			Text:      n.Text,
+			CreatedAt: n.CreatedAt,
+			SentAt:    n.SentAt,
+		}
+		views = append(views, view)
+		rows = append(rows, []string{
+			view.ID,
+			view.Channel + ":" + view.Recipient,
+			view.Status,
+			strconv.Itoa(view.Attempts),
+			cell(view.CreatedAt),
+			cell(view.LastError),
+			firstLine(view.Text),
+		})
+	}
+
+	return env.out.write(views, []string{"ID", "TARGET", "STATUS", "ATTEMPTS", "CREATED AT", "LAST ERROR", "TEXT"}, rows)
+}
+
+// firstLine shortens a notification text to fit a table row.
+func firstLine(text string) string {
+	line, _, _ := strings.Cut(text, "\n")
+	if runes := []rune(line); len(runes) > 60 {
+		return string(runes[:60]) + "…"
+	}
+	return line
+}
+
+func runNotificationsList(ctx context.Context, env *env, args []string) error {
+	fs := flag.NewFlagSet("notifications list", flag.ContinueOnError)
+	status := fs.String("status", "", "status of notifications, all if empty")
+	limit := fs.Uint64("limit", 50, "maximum number of notifications")
+	if _, err := parseArgs(fs, args, 0); err != nil {
+		return err
+	}
+
+	params := &repositories.ListNotificationsParams{Limit: *limit}
+	if *status != "" {
+		s := models.NotificationStatus(*status)
+		params.Status = &s
+	}
+
+	db, err := env.db(ctx)
+	if err != nil {
+		return err
+	}
+	notifications, err := repositories.NewNotificationOutboxRepository(db).List(ctx, params)
+	if err != nil {
+		return err
+	}
+
+	return writeNotifications(env, notifications)
+}
+
+func runNotificationsResend(ctx context.Context, env *env, args []string) error {
+	id, err := parseArgs(flag.NewFlagSet("notifications resend", flag.ContinueOnError), args, 1)
+	if err != nil {
+		return err
+	}
+
+	db, err := env.db(ctx)
+	if err != nil {
+		return err
+	}
+	resent, err := repositories.NewNotificationOutboxRepository(db).Resend(ctx, id[0])
+	if err != nil {
+		return err
+	}
+	env.logger.Info("resent notification", zap.String("notification_id", id[0]), zap.String("copy_id", resent.ID), zap.String("actor", actor()))
+
+	return writeNotifications(env, []*models.Notification{resent})
+}
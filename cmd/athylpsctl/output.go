@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+)
+
+// output prints results of commands as an aligned table for people or as JSON for scripts.
+type output struct {
+	format string
+	w      io.Writer
+}
+
+// write prints v as JSON or the rows under the header as a table.
+func (o *output) write(v any, header []string, rows [][]string) error {
+	if o.format == formatJSON {
+		enc := json.NewEncoder(o.w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+
+	tw := tabwriter.NewWriter(o.w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+// cell formats optional values of a table row, missing ones are shown as a dash.
+func cell[T any](v *T) string {
+	if v == nil {
+		return "-"
+	}
+
+	switch v := any(*v).(type) {
+	case time.Time:
+		return v.Format(time.RFC3339)
+	case string:
+		if v == "" {
+			return "-"
+		}
+		return v
+	}
+
+	return fmt.Sprint(*v)
+}
+
+// parseTime accepts RFC 3339 timestamps and dates, which are midnight UTC.
+func parseTime(s string) (*time.Time, error) {
+	return parseTimeIn(s, time.UTC)
+}
+
+// parseTimeIn accepts RFC 3339 timestamps and dates, which are midnight in the location.
+func parseTimeIn(s string, location *time.Location) (*time.Time, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return &t, nil
+	}
+	if t, err := time.ParseInLocation(time.DateOnly, s, location); err == nil {
+		return &t, nil
+	}
+
+	return nil, fmt.Errorf("invalid time %q, expected RFC 3339 or YYYY-MM-DD", s)
+}
+
+// parseArgs parses flags of the command and returns exactly n positional args,
+// which go before flags, e.g. users delete ID.
+func parseArgs(fs *flag.FlagSet, args []string, n int) ([]string, error) {
+	if len(args) < n {
+		return nil, errUsage
+	}
+	positional := args[:n]
+	if err := fs.Parse(args[n:]); err != nil || fs.NArg() > 0 {
+		return nil, errUsage
+	}
+
+	return positional, nil
+}
+
+// splitList splits a comma separated flag value, an empty value is an empty list.
+func splitList(s string) []string {
+	var items []string
+	for item := range strings.SplitSeq(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
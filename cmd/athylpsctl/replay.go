@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strconv"
+	"time"
+
+	"athylps/internal/app"
+	"athylps/internal/repositories"
+	"athylps/internal/usecases"
+)
+
+var replayCommand = &command{
+	name:    "replay",
+	usage:   "(-ids ID,... | -from TIME [-to TIME]) [-provider NAME] [-types TYPE,...] [-limit N] [-dry-run] [-suppress-notifications]",
+	summary: "Replay stored webhook events through the processing pipeline",
+	run:     runReplay,
+}
+
+type replayedEvent struct {
+	ID            string                  `json:"id"`
+	Provider      string                  `json:"provider"`
+	EventID       string                  `json:"event_id"`
+	EventType     string                  `json:"event_type"`
+	ReceivedAt    time.Time               `json:"received_at"`
+	Status        string                  `json:"status"`
+	Error         string                  `json:"error,omitempty"`
+	Notifications []previewedNotification `json:"notifications,omitempty"`
+}
+
+type previewedNotification struct {
+	Channel   string `json:"channel"`
+	Recipient string `json:"recipient"`
+	Locale    string `json:"locale"`
+	Text      string `json:"text"`
+}
+
+type replayResult struct {
+	Events    []replayedEvent `json:"events"`
+	Failed    int             `json:"failed"`
+	Truncated bool            `json:"truncated"`
+}
+
+func runReplay(ctx context.Context, env *env, args []string) error {
+	fs := flag.NewFlagSet("replay", flag.ContinueOnError)
+	ids := fs.String("ids", "", "comma separated internal ids of the events")
+	from := fs.String("from", "", "replay events received at or after the time, RFC 3339 or YYYY-MM-DD")
+	to := fs.String("to", "", "replay events received before the time, RFC 3339 or YYYY-MM-DD")
+	provider := fs.String("provider", "", "provider of the events, revenuecat or rustore")
+	types := fs.String("types", "", "comma separated event types, e.g. INITIAL_PURCHASE,RENEWAL")
+	limit := fs.Uint64("limit", usecases.MaxReplayedWebhookEvents, "maximum number of events to replay")
+	dryRun := fs.Bool("dry-run", false, "show notifications that would be sent without changing anything")
+	suppress := fs.Bool("suppress-notifications", false, "rebuild state without sending notifications again")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+
+	filter := &repositories.ListWebhookEventsParams{
+		IDs:        splitList(*ids),
+		EventTypes: splitList(*types),
+		Limit:      *limit,
+	}
+	var err error
+	if filter.From, err = parseTime(*from); err != nil {
+		return err
+	}
+	if filter.To, err = parseTime(*to); err != nil {
+		return err
+	}
+	if *provider != "" {
+		filter.Provider = provider
+	}
+
+	db, err := env.db(ctx)
+	if err != nil {
+		return err
+	}
+	usecase, err := app.NewReplayWebhookEventsUsecase(env.cfg, db, env.logger)
+	if err != nil {
+		return err
+	}
+
+	result, err := usecase.Perform(ctx, &usecases.ReplayWebhookEventsParams{
+		Filter:                filter,
+		DryRun:                *dryRun,
+		SuppressNotifications: *suppress,
+	})
+	if err != nil {
+		return err
+	}
+
+	out := toReplayResult(result, *dryRun)
+	rows := make([][]string, 0, len(out.Events))
+	for _, e := range out.Events {
+		details := e.Error
+		if *dryRun {
+			details = strconv.Itoa(len(e.Notifications)) + " notifications"
+		}
+		rows = append(rows, []string{e.ReceivedAt.Format(time.RFC3339), e.Provider, e.EventType, e.EventID, e.Status, details})
+	}
+	if err := env.out.write(out, []string{"RECEIVED AT", "PROVIDER", "TYPE", "EVENT ID", "STATUS", "DETAILS"}, rows); err != nil {
+		return err
+	}
+
+	if env.out.format == formatTable {
+		if *dryRun {
+			for _, e := range out.Events {
+				for _, n := range e.Notifications {
+					fmt.Fprintf(env.out.w, "\n%s %s to %s:%s\n%s\n", e.EventType, e.EventID, n.Channel, n.Recipient, n.Text)
+				}
+			}
+		}
+		if out.Truncated {
+			fmt.Fprintln(env.out.w, "\nMore events match the filter, replay them from the last received at")
+		}
+	}
+	if out.Failed > 0 {
+		return fmt.Errorf("%d of %d events failed to replay", out.Failed, len(out.Events))
+	}
+
+	return nil
+}
+
+func toReplayResult(result *usecases.ReplayWebhookEventsResult, dryRun bool) *replayResult {
+	out := &replayResult{Events: make([]replayedEvent, 0, len(result.Events)), Failed: result.Failed, Truncated: result.Truncated}
+	for _, replayed := range result.Events {
+		e := replayedEvent{
+			ID:         replayed.Event.ID,
+			Provider:   replayed.Event.Provider,
+			EventID:    replayed.Event.EventID,
+			EventType:  replayed.Event.EventType,
+			ReceivedAt: replayed.Event.ReceivedAt,
+			Status:     "replayed",
+		}
+		switch {
+		case replayed.Err != nil:
+			e.Status = "failed"
+			e.Error = replayed.Err.Error()
+		case dryRun:
+			e.Status = "previewed"
+			for _, n := range replayed.Notifications {
+				e.Notifications = append(e.Notifications, previewedNotification{
+					Channel:   n.Target.Channel,
+					Recipient: n.Target.Recipient,
+					Locale:    n.Locale,
+					Text:      n.Text,
+				})
+			}
+		}
+		out.Events = append(out.Events, e)
+	}
+
+	return out
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strconv"
+	"time"
+
+	"athylps/internal/repositories"
+	"athylps/internal/services"
+	"athylps/internal/usecases"
+)
+
+var revenueCommand = &command{
+	name:    "revenue",
+	usage:   "[-from TIME] [-to TIME] [-tz ZONE]",
+	summary: "Print revenue of a period, the current month by default",
+	run:     runRevenue,
+}
+
+type revenueTotal struct {
+	Name    string  `json:"name"`
+	Count   int     `json:"count"`
+	Revenue float64 `json:"revenue"`
+}
+
+type revenueReport struct {
+	From          time.Time      `json:"from"`
+	To            time.Time      `json:"to"`
+	Revenue       float64        `json:"revenue"` // USD, purchases and renewals
+	Purchases     revenueTotal   `json:"purchases"`
+	Renewals      revenueTotal   `json:"renewals"`
+	Refunds       revenueTotal   `json:"refunds"`
+	Cancellations int            `json:"cancellations"`
+	ByStore       []revenueTotal `json:"by_store"`
+	ByProduct     []revenueTotal `json:"by_product"`
+	ByCountry     []revenueTotal `json:"by_country"`
+}
+
+func runRevenue(ctx context.Context, env *env, args []string) error {
+	fs := flag.NewFlagSet("revenue", flag.ContinueOnError)
+	fromFlag := fs.String("from", "", "start of the period, RFC 3339 or YYYY-MM-DD, the first day of the current month by default")
+	toFlag := fs.String("to", "", "end of the period, exclusive, now by default")
+	tz := fs.String("tz", env.cfg.Digest.Timezone, "timezone of dates")
+	if _, err := parseArgs(fs, args, 0); err != nil {
+		return err
+	}
+
+	location, err := time.LoadLocation(*tz)
+	if err != nil {
+		return fmt.Errorf("invalid timezone %q: %w", *tz, err)
+	}
+	now := time.Now().In(location)
+	from := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, location)
+	to := now
+	if t, err := parseTimeIn(*fromFlag, location); err != nil {
+		return err
+	} else if t != nil {
+		from = *t
+	}
+	if t, err := parseTimeIn(*toFlag, location); err != nil {
+		return err
+	} else if t != nil {
+		to = *t
+	}
+
+	db, err := env.db(ctx)
+	if err != nil {
+		return err
+	}
+	data, err := usecases.NewGetRevenueUsecase(repositories.NewPurchaseEventRepository(db)).Perform(ctx, from, to)
+	if err != nil {
+		return err
+	}
+
+	report := &revenueReport{
+		From:          from,
+		To:            to,
+		Revenue:       data.Revenue,
+		Purchases:     toRevenueTotal(data.Purchases),
+		Renewals:      toRevenueTotal(data.Renewals),
+		Refunds:       toRevenueTotal(data.Refunds),
+		Cancellations: data.Cancellations,
+		ByStore:       toRevenueTotals(data.ByStore),
+		ByProduct:     toRevenueTotals(data.ByProduct),
+		ByCountry:     toRevenueTotals(data.ByCountry),
+	}
+	if env.out.format == formatJSON {
+		return env.out.write(report, nil, nil)
+	}
+
+	fmt.Fprintf(env.out.w, "Revenue from %s to %s\n\n", from.Format(time.RFC3339), to.Format(time.RFC3339))
+	rows := [][]string{
+		totalRow("Purchases", report.Purchases),
+		totalRow("Renewals", report.Renewals),
+		totalRow("Refunds", report.Refunds),
+		{"Cancellations", strconv.Itoa(report.Cancellations), "-"},
+		{"Revenue", "-", money(report.Revenue)},
+	}
+	if err := env.out.write(nil, []string{"", "COUNT", "USD"}, rows); err != nil {
+		return err
+	}
+	for _, breakdown := range []struct {
+		name   string
+		totals []revenueTotal
+	}{
+		{"STORE", report.ByStore},
+		{"PRODUCT", report.ByProduct},
+		{"COUNTRY", report.ByCountry},
+	} {
+		if len(breakdown.totals) == 0 {
+			continue
+		}
+		rows := make([][]string, 0, len(breakdown.totals))
+		for _, total := range breakdown.totals {
+			rows = append(rows, totalRow(cell(&total.Name), total))
+		}
+		fmt.Fprintln(env.out.w)
+		if err := env.out.write(nil, []string{breakdown.name, "COUNT", "USD"}, rows); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func toRevenueTotal(total services.DigestTotal) revenueTotal {
+	return revenueTotal{Name: total.Name, Count: total.Count, Revenue: total.Revenue}
+}
+
+func toRevenueTotals(totals []services.DigestTotal) []revenueTotal {
+	converted := make([]revenueTotal, 0, len(totals))
+	for _, total := range totals {
+		converted = append(converted, toRevenueTotal(total))
+	}
+	return converted
+}
+
+func totalRow(name string, total revenueTotal) []string {
+	return []string{name, strconv.Itoa(total.Count), money(total.Revenue)}
+}
+
+func money(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', 2, 64)
+}
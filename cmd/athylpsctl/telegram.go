@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"time"
+
+	"athylps/internal/services"
+)
+
+var telegramTestCommand = &command{
+	name:    "telegram test",
+	usage:   "[-chat ID] [-text TEXT]",
+	summary: "Send a test Telegram message to check the bot token and the chat",
+	run:     runTelegramTest,
+}
+
+type telegramTestResult struct {
+	ChatID string    `json:"chat_id"`
+	SentAt time.Time `json:"sent_at"`
+}
+
+func runTelegramTest(ctx context.Context, env *env, args []string) error {
+	fs := flag.NewFlagSet("telegram test", flag.ContinueOnError)
+	chatID := fs.String("chat", env.cfg.Telegram.NotifyChatID, "chat to send the message to")
+	text := fs.String("text", "", "text of the message, Telegram HTML")
+	if _, err := parseArgs(fs, args, 0); err != nil {
+		return err
+	}
+	if *text == "" {
+		*text = fmt.Sprintf("Test message from <b>athylpsctl</b> (%s)", env.cfg.Server.Env)
+	}
+
+	notifier := services.NewTgNotifierService(&env.cfg.Telegram, env.logger)
+	if err := notifier.Send(ctx, *chatID, *text); err != nil {
+		return err
+	}
+
+	result := &telegramTestResult{ChatID: *chatID, SentAt: time.Now().UTC()}
+	return env.out.write(result, []string{"CHAT", "SENT AT"}, [][]string{{result.ChatID, result.SentAt.Format(time.RFC3339)}})
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"time"
+
+	"athylps/internal/models"
+	"athylps/internal/repositories"
+
+	"go.uber.org/zap"
+)
+
+var usersLookupCommand = &command{
+	name:    "users lookup",
+	usage:   "QUERY",
+	summary: "Find users by id, email, Firebase uid or RevenueCat app user id with their subscriptions",
+	run:     runUsersLookup,
+}
+
+var usersListCommand = &command{
+	name:    "users list",
+	usage:   "[-email TEXT] [-locale LOCALE] [-from TIME] [-to TIME] [-deleted] [-limit N] [-offset N]",
+	summary: "List users, the newest first",
+	run:     runUsersList,
+}
+
+var usersDeleteCommand = &command{
+	name:    "users delete",
+	usage:   "ID",
+	summary: "Soft delete a user",
+	run:     runUsersDelete,
+}
+
+var usersRestoreCommand = &command{
+	name:    "users restore",
+	usage:   "ID",
+	summary: "Restore a soft deleted user",
+	run:     runUsersRestore,
+}
+
+type userView struct {
+	ID            string              `json:"id"`
+	Email         *string             `json:"email"`
+	FirebaseUID   *string             `json:"firebase_uid"`
+	RevenueCatID  *string             `json:"revenuecat_id"`
+	Locale        *string             `json:"locale"`
+	Timezone      *string             `json:"timezone"`
+	CreatedAt     *time.Time          `json:"created_at"`
+	DeletedAt     *time.Time          `json:"deleted_at"`
+	Subscriptions []*subscriptionView `json:"subscriptions,omitempty"`
+}
+
+type subscriptionView struct {
+	AppUserID      string     `json:"app_user_id"`
+	ProductID      string     `json:"product_id"`
+	EntitlementIDs []string   `json:"entitlement_ids"`
+	Store          string     `json:"store"`
+	Environment    *string    `json:"environment"`
+	Status         string     `json:"status"`
+	PurchasedAt    *time.Time `json:"purchased_at"`
+	ExpiresAt      *time.Time `json:"expires_at"`
+	LastEventType  string     `json:"last_event_type"`
+	LastEventAt    *time.Time `json:"last_event_at"`
+}
+
+var userHeader = []string{"ID", "EMAIL", "FIREBASE UID", "REVENUECAT ID", "LOCALE", "CREATED AT", "DELETED AT"}
+
+func toUserView(u *models.User) *userView {
+	return &userView{
+		ID:           u.ID,
+		Email:        u.Email,
+		FirebaseUID:  u.FirebaseUID,
+		RevenueCatID: u.RevenueCatID,
+		Locale:       u.Locale,
+		Timezone:     u.Timezone,
+		CreatedAt:    u.CreatedAt,
+		DeletedAt:    u.DeletedAt,
+	}
+}
+
+func (v *userView) row() []string {
+	return []string{v.ID, cell(v.Email), cell(v.FirebaseUID), cell(v.RevenueCatID), cell(v.Locale), cell(v.CreatedAt), cell(v.DeletedAt)}
+}
+
+func toSubscriptionView(s *models.Subscription) *subscriptionView {
+	return &subscriptionView{
+		AppUserID:      s.AppUserID,
+		ProductID:      s.ProductID,
+		EntitlementIDs: s.EntitlementIDs,
+		Store:          s.Store,
+		Environment:    s.Environment,
+		Status:         string(s.Status),
+		PurchasedAt:    s.PurchasedAt,
+		ExpiresAt:      s.ExpiresAt,
+		LastEventType:  s.LastEventType,
+		LastEventAt:    s.LastEventAt,
+	}
+}
+
+func writeUsers(env *env, users []*userView) error {
+	rows := make([][]string, 0, len(users))
+	for _, u := range users {
+		rows = append(rows, u.row())
+	}
+
+	return env.out.write(users, userHeader, rows)
+}
+
+func runUsersLookup(ctx context.Context, env *env, args []string) error {
+	query, err := parseArgs(flag.NewFlagSet("users lookup", flag.ContinueOnError), args, 1)
+	if err != nil {
+		return err
+	}
+
+	db, err := env.db(ctx)
+	if err != nil {
+		return err
+	}
+	found, err := repositories.NewUserRepository(db).FindUsers(ctx, query[0])
+	if err != nil {
+		return err
+	}
+	if len(found) == 0 {
+		return fmt.Errorf("no users found by %q", query[0])
+	}
+
+	subscriptionRepository := repositories.NewSubscriptionRepository(db)
+	users := make([]*userView, 0, len(found))
+	for _, u := range found {
+		subscriptions, err := subscriptionRepository.ListByUserID(ctx, u.ID)
+		if err != nil {
+			return err
+		}
+		view := toUserView(u)
+		for _, s := range subscriptions {
+			view.Subscriptions = append(view.Subscriptions, toSubscriptionView(s))
+		}
+		users = append(users, view)
+	}
+
+	if env.out.format == formatJSON {
+		return env.out.write(users, nil, nil)
+	}
+	for i, u := range users {
+		if i > 0 {
+			fmt.Fprintln(env.out.w)
+		}
+		if err := env.out.write(nil, userHeader, [][]string{u.row()}); err != nil {
+			return err
+		}
+		if len(u.Subscriptions) == 0 {
+			continue
+		}
+
+		rows := make([][]string, 0, len(u.Subscriptions))
+		for _, s := range u.Subscriptions {
+			rows = append(rows, []string{s.AppUserID, s.ProductID, fmt.Sprint(s.EntitlementIDs), s.Store, cell(s.Environment), s.Status, cell(s.ExpiresAt), s.LastEventType, cell(s.LastEventAt)})
+		}
+		fmt.Fprintln(env.out.w)
+		header := []string{"APP USER ID", "PRODUCT", "ENTITLEMENTS", "STORE", "ENVIRONMENT", "STATUS", "EXPIRES AT", "LAST EVENT", "LAST EVENT AT"}
+		if err := env.out.write(nil, header, rows); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func runUsersList(ctx context.Context, env *env, args []string) error {
+	fs := flag.NewFlagSet("users list", flag.ContinueOnError)
+	email := fs.String("email", "", "part of the email")
+	locale := fs.String("locale", "", "locale of users, e.g. ru-RU")
+	from := fs.String("from", "", "users created at or after the time, RFC 3339 or YYYY-MM-DD")
+	to := fs.String("to", "", "users created before the time, RFC 3339 or YYYY-MM-DD")
+	deleted := fs.Bool("deleted", false, "include deleted users")
+	limit := fs.Uint64("limit", 50, "maximum number of users")
+	offset := fs.Uint64("offset", 0, "number of users to skip")
+	if _, err := parseArgs(fs, args, 0); err != nil {
+		return err
+	}
+
+	params := &repositories.ListUsersParams{IncludeDeleted: *deleted, Limit: *limit, Offset: *offset}
+	if *email != "" {
+		params.EmailContains = email
+	}
+	if *locale != "" {
+		params.Locale = locale
+	}
+	var err error
+	if params.CreatedAfter, err = parseTime(*from); err != nil {
+		return err
+	}
+	if params.CreatedBefore, err = parseTime(*to); err != nil {
+		return err
+	}
+
+	db, err := env.db(ctx)
+	if err != nil {
+		return err
+	}
+	found, err := repositories.NewUserRepository(db).ListUsers(ctx, params)
+	if err != nil {
+		return err
+	}
+
+	users := make([]*userView, 0, len(found))
+	for _, u := range found {
+		users = append(users, toUserView(u))
+	}
+
+	return writeUsers(env, users)
+}
+
+func runUsersDelete(ctx context.Context, env *env, args []string) error {
+	id, err := parseArgs(flag.NewFlagSet("users delete", flag.ContinueOnError), args, 1)
+	if err != nil {
+		return err
+	}
+
+	db, err := env.db(ctx)
+	if err != nil {
+		return err
+	}
+	users := repositories.NewUserRepository(db)
+	if err := users.DeleteUser(ctx, id[0]); err != nil {
+		return err
+	}
+	env.logger.Info("deleted user", zap.String("user_id", id[0]), zap.String("actor", actor()))
+
+	found, err := users.FindUsers(ctx, id[0])
+	if err != nil {
+		return err
+	}
+
+	return writeUsers(env, []*userView{toUserView(found[0])})
+}
+
+func runUsersRestore(ctx context.Context, env *env, args []string) error {
+	id, err := parseArgs(flag.NewFlagSet("users restore", flag.ContinueOnError), args, 1)
+	if err != nil {
+		return err
+	}
+
+	db, err := env.db(ctx)
+	if err != nil {
+		return err
+	}
+	restored, err := repositories.NewUserRepository(db).RestoreUser(ctx, id[0])
+	if err != nil {
+		return err
+	}
+	env.logger.Info("restored user", zap.String("user_id", id[0]), zap.String("actor", actor()))
+
+	return writeUsers(env, []*userView{toUserView(restored)})
+}
@@ -3,10 +3,43 @@
 // Code generated by github.com/oapi-codegen/oapi-codegen/v2 version v2.5.1 DO NOT EDIT.
 package api
 
+import (
+	"time"
+)
+
 const (
 	BearerAuthScopes = "bearerAuth.Scopes"
 )
 
+// Defines values for AdminSubscriptionStatus.
+const (
+	Active      AdminSubscriptionStatus = "active"
+	Cancelled   AdminSubscriptionStatus = "cancelled"
+	Expired     AdminSubscriptionStatus = "expired"
+	GracePeriod AdminSubscriptionStatus = "grace_period"
+	Paused      AdminSubscriptionStatus = "paused"
+)
+
+// Defines values for PreviewedNotificationChannel.
+const (
+	Email    PreviewedNotificationChannel = "email"
+	Telegram PreviewedNotificationChannel = "telegram"
+	Webhook  PreviewedNotificationChannel = "webhook"
+)
+
+// Defines values for ReplayWebhookEventsRequestProvider.
+const (
+	ReplayWebhookEventsRequestProviderRevenuecat ReplayWebhookEventsRequestProvider = "revenuecat"
+	ReplayWebhookEventsRequestProviderRustore    ReplayWebhookEventsRequestProvider = "rustore"
+)
+
+// Defines values for ReplayedWebhookEventStatus.
+const (
+	Failed    ReplayedWebhookEventStatus = "failed"
+	Previewed ReplayedWebhookEventStatus = "previewed"
+	Replayed  ReplayedWebhookEventStatus = "replayed"
+)
+
 // Defines values for RevenueCatWebhookEventEventEnvironment.
 const (
 	PRODUCTION RevenueCatWebhookEventEventEnvironment = "PRODUCTION"
@@ -56,6 +89,184 @@ const (
 	Success WebhookResponseStatus = "success"
 )
 
+// Defines values for ListWebhookEventsParamsProvider.
+const (
+	ListWebhookEventsParamsProviderRevenuecat ListWebhookEventsParamsProvider = "revenuecat"
+	ListWebhookEventsParamsProviderRustore    ListWebhookEventsParamsProvider = "rustore"
+)
+
+// AccountDeletionResponse defines model for AccountDeletionResponse.
+type AccountDeletionResponse struct {
+	DeletedAt time.Time `json:"deleted_at"`
+
+	// PurgeAfter Time personal data is purged after
+	PurgeAfter time.Time `json:"purge_after"`
+}
+
+// AdminAction Recorded call of the admin API
+type AdminAction struct {
+	// AdminUid Firebase uid of the admin
+	AdminUid  string    `json:"admin_uid"`
+	CreatedAt time.Time `json:"created_at"`
+	Id        string    `json:"id"`
+	Method    string    `json:"method"`
+	Path      string    `json:"path"`
+
+	// Request Body of the request, absent if it wasn't JSON
+	Request *map[string]interface{} `json:"request,omitempty"`
+	Route   string                  `json:"route"`
+
+	// Status Status of the response
+	Status int `json:"status"`
+}
+
+// AdminActionsResponse defines model for AdminActionsResponse.
+type AdminActionsResponse struct {
+	Actions []AdminAction `json:"actions"`
+}
+
+// AdminPurchaseEvent defines model for AdminPurchaseEvent.
+type AdminPurchaseEvent struct {
+	CountryCode *string   `json:"country_code,omitempty"`
+	Environment *string   `json:"environment,omitempty"`
+	EventType   string    `json:"event_type"`
+	Id          string    `json:"id"`
+	OccurredAt  time.Time `json:"occurred_at"`
+
+	// Price Price in USD
+	Price     *float64 `json:"price,omitempty"`
+	ProductId *string  `json:"product_id,omitempty"`
+	Store     string   `json:"store"`
+
+	// WebhookEventId Webhook event the purchase event was reported by
+	WebhookEventId string `json:"webhook_event_id"`
+}
+
+// AdminSubscription Current state of a store subscription or a manual grant
+type AdminSubscription struct {
+	AppUserId      string     `json:"app_user_id"`
+	CancelledAt    *time.Time `json:"cancelled_at,omitempty"`
+	EntitlementIds []string   `json:"entitlement_ids"`
+	Environment    *string    `json:"environment,omitempty"`
+
+	// ExpiresAt End of the current period, absent for lifetime purchases and grants
+	ExpiresAt            *time.Time `json:"expires_at,omitempty"`
+	GracePeriodExpiresAt *time.Time `json:"grace_period_expires_at,omitempty"`
+	LastEventAt          *time.Time `json:"last_event_at,omitempty"`
+
+	// LastEventType Type of the latest event that changed the subscription
+	LastEventType string                  `json:"last_event_type"`
+	ProductId     string                  `json:"product_id"`
+	PurchasedAt   *time.Time              `json:"purchased_at,omitempty"`
+	Status        AdminSubscriptionStatus `json:"status"`
+
+	// Store Store of the purchase, `MANUAL` for entitlements granted by admins
+	Store string `json:"store"`
+}
+
+// AdminSubscriptionStatus defines model for AdminSubscription.Status.
+type AdminSubscriptionStatus string
+
+// AdminUser User as admins see it
+type AdminUser struct {
+	CreatedAt time.Time `json:"created_at"`
+
+	// DeletedAt Time the user was deleted, absent for active users
+	DeletedAt   *time.Time `json:"deleted_at,omitempty"`
+	Email       *string    `json:"email,omitempty"`
+	FirebaseUid *string    `json:"firebase_uid,omitempty"`
+
+	// Id Internal user id (UUID)
+	Id     string  `json:"id"`
+	Locale *string `json:"locale,omitempty"`
+
+	// RevenuecatId RevenueCat app user id linked to the user
+	RevenuecatId *string   `json:"revenuecat_id,omitempty"`
+	Timezone     *string   `json:"timezone,omitempty"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// AdminUserDetails defines model for AdminUserDetails.
+type AdminUserDetails struct {
+	// Entitlements Entitlements the user has right now
+	Entitlements []Entitlement `json:"entitlements"`
+
+	// PurchaseEvents The latest purchase events of the user, the most recent first
+	PurchaseEvents []AdminPurchaseEvent `json:"purchase_events"`
+
+	// Subscriptions All subscriptions of the user, expired ones and manual grants included
+	Subscriptions []AdminSubscription `json:"subscriptions"`
+
+	// User User as admins see it
+	User AdminUser `json:"user"`
+}
+
+// AdminUsersResponse defines model for AdminUsersResponse.
+type AdminUsersResponse struct {
+	Users []AdminUser `json:"users"`
+}
+
+// AdminWebhookEvent Received webhook event
+type AdminWebhookEvent struct {
+	Environment *string `json:"environment,omitempty"`
+
+	// EventId Id of the event assigned by the provider
+	EventId   string `json:"event_id"`
+	EventType string `json:"event_type"`
+
+	// Id Internal id of the event
+	Id string `json:"id"`
+
+	// Payload Payload of the event, only returned for a single event
+	Payload     *map[string]interface{} `json:"payload,omitempty"`
+	ProcessedAt *time.Time              `json:"processed_at,omitempty"`
+	Provider    string                  `json:"provider"`
+	ReceivedAt  time.Time               `json:"received_at"`
+
+	// UserId User the event was attributed to when it was processed
+	UserId *string `json:"user_id,omitempty"`
+}
+
+// AdminWebhookEventsResponse defines model for AdminWebhookEventsResponse.
+type AdminWebhookEventsResponse struct {
+	Events []AdminWebhookEvent `json:"events"`
+}
+
+// Entitlement Active entitlement of the user
+type Entitlement struct {
+	// ExpiresAt End of the current period, absent for lifetime purchases
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+
+	// GracePeriodExpiresAt End of the grace period, set when the entitlement is in grace period
+	GracePeriodExpiresAt *time.Time `json:"grace_period_expires_at,omitempty"`
+
+	// Id Entitlement identifier
+	Id string `json:"id"`
+
+	// IsInGracePeriod Renewal has failed, the entitlement stays active until the grace period ends
+	IsInGracePeriod bool `json:"is_in_grace_period"`
+
+	// IsSandbox Purchased in a sandbox environment
+	IsSandbox bool `json:"is_sandbox"`
+
+	// ProductId Product that grants the entitlement
+	ProductId string `json:"product_id"`
+
+	// PurchasedAt Time of the latest purchase or renewal
+	PurchasedAt *time.Time `json:"purchased_at,omitempty"`
+
+	// Store Store the product was purchased in
+	Store string `json:"store"`
+
+	// WillRenew The subscription renews automatically at the end of the period
+	WillRenew bool `json:"will_renew"`
+}
+
+// EntitlementsResponse defines model for EntitlementsResponse.
+type EntitlementsResponse struct {
+	Entitlements []Entitlement `json:"entitlements"`
+}
+
 // ErrorResponse Error response structure
 type ErrorResponse struct {
 	// Details Additional error details
@@ -68,10 +279,163 @@ type ErrorResponse struct {
 	Message *string `json:"message,omitempty"`
 }
 
+// ExportedDevice App user id the app has logged in to RevenueCat with, anonymous ones identify an installation of the app
+type ExportedDevice struct {
+	AppUserId string     `json:"app_user_id"`
+	LinkedAt  *time.Time `json:"linked_at,omitempty"`
+}
+
+// ExportedProfile defines model for ExportedProfile.
+type ExportedProfile struct {
+	CreatedAt    *time.Time `json:"created_at,omitempty"`
+	Email        *string    `json:"email,omitempty"`
+	FirebaseUid  *string    `json:"firebase_uid,omitempty"`
+	Id           string     `json:"id"`
+	Locale       *string    `json:"locale,omitempty"`
+	RevenuecatId *string    `json:"revenuecat_id,omitempty"`
+	Timezone     *string    `json:"timezone,omitempty"`
+	UpdatedAt    *time.Time `json:"updated_at,omitempty"`
+}
+
+// ExportedPurchase defines model for ExportedPurchase.
+type ExportedPurchase struct {
+	CountryCode *string `json:"country_code,omitempty"`
+
+	// Currency ISO 4217 code of the currency of the purchase
+	Currency   *string   `json:"currency,omitempty"`
+	EventType  string    `json:"event_type"`
+	OccurredAt time.Time `json:"occurred_at"`
+
+	// Price Price in USD
+	Price                    *float64 `json:"price,omitempty"`
+	PriceInPurchasedCurrency *float64 `json:"price_in_purchased_currency,omitempty"`
+	ProductId                *string  `json:"product_id,omitempty"`
+	Store                    string   `json:"store"`
+}
+
+// ExportedStoreEvent defines model for ExportedStoreEvent.
+type ExportedStoreEvent struct {
+	EventType string `json:"event_type"`
+
+	// Payload The event as the store has sent it
+	Payload    *map[string]interface{} `json:"payload,omitempty"`
+	Provider   string                  `json:"provider"`
+	ReceivedAt time.Time               `json:"received_at"`
+}
+
+// ExportedSubscription defines model for ExportedSubscription.
+type ExportedSubscription struct {
+	CancelledAt    *time.Time `json:"cancelled_at,omitempty"`
+	EntitlementIds []string   `json:"entitlement_ids"`
+	Environment    *string    `json:"environment,omitempty"`
+	ExpiresAt      *time.Time `json:"expires_at,omitempty"`
+	ProductId      string     `json:"product_id"`
+	PurchasedAt    *time.Time `json:"purchased_at,omitempty"`
+	Status         string     `json:"status"`
+	Store          string     `json:"store"`
+}
+
+// GrantEntitlementRequest defines model for GrantEntitlementRequest.
+type GrantEntitlementRequest struct {
+	EntitlementId string `json:"entitlement_id"`
+
+	// ExpiresAt Time the grant expires, the entitlement is granted forever if absent
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// LinkRevenueCatCustomerRequest defines model for LinkRevenueCatCustomerRequest.
+type LinkRevenueCatCustomerRequest struct {
+	// AppUserId App user id the app has logged in to RevenueCat with, the user's id or Firebase uid
+	AppUserId string `json:"app_user_id"`
+}
+
+// PreviewedNotification defines model for PreviewedNotification.
+type PreviewedNotification struct {
+	Channel PreviewedNotificationChannel `json:"channel"`
+	Locale  *string                      `json:"locale,omitempty"`
+
+	// Recipient Chat id, webhook name or email address
+	Recipient string `json:"recipient"`
+
+	// Text Rendered text of the notification
+	Text string `json:"text"`
+}
+
+// PreviewedNotificationChannel defines model for PreviewedNotification.Channel.
+type PreviewedNotificationChannel string
+
+// ReplayWebhookEventsRequest Events to replay, either ids or a time range is required
+type ReplayWebhookEventsRequest struct {
+	// DryRun Show what would be sent without changing anything
+	DryRun *bool `json:"dry_run,omitempty"`
+
+	// EventTypes Types of the events, e.g. INITIAL_PURCHASE
+	EventTypes *[]string `json:"event_types,omitempty"`
+
+	// From Replay events received at or after the time
+	From *time.Time `json:"from,omitempty"`
+
+	// Ids Internal ids of the events
+	Ids *[]string `json:"ids,omitempty"`
+
+	// Limit Maximum number of events to replay, 1000 by default
+	Limit *int `json:"limit,omitempty"`
+
+	// Provider Provider of the events
+	Provider *ReplayWebhookEventsRequestProvider `json:"provider,omitempty"`
+
+	// SuppressNotifications Rebuild state without sending notifications again
+	SuppressNotifications *bool `json:"suppress_notifications,omitempty"`
+
+	// To Replay events received before the time
+	To *time.Time `json:"to,omitempty"`
+}
+
+// ReplayWebhookEventsRequestProvider Provider of the events
+type ReplayWebhookEventsRequestProvider string
+
+// ReplayWebhookEventsResponse defines model for ReplayWebhookEventsResponse.
+type ReplayWebhookEventsResponse struct {
+	Events []ReplayedWebhookEvent `json:"events"`
+
+	// Failed Number of events that failed to replay
+	Failed int `json:"failed"`
+
+	// Truncated More events match the request than have been replayed
+	Truncated bool `json:"truncated"`
+}
+
+// ReplayedWebhookEvent defines model for ReplayedWebhookEvent.
+type ReplayedWebhookEvent struct {
+	// Error Why the event failed to replay
+	Error *string `json:"error,omitempty"`
+
+	// EventId Id of the event assigned by the provider
+	EventId   string `json:"event_id"`
+	EventType string `json:"event_type"`
+
+	// Id Internal id of the event
+	Id string `json:"id"`
+
+	// Notifications Notifications that would be sent, only in dry run
+	Notifications *[]PreviewedNotification `json:"notifications,omitempty"`
+	Provider      string                   `json:"provider"`
+	ReceivedAt    time.Time                `json:"received_at"`
+
+	// Status Outcome of the replay, `previewed` in dry run
+	Status ReplayedWebhookEventStatus `json:"status"`
+}
+
+// ReplayedWebhookEventStatus Outcome of the replay, `previewed` in dry run
+type ReplayedWebhookEventStatus string
+
 // RevenueCatWebhookEvent RevenueCat webhook event payload
 type RevenueCatWebhookEvent struct {
 	// Event Event details
 	Event struct {
+		// Aliases All app user ids ever used by the customer
+		Aliases *[]string `json:"aliases,omitempty"`
+
 		// AppId Unique identifier of the app the event is associated with. Corresponds to an app within a project
 		AppId string `json:"app_id"`
 
@@ -87,15 +451,27 @@ type RevenueCatWebhookEvent struct {
 		// Currency ISO 4217 currency code
 		Currency *string `json:"currency,omitempty"`
 
+		// EntitlementIds Entitlements granted by the product
+		EntitlementIds *[]string `json:"entitlement_ids"`
+
 		// Environment Environment where the event occurred
 		Environment RevenueCatWebhookEventEventEnvironment `json:"environment"`
 
+		// EventTimestampMs Time when the event was generated in milliseconds
+		EventTimestampMs *int64 `json:"event_timestamp_ms,omitempty"`
+
 		// ExpirationAtMs Expiration timestamp in milliseconds
 		ExpirationAtMs *int64 `json:"expiration_at_ms,omitempty"`
 
+		// GracePeriodExpirationAtMs Grace period expiration timestamp in milliseconds (for BILLING_ISSUE events)
+		GracePeriodExpirationAtMs *int64 `json:"grace_period_expiration_at_ms,omitempty"`
+
 		// Id Unique identifier of the event
 		Id string `json:"id"`
 
+		// OriginalAppUserId The first app user id used by the customer
+		OriginalAppUserId *string `json:"original_app_user_id,omitempty"`
+
 		// PeriodType Period type of the transaction
 		PeriodType *RevenueCatWebhookEventEventPeriodType `json:"period_type,omitempty"`
 
@@ -117,6 +493,12 @@ type RevenueCatWebhookEvent struct {
 		// Store Store where the purchase was made
 		Store RevenueCatWebhookEventEventStore `json:"store"`
 
+		// TransferredFrom App user ids the transaction was transferred from (for TRANSFER events)
+		TransferredFrom *[]string `json:"transferred_from,omitempty"`
+
+		// TransferredTo App user ids the transaction was transferred to (for TRANSFER events)
+		TransferredTo *[]string `json:"transferred_to,omitempty"`
+
 		// Type Type of RevenueCat event
 		Type RevenueCatWebhookEventEventType `json:"type"`
 	} `json:"event"`
@@ -136,10 +518,48 @@ type RevenueCatWebhookEventEventType string
 
 // RuStoreWebhookEvent RuStore purchase notification event
 type RuStoreWebhookEvent struct {
-	// Payload Encrypt payload of the event
+	// Payload Base64 encoded AES-GCM encrypted payload of the event, prefixed with a 12 byte nonce
 	Payload string `json:"payload"`
 }
 
+// User User profile
+type User struct {
+	// CreatedAt Time the user was created
+	CreatedAt time.Time `json:"created_at"`
+
+	// Email Email of the user, absent for users signed in without email
+	Email *string `json:"email,omitempty"`
+
+	// Id Internal user id (UUID)
+	Id string `json:"id"`
+
+	// Locale Preferred locale of the user
+	Locale *string `json:"locale,omitempty"`
+
+	// RevenuecatId RevenueCat app user id linked to the user
+	RevenuecatId *string `json:"revenuecat_id,omitempty"`
+
+	// Timezone IANA timezone of the user
+	Timezone *string `json:"timezone,omitempty"`
+
+	// UpdatedAt Time the user was last updated
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// UserDataExport Everything we hold about the user
+type UserDataExport struct {
+	Devices    []ExportedDevice `json:"devices"`
+	ExportedAt time.Time        `json:"exported_at"`
+	Profile    ExportedProfile  `json:"profile"`
+
+	// Purchases Purchases, renewals, refunds and cancellations, the most recent first
+	Purchases []ExportedPurchase `json:"purchases"`
+
+	// StoreEvents Events stores have sent about the user's purchases, in the order they were received
+	StoreEvents   []ExportedStoreEvent   `json:"store_events"`
+	Subscriptions []ExportedSubscription `json:"subscriptions"`
+}
+
 // WebhookResponse Successful webhook response
 type WebhookResponse struct {
 	// Message Human-readable message
@@ -152,8 +572,132 @@ type WebhookResponse struct {
 // WebhookResponseStatus Status of the webhook processing
 type WebhookResponseStatus string
 
+// AcceptLanguage defines model for AcceptLanguage.
+type AcceptLanguage = string
+
+// Limit defines model for Limit.
+type Limit = int
+
+// Offset defines model for Offset.
+type Offset = int
+
+// Timezone defines model for Timezone.
+type Timezone = string
+
+// UserID defines model for UserID.
+type UserID = string
+
+// BadRequest Error response structure
+type BadRequest = ErrorResponse
+
+// Forbidden Error response structure
+type Forbidden = ErrorResponse
+
+// InternalServerError Error response structure
+type InternalServerError = ErrorResponse
+
+// NotFound Error response structure
+type NotFound = ErrorResponse
+
+// Unauthorized Error response structure
+type Unauthorized = ErrorResponse
+
+// ListAdminActionsParams defines parameters for ListAdminActions.
+type ListAdminActionsParams struct {
+	// AdminUid Firebase uid of the admin
+	AdminUid *string `form:"admin_uid,omitempty" json:"admin_uid,omitempty"`
+
+	// From Actions made at or after the time
+	From *time.Time `form:"from,omitempty" json:"from,omitempty"`
+
+	// To Actions made before the time
+	To *time.Time `form:"to,omitempty" json:"to,omitempty"`
+
+	// Limit Maximum number of items
+	Limit *Limit `form:"limit,omitempty" json:"limit,omitempty"`
+
+	// Offset Number of items to skip
+	Offset *Offset `form:"offset,omitempty" json:"offset,omitempty"`
+}
+
+// SearchUsersParams defines parameters for SearchUsers.
+type SearchUsersParams struct {
+	// Query Id, email, Firebase uid or RevenueCat app user id of the user, the other filters are ignored if set
+	Query *string `form:"query,omitempty" json:"query,omitempty"`
+
+	// Email Part of the email, case insensitive
+	Email  *string `form:"email,omitempty" json:"email,omitempty"`
+	Locale *string `form:"locale,omitempty" json:"locale,omitempty"`
+
+	// CreatedAfter Users created at or after the time
+	CreatedAfter *time.Time `form:"created_after,omitempty" json:"created_after,omitempty"`
+
+	// CreatedBefore Users created before the time
+	CreatedBefore  *time.Time `form:"created_before,omitempty" json:"created_before,omitempty"`
+	IncludeDeleted *bool      `form:"include_deleted,omitempty" json:"include_deleted,omitempty"`
+
+	// Limit Maximum number of items
+	Limit *Limit `form:"limit,omitempty" json:"limit,omitempty"`
+
+	// Offset Number of items to skip
+	Offset *Offset `form:"offset,omitempty" json:"offset,omitempty"`
+}
+
+// ListWebhookEventsParams defines parameters for ListWebhookEvents.
+type ListWebhookEventsParams struct {
+	Provider *ListWebhookEventsParamsProvider `form:"provider,omitempty" json:"provider,omitempty"`
+
+	// EventType Types of the events, comma separated
+	EventType *string `form:"event_type,omitempty" json:"event_type,omitempty"`
+
+	// UserId Events attributed to the user
+	UserId *string `form:"user_id,omitempty" json:"user_id,omitempty"`
+
+	// From Events received at or after the time
+	From *time.Time `form:"from,omitempty" json:"from,omitempty"`
+
+	// To Events received before the time
+	To *time.Time `form:"to,omitempty" json:"to,omitempty"`
+
+	// Limit Maximum number of items
+	Limit *Limit `form:"limit,omitempty" json:"limit,omitempty"`
+
+	// Offset Number of items to skip
+	Offset *Offset `form:"offset,omitempty" json:"offset,omitempty"`
+}
+
+// ListWebhookEventsParamsProvider defines parameters for ListWebhookEvents.
+type ListWebhookEventsParamsProvider string
+
+// HandleDonationAlertsWebhookParams defines parameters for HandleDonationAlertsWebhook.
+type HandleDonationAlertsWebhookParams struct {
+	// Code Authorization code
+	Code *string `form:"code,omitempty" json:"code,omitempty"`
+
+	// Error Error code if the authorization was declined
+	Error *string `form:"error,omitempty" json:"error,omitempty"`
+}
+
+// GetMeParams defines parameters for GetMe.
+type GetMeParams struct {
+	// AcceptLanguage Preferred locales of the user, the most preferred one is stored in the profile
+	AcceptLanguage *AcceptLanguage `json:"Accept-Language,omitempty"`
+
+	// XTimezone IANA timezone of the device, stored in the profile
+	XTimezone *Timezone `json:"X-Timezone,omitempty"`
+}
+
+// GrantEntitlementJSONRequestBody defines body for GrantEntitlement for application/json ContentType.
+type GrantEntitlementJSONRequestBody = GrantEntitlementRequest
+
+// ReplayWebhookEventsJSONRequestBody defines body for ReplayWebhookEvents for application/json ContentType.
+type ReplayWebhookEventsJSONRequestBody = ReplayWebhookEventsRequest
+
 // HandleRevenueCatWebhookJSONRequestBody defines body for HandleRevenueCatWebhook for application/json ContentType.
 type HandleRevenueCatWebhookJSONRequestBody = RevenueCatWebhookEvent
 
 // HandleRustoreWebhookJSONRequestBody defines body for HandleRustoreWebhook for application/json ContentType.
 type HandleRustoreWebhookJSONRequestBody = RuStoreWebhookEvent
+
+// LinkRevenueCatCustomerJSONRequestBody defines body for LinkRevenueCatCustomer for application/json ContentType.
+type LinkRevenueCatCustomerJSONRequestBody = LinkRevenueCatCustomerRequest
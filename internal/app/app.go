@@ -3,11 +3,19 @@ package app
 import (
 	"context"
 	"fmt"
-	"log"
+	"net"
 	"net/http"
+	"time"
 
 	"athylps/internal/config"
+	"athylps/internal/handlers/admin"
+	"athylps/internal/handlers/bot"
 	"athylps/internal/handlers/hooks"
+	"athylps/internal/handlers/middlewares"
+	"athylps/internal/handlers/users"
+	"athylps/internal/metrics"
+	"athylps/internal/models"
+	"athylps/internal/repositories"
 	"athylps/internal/services"
 	"athylps/internal/usecases"
 
@@ -15,39 +23,218 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 	"go.uber.org/zap"
 )
 
+// Run builds the app and serves it until the context is cancelled, then shuts it down gracefully.
+// The database pool is owned by the caller and must be closed after Run returns.
 func Run(
+	ctx context.Context,
 	cfg *config.Config,
 	logger *zap.Logger,
 	dbpool *pgxpool.Pool,
-) {
-	app, err := firebase.NewApp(context.Background(), nil)
+) error {
+	app, err := firebase.NewApp(ctx, nil)
 	if err != nil {
-		logger.Fatal("failed to initialize firebase app")
+		return fmt.Errorf("failed to initialize firebase app: %w", err)
 	}
 
 	logger.Info("Initialized Firebase", zap.Any("app", app))
 
+	authClient, err := app.Auth(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to initialize firebase auth client: %w", err)
+	}
+
 	r := chi.NewRouter()
-	r.Use(middleware.Logger)
+	r.Use(middlewares.Tracing)
+	r.Use(middleware.RequestID)
+	r.Use(middlewares.AccessLog(logger))
 	r.Use(middleware.Recoverer)
+	r.Use(middlewares.Metrics)
 
 	r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte("ok"))
 	})
 
+	transactor := repositories.NewTransactor(dbpool)
+	subscriptionRepository := repositories.NewSubscriptionRepository(dbpool)
+	revenueCatCustomerRepository := repositories.NewRevenueCatCustomerRepository(dbpool)
+	userRepository := repositories.NewUserRepository(dbpool)
+	purchaseEventRepository := repositories.NewPurchaseEventRepository(dbpool)
+
+	notificationOutboxRepository := repositories.NewNotificationOutboxRepository(dbpool)
+
 	tgNotifierService := services.NewTgNotifierService(&cfg.Telegram, logger)
-	purchaseNotificationUsecase := usecases.NewSendPurchaseNotificationUsecase(tgNotifierService, logger)
+	webhookClient := &http.Client{Timeout: 10 * time.Second, Transport: otelhttp.NewTransport(http.DefaultTransport)}
+	notifiers := map[string]services.Notifier{
+		models.NotificationChannelTelegram: tgNotifierService,
+		models.NotificationChannelWebhook:  services.NewWebhookNotifierService(cfg.Notify.Webhooks, webhookClient),
+	}
+	if cfg.Notify.SMTP.Enabled() {
+		notifiers[models.NotificationChannelEmail] = services.NewEmailNotifierService(&cfg.Notify.SMTP)
+	}
+	notificationTemplates, err := services.NewNotificationTemplates(&cfg.Notify)
+	if err != nil {
+		return fmt.Errorf("failed to load notification templates: %w", err)
+	}
+	notificationRouter, err := services.NewNotificationRouter(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to configure notification routes: %w", err)
+	}
+	webhooks := newWebhookPipeline(cfg, dbpool, notificationRouter, notificationTemplates, logger)
+	dispatchNotificationsUsecase := usecases.NewDispatchNotificationsUsecase(
+		&usecases.NotificationLimits{
+			MinInterval:    cfg.Notify.MinInterval,
+			BurstThreshold: cfg.Notify.BurstThreshold,
+			BurstWindow:    cfg.Notify.BurstWindow,
+		},
+		transactor,
+		notificationOutboxRepository,
+		services.NewNotifierService(notifiers),
+		notificationTemplates,
+		logger,
+	)
+	workers := []worker{
+		{name: "notifications_dispatcher", interval: cfg.Telegram.DispatchInterval, fn: dispatchNotificationsUsecase.Perform},
+	}
 
-	r.Post("/hooks/revenuecat", hooks.HandleRevenueCatWebHook(&cfg.RevenueCat, logger, purchaseNotificationUsecase))
-	r.Post("/hooks/rustore", hooks.HandleRustoreWebHook(&cfg.Rustore, logger, purchaseNotificationUsecase))
-	r.Get("/hooks/donationalerts", hooks.HandleDonationAlertsWebhook())
+	metricsHandler := metrics.Handler(dbpool)
+	if cfg.Server.MetricsPort == "" {
+		r.Method(http.MethodGet, "/metrics", metricsHandler)
+	} else {
+		// Metrics are still scraped while requests drain, the worker is stopped after them
+		workers = append(workers, worker{name: "metrics_server", interval: 10 * time.Second, fn: func(ctx context.Context) error {
+			return serveInternal(ctx, cfg.Server.MetricsPort, metricsHandler, logger)
+		}})
+	}
+	digestAt, digestLocation, err := cfg.Digest.Schedule()
+	if err != nil {
+		return err
+	}
+	if cfg.Digest.Daily || cfg.Digest.Weekly {
+		sendDigestsUsecase := usecases.NewSendDigestsUsecase(
+			&usecases.DigestSchedule{
+				Daily:    cfg.Digest.Daily,
+				Weekly:   cfg.Digest.Weekly,
+				At:       digestAt,
+				Location: digestLocation,
+				ChatID:   cfg.Digest.ChatID,
+			},
+			transactor,
+			purchaseEventRepository,
+			repositories.NewDigestRunRepository(dbpool),
+			notificationOutboxRepository,
+			notificationTemplates,
+			logger,
+		)
+		workers = append(workers, worker{name: "digests_scheduler", interval: time.Minute, fn: sendDigestsUsecase.Perform})
+	}
 
-	addr := fmt.Sprintf(":%s", cfg.Server.Port)
-	log.Printf("Starting server on %s (environment: %s)", addr, cfg.Server.Env)
-	if err := http.ListenAndServe(addr, r); err != nil {
-		log.Fatalf("Server failed to start: %v", err)
+	if cfg.Telegram.BotCommands {
+		statsBot, err := bot.New(&cfg.Telegram, logger)
+		if err != nil {
+			return err
+		}
+		getRevenueReportUsecase := usecases.NewGetRevenueReportUsecase(digestLocation, purchaseEventRepository, notificationTemplates)
+		statsBot.RegisterHandlerMatchFunc(bot.Command("today"), bot.HandleRevenueReport(logger, getRevenueReportUsecase, services.DigestDaily))
+		statsBot.RegisterHandlerMatchFunc(bot.Command("week"), bot.HandleRevenueReport(logger, getRevenueReportUsecase, services.DigestWeekly))
+		statsBot.RegisterHandlerMatchFunc(bot.Command("mrr"), bot.HandleMRR(logger, usecases.NewGetMRRUsecase(subscriptionRepository, purchaseEventRepository, cfg.Revenue.IncludeSandbox), notificationTemplates))
+		statsBot.RegisterHandlerMatchFunc(bot.Command("user"), bot.HandleUserSummary(logger, usecases.NewGetUserSummaryUsecase(userRepository, subscriptionRepository), notificationTemplates))
+		statsBot.RegisterHandlerMatchFunc(bot.Command("last"), bot.HandleLatestPurchases(logger, usecases.NewListLatestPurchasesUsecase(purchaseEventRepository), notificationTemplates, digestLocation))
+		if cfg.Telegram.BotWebhookURL != "" {
+			r.Post("/hooks/telegram", statsBot.WebhookHandler())
+		}
+		// Listen blocks until shutdown, the interval only matters when it fails to start
+		workers = append(workers, worker{name: "telegram_bot", interval: 10 * time.Second, fn: func(ctx context.Context) error {
+			return bot.Listen(ctx, statsBot, &cfg.Telegram)
+		}})
 	}
+
+	r.Post("/hooks/revenuecat", hooks.HandleRevenueCatWebHook(&cfg.RevenueCat, logger, webhooks.process))
+	rustoreNotificationDecoder, err := services.NewRustoreNotificationDecoder(&cfg.Rustore)
+	if err != nil {
+		return fmt.Errorf("failed to initialize rustore notification decoder: %w", err)
+	}
+
+	r.Post("/hooks/rustore", hooks.HandleRustoreWebHook(&cfg.Rustore, &cfg.Revenue, rustoreNotificationDecoder, logger, webhooks.process))
+
+	donationAlertsClient := services.NewDonationAlertsClient(&cfg.DonationAlerts, &http.Client{Timeout: 30 * time.Second})
+	donationAlertsTokenRepository := repositories.NewDonationAlertsTokenRepository(dbpool)
+	connectDonationAlertsUsecase := usecases.NewConnectDonationAlertsUsecase(cfg.DonationAlerts.ClientSecret, donationAlertsClient, donationAlertsTokenRepository, logger)
+	r.Get("/hooks/donationalerts", hooks.HandleDonationAlertsWebhook(logger, connectDonationAlertsUsecase))
+
+	if cfg.DonationAlerts.Enabled() {
+		pollDonationsUsecase := usecases.NewPollDonationsUsecase(
+			donationAlertsClient,
+			donationAlertsTokenRepository,
+			transactor,
+			repositories.NewDonationRepository(dbpool),
+			notificationOutboxRepository,
+			notificationRouter,
+			notificationTemplates,
+			logger,
+		)
+		workers = append(workers, worker{name: "donationalerts_poller", interval: cfg.DonationAlerts.PollInterval, fn: pollDonationsUsecase.Perform})
+	}
+
+	provisionUserUsecase := usecases.NewProvisionUserUsecase(userRepository, logger)
+	linkRevenueCatCustomerUsecase := usecases.NewLinkRevenueCatCustomerUsecase(revenueCatCustomerRepository, userRepository, logger)
+	getEntitlementsUsecase := usecases.NewGetEntitlementsUsecase(subscriptionRepository)
+	webhookEventRepository := repositories.NewWebhookEventRepository(dbpool)
+	deleteAccountUsecase := usecases.NewDeleteAccountUsecase(
+		cfg.Account.DeletionRetention,
+		transactor,
+		userRepository,
+		revenueCatCustomerRepository,
+		authClient,
+		logger,
+	)
+	exportUserDataUsecase := usecases.NewExportUserDataUsecase(
+		revenueCatCustomerRepository,
+		subscriptionRepository,
+		purchaseEventRepository,
+		webhookEventRepository,
+	)
+	purgeDeletedUsersUsecase := usecases.NewPurgeDeletedUsersUsecase(transactor, userRepository, authClient, logger)
+	workers = append(workers, worker{name: "deleted_users_purger", interval: cfg.Account.PurgeInterval, fn: purgeDeletedUsersUsecase.Perform})
+
+	r.Route("/v1", func(r chi.Router) {
+		r.Use(middlewares.FirebaseAuth(authClient, logger))
+		r.Use(middlewares.ProvisionUser(provisionUserUsecase, logger))
+
+		r.Get("/me", users.HandleGetMe())
+		r.Delete("/me", users.HandleDeleteMe(logger, deleteAccountUsecase))
+		r.Get("/me/export", users.HandleExportMe(logger, exportUserDataUsecase))
+		r.Get("/me/entitlements", users.HandleGetEntitlements(logger, getEntitlementsUsecase))
+		r.Post("/me/revenuecat", users.HandleLinkRevenueCatCustomer(logger, linkRevenueCatCustomerUsecase))
+	})
+
+	adminActionRepository := repositories.NewAdminActionRepository(dbpool)
+	listWebhookEventsUsecase := usecases.NewListWebhookEventsUsecase(webhookEventRepository)
+
+	r.Route("/admin/v1", func(r chi.Router) {
+		// Revoked sessions and disabled admins lose access right away instead of when the token expires
+		r.Use(middlewares.FirebaseAuth(middlewares.NewRevocationChecking(authClient), logger))
+		r.Use(middlewares.RequireAdmin(&cfg.Admin, logger))
+		r.Use(middlewares.RecordAdminActions(adminActionRepository, logger))
+
+		r.Get("/users", admin.HandleSearchUsers(logger, usecases.NewSearchUsersUsecase(userRepository)))
+		r.Get("/users/{id}", admin.HandleGetUserDetails(logger, usecases.NewGetUserDetailsUsecase(userRepository, subscriptionRepository, purchaseEventRepository)))
+		r.Post("/users/{id}/entitlements", admin.HandleGrantEntitlement(logger, usecases.NewGrantEntitlementUsecase(userRepository, subscriptionRepository, logger)))
+		r.Delete("/users/{id}/entitlements/{entitlementID}", admin.HandleRevokeEntitlement(logger, usecases.NewRevokeEntitlementUsecase(subscriptionRepository, logger)))
+		r.Get("/webhook-events", admin.HandleListWebhookEvents(logger, listWebhookEventsUsecase))
+		r.Get("/webhook-events/{id}", admin.HandleGetWebhookEvent(logger, listWebhookEventsUsecase))
+		r.Post("/webhook-events/replay", admin.HandleReplayWebhookEvents(logger, webhooks.replay))
+		r.Get("/actions", admin.HandleListAdminActions(logger, usecases.NewListAdminActionsUsecase(adminActionRepository)))
+	})
+
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%s", cfg.Server.Port))
+	if err != nil {
+		return fmt.Errorf("failed to listen on port %s: %w", cfg.Server.Port, err)
+	}
+	logger.Info("Starting server", zap.String("port", cfg.Server.Port), zap.String("environment", cfg.Server.Env))
+
+	return serve(ctx, newServer(&cfg.Server, r, logger), listener, workers, cfg.Server.ShutdownTimeout, logger)
 }
@@ -0,0 +1,134 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"athylps/internal/config"
+
+	"go.uber.org/zap"
+)
+
+// worker is a background job run every interval for the lifetime of the app.
+type worker struct {
+	name     string
+	interval time.Duration
+	fn       func(ctx context.Context) error
+}
+
+// newServer returns an http.Server with timeouts and request body size limit from the config.
+func newServer(cfg *config.ServerConfig, handler http.Handler, logger *zap.Logger) *http.Server {
+	return &http.Server{
+		Handler:           http.MaxBytesHandler(handler, cfg.MaxBodyBytes),
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		ReadTimeout:       cfg.ReadTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+		ErrorLog:          zap.NewStdLog(logger),
+	}
+}
+
+// serveInternal serves the handler on the port until the context is cancelled, e.g. /metrics
+// on a port that isn't exposed publicly.
+func serveInternal(ctx context.Context, port string, handler http.Handler, logger *zap.Logger) error {
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%s", port))
+	if err != nil {
+		return fmt.Errorf("failed to listen on port %s: %w", port, err)
+	}
+
+	server := &http.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: 5 * time.Second,
+		ErrorLog:          zap.NewStdLog(logger),
+	}
+	// Also stops the shutdown goroutine if the server fails
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+
+	logger.Info("started internal server", zap.String("addr", listener.Addr().String()))
+	if err := server.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("internal server failed: %w", err)
+	}
+	<-stopped
+
+	return nil
+}
+
+// serve accepts requests on the listener and runs the workers until the context is cancelled.
+// Then it stops accepting requests and waits for in-flight ones, and only after that stops the workers,
+// so requests can still rely on them. Both steps share the shutdown timeout.
+func serve(
+	ctx context.Context,
+	server *http.Server,
+	listener net.Listener,
+	workers []worker,
+	shutdownTimeout time.Duration,
+	logger *zap.Logger,
+) error {
+	workersCtx, stopWorkers := context.WithCancel(context.WithoutCancel(ctx))
+	defer stopWorkers()
+
+	var wg sync.WaitGroup
+	for _, w := range workers {
+		wg.Go(func() {
+			runPeriodically(workersCtx, logger, w.name, w.interval, w.fn)
+		})
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.Serve(listener)
+	}()
+	logger.Info("started server", zap.String("addr", listener.Addr().String()))
+
+	var err error
+	select {
+	case <-ctx.Done():
+		logger.Info("shutting down server")
+	case err = <-serveErr:
+		err = fmt.Errorf("server failed: %w", err)
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), shutdownTimeout)
+	defer cancel()
+
+	if shutdownErr := server.Shutdown(shutdownCtx); shutdownErr != nil {
+		err = errors.Join(err, fmt.Errorf("failed to drain requests: %w", shutdownErr))
+		_ = server.Close()
+	}
+
+	stopWorkers()
+	stopped := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	default:
+		select {
+		case <-stopped:
+		case <-shutdownCtx.Done():
+			err = errors.Join(err, errors.New("workers didn't stop in time"))
+		}
+	}
+
+	if err == nil {
+		logger.Info("server stopped")
+	}
+
+	return err
+}
@@ -0,0 +1,228 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"athylps/internal/config"
+
+	"go.uber.org/zap"
+)
+
+func testServerConfig() *config.ServerConfig {
+	return &config.ServerConfig{
+		ReadHeaderTimeout: time.Second,
+		ReadTimeout:       5 * time.Second,
+		WriteTimeout:      5 * time.Second,
+		IdleTimeout:       5 * time.Second,
+		ShutdownTimeout:   2 * time.Second,
+		MaxBodyBytes:      16,
+	}
+}
+
+type testApp struct {
+	url    string
+	cancel context.CancelFunc
+	done   chan error
+}
+
+func startTestApp(t *testing.T, cfg *config.ServerConfig, handler http.Handler, workers []worker) *testApp {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	app := &testApp{
+		url:    "http://" + listener.Addr().String(),
+		cancel: cancel,
+		done:   make(chan error, 1),
+	}
+	logger := zap.NewNop()
+	go func() {
+		app.done <- serve(ctx, newServer(cfg, handler, logger), listener, workers, cfg.ShutdownTimeout, logger)
+	}()
+	t.Cleanup(cancel)
+
+	return app
+}
+
+var errTestAppNotStopped = errors.New("app didn't stop")
+
+func (a *testApp) stop() error {
+	a.cancel()
+	select {
+	case err := <-a.done:
+		return err
+	case <-time.After(5 * time.Second):
+		return errTestAppNotStopped
+	}
+}
+
+func Test_Serve_DrainsInFlightRequests(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.Write([]byte("done"))
+	})
+	app := startTestApp(t, testServerConfig(), handler, nil)
+
+	type result struct {
+		body string
+		err  error
+	}
+	responses := make(chan result, 1)
+	go func() {
+		resp, err := http.Get(app.url)
+		if err != nil {
+			responses <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		responses <- result{body: string(body), err: err}
+	}()
+	<-started
+
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- app.stop()
+	}()
+
+	select {
+	case <-stopped:
+		t.Fatal("app stopped before the in-flight request completed")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(release)
+	res := <-responses
+	if res.err != nil {
+		t.Fatalf("in-flight request failed: %v", res.err)
+	}
+	if res.body != "done" {
+		t.Errorf("body = %q, want %q", res.body, "done")
+	}
+	if err := <-stopped; err != nil {
+		t.Errorf("unexpected shutdown error: %v", err)
+	}
+
+	if _, err := http.Get(app.url); err == nil {
+		t.Error("server accepts requests after shutdown")
+	}
+}
+
+func Test_Serve_StopsWorkers(t *testing.T) {
+	var running, runs atomic.Int32
+	workers := []worker{
+		{
+			name:     "test",
+			interval: 10 * time.Millisecond,
+			fn: func(ctx context.Context) error {
+				running.Add(1)
+				defer running.Add(-1)
+				runs.Add(1)
+				return nil
+			},
+		},
+	}
+	app := startTestApp(t, testServerConfig(), http.NotFoundHandler(), workers)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for runs.Load() < 2 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if runs.Load() < 2 {
+		t.Fatal("worker didn't run")
+	}
+
+	if err := app.stop(); err != nil {
+		t.Fatalf("unexpected shutdown error: %v", err)
+	}
+	if running.Load() != 0 {
+		t.Error("worker is still running after shutdown")
+	}
+
+	after := runs.Load()
+	time.Sleep(50 * time.Millisecond)
+	if runs.Load() != after {
+		t.Error("worker ran after shutdown")
+	}
+}
+
+func Test_Serve_ShutdownDeadline(t *testing.T) {
+	cfg := testServerConfig()
+	cfg.ShutdownTimeout = 100 * time.Millisecond
+
+	started := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-r.Context().Done() // Never finishes on its own, only when the connection is closed
+	})
+	app := startTestApp(t, cfg, handler, nil)
+
+	go func() {
+		resp, err := http.Get(app.url)
+		if err == nil {
+			resp.Body.Close()
+		}
+	}()
+	<-started
+
+	err := app.stop()
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want deadline exceeded", err)
+	}
+}
+
+func Test_Serve_LimitsRequestBody(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := io.ReadAll(r.Body); err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				w.WriteHeader(http.StatusRequestEntityTooLarge)
+				return
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	app := startTestApp(t, testServerConfig(), handler, nil)
+
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{name: "within limit", body: "small", want: http.StatusOK},
+		{name: "over limit", body: strings.Repeat("x", 17), want: http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := http.Post(app.url, "text/plain", strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.want {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.want)
+			}
+		})
+	}
+
+	if err := app.stop(); err != nil {
+		t.Errorf("unexpected shutdown error: %v", err)
+	}
+}
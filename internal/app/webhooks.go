@@ -0,0 +1,76 @@
+package app
+
+import (
+	"fmt"
+
+	"athylps/internal/config"
+	"athylps/internal/handlers/hooks"
+	"athylps/internal/repositories"
+	"athylps/internal/services"
+	"athylps/internal/usecases"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+// webhookPipeline processes webhook events and replays stored ones, the server and athylpsctl share it.
+type webhookPipeline struct {
+	process *usecases.ProcessWebhookEventUsecase
+	replay  *usecases.ReplayWebhookEventsUsecase
+}
+
+func newWebhookPipeline(
+	cfg *config.Config,
+	dbpool *pgxpool.Pool,
+	notificationRouter *services.NotificationRouter,
+	templates *services.NotificationTemplates,
+	logger *zap.Logger,
+) *webhookPipeline {
+	purchaseNotificationUsecase := usecases.NewSendPurchaseNotificationUsecase(
+		repositories.NewNotificationOutboxRepository(dbpool),
+		notificationRouter,
+		templates,
+		logger,
+	)
+
+	subscriptionRepository := repositories.NewSubscriptionRepository(dbpool)
+	webhookEventRepository := repositories.NewWebhookEventRepository(dbpool)
+	processWebhookEventUsecase := usecases.NewProcessWebhookEventUsecase(
+		repositories.NewTransactor(dbpool),
+		webhookEventRepository,
+		usecases.NewResolveRevenueCatCustomerUsecase(repositories.NewRevenueCatCustomerRepository(dbpool), logger),
+		repositories.NewUserRepository(dbpool),
+		usecases.NewUpdateSubscriptionStateUsecase(subscriptionRepository, logger),
+		repositories.NewPurchaseEventRepository(dbpool),
+		purchaseNotificationUsecase,
+		cfg.Revenue.IncludeSandbox,
+		logger,
+	)
+
+	return &webhookPipeline{
+		process: processWebhookEventUsecase,
+		replay: usecases.NewReplayWebhookEventsUsecase(
+			webhookEventRepository,
+			hooks.NewStoredEventDecoder(&cfg.Rustore, &cfg.Revenue),
+			processWebhookEventUsecase,
+			purchaseNotificationUsecase,
+			logger,
+		),
+	}
+}
+
+// NewReplayWebhookEventsUsecase builds the replay of stored webhook events for athylpsctl,
+// replayed notifications are delivered by the running server.
+func NewReplayWebhookEventsUsecase(cfg *config.Config, dbpool *pgxpool.Pool, logger *zap.Logger) (*usecases.ReplayWebhookEventsUsecase, error) {
+	templates, err := services.NewNotificationTemplates(&cfg.Notify)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load notification templates: %w", err)
+	}
+
+	notificationRouter, err := services.NewNotificationRouter(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure notification routes: %w", err)
+	}
+
+	return newWebhookPipeline(cfg, dbpool, notificationRouter, templates, logger).replay, nil
+}
@@ -0,0 +1,35 @@
+package app
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// runPeriodically calls fn every interval until the context is cancelled.
+// Errors are logged and don't stop the loop.
+func runPeriodically(
+	ctx context.Context,
+	logger *zap.Logger,
+	name string,
+	interval time.Duration,
+	fn func(ctx context.Context) error,
+) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	logger.Info("started worker", zap.String("worker", name), zap.Duration("interval", interval))
+	for {
+		if err := fn(ctx); err != nil {
+			logger.Error("worker run failed", zap.String("worker", name), zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			logger.Info("stopped worker", zap.String("worker", name))
+			return
+		case <-ticker.C:
+		}
+	}
+}
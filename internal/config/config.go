@@ -1,23 +1,42 @@
 package config
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/caarlos0/env/v11"
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
-	Server     ServerConfig
-	Database   DatabaseConfig
-	RevenueCat RevenueCatConfig
-	Telegram   TelegramConfig
-	Rustore    RustoreConfig
+	Server         ServerConfig
+	Database       DatabaseConfig
+	RevenueCat     RevenueCatConfig
+	Telegram       TelegramConfig
+	Notify         NotifyConfig
+	Digest         DigestConfig
+	Revenue        RevenueConfig
+	Rustore        RustoreConfig
+	DonationAlerts DonationAlertsConfig
+	Tracing        TracingConfig
+	Admin          AdminConfig
+	Account        AccountConfig
 }
 
 type ServerConfig struct {
-	Port string `env:"PORT" envDefault:"8080"`
-	Env  string `env:"ENV" envDefault:"development"`
+	Port              string        `env:"PORT" envDefault:"8080"`
+	Env               string        `env:"ENV" envDefault:"development"`
+	ReadHeaderTimeout time.Duration `env:"SERVER_READ_HEADER_TIMEOUT" envDefault:"5s"`
+	ReadTimeout       time.Duration `env:"SERVER_READ_TIMEOUT" envDefault:"15s"`
+	WriteTimeout      time.Duration `env:"SERVER_WRITE_TIMEOUT" envDefault:"30s"`
+	IdleTimeout       time.Duration `env:"SERVER_IDLE_TIMEOUT" envDefault:"60s"`
+	// Time to drain in-flight requests and stop workers, must be less than the container stop grace period
+	ShutdownTimeout time.Duration `env:"SERVER_SHUTDOWN_TIMEOUT" envDefault:"20s"`
+	MaxBodyBytes    int64         `env:"SERVER_MAX_BODY_BYTES" envDefault:"1048576"`
+	// Port to serve /metrics on, so it isn't exposed publicly. Served on PORT if empty
+	MetricsPort string `env:"METRICS_PORT"`
 }
 
 type DatabaseConfig struct {
@@ -35,10 +54,160 @@ type RevenueCatConfig struct {
 type TelegramConfig struct {
 	BotToken     string `env:"BOT_TOKEN,required"`
 	NotifyChatID string `env:"NOTIFY_CHAT_ID,required"`
+	// How often the outbox is checked for notifications to deliver
+	DispatchInterval time.Duration `env:"NOTIFY_DISPATCH_INTERVAL" envDefault:"1s"`
+	// Answer stats commands like /today and /mrr in NOTIFY_CHAT_ID and to BOT_ALLOWED_USER_IDS.
+	// Off by default: only one replica may long poll updates, with several of them set BOT_WEBHOOK_URL
+	BotCommands bool `env:"BOT_COMMANDS" envDefault:"false"`
+	// Telegram user ids allowed to use commands in any chat, e.g. in private messages to the bot
+	BotAllowedUserIDs []int64 `env:"BOT_ALLOWED_USER_IDS"`
+	// Public url of /hooks/telegram to receive updates at, long polling is used if empty
+	BotWebhookURL string `env:"BOT_WEBHOOK_URL"`
+	// Required with BOT_WEBHOOK_URL, updates without it in X-Telegram-Bot-Api-Secret-Token are rejected
+	BotWebhookSecret string `env:"BOT_WEBHOOK_SECRET"`
+}
+
+type NotifyConfig struct {
+	// JSON array of NotificationRoute, notifications no route matches go to NOTIFY_CHAT_ID
+	Routes NotificationRoutes `env:"NOTIFY_ROUTES"`
+	// Named JSON webhook urls routes refer to, e.g. finance:https://hooks.slack.com/services/...
+	Webhooks map[string]string `env:"NOTIFY_WEBHOOKS"`
+	SMTP     SMTPConfig
+	// Directory with <locale>/<event_type>.tmpl files replacing the embedded notification templates
+	TemplatesDir string `env:"NOTIFY_TEMPLATES_DIR"`
+	// Locale of targets without one and the fallback for templates missing in other locales
+	Locale string `env:"NOTIFY_LOCALE" envDefault:"ru"`
+	// Telegram chat for notifications about SANDBOX and TEST events no route lists the environment of.
+	// If empty, sandbox notifications are discarded and TEST confirmations go to NOTIFY_CHAT_ID
+	SandboxChatID string `env:"NOTIFY_SANDBOX_CHAT_ID"`
+	// Minimum interval between notifications to a target, Telegram allows 20 messages a minute in a group
+	MinInterval time.Duration `env:"NOTIFY_MIN_INTERVAL" envDefault:"3s"`
+	// More than BurstThreshold purchase notifications to a target within BurstWindow are collapsed
+	// into a summary, 0 disables collapsing
+	BurstThreshold int           `env:"NOTIFY_BURST_THRESHOLD" envDefault:"5"`
+	BurstWindow    time.Duration `env:"NOTIFY_BURST_WINDOW" envDefault:"1m"`
+}
+
+// NotificationRoute sends notifications of matching events to its targets.
+// Empty conditions match any event, price conditions never match events without a price.
+// Every matching route is applied, a matching route without targets discards the notification.
+type NotificationRoute struct {
+	EventTypes   []string             `json:"event_types"`
+	Stores       []string             `json:"stores"`
+	Environments []string             `json:"environments"`
+	MinPrice     *float32             `json:"min_price"`
+	MaxPrice     *float32             `json:"max_price"`
+	Targets      []NotificationTarget `json:"targets"`
+}
+
+type NotificationTarget struct {
+	Channel string `json:"channel"` // telegram, webhook or email
+	To      string `json:"to"`      // Chat id, webhook name or email address
+	Locale  string `json:"locale"`  // Locale of notification templates, NOTIFY_LOCALE if empty
+}
+
+type NotificationRoutes []NotificationRoute
+
+func (r *NotificationRoutes) UnmarshalText(text []byte) error {
+	return json.Unmarshal(text, (*[]NotificationRoute)(r))
+}
+
+type SMTPConfig struct {
+	Host     string `env:"SMTP_HOST"`
+	Port     string `env:"SMTP_PORT" envDefault:"587"`
+	Username string `env:"SMTP_USERNAME"`
+	Password string `env:"SMTP_PASSWORD"`
+	From     string `env:"SMTP_FROM"`
+}
+
+// Enabled reports whether email notifications are configured, they are optional.
+func (smtpConfig *SMTPConfig) Enabled() bool {
+	return smtpConfig.Host != ""
+}
+
+type DigestConfig struct {
+	Daily  bool `env:"DIGEST_DAILY" envDefault:"true"`
+	Weekly bool `env:"DIGEST_WEEKLY" envDefault:"true"` // Sent on Mondays for the previous week
+	// Local time of day digests are sent at, HH:MM
+	At       string `env:"DIGEST_AT" envDefault:"09:00"`
+	Timezone string `env:"DIGEST_TIMEZONE" envDefault:"Europe/Moscow"`
+	// Telegram chat for digests, NOTIFY_CHAT_ID if empty
+	ChatID string `env:"DIGEST_CHAT_ID"`
+}
+
+// Schedule returns the time of day digests are sent at as an offset from midnight and the timezone of days.
+func (digestConfig *DigestConfig) Schedule() (time.Duration, *time.Location, error) {
+	at, err := time.Parse("15:04", digestConfig.At)
+	if err != nil {
+		return 0, nil, fmt.Errorf("invalid DIGEST_AT %q: %w", digestConfig.At, err)
+	}
+
+	location, err := time.LoadLocation(digestConfig.Timezone)
+	if err != nil {
+		return 0, nil, fmt.Errorf("invalid DIGEST_TIMEZONE %q: %w", digestConfig.Timezone, err)
+	}
+
+	return time.Duration(at.Hour())*time.Hour + time.Duration(at.Minute())*time.Minute, location, nil
+}
+
+type RevenueConfig struct {
+	// Record purchase events and count subscriptions of non-production environments in revenue stats
+	IncludeSandbox bool `env:"REVENUE_INCLUDE_SANDBOX" envDefault:"false"`
+	// USD per unit of currencies stores report prices in without USD, like RuStore, e.g. RUB:0.0125,EUR:1.16.
+	// Purchases in other currencies aren't counted in revenue stats
+	USDRates map[string]float64 `env:"REVENUE_USD_RATES" envDefault:"RUB:0.0125"`
+}
+
+const (
+	TracingExporterNone   = "none"
+	TracingExporterOTLP   = "otlp"
+	TracingExporterStdout = "stdout"
+)
+
+type TracingConfig struct {
+	// none, otlp or stdout for local development. The OTLP exporter sends traces over HTTP and is
+	// configured by the standard OTEL_EXPORTER_OTLP_* variables, e.g. OTEL_EXPORTER_OTLP_ENDPOINT
+	Exporter    string  `env:"TRACING_EXPORTER" envDefault:"none"`
+	ServiceName string  `env:"OTEL_SERVICE_NAME" envDefault:"athylps"`
+	SampleRatio float64 `env:"TRACING_SAMPLE_RATIO" envDefault:"1"`
+}
+
+type AdminConfig struct {
+	// Firebase uids allowed to use the admin API besides users with the admin custom claim
+	UIDs []string `env:"ADMIN_UIDS"`
+}
+
+type AccountConfig struct {
+	// Personal data of deleted accounts is purged after the retention, until then an admin can restore the account
+	DeletionRetention time.Duration `env:"ACCOUNT_DELETION_RETENTION" envDefault:"720h"`
+	PurgeInterval     time.Duration `env:"ACCOUNT_PURGE_INTERVAL" envDefault:"1h"`
 }
 
 type RustoreConfig struct {
 	NotifySecret string `env:"RUSTORE_NOTIFY_SECRET,required"`
+	// RuStore has no entitlements, every RuStore subscription grants this one
+	EntitlementID string `env:"RUSTORE_ENTITLEMENT_ID" envDefault:"premium"`
+}
+
+type DonationAlertsConfig struct {
+	ClientID     string        `env:"DA_CLIENT_ID"`
+	ClientSecret string        `env:"DA_CLIENT_SECRET"`
+	RedirectURL  string        `env:"DA_REDIRECT_URL"`
+	BaseURL      string        `env:"DA_BASE_URL" envDefault:"https://www.donationalerts.com"`
+	PollInterval time.Duration `env:"DA_POLL_INTERVAL" envDefault:"1m"`
+}
+
+// Enabled reports whether DonationAlerts integration is configured, it's optional.
+func (daConfig *DonationAlertsConfig) Enabled() bool {
+	return daConfig.ClientID != ""
+}
+
+// validate rejects a bot webhook without a secret, anyone could post forged updates to it otherwise.
+func (tgConfig *TelegramConfig) validate() error {
+	if tgConfig.BotWebhookURL != "" && tgConfig.BotWebhookSecret == "" {
+		return errors.New("BOT_WEBHOOK_SECRET is required with BOT_WEBHOOK_URL")
+	}
+	return nil
 }
 
 func Load() (*Config, error) {
@@ -47,6 +216,9 @@ func Load() (*Config, error) {
 	if err := env.Parse(cfg); err != nil {
 		return nil, fmt.Errorf("failed to parse config: %w", err)
 	}
+	if err := cfg.Telegram.validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
 
 	return cfg, nil
 }
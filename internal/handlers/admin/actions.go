@@ -0,0 +1,62 @@
+package admin
+
+import (
+	"context"
+	"net/http"
+
+	"athylps/internal/api"
+	"athylps/internal/handlers"
+	"athylps/internal/logging"
+	"athylps/internal/models"
+	"athylps/internal/repositories"
+
+	"go.uber.org/zap"
+)
+
+type listAdminActionsUsecase interface {
+	Perform(ctx context.Context, params *repositories.ListAdminActionsParams) ([]*models.AdminAction, error)
+}
+
+func HandleListAdminActions(logger *zap.Logger, usecase listAdminActionsUsecase) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		params := &repositories.ListAdminActionsParams{AdminUID: queryString(query, "admin_uid")}
+
+		var err error
+		if params.From, err = queryTime(query, "from"); err != nil {
+			handlers.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if params.To, err = queryTime(query, "to"); err != nil {
+			handlers.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if params.Limit, params.Offset, err = queryPage(query); err != nil {
+			handlers.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		actions, err := usecase.Perform(r.Context(), params)
+		if err != nil {
+			logging.FromContext(r.Context(), logger).Error("failed to list admin actions", zap.Error(err))
+			handlers.WriteError(w, http.StatusInternalServerError, "An unexpected error occurred")
+			return
+		}
+
+		resp := api.AdminActionsResponse{Actions: make([]api.AdminAction, 0, len(actions))}
+		for _, a := range actions {
+			resp.Actions = append(resp.Actions, api.AdminAction{
+				Id:        a.ID,
+				AdminUid:  a.AdminUID,
+				Method:    a.Method,
+				Route:     a.Route,
+				Path:      a.Path,
+				Request:   handlers.JSONObject(a.Request),
+				Status:    a.Status,
+				CreatedAt: a.CreatedAt,
+			})
+		}
+
+		handlers.WriteJSON(w, http.StatusOK, resp)
+	}
+}
@@ -0,0 +1,54 @@
+package admin
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 100
+)
+
+// queryString returns the parameter or nil if it's absent or empty.
+func queryString(query url.Values, name string) *string {
+	value := strings.TrimSpace(query.Get(name))
+	if value == "" {
+		return nil
+	}
+	return &value
+}
+
+// queryTime parses an RFC 3339 parameter, nil if it's absent.
+func queryTime(query url.Values, name string) (*time.Time, error) {
+	value := queryString(query, name)
+	if value == nil {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, *value)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be an RFC 3339 time", name)
+	}
+	return &t, nil
+}
+
+// queryPage parses limit and offset of a list, the error message is safe to respond with.
+func queryPage(query url.Values) (limit uint64, offset uint64, err error) {
+	limit = defaultPageLimit
+	if value := queryString(query, "limit"); value != nil {
+		limit, err = strconv.ParseUint(*value, 10, 64)
+		if err != nil || limit < 1 || limit > maxPageLimit {
+			return 0, 0, fmt.Errorf("limit must be between 1 and %d", maxPageLimit)
+		}
+	}
+	if value := queryString(query, "offset"); value != nil {
+		offset, err = strconv.ParseUint(*value, 10, 64)
+		if err != nil {
+			return 0, 0, fmt.Errorf("offset must be a non-negative integer")
+		}
+	}
+	return limit, offset, nil
+}
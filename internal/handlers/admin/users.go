@@ -0,0 +1,226 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"athylps/internal/api"
+	"athylps/internal/handlers"
+	"athylps/internal/logging"
+	"athylps/internal/models"
+	"athylps/internal/repositories"
+	"athylps/internal/usecases"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+)
+
+type searchUsersUsecase interface {
+	Perform(ctx context.Context, params *usecases.SearchUsersParams) ([]*models.User, error)
+}
+
+type getUserDetailsUsecase interface {
+	Perform(ctx context.Context, userID string) (*usecases.UserDetails, error)
+}
+
+type grantEntitlementUsecase interface {
+	Perform(ctx context.Context, params *usecases.GrantEntitlementParams) (*models.Subscription, error)
+}
+
+type revokeEntitlementUsecase interface {
+	Perform(ctx context.Context, params *usecases.RevokeEntitlementParams) (*models.Subscription, error)
+}
+
+func HandleSearchUsers(logger *zap.Logger, usecase searchUsersUsecase) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		params := &usecases.SearchUsersParams{
+			Query: query.Get("query"),
+			Filter: repositories.ListUsersParams{
+				EmailContains: queryString(query, "email"),
+				Locale:        queryString(query, "locale"),
+			},
+		}
+
+		var err error
+		if params.Filter.CreatedAfter, err = queryTime(query, "created_after"); err != nil {
+			handlers.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if params.Filter.CreatedBefore, err = queryTime(query, "created_before"); err != nil {
+			handlers.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if value := queryString(query, "include_deleted"); value != nil {
+			if params.Filter.IncludeDeleted, err = strconv.ParseBool(*value); err != nil {
+				handlers.WriteError(w, http.StatusBadRequest, "include_deleted must be a boolean")
+				return
+			}
+		}
+		if params.Filter.Limit, params.Filter.Offset, err = queryPage(query); err != nil {
+			handlers.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		users, err := usecase.Perform(r.Context(), params)
+		if err != nil {
+			logging.FromContext(r.Context(), logger).Error("failed to search users", zap.Error(err))
+			handlers.WriteError(w, http.StatusInternalServerError, "An unexpected error occurred")
+			return
+		}
+
+		resp := api.AdminUsersResponse{Users: make([]api.AdminUser, 0, len(users))}
+		for _, u := range users {
+			resp.Users = append(resp.Users, toApiAdminUser(u))
+		}
+
+		handlers.WriteJSON(w, http.StatusOK, resp)
+	}
+}
+
+func HandleGetUserDetails(logger *zap.Logger, usecase getUserDetailsUsecase) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID := chi.URLParam(r, "id")
+		details, err := usecase.Perform(r.Context(), userID)
+		if errors.Is(err, repositories.ErrNotFound) {
+			handlers.WriteError(w, http.StatusNotFound, "User not found")
+			return
+		}
+		if err != nil {
+			logging.FromContext(r.Context(), logger).Error("failed to get user details", zap.Error(err), zap.String("user_id", userID))
+			handlers.WriteError(w, http.StatusInternalServerError, "An unexpected error occurred")
+			return
+		}
+
+		resp := api.AdminUserDetails{
+			User:           toApiAdminUser(details.User),
+			Entitlements:   make([]api.Entitlement, 0, len(details.Entitlements)),
+			Subscriptions:  make([]api.AdminSubscription, 0, len(details.Subscriptions)),
+			PurchaseEvents: make([]api.AdminPurchaseEvent, 0, len(details.PurchaseEvents)),
+		}
+		for _, e := range details.Entitlements {
+			resp.Entitlements = append(resp.Entitlements, handlers.ToApiEntitlement(e))
+		}
+		for _, s := range details.Subscriptions {
+			resp.Subscriptions = append(resp.Subscriptions, toApiAdminSubscription(s))
+		}
+		for _, e := range details.PurchaseEvents {
+			resp.PurchaseEvents = append(resp.PurchaseEvents, api.AdminPurchaseEvent{
+				Id:             e.ID,
+				WebhookEventId: e.WebhookEventID,
+				EventType:      e.EventType,
+				Store:          e.Store,
+				Environment:    e.Environment,
+				ProductId:      e.ProductID,
+				CountryCode:    e.CountryCode,
+				Price:          e.Price,
+				OccurredAt:     e.OccurredAt,
+			})
+		}
+
+		handlers.WriteJSON(w, http.StatusOK, resp)
+	}
+}
+
+func HandleGrantEntitlement(logger *zap.Logger, usecase grantEntitlementUsecase) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var body api.GrantEntitlementRequest
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			handlers.WriteError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		if body.EntitlementId == "" {
+			handlers.WriteError(w, http.StatusBadRequest, "entitlement_id is required")
+			return
+		}
+
+		userID := chi.URLParam(r, "id")
+		granted, err := usecase.Perform(r.Context(), &usecases.GrantEntitlementParams{
+			UserID:        userID,
+			EntitlementID: body.EntitlementId,
+			ExpiresAt:     body.ExpiresAt,
+			GrantedBy:     adminUID(r.Context()),
+		})
+		switch {
+		case errors.Is(err, usecases.ErrGrantExpired):
+			handlers.WriteError(w, http.StatusBadRequest, "expires_at must be in the future")
+			return
+		case errors.Is(err, repositories.ErrNotFound):
+			handlers.WriteError(w, http.StatusNotFound, "User not found")
+			return
+		case err != nil:
+			logging.FromContext(r.Context(), logger).Error("failed to grant entitlement", zap.Error(err), zap.String("user_id", userID))
+			handlers.WriteError(w, http.StatusInternalServerError, "An unexpected error occurred")
+			return
+		}
+
+		handlers.WriteJSON(w, http.StatusOK, toApiAdminSubscription(granted))
+	}
+}
+
+func HandleRevokeEntitlement(logger *zap.Logger, usecase revokeEntitlementUsecase) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID := chi.URLParam(r, "id")
+		revoked, err := usecase.Perform(r.Context(), &usecases.RevokeEntitlementParams{
+			UserID:        userID,
+			EntitlementID: chi.URLParam(r, "entitlementID"),
+			RevokedBy:     adminUID(r.Context()),
+		})
+		if errors.Is(err, repositories.ErrNotFound) {
+			handlers.WriteError(w, http.StatusNotFound, "Entitlement has not been granted to the user")
+			return
+		}
+		if err != nil {
+			logging.FromContext(r.Context(), logger).Error("failed to revoke entitlement", zap.Error(err), zap.String("user_id", userID))
+			handlers.WriteError(w, http.StatusInternalServerError, "An unexpected error occurred")
+			return
+		}
+
+		handlers.WriteJSON(w, http.StatusOK, toApiAdminSubscription(revoked))
+	}
+}
+
+func toApiAdminUser(u *models.User) api.AdminUser {
+	resp := api.AdminUser{
+		Id:           u.ID,
+		Email:        u.Email,
+		FirebaseUid:  u.FirebaseUID,
+		RevenuecatId: u.RevenueCatID,
+		Timezone:     u.Timezone,
+		Locale:       u.Locale,
+		DeletedAt:    u.DeletedAt,
+	}
+	if u.CreatedAt != nil {
+		resp.CreatedAt = *u.CreatedAt
+	}
+	if u.UpdatedAt != nil {
+		resp.UpdatedAt = *u.UpdatedAt
+	}
+
+	return resp
+}
+
+func toApiAdminSubscription(s *models.Subscription) api.AdminSubscription {
+	entitlementIDs := s.EntitlementIDs
+	if entitlementIDs == nil {
+		entitlementIDs = []string{}
+	}
+
+	return api.AdminSubscription{
+		AppUserId:            s.AppUserID,
+		ProductId:            s.ProductID,
+		EntitlementIds:       entitlementIDs,
+		Store:                s.Store,
+		Environment:          s.Environment,
+		Status:               api.AdminSubscriptionStatus(s.Status),
+		PurchasedAt:          s.PurchasedAt,
+		ExpiresAt:            s.ExpiresAt,
+		GracePeriodExpiresAt: s.GracePeriodExpiresAt,
+		CancelledAt:          s.CancelledAt,
+		LastEventType:        s.LastEventType,
+		LastEventAt:          s.LastEventAt,
+	}
+}
@@ -0,0 +1,214 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"athylps/internal/api"
+	"athylps/internal/handlers"
+	"athylps/internal/handlers/middlewares"
+	"athylps/internal/logging"
+	"athylps/internal/models"
+	"athylps/internal/repositories"
+	"athylps/internal/usecases"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+)
+
+type listWebhookEventsUsecase interface {
+	Perform(ctx context.Context, params *repositories.ListWebhookEventsParams) ([]*models.WebhookEvent, error)
+	Get(ctx context.Context, id string) (*models.WebhookEvent, error)
+}
+
+type replayWebhookEventsUsecase interface {
+	Perform(ctx context.Context, params *usecases.ReplayWebhookEventsParams) (*usecases.ReplayWebhookEventsResult, error)
+}
+
+func HandleListWebhookEvents(logger *zap.Logger, usecase listWebhookEventsUsecase) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		params := &repositories.ListWebhookEventsParams{
+			Provider: queryString(query, "provider"),
+			UserID:   queryString(query, "user_id"),
+		}
+		if eventTypes := queryString(query, "event_type"); eventTypes != nil {
+			for _, eventType := range strings.Split(*eventTypes, ",") {
+				if eventType = strings.TrimSpace(eventType); eventType != "" {
+					params.EventTypes = append(params.EventTypes, eventType)
+				}
+			}
+		}
+
+		var err error
+		if params.From, err = queryTime(query, "from"); err != nil {
+			handlers.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if params.To, err = queryTime(query, "to"); err != nil {
+			handlers.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if params.Limit, params.Offset, err = queryPage(query); err != nil {
+			handlers.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		events, err := usecase.Perform(r.Context(), params)
+		if err != nil {
+			logging.FromContext(r.Context(), logger).Error("failed to list webhook events", zap.Error(err))
+			handlers.WriteError(w, http.StatusInternalServerError, "An unexpected error occurred")
+			return
+		}
+
+		resp := api.AdminWebhookEventsResponse{Events: make([]api.AdminWebhookEvent, 0, len(events))}
+		for _, e := range events {
+			resp.Events = append(resp.Events, toApiAdminWebhookEvent(e))
+		}
+
+		handlers.WriteJSON(w, http.StatusOK, resp)
+	}
+}
+
+func HandleGetWebhookEvent(logger *zap.Logger, usecase listWebhookEventsUsecase) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := chi.URLParam(r, "id")
+		event, err := usecase.Get(r.Context(), id)
+		if errors.Is(err, repositories.ErrNotFound) {
+			handlers.WriteError(w, http.StatusNotFound, "Webhook event not found")
+			return
+		}
+		if err != nil {
+			logging.FromContext(r.Context(), logger).Error("failed to get webhook event", zap.Error(err), zap.String("id", id))
+			handlers.WriteError(w, http.StatusInternalServerError, "An unexpected error occurred")
+			return
+		}
+
+		resp := toApiAdminWebhookEvent(event)
+		resp.Payload = handlers.JSONObject(event.Payload)
+		handlers.WriteJSON(w, http.StatusOK, resp)
+	}
+}
+
+func HandleReplayWebhookEvents(logger *zap.Logger, usecase replayWebhookEventsUsecase) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var body api.ReplayWebhookEventsRequest
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			handlers.WriteError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		if body.Limit != nil && (*body.Limit < 1 || *body.Limit > usecases.MaxReplayedWebhookEvents) {
+			handlers.WriteError(w, http.StatusBadRequest, "limit must be between 1 and 1000")
+			return
+		}
+
+		filter := &repositories.ListWebhookEventsParams{
+			IDs:        valueOrNil(body.Ids),
+			EventTypes: valueOrNil(body.EventTypes),
+			From:       body.From,
+			To:         body.To,
+		}
+		if body.Provider != nil {
+			provider := string(*body.Provider)
+			filter.Provider = &provider
+		}
+		if body.Limit != nil {
+			filter.Limit = uint64(*body.Limit)
+		}
+		params := &usecases.ReplayWebhookEventsParams{
+			Filter:                filter,
+			DryRun:                body.DryRun != nil && *body.DryRun,
+			SuppressNotifications: body.SuppressNotifications != nil && *body.SuppressNotifications,
+		}
+
+		logger := logging.FromContext(r.Context(), logger)
+		if !params.DryRun {
+			// Replaying changes state and may send notifications, it's recorded with the acting admin
+			logger.Info("replaying webhook events", zap.String("admin_uid", adminUID(r.Context())), zap.Any("request", body))
+		}
+
+		result, err := usecase.Perform(r.Context(), params)
+		if errors.Is(err, usecases.ErrReplayScopeRequired) {
+			handlers.WriteError(w, http.StatusBadRequest, "ids or a time range are required")
+			return
+		}
+		if err != nil {
+			logger.Error("failed to replay webhook events", zap.Error(err))
+			handlers.WriteError(w, http.StatusInternalServerError, "An unexpected error occurred")
+			return
+		}
+
+		handlers.WriteJSON(w, http.StatusOK, toApiReplayResult(result, params.DryRun))
+	}
+}
+
+func toApiAdminWebhookEvent(e *models.WebhookEvent) api.AdminWebhookEvent {
+	return api.AdminWebhookEvent{
+		Id:          e.ID,
+		Provider:    e.Provider,
+		EventId:     e.EventID,
+		EventType:   e.EventType,
+		Environment: e.Environment,
+		UserId:      e.UserID,
+		ReceivedAt:  e.ReceivedAt,
+		ProcessedAt: e.ProcessedAt,
+	}
+}
+
+func toApiReplayResult(result *usecases.ReplayWebhookEventsResult, dryRun bool) api.ReplayWebhookEventsResponse {
+	resp := api.ReplayWebhookEventsResponse{
+		Events:    make([]api.ReplayedWebhookEvent, 0, len(result.Events)),
+		Failed:    result.Failed,
+		Truncated: result.Truncated,
+	}
+	for _, replayed := range result.Events {
+		event := api.ReplayedWebhookEvent{
+			Id:         replayed.Event.ID,
+			Provider:   replayed.Event.Provider,
+			EventId:    replayed.Event.EventID,
+			EventType:  replayed.Event.EventType,
+			ReceivedAt: replayed.Event.ReceivedAt,
+			Status:     api.Replayed,
+		}
+		switch {
+		case replayed.Err != nil:
+			message := replayed.Err.Error()
+			event.Status = api.Failed
+			event.Error = &message
+		case dryRun:
+			event.Status = api.Previewed
+			notifications := make([]api.PreviewedNotification, 0, len(replayed.Notifications))
+			for _, n := range replayed.Notifications {
+				locale := n.Locale
+				notifications = append(notifications, api.PreviewedNotification{
+					Channel:   api.PreviewedNotificationChannel(n.Target.Channel),
+					Recipient: n.Target.Recipient,
+					Locale:    &locale,
+					Text:      n.Text,
+				})
+			}
+			event.Notifications = &notifications
+		}
+		resp.Events = append(resp.Events, event)
+	}
+
+	return resp
+}
+
+func adminUID(ctx context.Context) string {
+	token, ok := middlewares.AuthTokenFromContext(ctx)
+	if !ok {
+		return ""
+	}
+	return token.UID
+}
+
+func valueOrNil[T any](v *[]T) []T {
+	if v == nil {
+		return nil
+	}
+	return *v
+}
@@ -0,0 +1,133 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"athylps/internal/config"
+
+	tgbot "github.com/go-telegram/bot"
+	tgmodels "github.com/go-telegram/bot/models"
+	"go.uber.org/zap"
+)
+
+// New returns the bot answering commands in the notification chat and to the allowed users.
+// Commands are registered with RegisterHandlerMatchFunc and Command.
+func New(cfg *config.TelegramConfig, logger *zap.Logger, opts ...tgbot.Option) (*tgbot.Bot, error) {
+	opts = append([]tgbot.Option{
+		tgbot.WithMiddlewares(AllowOnly(cfg.NotifyChatID, cfg.BotAllowedUserIDs, logger)),
+		tgbot.WithDefaultHandler(Ignore),
+		tgbot.WithAllowedUpdates(tgbot.AllowedUpdates{"message"}),
+		tgbot.WithWebhookSecretToken(cfg.BotWebhookSecret),
+		tgbot.WithErrorsHandler(func(err error) {
+			logger.Error("telegram bot error", zap.Error(err))
+		}),
+	}, opts...)
+
+	b, err := tgbot.New(cfg.BotToken, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create telegram bot: %w", err)
+	}
+
+	return b, nil
+}
+
+// Listen receives updates until the context is cancelled. With the webhook url configured
+// the webhook is registered and updates come to b.WebhookHandler(), otherwise they are long polled.
+func Listen(ctx context.Context, b *tgbot.Bot, cfg *config.TelegramConfig) error {
+	if cfg.BotWebhookURL == "" {
+		// Updates can't be polled while a webhook is set
+		if _, err := b.DeleteWebhook(ctx, &tgbot.DeleteWebhookParams{}); err != nil {
+			return fmt.Errorf("failed to delete telegram webhook: %w", err)
+		}
+		b.Start(ctx)
+		return nil
+	}
+
+	_, err := b.SetWebhook(ctx, &tgbot.SetWebhookParams{
+		URL:            cfg.BotWebhookURL,
+		AllowedUpdates: []string{"message"},
+		SecretToken:    cfg.BotWebhookSecret,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set telegram webhook: %w", err)
+	}
+	b.StartWebhook(ctx)
+
+	return nil
+}
+
+// Command matches messages starting with the command, in groups also addressed as /command@bot.
+func Command(name string) tgbot.MatchFunc {
+	return func(update *tgmodels.Update) bool {
+		if update.Message == nil {
+			return false
+		}
+
+		for _, e := range update.Message.Entities {
+			if e.Type != tgmodels.MessageEntityTypeBotCommand || e.Offset != 0 || e.Length > len(update.Message.Text) {
+				continue
+			}
+			command, _, _ := strings.Cut(update.Message.Text[1:e.Length], "@")
+			return command == name
+		}
+
+		return false
+	}
+}
+
+// AllowOnly passes on messages from the chat, e.g. the notification chat, and from the users
+// wherever they write from. Everything else is ignored, so the bot doesn't reveal stats to strangers.
+func AllowOnly(chatID string, userIDs []int64, logger *zap.Logger) tgbot.Middleware {
+	return func(next tgbot.HandlerFunc) tgbot.HandlerFunc {
+		return func(ctx context.Context, b *tgbot.Bot, update *tgmodels.Update) {
+			if update.Message == nil {
+				return
+			}
+
+			chat := update.Message.Chat
+			fromChat := strconv.FormatInt(chat.ID, 10) == chatID ||
+				(chat.Username != "" && strings.TrimPrefix(chatID, "@") == chat.Username)
+			fromUser := update.Message.From != nil && slices.Contains(userIDs, update.Message.From.ID)
+			if !fromChat && !fromUser {
+				fields := []zap.Field{zap.Int64("chat_id", chat.ID)}
+				if update.Message.From != nil {
+					fields = append(fields, zap.Int64("user_id", update.Message.From.ID))
+				}
+				logger.Warn("ignored telegram message from a not allowed chat", fields...)
+				return
+			}
+
+			next(ctx, b, update)
+		}
+	}
+}
+
+// Ignore is the handler of messages that aren't commands.
+func Ignore(context.Context, *tgbot.Bot, *tgmodels.Update) {}
+
+// reply sends the HTML text to the chat the message came from as a reply to it.
+// Errors are only logged, there is no one to return them to.
+func reply(ctx context.Context, b *tgbot.Bot, update *tgmodels.Update, text string, logger *zap.Logger) {
+	_, err := b.SendMessage(ctx, &tgbot.SendMessageParams{
+		ChatID:          update.Message.Chat.ID,
+		Text:            text,
+		ParseMode:       tgmodels.ParseModeHTML,
+		ReplyParameters: &tgmodels.ReplyParameters{MessageID: update.Message.ID, AllowSendingWithoutReply: true},
+	})
+	if err != nil {
+		logger.Error("failed to reply to telegram command", zap.Error(err), zap.Int64("chat_id", update.Message.Chat.ID))
+	}
+}
+
+// commandArgs returns space separated arguments following the command.
+func commandArgs(update *tgmodels.Update) []string {
+	fields := strings.Fields(update.Message.Text)
+	if len(fields) == 0 {
+		return nil
+	}
+	return fields[1:]
+}
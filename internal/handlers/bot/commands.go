@@ -0,0 +1,183 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"athylps/internal/models"
+	"athylps/internal/repositories"
+	"athylps/internal/services"
+	"athylps/internal/usecases"
+
+	tgbot "github.com/go-telegram/bot"
+	tgmodels "github.com/go-telegram/bot/models"
+	"go.uber.org/zap"
+)
+
+const defaultLatestPurchases = 10
+
+const failedReply = "Не удалось получить данные, попробуйте позже"
+
+type revenueReportUsecase interface {
+	Perform(ctx context.Context, kind string) (string, error)
+}
+
+type mrrUsecase interface {
+	Perform(ctx context.Context) (*usecases.MRR, error)
+}
+
+type userSummaryUsecase interface {
+	Perform(ctx context.Context, email string) (*usecases.UserSummary, error)
+}
+
+type mrrRenderer interface {
+	RenderMRR(locale string, data *services.MRRData) (string, error)
+}
+
+type userSummaryRenderer interface {
+	RenderUserSummary(locale string, data *services.UserSummaryData) (string, error)
+}
+
+type latestPurchasesUsecase interface {
+	Perform(ctx context.Context, limit int) ([]*models.PurchaseEvent, error)
+}
+
+type latestPurchasesRenderer interface {
+	RenderLatestPurchases(locale string, data *services.LatestPurchasesData) (string, error)
+}
+
+// HandleRevenueReport answers /today and /week with the revenue of the current day or week so far.
+func HandleRevenueReport(logger *zap.Logger, usecase revenueReportUsecase, kind string) tgbot.HandlerFunc {
+	return func(ctx context.Context, b *tgbot.Bot, update *tgmodels.Update) {
+		report, err := usecase.Perform(ctx, kind)
+		if err != nil {
+			logger.Error("failed to get revenue report", zap.Error(err), zap.String("kind", kind))
+			reply(ctx, b, update, failedReply, logger)
+			return
+		}
+
+		reply(ctx, b, update, report, logger)
+	}
+}
+
+// HandleMRR answers /mrr, the reply is rendered in the default notification locale.
+func HandleMRR(logger *zap.Logger, usecase mrrUsecase, templates mrrRenderer) tgbot.HandlerFunc {
+	return func(ctx context.Context, b *tgbot.Bot, update *tgmodels.Update) {
+		mrr, err := usecase.Perform(ctx)
+		if err != nil {
+			logger.Error("failed to get mrr", zap.Error(err))
+			reply(ctx, b, update, failedReply, logger)
+			return
+		}
+
+		text, err := templates.RenderMRR("", &services.MRRData{
+			Amount:        mrr.Amount,
+			Subscriptions: mrr.Subscriptions,
+			Unpriced:      mrr.Unpriced,
+		})
+		if err != nil {
+			logger.Error("failed to render mrr", zap.Error(err))
+			reply(ctx, b, update, failedReply, logger)
+			return
+		}
+
+		reply(ctx, b, update, text, logger)
+	}
+}
+
+// HandleUserSummary answers /user <email>, the reply is rendered in the default notification locale.
+func HandleUserSummary(logger *zap.Logger, usecase userSummaryUsecase, templates userSummaryRenderer) tgbot.HandlerFunc {
+	return func(ctx context.Context, b *tgbot.Bot, update *tgmodels.Update) {
+		args := commandArgs(update)
+		if len(args) != 1 {
+			reply(ctx, b, update, "Использование: /user &lt;email&gt;", logger)
+			return
+		}
+
+		summary, err := usecase.Perform(ctx, args[0])
+		if errors.Is(err, repositories.ErrNotFound) {
+			reply(ctx, b, update, "Пользователь не найден", logger)
+			return
+		}
+		if err != nil {
+			logger.Error("failed to get user summary", zap.Error(err))
+			reply(ctx, b, update, failedReply, logger)
+			return
+		}
+
+		text, err := templates.RenderUserSummary("", &services.UserSummaryData{
+			User:          summary.User,
+			Subscriptions: summary.Subscriptions,
+			Entitlements:  summary.Entitlements,
+		})
+		if err != nil {
+			logger.Error("failed to render user summary", zap.Error(err))
+			reply(ctx, b, update, failedReply, logger)
+			return
+		}
+
+		reply(ctx, b, update, text, logger)
+	}
+}
+
+// HandleLatestPurchases answers /last [n] with the n latest purchase events, 10 by default.
+// The reply is rendered in the default notification locale with times in the location.
+func HandleLatestPurchases(
+	logger *zap.Logger,
+	usecase latestPurchasesUsecase,
+	templates latestPurchasesRenderer,
+	location *time.Location,
+) tgbot.HandlerFunc {
+	return func(ctx context.Context, b *tgbot.Bot, update *tgmodels.Update) {
+		limit := defaultLatestPurchases
+		if args := commandArgs(update); len(args) > 0 {
+			n, err := strconv.Atoi(args[0])
+			if err != nil || n < 1 {
+				reply(ctx, b, update, "Использование: /last [количество]", logger)
+				return
+			}
+			limit = n
+		}
+
+		events, err := usecase.Perform(ctx, limit)
+		if err != nil {
+			logger.Error("failed to list latest purchases", zap.Error(err))
+			reply(ctx, b, update, failedReply, logger)
+			return
+		}
+
+		data := &services.LatestPurchasesData{Purchases: make([]services.LatestPurchase, 0, len(events))}
+		for _, e := range events {
+			data.Purchases = append(data.Purchases, services.LatestPurchase{
+				OccurredAt:  e.OccurredAt.In(location),
+				EventType:   e.EventType,
+				Store:       e.Store,
+				ProductID:   e.ProductID,
+				CountryCode: e.CountryCode,
+				Price:       e.Price,
+				LocalPrice:  localPrice(e),
+			})
+		}
+
+		text, err := templates.RenderLatestPurchases("", data)
+		if err != nil {
+			logger.Error("failed to render latest purchases", zap.Error(err))
+			reply(ctx, b, update, failedReply, logger)
+			return
+		}
+
+		reply(ctx, b, update, text, logger)
+	}
+}
+
+// localPrice returns the price in the currency of the purchase, nil if it's unknown or USD, which Price already is.
+func localPrice(e *models.PurchaseEvent) *services.Money {
+	if e.PriceInPurchasedCurrency == nil || e.Currency == nil || strings.EqualFold(*e.Currency, "USD") {
+		return nil
+	}
+
+	return &services.Money{Amount: *e.PriceInPurchasedCurrency, Currency: strings.ToUpper(*e.Currency)}
+}
@@ -0,0 +1,213 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"athylps/internal/config"
+	"athylps/internal/models"
+	"athylps/internal/repositories"
+	"athylps/internal/services"
+	"athylps/internal/usecases"
+
+	tgbot "github.com/go-telegram/bot"
+	tgmodels "github.com/go-telegram/bot/models"
+	"go.uber.org/zap"
+)
+
+const (
+	testNotifyChatID  = -100123
+	testAllowedUserID = 42
+)
+
+type sentMessage struct {
+	ChatID string
+	Text   string
+}
+
+// fakeBotAPI records messages sent through the Telegram Bot API.
+type fakeBotAPI struct {
+	mu   sync.Mutex
+	sent []sentMessage
+}
+
+func (api *fakeBotAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasSuffix(r.URL.Path, "/sendMessage") {
+		w.Write([]byte(`{"ok":true,"result":true}`))
+		return
+	}
+	if err := r.ParseMultipartForm(1 << 20); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	api.mu.Lock()
+	api.sent = append(api.sent, sentMessage{ChatID: r.FormValue("chat_id"), Text: r.FormValue("text")})
+	api.mu.Unlock()
+
+	fmt.Fprintf(w, `{"ok":true,"result":{"message_id":1,"date":0,"chat":{"id":%s,"type":"group"}}}`, r.FormValue("chat_id"))
+}
+
+func (api *fakeBotAPI) takeSent() []sentMessage {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	sent := api.sent
+	api.sent = nil
+	return sent
+}
+
+type fakeRevenueReport struct{}
+
+func (fakeRevenueReport) Perform(_ context.Context, kind string) (string, error) {
+	return "report " + kind, nil
+}
+
+type fakeMRR struct{}
+
+func (fakeMRR) Perform(context.Context) (*usecases.MRR, error) {
+	return &usecases.MRR{Amount: 49.9, Subscriptions: 12, Unpriced: 2}, nil
+}
+
+type fakeUserSummary struct{}
+
+func (fakeUserSummary) Perform(_ context.Context, email string) (*usecases.UserSummary, error) {
+	if email != "a@example.com" {
+		return nil, fmt.Errorf("failed to get user by email: %w", repositories.ErrNotFound)
+	}
+	expiresAt := time.Date(2026, 1, 8, 0, 0, 0, 0, time.UTC)
+	return &usecases.UserSummary{
+		User:          &models.User{ID: "user_1", Email: &email},
+		Subscriptions: []*models.Subscription{{ProductID: "premium", Store: "APP_STORE", Status: models.SubscriptionStatusActive, ExpiresAt: &expiresAt}},
+		Entitlements:  []*models.Entitlement{{ID: "pro"}},
+	}, nil
+}
+
+type fakeLatestPurchases struct {
+	limit int
+}
+
+func (p *fakeLatestPurchases) Perform(_ context.Context, limit int) ([]*models.PurchaseEvent, error) {
+	p.limit = limit
+	price := 4.99
+	product := "premium"
+	rubles, currency := 299.0, "RUB"
+	return []*models.PurchaseEvent{
+		{
+			EventType:  "INITIAL_PURCHASE",
+			Store:      "APP_STORE",
+			ProductID:  &product,
+			Price:      &price,
+			OccurredAt: time.Date(2025, 12, 8, 10, 30, 0, 0, time.UTC),
+		},
+		// No USD rate is configured for the currency
+		{
+			EventType:                "RENEWAL",
+			Store:                    "RU_STORE",
+			ProductID:                &product,
+			PriceInPurchasedCurrency: &rubles,
+			Currency:                 &currency,
+			OccurredAt:               time.Date(2025, 12, 8, 9, 0, 0, 0, time.UTC),
+		},
+	}, nil
+}
+
+func newTestBot(t *testing.T) (*tgbot.Bot, *fakeBotAPI, *fakeLatestPurchases) {
+	api := &fakeBotAPI{}
+	server := httptest.NewServer(api)
+	t.Cleanup(server.Close)
+
+	cfg := &config.TelegramConfig{
+		BotToken:          "123:token",
+		NotifyChatID:      fmt.Sprint(testNotifyChatID),
+		BotAllowedUserIDs: []int64{testAllowedUserID},
+	}
+	b, err := New(cfg, zap.NewNop(), tgbot.WithServerURL(server.URL), tgbot.WithSkipGetMe(), tgbot.WithNotAsyncHandlers())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	templates, err := services.NewNotificationTemplates(&config.NotifyConfig{Locale: "ru"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	latest := &fakeLatestPurchases{}
+	logger := zap.NewNop()
+	b.RegisterHandlerMatchFunc(Command("today"), HandleRevenueReport(logger, fakeRevenueReport{}, "daily"))
+	b.RegisterHandlerMatchFunc(Command("mrr"), HandleMRR(logger, fakeMRR{}, templates))
+	b.RegisterHandlerMatchFunc(Command("user"), HandleUserSummary(logger, fakeUserSummary{}, templates))
+	b.RegisterHandlerMatchFunc(Command("last"), HandleLatestPurchases(logger, latest, templates, time.UTC))
+
+	return b, api, latest
+}
+
+func commandUpdate(chatID int64, userID int64, text string) *tgmodels.Update {
+	command, _, _ := strings.Cut(text, " ")
+	return &tgmodels.Update{Message: &tgmodels.Message{
+		ID:       7,
+		Chat:     tgmodels.Chat{ID: chatID},
+		From:     &tgmodels.User{ID: userID},
+		Text:     text,
+		Entities: []tgmodels.MessageEntity{{Type: tgmodels.MessageEntityTypeBotCommand, Length: len(command)}},
+	}}
+}
+
+func Test_Commands(t *testing.T) {
+	b, api, latest := newTestBot(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		update *tgmodels.Update
+		want   string
+	}{
+		{"report in the notification chat", commandUpdate(testNotifyChatID, 1, "/today"), "report daily"},
+		{"command addressed to the bot", commandUpdate(testNotifyChatID, 1, "/today@athylps_bot"), "report daily"},
+		{"allowed user in private chat", commandUpdate(testAllowedUserID, testAllowedUserID, "/today"), "report daily"},
+		{"mrr", commandUpdate(testNotifyChatID, 1, "/mrr"), "📈 MRR: <b>$49.90</b>\nАктивных подписок: 12\nИз них без известной цены: 2"},
+		{"user summary", commandUpdate(testNotifyChatID, 1, "/user a@example.com"), "Email: a@example.com\nДоступы: pro\n\n<b>Подписки</b>\n• premium (App Store) – active, до 08.01.2026"},
+		{"unknown user", commandUpdate(testNotifyChatID, 1, "/user b@example.com"), "Пользователь не найден"},
+		{"user without email", commandUpdate(testNotifyChatID, 1, "/user"), "Использование: /user &lt;email&gt;"},
+		{"latest purchases", commandUpdate(testNotifyChatID, 1, "/last 3"), "🧾 <b>Последние события</b>\n\n08.12 10:30 INITIAL_PURCHASE App Store premium $4.99\n08.12 09:00 RENEWAL RuStore premium 299 ₽"},
+		{"invalid number of purchases", commandUpdate(testNotifyChatID, 1, "/last many"), "Использование: /last [количество]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b.ProcessUpdate(ctx, tt.update)
+
+			sent := api.takeSent()
+			if len(sent) != 1 {
+				t.Fatalf("expected a reply, got %+v", sent)
+			}
+			if want := fmt.Sprint(tt.update.Message.Chat.ID); sent[0].ChatID != want {
+				t.Errorf("expected reply to chat %s, got %s", want, sent[0].ChatID)
+			}
+			if !strings.Contains(sent[0].Text, tt.want) {
+				t.Errorf("expected reply containing %q, got %q", tt.want, sent[0].Text)
+			}
+		})
+	}
+
+	if latest.limit != 3 {
+		t.Errorf("expected 3 latest purchases to be requested, got %d", latest.limit)
+	}
+}
+
+func Test_Commands_NotAllowed(t *testing.T) {
+	b, api, _ := newTestBot(t)
+	ctx := context.Background()
+
+	b.ProcessUpdate(ctx, commandUpdate(-100999, 1, "/today"))
+	b.ProcessUpdate(ctx, commandUpdate(1, 1, "/today"))
+	b.ProcessUpdate(ctx, commandUpdate(testNotifyChatID, 1, "hello"))
+
+	if sent := api.takeSent(); len(sent) != 0 {
+		t.Errorf("expected no replies, got %+v", sent)
+	}
+}
@@ -0,0 +1,21 @@
+package handlers
+
+import (
+	"athylps/internal/api"
+	"athylps/internal/models"
+)
+
+// ToApiEntitlement converts an entitlement for responses of the user and admin APIs.
+func ToApiEntitlement(e *models.Entitlement) api.Entitlement {
+	return api.Entitlement{
+		Id:                   e.ID,
+		ProductId:            e.ProductID,
+		Store:                e.Store,
+		PurchasedAt:          e.PurchasedAt,
+		ExpiresAt:            e.ExpiresAt,
+		GracePeriodExpiresAt: e.GracePeriodExpiresAt,
+		IsInGracePeriod:      e.IsInGracePeriod,
+		WillRenew:            e.WillRenew,
+		IsSandbox:            e.IsSandbox,
+	}
+}
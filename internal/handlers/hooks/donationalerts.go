@@ -1,12 +1,55 @@
 package hooks
 
 import (
+	"context"
+	"errors"
 	"net/http"
+
+	"athylps/internal/logging"
+	"athylps/internal/usecases"
+
+	"go.uber.org/zap"
 )
 
-func HandleDonationAlertsWebhook() http.HandlerFunc {
+type connectDonationAlertsUsecase interface {
+	Perform(ctx context.Context, params *usecases.ConnectDonationAlertsParams) error
+}
+
+// HandleDonationAlertsWebhook handles the redirect of the DonationAlerts OAuth authorization-code flow,
+// started with athylpsctl donationalerts authorize.
+func HandleDonationAlertsWebhook(logger *zap.Logger, usecase connectDonationAlertsUsecase) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Add("Content-type", "text/html")
+
+		query := r.URL.Query()
+		if authErr := query.Get("error"); authErr != "" {
+			logging.FromContext(r.Context(), logger).Warn("donationalerts authorization failed", zap.String("error", authErr))
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("<html>authorization failed</html>"))
+			return
+		}
+
+		code := query.Get("code")
+		if code == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("<html>code is required</html>"))
+			return
+		}
+
+		err := usecase.Perform(r.Context(), &usecases.ConnectDonationAlertsParams{Code: code, State: query.Get("state")})
+		if errors.Is(err, usecases.ErrInvalidOAuthState) {
+			logging.FromContext(r.Context(), logger).Warn("donationalerts callback with invalid state")
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("<html>invalid or expired state, start the authorization again</html>"))
+			return
+		}
+		if err != nil {
+			logging.FromContext(r.Context(), logger).Error("failed to connect donationalerts account", zap.Error(err))
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("<html>failed to connect account</html>"))
+			return
+		}
+
 		w.Write([]byte("<html>ok</html>"))
 	}
 }
@@ -4,11 +4,17 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"io"
 	"net/http"
 	"strings"
+	"time"
 
 	"athylps/internal/api"
 	"athylps/internal/config"
+	"athylps/internal/handlers"
+	"athylps/internal/logging"
+	"athylps/internal/metrics"
+	"athylps/internal/models"
 	"athylps/internal/usecases"
 
 	"go.uber.org/zap"
@@ -16,27 +22,38 @@ import (
 
 var ErrUnauthorized = errors.New("Unauthorized")
 
-type sendPurchaseNotificationUsecase interface {
-	Perform(ctx context.Context, params *usecases.SendPurchaseNotificationParams)
+type processWebhookEventUsecase interface {
+	Perform(ctx context.Context, params *usecases.ProcessWebhookEventParams) error
 }
 
 func HandleRevenueCatWebHook(
 	cfg *config.RevenueCatConfig,
 	logger *zap.Logger,
-	usecase sendPurchaseNotificationUsecase,
+	usecase processWebhookEventUsecase,
 ) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logging.FromContext(r.Context(), logger)
 		authHeader := r.Header.Get("Authorization")
 		err := validateToken(authHeader, cfg.BearerToken)
 		if err != nil {
-			logger.Warn("validate token failed", zap.Error(err), zap.String("auth_header", authHeader))
+			metrics.WebhookAuthFailures.WithLabelValues(models.ProviderRevenueCat).Inc()
+			// The header itself isn't logged, it may be the real token sent to a wrong environment
+			logger.Warn("validate token failed", zap.Error(err), zap.Bool("header_present", authHeader != ""))
+			resp := api.WebhookResponse{Status: api.Success}
+			_ = json.NewEncoder(w).Encode(resp)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			logger.Warn("failed to read revenuecat hook request", zap.Error(err))
 			resp := api.WebhookResponse{Status: api.Success}
 			_ = json.NewEncoder(w).Encode(resp)
 			return
 		}
 
 		var data api.RevenueCatWebhookEvent
-		err = json.NewDecoder(r.Body).Decode(&data)
+		err = json.Unmarshal(body, &data)
 		if err != nil {
 			logger.Warn("failed to decode revenuecat hook request")
 			resp := api.WebhookResponse{Status: api.Success}
@@ -44,21 +61,58 @@ func HandleRevenueCatWebHook(
 			return
 		}
 
-		usecase.Perform(r.Context(), &usecases.SendPurchaseNotificationParams{
-			EventType:     string(data.Event.Type),
-			CountryCode:   data.Event.CountryCode,
-			Price:         data.Event.Price,
-			ProductID:     data.Event.ProductId,
-			RenewalNumber: data.Event.RenewalNumber,
-			Store:         string(data.Event.Store),
-		})
+		params := revenueCatEventToParams(&data, body)
+		countWebhookEvent(params)
+		err = usecase.Perform(r.Context(), params)
+		if err != nil {
+			// Respond with an error so RevenueCat redelivers the event later
+			logger.Error("failed to process revenuecat event", zap.Error(err), zap.String("event_id", data.Event.Id))
+			writeInternalError(w)
+			return
+		}
 
-		logger.Info("[RC webhook]", zap.Any("data", data))
+		logger.Info("[RC webhook]", zap.String("event_id", data.Event.Id), zap.String("event_type", string(data.Event.Type)))
 		resp := api.WebhookResponse{Status: api.Success}
 		_ = json.NewEncoder(w).Encode(resp)
 	}
 }
 
+func revenueCatEventToParams(data *api.RevenueCatWebhookEvent, payload []byte) *usecases.ProcessWebhookEventParams {
+	environment := string(data.Event.Environment)
+	return &usecases.ProcessWebhookEventParams{
+		Provider:                 models.ProviderRevenueCat,
+		EventID:                  data.Event.Id,
+		EventType:                string(data.Event.Type),
+		Environment:              &environment,
+		Payload:                  payload,
+		AppUserID:                data.Event.AppUserId,
+		OriginalAppUserID:        data.Event.OriginalAppUserId,
+		Aliases:                  valueOrNil(data.Event.Aliases),
+		CountryCode:              data.Event.CountryCode,
+		Price:                    data.Event.Price,
+		PriceInPurchasedCurrency: data.Event.PriceInPurchasedCurrency,
+		Currency:                 data.Event.Currency,
+		ProductID:                data.Event.ProductId,
+		EntitlementIDs:           valueOrNil(data.Event.EntitlementIds),
+		RenewalNumber:            data.Event.RenewalNumber,
+		Store:                    string(data.Event.Store),
+		EventAt:                  msToTime(data.Event.EventTimestampMs),
+		PurchasedAt:              msToTime(data.Event.PurchasedAtMs),
+		ExpiresAt:                msToTime(data.Event.ExpirationAtMs),
+		GracePeriodExpiresAt:     msToTime(data.Event.GracePeriodExpirationAtMs),
+		TransferredFrom:          valueOrNil(data.Event.TransferredFrom),
+		TransferredTo:            valueOrNil(data.Event.TransferredTo),
+	}
+}
+
+func writeBadRequest(w http.ResponseWriter, message string) {
+	handlers.WriteError(w, http.StatusBadRequest, message)
+}
+
+func writeInternalError(w http.ResponseWriter) {
+	handlers.WriteError(w, http.StatusInternalServerError, "An unexpected error occurred")
+}
+
 func validateToken(authHeader string, token string) error {
 	if authHeader == "" {
 		return ErrUnauthorized
@@ -76,3 +130,26 @@ func validateToken(authHeader string, token string) error {
 
 	return nil
 }
+
+func countWebhookEvent(params *usecases.ProcessWebhookEventParams) {
+	environment := "unknown"
+	if params.Environment != nil {
+		environment = *params.Environment
+	}
+	metrics.WebhookEvents.WithLabelValues(params.Provider, params.EventType, environment).Inc()
+}
+
+func msToTime(ms *int64) *time.Time {
+	if ms == nil {
+		return nil
+	}
+	t := time.UnixMilli(*ms).UTC()
+	return &t
+}
+
+func valueOrNil[T any](v *[]T) []T {
+	if v == nil {
+		return nil
+	}
+	return *v
+}
@@ -1,20 +1,147 @@
 package hooks
 
 import (
+	"encoding/json"
+	"errors"
+	"math"
 	"net/http"
+	"strings"
 
+	"athylps/internal/api"
 	"athylps/internal/config"
+	"athylps/internal/logging"
+	"athylps/internal/models"
+	"athylps/internal/services"
+	"athylps/internal/usecases"
 
 	"go.uber.org/zap"
 )
 
+const rustoreStore = "RU_STORE"
+
+type rustoreNotificationDecoder interface {
+	Decode(payload string) (*services.RustoreNotification, []byte, error)
+}
+
 func HandleRustoreWebHook(
 	cfg *config.RustoreConfig,
+	revenue *config.RevenueConfig,
+	decoder rustoreNotificationDecoder,
 	logger *zap.Logger,
-	usecase sendPurchaseNotificationUsecase,
+	usecase processWebhookEventUsecase,
 ) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(200)
-		w.Write([]byte("ok"))
+		logger := logging.FromContext(r.Context(), logger)
+		var data api.RuStoreWebhookEvent
+		err := json.NewDecoder(r.Body).Decode(&data)
+		if err != nil {
+			logger.Warn("failed to decode rustore hook request", zap.Error(err))
+			writeBadRequest(w, "Invalid request payload")
+			return
+		}
+
+		notification, plaintext, err := decoder.Decode(data.Payload)
+		if err != nil {
+			logger.Warn("failed to decrypt rustore notification", zap.Error(err))
+			if errors.Is(err, services.ErrInvalidRustorePayload) {
+				writeBadRequest(w, "Invalid notification payload")
+				return
+			}
+			writeInternalError(w)
+			return
+		}
+
+		params := rustoreNotificationToParams(notification, plaintext, cfg.EntitlementID, revenue.USDRates)
+		countWebhookEvent(params)
+		err = usecase.Perform(r.Context(), params)
+		if err != nil {
+			// Respond with an error so RuStore redelivers the notification later
+			logger.Error("failed to process rustore notification", zap.Error(err), zap.String("notification_id", notification.NotificationID))
+			writeInternalError(w)
+			return
+		}
+
+		logger.Info(
+			"[RuStore webhook]",
+			zap.String("notification_id", notification.NotificationID),
+			zap.String("type", string(notification.Type)),
+		)
+		resp := api.WebhookResponse{Status: api.Success}
+		_ = json.NewEncoder(w).Encode(resp)
+	}
+}
+
+// rustoreNotificationToParams maps the notification to the params of the pipeline. RuStore reports prices only
+// in the currency of the purchase, they are converted to USD with usdRates to be counted in revenue.
+func rustoreNotificationToParams(
+	n *services.RustoreNotification,
+	payload []byte,
+	entitlementID string,
+	usdRates map[string]float64,
+) *usecases.ProcessWebhookEventParams {
+	environment := "PRODUCTION"
+	if n.Sandbox {
+		environment = "SANDBOX"
+	}
+
+	var eventType string
+	switch n.Type {
+	case services.RustoreNotificationPurchase:
+		eventType = "NON_RENEWING_PURCHASE"
+		if n.IsSubscription() {
+			eventType = "INITIAL_PURCHASE"
+		}
+	case services.RustoreNotificationSubscriptionRenewal:
+		eventType = "RENEWAL"
+	case services.RustoreNotificationCancellation:
+		eventType = "CANCELLATION"
+	case services.RustoreNotificationRefund:
+		eventType = "REFUND"
 	}
+
+	purchasedAt := n.PurchaseTime
+	productID := n.ProductID
+	price := float32(n.Price())
+	currency := n.Currency
+
+	var entitlementIDs []string
+	if n.IsSubscription() && entitlementID != "" {
+		entitlementIDs = []string{entitlementID}
+	}
+
+	return &usecases.ProcessWebhookEventParams{
+		Provider:                 models.ProviderRuStore,
+		EventID:                  n.NotificationID,
+		EventType:                eventType,
+		Environment:              &environment,
+		Payload:                  payload,
+		Store:                    rustoreStore,
+		AppUserID:                n.DeveloperPayload,
+		CountryCode:              n.CountryCode,
+		Price:                    usdPrice(n.Price(), currency, usdRates),
+		PriceInPurchasedCurrency: &price,
+		Currency:                 &currency,
+		ProductID:                &productID,
+		EntitlementIDs:           entitlementIDs,
+		// Without it the event is ordered by the time it has been received
+		EventAt:     n.EventTime,
+		PurchasedAt: &purchasedAt,
+		ExpiresAt:   n.ExpirationTime,
+	}
+}
+
+// usdPrice converts the price to USD, nil if the rate of the currency isn't configured.
+func usdPrice(price float64, currency string, usdRates map[string]float64) *float32 {
+	if strings.EqualFold(currency, "USD") {
+		usd := float32(price)
+		return &usd
+	}
+	rate, ok := usdRates[strings.ToUpper(currency)]
+	if !ok {
+		return nil
+	}
+
+	// Rounded to cents like prices reported in USD
+	usd := float32(math.Round(price*rate*100) / 100)
+	return &usd
 }
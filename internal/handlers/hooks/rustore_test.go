@@ -0,0 +1,74 @@
+package hooks
+
+import (
+	"testing"
+	"time"
+
+	"athylps/internal/services"
+)
+
+func Test_RustoreNotificationToParams_Price(t *testing.T) {
+	expiresAt := time.Date(2026, 1, 4, 0, 0, 0, 0, time.UTC)
+	rates := map[string]float64{"RUB": 0.0125}
+
+	for _, tt := range []struct {
+		name     string
+		amount   int64
+		currency string
+		wantUSD  *float32
+	}{
+		{name: "rubles", amount: 14900, currency: "RUB", wantUSD: ptr[float32](1.86)},
+		{name: "dollars", amount: 499, currency: "USD", wantUSD: ptr[float32](4.99)},
+		{name: "currency without a rate", amount: 1500, currency: "KZT"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			params := rustoreNotificationToParams(&services.RustoreNotification{
+				NotificationID: "notification-1",
+				Type:           services.RustoreNotificationPurchase,
+				ProductID:      "premium_monthly",
+				Amount:         tt.amount,
+				Currency:       tt.currency,
+				PurchaseTime:   expiresAt.AddDate(0, -1, 0),
+				ExpirationTime: &expiresAt,
+			}, []byte(`{}`), "premium", rates)
+
+			if *params.PriceInPurchasedCurrency != float32(tt.amount)/100 || *params.Currency != tt.currency {
+				t.Errorf("expected the price in %s to be kept, got %v %v", tt.currency, *params.PriceInPurchasedCurrency, *params.Currency)
+			}
+			switch {
+			case tt.wantUSD == nil && params.Price != nil:
+				t.Errorf("expected no USD price without a rate, got %v", *params.Price)
+			case tt.wantUSD != nil && (params.Price == nil || *params.Price != *tt.wantUSD):
+				t.Errorf("expected USD price %v, got %v", *tt.wantUSD, params.Price)
+			}
+		})
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func Test_RustoreNotificationToParams_EventTime(t *testing.T) {
+	purchasedAt := time.Date(2025, 12, 4, 0, 0, 0, 0, time.UTC)
+	cancelledAt := purchasedAt.AddDate(0, 0, 10)
+	expiresAt := purchasedAt.AddDate(0, 1, 0)
+	notification := &services.RustoreNotification{
+		NotificationID: "notification-2",
+		Type:           services.RustoreNotificationCancellation,
+		ProductID:      "premium_monthly",
+		Currency:       "RUB",
+		PurchaseTime:   purchasedAt,
+		ExpirationTime: &expiresAt,
+	}
+
+	if params := rustoreNotificationToParams(notification, []byte(`{}`), "premium", nil); params.EventAt != nil {
+		t.Errorf("expected no event time to be made up, the time of receipt is used, got %v", params.EventAt)
+	}
+
+	notification.EventTime = &cancelledAt
+	params := rustoreNotificationToParams(notification, []byte(`{}`), "premium", nil)
+	if params.EventAt == nil || !params.EventAt.Equal(cancelledAt) {
+		t.Errorf("expected the event time of the notification, got %v", params.EventAt)
+	}
+}
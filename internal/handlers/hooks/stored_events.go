@@ -0,0 +1,45 @@
+package hooks
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"athylps/internal/api"
+	"athylps/internal/config"
+	"athylps/internal/models"
+	"athylps/internal/services"
+	"athylps/internal/usecases"
+)
+
+// StoredEventDecoder turns stored webhook events back into the params their webhook has processed them with,
+// so they can be replayed. RevenueCat events are stored as received, RuStore ones as decrypted plaintext.
+type StoredEventDecoder struct {
+	rustoreEntitlementID string
+	usdRates             map[string]float64
+}
+
+func NewStoredEventDecoder(cfg *config.RustoreConfig, revenue *config.RevenueConfig) *StoredEventDecoder {
+	return &StoredEventDecoder{
+		rustoreEntitlementID: cfg.EntitlementID,
+		usdRates:             revenue.USDRates,
+	}
+}
+
+func (d *StoredEventDecoder) Decode(event *models.WebhookEvent) (*usecases.ProcessWebhookEventParams, error) {
+	switch event.Provider {
+	case models.ProviderRevenueCat:
+		var data api.RevenueCatWebhookEvent
+		if err := json.Unmarshal(event.Payload, &data); err != nil {
+			return nil, fmt.Errorf("failed to decode revenuecat event %s: %w", event.EventID, err)
+		}
+		return revenueCatEventToParams(&data, event.Payload), nil
+	case models.ProviderRuStore:
+		notification, err := services.ParseRustoreNotification(event.Payload)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode rustore notification %s: %w", event.EventID, err)
+		}
+		return rustoreNotificationToParams(notification, event.Payload, d.rustoreEntitlementID, d.usdRates), nil
+	}
+
+	return nil, fmt.Errorf("unknown webhook provider %q", event.Provider)
+}
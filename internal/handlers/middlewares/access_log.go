@@ -0,0 +1,62 @@
+package middlewares
+
+import (
+	"net"
+	"net/http"
+	"time"
+
+	"athylps/internal/logging"
+	"athylps/internal/tracing"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"go.uber.org/zap"
+)
+
+// AccessLog puts a logger with the request id and trace into the request context for handlers and usecases,
+// see logging.FromContext, and logs every request once it's handled. Must be used after chi's RequestID.
+func AccessLog(logger *zap.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			requestID := middleware.GetReqID(r.Context())
+			requestLogger := tracing.Logger(r.Context(), logger).With(zap.String("request_id", requestID))
+			if requestID != "" {
+				w.Header().Set(middleware.RequestIDHeader, requestID)
+			}
+
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			defer func() {
+				status := ww.Status()
+				if status == 0 {
+					status = http.StatusOK
+				}
+				fields := []zap.Field{
+					zap.String("method", r.Method),
+					// The query is left out, it may carry secrets like OAuth codes
+					zap.String("path", r.URL.Path),
+					zap.String("route", routePattern(r)),
+					zap.Int("status", status),
+					zap.Int("bytes", ww.BytesWritten()),
+					zap.Duration("latency", time.Since(start)),
+					zap.String("remote_ip", remoteIP(r)),
+					zap.String("user_agent", r.UserAgent()),
+				}
+				if status >= http.StatusInternalServerError {
+					requestLogger.Error("request", fields...)
+					return
+				}
+				requestLogger.Info("request", fields...)
+			}()
+
+			next.ServeHTTP(ww, r.WithContext(logging.WithLogger(r.Context(), requestLogger)))
+		})
+	}
+}
+
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,63 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"athylps/internal/logging"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func Test_AccessLog(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	r := chi.NewRouter()
+	r.Use(middleware.RequestID)
+	r.Use(AccessLog(zap.New(core)))
+	r.Post("/hooks/{provider}", func(w http.ResponseWriter, r *http.Request) {
+		logging.FromContext(r.Context(), zap.NewNop()).Info("handled")
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/hooks/revenuecat?token=secret", nil)
+	req.Header.Set(middleware.RequestIDHeader, "req-1")
+	req.RemoteAddr = "203.0.113.7:51234"
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	if got := rec.Header().Get(middleware.RequestIDHeader); got != "req-1" {
+		t.Errorf("expected request id in the response, got %q", got)
+	}
+
+	entries := logs.All()
+	if len(entries) != 2 {
+		t.Fatalf("expected handler and access log entries, got %d", len(entries))
+	}
+	if got := entries[0].ContextMap()["request_id"]; got != "req-1" {
+		t.Errorf("expected handler to log with the request logger, got request_id %v", got)
+	}
+
+	access := entries[1]
+	if access.Level != zapcore.ErrorLevel {
+		t.Errorf("expected failed request to be logged as error, got %s", access.Level)
+	}
+	want := map[string]any{
+		"request_id": "req-1",
+		"method":     http.MethodPost,
+		"path":       "/hooks/revenuecat",
+		"route":      "/hooks/{provider}",
+		"status":     int64(http.StatusInternalServerError),
+		"remote_ip":  "203.0.113.7",
+	}
+	fields := access.ContextMap()
+	for key, value := range want {
+		if fields[key] != value {
+			t.Errorf("expected %s to be %v, got %v", key, value, fields[key])
+		}
+	}
+}
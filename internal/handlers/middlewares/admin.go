@@ -0,0 +1,38 @@
+package middlewares
+
+import (
+	"net/http"
+	"slices"
+
+	"athylps/internal/config"
+	"athylps/internal/handlers"
+	"athylps/internal/logging"
+
+	"go.uber.org/zap"
+)
+
+// adminClaim is the Firebase custom claim granting access to the admin API, set with the Admin SDK.
+const adminClaim = "admin"
+
+// RequireAdmin lets through requests of admins, users with the admin custom claim or an uid from ADMIN_UIDS.
+// It must run after FirebaseAuth.
+func RequireAdmin(cfg *config.AdminConfig, logger *zap.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, ok := AuthTokenFromContext(r.Context())
+			if !ok {
+				handlers.WriteError(w, http.StatusUnauthorized, "Bearer token is required")
+				return
+			}
+
+			isAdmin, _ := token.Claims[adminClaim].(bool)
+			if !isAdmin && !slices.Contains(cfg.UIDs, token.UID) {
+				logging.FromContext(r.Context(), logger).Warn("admin api access denied")
+				handlers.WriteError(w, http.StatusForbidden, "Admin access is required")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
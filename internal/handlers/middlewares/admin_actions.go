@@ -0,0 +1,73 @@
+package middlewares
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"athylps/internal/logging"
+	"athylps/internal/repositories"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"go.uber.org/zap"
+)
+
+// maxRecordedRequestSize is the largest request body stored with an admin action, larger bodies are left out.
+const maxRecordedRequestSize = 64 << 10
+
+type adminActionRecorder interface {
+	Create(ctx context.Context, p *repositories.CreateAdminActionParams) error
+}
+
+// RecordAdminActions records every mutating request, anything but GET, HEAD and OPTIONS,
+// with the acting admin, the JSON body and the response status once it's handled.
+// It must run after RequireAdmin, so only admins' requests are recorded.
+func RecordAdminActions(actions adminActionRecorder, logger *zap.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions:
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// One byte more than recorded tells the body is too large, the handler still reads it whole
+			body, _ := io.ReadAll(io.LimitReader(r.Body, maxRecordedRequestSize+1))
+			r.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), r.Body))
+
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r)
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			action := &repositories.CreateAdminActionParams{
+				Method: r.Method,
+				Route:  routePattern(r),
+				Path:   r.URL.Path,
+				Status: status,
+			}
+			if token, ok := AuthTokenFromContext(r.Context()); ok {
+				action.AdminUID = token.UID
+			}
+			if len(body) <= maxRecordedRequestSize && json.Valid(body) {
+				action.Request = body
+			}
+
+			// The action is done, it's recorded even if the client has gone away
+			if err := actions.Create(context.WithoutCancel(r.Context()), action); err != nil {
+				logging.FromContext(r.Context(), logger).Error(
+					"failed to record admin action",
+					zap.Error(err),
+					zap.String("admin_uid", action.AdminUID),
+					zap.String("method", action.Method),
+					zap.String("path", action.Path),
+					zap.Int("status", status),
+				)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package middlewares
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"athylps/internal/repositories"
+
+	"firebase.google.com/go/v4/auth"
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+)
+
+type fakeAdminActionRecorder struct {
+	actions []*repositories.CreateAdminActionParams
+}
+
+func (r *fakeAdminActionRecorder) Create(_ context.Context, p *repositories.CreateAdminActionParams) error {
+	r.actions = append(r.actions, p)
+	return nil
+}
+
+func Test_RecordAdminActions(t *testing.T) {
+	recorder := &fakeAdminActionRecorder{}
+	r := chi.NewRouter()
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(WithAuthToken(r.Context(), &auth.Token{UID: "admin-1"})))
+		})
+	})
+	r.Use(RecordAdminActions(recorder, zap.NewNop()))
+	r.Get("/admin/v1/users/{id}", func(w http.ResponseWriter, r *http.Request) {})
+	r.Post("/admin/v1/users/{id}/entitlements", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if string(body) != `{"entitlement_id":"premium"}` {
+			t.Errorf("expected the handler to read the whole body, got %q", body)
+		}
+		w.WriteHeader(http.StatusCreated)
+	})
+	r.Delete("/admin/v1/users/{id}/entitlements/{entitlementID}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	requests := []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/admin/v1/users/1", nil),
+		httptest.NewRequest(http.MethodPost, "/admin/v1/users/1/entitlements", strings.NewReader(`{"entitlement_id":"premium"}`)),
+		httptest.NewRequest(http.MethodDelete, "/admin/v1/users/1/entitlements/premium", strings.NewReader("not json")),
+	}
+	for _, req := range requests {
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	if len(recorder.actions) != 2 {
+		t.Fatalf("expected mutating requests only to be recorded, got %+v", recorder.actions)
+	}
+	grant := recorder.actions[0]
+	if grant.AdminUID != "admin-1" || grant.Route != "/admin/v1/users/{id}/entitlements" || grant.Path != "/admin/v1/users/1/entitlements" ||
+		grant.Status != http.StatusCreated || string(grant.Request) != `{"entitlement_id":"premium"}` {
+		t.Errorf("unexpected grant action: %+v", grant)
+	}
+	revoke := recorder.actions[1]
+	if revoke.Method != http.MethodDelete || revoke.Status != http.StatusNotFound || revoke.Request != nil {
+		t.Errorf("expected failed revoke to be recorded without the invalid body, got %+v", revoke)
+	}
+}
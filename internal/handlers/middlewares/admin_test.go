@@ -0,0 +1,47 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"athylps/internal/config"
+
+	"firebase.google.com/go/v4/auth"
+	"go.uber.org/zap"
+)
+
+func Test_RequireAdmin(t *testing.T) {
+	handler := RequireAdmin(&config.AdminConfig{UIDs: []string{"allowlisted-uid"}}, zap.NewNop())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name       string
+		token      *auth.Token
+		wantStatus int
+	}{
+		{name: "admin claim", token: &auth.Token{UID: "uid", Claims: map[string]any{"admin": true}}, wantStatus: http.StatusOK},
+		{name: "allowlisted uid", token: &auth.Token{UID: "allowlisted-uid", Claims: map[string]any{}}, wantStatus: http.StatusOK},
+		{name: "admin claim disabled", token: &auth.Token{UID: "uid", Claims: map[string]any{"admin": false}}, wantStatus: http.StatusForbidden},
+		{name: "non-boolean claim", token: &auth.Token{UID: "uid", Claims: map[string]any{"admin": "true"}}, wantStatus: http.StatusForbidden},
+		{name: "regular user", token: &auth.Token{UID: "uid", Claims: map[string]any{}}, wantStatus: http.StatusForbidden},
+		{name: "no token", wantStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/admin/v1/users", nil)
+			if tt.token != nil {
+				req = req.WithContext(WithAuthToken(req.Context(), tt.token))
+			}
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package middlewares
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"athylps/internal/handlers"
+	"athylps/internal/logging"
+
+	"firebase.google.com/go/v4/auth"
+	"go.uber.org/zap"
+)
+
+type authTokenContextKey struct{}
+
+type idTokenVerifier interface {
+	VerifyIDToken(ctx context.Context, idToken string) (*auth.Token, error)
+}
+
+type revocationCheckingVerifier interface {
+	VerifyIDTokenAndCheckRevoked(ctx context.Context, idToken string) (*auth.Token, error)
+}
+
+// RevocationChecking verifies ID tokens like the Firebase auth client does and also rejects tokens
+// of disabled users and tokens issued before the user's sessions were revoked.
+type RevocationChecking struct {
+	verifier revocationCheckingVerifier
+}
+
+// NewRevocationChecking returns the verifier for FirebaseAuth where a token mustn't outlive a revocation,
+// e.g. admin routes. Every request costs a call to Firebase.
+func NewRevocationChecking(verifier revocationCheckingVerifier) *RevocationChecking {
+	return &RevocationChecking{verifier: verifier}
+}
+
+func (v *RevocationChecking) VerifyIDToken(ctx context.Context, idToken string) (*auth.Token, error) {
+	return v.verifier.VerifyIDTokenAndCheckRevoked(ctx, idToken)
+}
+
+// FirebaseAuth verifies the Firebase ID token passed as `Authorization: Bearer <token>`
+// and puts the verified token into the request context. Requests without a valid token are rejected.
+func FirebaseAuth(verifier idTokenVerifier, logger *zap.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			idToken, ok := bearerToken(r.Header.Get("Authorization"))
+			if !ok {
+				handlers.WriteError(w, http.StatusUnauthorized, "Bearer token is required")
+				return
+			}
+
+			token, err := verifier.VerifyIDToken(r.Context(), idToken)
+			if err != nil {
+				logging.FromContext(r.Context(), logger).Info("failed to verify firebase id token", zap.Error(err))
+				handlers.WriteError(w, http.StatusUnauthorized, "Invalid or expired token")
+				return
+			}
+
+			ctx := WithAuthToken(r.Context(), token)
+			ctx = logging.WithLogger(ctx, logging.FromContext(ctx, logger).With(zap.String("firebase_uid", token.UID)))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// WithAuthToken returns a copy of the context carrying the verified Firebase ID token.
+func WithAuthToken(ctx context.Context, token *auth.Token) context.Context {
+	return context.WithValue(ctx, authTokenContextKey{}, token)
+}
+
+// AuthTokenFromContext returns the verified Firebase ID token put into the context by FirebaseAuth.
+func AuthTokenFromContext(ctx context.Context) (*auth.Token, bool) {
+	token, ok := ctx.Value(authTokenContextKey{}).(*auth.Token)
+	return token, ok && token != nil
+}
+
+func bearerToken(authHeader string) (string, bool) {
+	scheme, token, found := strings.Cut(authHeader, " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
@@ -0,0 +1,157 @@
+package middlewares
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"firebase.google.com/go/v4/auth"
+	"go.uber.org/zap"
+)
+
+// localTokenVerifier verifies HS256 tokens signed with a local key, standing in for the Firebase verifier.
+type localTokenVerifier struct {
+	key []byte
+	// Users whose sessions have been revoked, only VerifyIDTokenAndCheckRevoked rejects their tokens
+	revoked map[string]bool
+}
+
+func (v *localTokenVerifier) sign(t *testing.T, claims map[string]any) string {
+	t.Helper()
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unsigned := header + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(v.mac(unsigned))
+}
+
+func (v *localTokenVerifier) mac(unsigned string) []byte {
+	mac := hmac.New(sha256.New, v.key)
+	mac.Write([]byte(unsigned))
+	return mac.Sum(nil)
+}
+
+func (v *localTokenVerifier) VerifyIDToken(_ context.Context, idToken string) (*auth.Token, error) {
+	parts := strings.Split(idToken, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || !hmac.Equal(signature, v.mac(parts[0]+"."+parts[1])) {
+		return nil, errors.New("invalid signature")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, err
+	}
+	var claims map[string]any
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, err
+	}
+	exp, _ := claims["exp"].(float64)
+	if int64(exp) < time.Now().Unix() {
+		return nil, errors.New("token expired")
+	}
+	uid, _ := claims["sub"].(string)
+	return &auth.Token{UID: uid, Subject: uid, Expires: int64(exp), Claims: claims}, nil
+}
+
+func (v *localTokenVerifier) VerifyIDTokenAndCheckRevoked(ctx context.Context, idToken string) (*auth.Token, error) {
+	token, err := v.VerifyIDToken(ctx, idToken)
+	if err != nil {
+		return nil, err
+	}
+	if v.revoked[token.UID] {
+		return nil, errors.New("id token has been revoked")
+	}
+	return token, nil
+}
+
+func Test_FirebaseAuth(t *testing.T) {
+	verifier := &localTokenVerifier{key: []byte("local-test-key")}
+	other := &localTokenVerifier{key: []byte("other-key")}
+	valid := verifier.sign(t, map[string]any{"sub": "firebase-uid", "email": "user@example.com", "exp": time.Now().Add(time.Hour).Unix()})
+
+	handler := FirebaseAuth(verifier, zap.NewNop())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := AuthTokenFromContext(r.Context())
+		if !ok {
+			t.Fatal("expected token in context")
+		}
+		w.Write([]byte(token.UID + " " + token.Claims["email"].(string)))
+	}))
+
+	tests := []struct {
+		name       string
+		authHeader string
+		wantStatus int
+		wantBody   string
+	}{
+		{name: "valid token", authHeader: "Bearer " + valid, wantStatus: http.StatusOK, wantBody: "firebase-uid user@example.com"},
+		{name: "lowercase scheme", authHeader: "bearer " + valid, wantStatus: http.StatusOK, wantBody: "firebase-uid user@example.com"},
+		{name: "missing header", authHeader: "", wantStatus: http.StatusUnauthorized},
+		{name: "wrong scheme", authHeader: "Basic " + valid, wantStatus: http.StatusUnauthorized},
+		{name: "empty token", authHeader: "Bearer ", wantStatus: http.StatusUnauthorized},
+		{name: "foreign signature", authHeader: "Bearer " + other.sign(t, map[string]any{"sub": "x", "exp": time.Now().Add(time.Hour).Unix()}), wantStatus: http.StatusUnauthorized},
+		{name: "expired token", authHeader: "Bearer " + verifier.sign(t, map[string]any{"sub": "x", "exp": time.Now().Add(-time.Hour).Unix()}), wantStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/v1/me", nil)
+			if tt.authHeader != "" {
+				req.Header.Set("Authorization", tt.authHeader)
+			}
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+			if tt.wantBody != "" && rec.Body.String() != tt.wantBody {
+				t.Errorf("unexpected body: %s", rec.Body.String())
+			}
+		})
+	}
+}
+
+func Test_FirebaseAuth_RevocationChecking(t *testing.T) {
+	verifier := &localTokenVerifier{key: []byte("local-test-key"), revoked: map[string]bool{"revoked-uid": true}}
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	exp := time.Now().Add(time.Hour).Unix()
+
+	tests := []struct {
+		name       string
+		verifier   idTokenVerifier
+		uid        string
+		wantStatus int
+	}{
+		{name: "active session", verifier: NewRevocationChecking(verifier), uid: "admin-uid", wantStatus: http.StatusOK},
+		{name: "revoked session", verifier: NewRevocationChecking(verifier), uid: "revoked-uid", wantStatus: http.StatusUnauthorized},
+		{name: "revoked session without the check", verifier: verifier, uid: "revoked-uid", wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/admin/v1/users", nil)
+			req.Header.Set("Authorization", "Bearer "+verifier.sign(t, map[string]any{"sub": tt.uid, "exp": exp}))
+			rec := httptest.NewRecorder()
+
+			FirebaseAuth(tt.verifier, zap.NewNop())(ok).ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+		})
+	}
+}
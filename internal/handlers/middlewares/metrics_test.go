@@ -0,0 +1,52 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"athylps/internal/metrics"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func Test_Metrics_LabelsRequestsByRoute(t *testing.T) {
+	r := chi.NewRouter()
+	r.Use(Metrics)
+	r.Route("/v1", func(r chi.Router) {
+		r.Get("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		})
+		r.Get("/me", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("{}"))
+		})
+	})
+
+	requests := []struct {
+		path   string
+		route  string
+		status string
+	}{
+		{path: "/v1/users/42", route: "/v1/users/{id}", status: "204"},
+		{path: "/v1/users/43", route: "/v1/users/{id}", status: "204"},
+		{path: "/v1/me", route: "/v1/me", status: "200"},
+		{path: "/wp-admin/index.php", route: unmatchedRoute, status: "404"},
+	}
+	before := make(map[string]float64)
+	for _, req := range requests {
+		before[req.route] = testutil.ToFloat64(metrics.HTTPRequests.WithLabelValues(http.MethodGet, req.route, req.status))
+	}
+
+	for _, req := range requests {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, req.path, nil))
+	}
+
+	want := map[string]float64{"/v1/users/{id}": 2, "/v1/me": 1, unmatchedRoute: 1}
+	for _, req := range requests {
+		got := testutil.ToFloat64(metrics.HTTPRequests.WithLabelValues(http.MethodGet, req.route, req.status)) - before[req.route]
+		if got != want[req.route] {
+			t.Errorf("expected %v requests to %s with status %s, got %v", want[req.route], req.route, req.status, got)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package middlewares
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"athylps/internal/handlers"
+	"athylps/internal/logging"
+	"athylps/internal/models"
+	"athylps/internal/repositories"
+	"athylps/internal/usecases"
+
+	"go.uber.org/zap"
+)
+
+// TimezoneHeader is sent by the mobile app with the IANA timezone of the device.
+const TimezoneHeader = "X-Timezone"
+
+const maxLocaleLength = 35
+
+type userContextKey struct{}
+
+type provisionUserUsecase interface {
+	Perform(ctx context.Context, params *usecases.ProvisionUserParams) (*models.User, error)
+}
+
+// ProvisionUser makes sure the user authenticated by FirebaseAuth exists in our database
+// and puts the user into the request context. Must be used after FirebaseAuth.
+func ProvisionUser(usecase provisionUserUsecase, logger *zap.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, ok := AuthTokenFromContext(r.Context())
+			if !ok {
+				handlers.WriteError(w, http.StatusUnauthorized, "Bearer token is required")
+				return
+			}
+
+			var email *string
+			if claim, ok := token.Claims["email"].(string); ok && claim != "" {
+				email = &claim
+			}
+
+			user, err := usecase.Perform(r.Context(), &usecases.ProvisionUserParams{
+				FirebaseUID: token.UID,
+				Email:       email,
+				Timezone:    parseTimezone(r.Header.Get(TimezoneHeader)),
+				Locale:      parseLocale(r.Header.Get("Accept-Language")),
+			})
+			if errors.Is(err, repositories.ErrNotFound) {
+				handlers.WriteError(w, http.StatusForbidden, "User has been deleted")
+				return
+			}
+			if err != nil {
+				logging.FromContext(r.Context(), logger).Error("failed to provision user", zap.Error(err))
+				handlers.WriteError(w, http.StatusInternalServerError, "An unexpected error occurred")
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(WithUser(r.Context(), user)))
+		})
+	}
+}
+
+// WithUser returns a copy of the context carrying the authenticated user.
+func WithUser(ctx context.Context, user *models.User) context.Context {
+	return context.WithValue(ctx, userContextKey{}, user)
+}
+
+// UserFromContext returns the authenticated user put into the context by ProvisionUser.
+func UserFromContext(ctx context.Context) (*models.User, bool) {
+	user, ok := ctx.Value(userContextKey{}).(*models.User)
+	return user, ok && user != nil
+}
+
+func parseTimezone(header string) *string {
+	header = strings.TrimSpace(header)
+	if header == "" || header == "Local" {
+		return nil
+	}
+
+	if _, err := time.LoadLocation(header); err != nil {
+		return nil
+	}
+
+	return &header
+}
+
+// parseLocale returns the most preferred language tag of the Accept-Language header.
+func parseLocale(header string) *string {
+	var best string
+	bestQuality := -1.0
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		tag = strings.TrimSpace(tag)
+		if !isLanguageTag(tag) {
+			continue
+		}
+
+		quality := 1.0
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(q, 64)
+			if err != nil {
+				continue
+			}
+			quality = parsed
+		}
+
+		if quality > bestQuality {
+			best, bestQuality = tag, quality
+		}
+	}
+
+	if best == "" || bestQuality <= 0 {
+		return nil
+	}
+
+	return &best
+}
+
+func isLanguageTag(tag string) bool {
+	if tag == "" || tag == "*" || len(tag) > maxLocaleLength {
+		return false
+	}
+
+	for _, c := range tag {
+		if !(c == '-' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9') {
+			return false
+		}
+	}
+
+	return true
+}
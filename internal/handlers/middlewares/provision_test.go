@@ -0,0 +1,93 @@
+package middlewares
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"athylps/internal/models"
+	"athylps/internal/repositories"
+	"athylps/internal/usecases"
+
+	"firebase.google.com/go/v4/auth"
+	"go.uber.org/zap"
+)
+
+type fakeProvisionUserUsecase struct {
+	params  *usecases.ProvisionUserParams
+	deleted bool
+}
+
+func (u *fakeProvisionUserUsecase) Perform(_ context.Context, params *usecases.ProvisionUserParams) (*models.User, error) {
+	u.params = params
+	if u.deleted {
+		return nil, repositories.ErrNotFound
+	}
+	return &models.User{ID: "user-id", Email: params.Email, Locale: params.Locale, Timezone: params.Timezone}, nil
+}
+
+func Test_ProvisionUser(t *testing.T) {
+	usecase := &fakeProvisionUserUsecase{}
+	handler := ProvisionUser(usecase, zap.NewNop())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, ok := UserFromContext(r.Context())
+		if !ok {
+			t.Fatal("expected user in context")
+		}
+		w.Write([]byte(user.ID))
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/me", nil)
+	req.Header.Set("Accept-Language", "en;q=0.8, ru-RU, ru;q=0.9, *;q=0.1")
+	req.Header.Set(TimezoneHeader, "Europe/Moscow")
+	token := &auth.Token{UID: "firebase-uid", Claims: map[string]any{"email": "user@example.com"}}
+	req = req.WithContext(WithAuthToken(req.Context(), token))
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK || rec.Body.String() != "user-id" {
+		t.Fatalf("unexpected response: %d %s", rec.Code, rec.Body.String())
+	}
+	p := usecase.params
+	if p.FirebaseUID != "firebase-uid" || *p.Email != "user@example.com" || *p.Locale != "ru-RU" || *p.Timezone != "Europe/Moscow" {
+		t.Errorf("unexpected provision params: %+v", p)
+	}
+
+	usecase.deleted = true
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("expected deleted user to be forbidden, got %d", rec.Code)
+	}
+}
+
+func Test_ParseLocale(t *testing.T) {
+	tests := map[string]string{
+		"":                         "",
+		"ru":                       "ru",
+		"ru-RU,ru;q=0.9,en;q=0.8":  "ru-RU",
+		"en;q=0.5, de-DE;q=0.7":    "de-DE",
+		"*":                        "",
+		"<script>":                 "",
+		"en;q=0":                   "",
+		"fr-CA;q=abc, it-IT;q=0.3": "it-IT",
+	}
+	for header, want := range tests {
+		got := parseLocale(header)
+		if (got == nil && want != "") || (got != nil && *got != want) {
+			t.Errorf("parseLocale(%q) = %v, want %q", header, got, want)
+		}
+	}
+}
+
+func Test_ParseTimezone(t *testing.T) {
+	if tz := parseTimezone("America/New_York"); tz == nil || *tz != "America/New_York" {
+		t.Errorf("expected valid timezone, got %v", tz)
+	}
+	for _, header := range []string{"", "Local", "Mars/Olympus", "../../etc/passwd"} {
+		if tz := parseTimezone(header); tz != nil {
+			t.Errorf("expected %q to be rejected, got %s", header, *tz)
+		}
+	}
+}
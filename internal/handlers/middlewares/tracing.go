@@ -0,0 +1,32 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// untracedPaths are polled by infrastructure, their spans would only be noise.
+var untracedPaths = map[string]bool{
+	"/health":  true,
+	"/metrics": true,
+}
+
+// Tracing starts a span for the request, continuing the trace of the caller if it sent a traceparent.
+// The span is named after the chi route pattern, e.g. POST /hooks/revenuecat. Must be used on the root router.
+func Tracing(next http.Handler) http.Handler {
+	named := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r)
+
+		route := routePattern(r)
+		span := trace.SpanFromContext(r.Context())
+		span.SetName(r.Method + " " + route)
+		span.SetAttributes(semconv.HTTPRoute(route))
+	})
+
+	return otelhttp.NewHandler(named, "http.request", otelhttp.WithFilter(func(r *http.Request) bool {
+		return !untracedPaths[r.URL.Path]
+	}))
+}
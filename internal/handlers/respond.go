@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"athylps/internal/api"
+)
+
+// WriteJSON writes v as a JSON response with the given status code.
+func WriteJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// WriteError writes an api.ErrorResponse with the given status code and human-readable message.
+func WriteError(w http.ResponseWriter, status int, message string) {
+	WriteJSON(w, status, api.ErrorResponse{
+		Error:   http.StatusText(status),
+		Message: &message,
+	})
+}
+
+// JSONObject decodes stored JSON for a response, nil if it's absent or not an object.
+func JSONObject(raw []byte) *map[string]any {
+	var object map[string]any
+	if len(raw) == 0 || json.Unmarshal(raw, &object) != nil || object == nil {
+		return nil
+	}
+	return &object
+}
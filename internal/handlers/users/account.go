@@ -0,0 +1,142 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"athylps/internal/api"
+	"athylps/internal/handlers"
+	"athylps/internal/handlers/middlewares"
+	"athylps/internal/logging"
+	"athylps/internal/models"
+	"athylps/internal/repositories"
+	"athylps/internal/usecases"
+
+	"go.uber.org/zap"
+)
+
+type deleteAccountUsecase interface {
+	Perform(ctx context.Context, user *models.User) (*models.User, error)
+}
+
+type exportUserDataUsecase interface {
+	Perform(ctx context.Context, user *models.User) (*usecases.UserDataExport, error)
+}
+
+func HandleDeleteMe(logger *zap.Logger, usecase deleteAccountUsecase) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := middlewares.UserFromContext(r.Context())
+		if !ok {
+			handlers.WriteError(w, http.StatusUnauthorized, "Bearer token is required")
+			return
+		}
+
+		deleted, err := usecase.Perform(r.Context(), user)
+		if errors.Is(err, repositories.ErrNotFound) {
+			handlers.WriteError(w, http.StatusForbidden, "User has been deleted")
+			return
+		}
+		if err != nil {
+			logging.FromContext(r.Context(), logger).Error("failed to delete account", zap.Error(err), zap.String("user_id", user.ID))
+			handlers.WriteError(w, http.StatusInternalServerError, "An unexpected error occurred")
+			return
+		}
+
+		resp := api.AccountDeletionResponse{}
+		if deleted.DeletedAt != nil {
+			resp.DeletedAt = *deleted.DeletedAt
+		}
+		if deleted.PurgeAfter != nil {
+			resp.PurgeAfter = *deleted.PurgeAfter
+		}
+
+		handlers.WriteJSON(w, http.StatusAccepted, resp)
+	}
+}
+
+func HandleExportMe(logger *zap.Logger, usecase exportUserDataUsecase) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := middlewares.UserFromContext(r.Context())
+		if !ok {
+			handlers.WriteError(w, http.StatusUnauthorized, "Bearer token is required")
+			return
+		}
+
+		export, err := usecase.Perform(r.Context(), user)
+		if err != nil {
+			logging.FromContext(r.Context(), logger).Error("failed to export user data", zap.Error(err), zap.String("user_id", user.ID))
+			handlers.WriteError(w, http.StatusInternalServerError, "An unexpected error occurred")
+			return
+		}
+
+		filename := fmt.Sprintf("athylps-export-%s.json", export.ExportedAt.UTC().Format("2006-01-02"))
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+		handlers.WriteJSON(w, http.StatusOK, toApiUserDataExport(export))
+	}
+}
+
+func toApiUserDataExport(e *usecases.UserDataExport) api.UserDataExport {
+	resp := api.UserDataExport{
+		ExportedAt: e.ExportedAt,
+		Profile: api.ExportedProfile{
+			Id:           e.User.ID,
+			Email:        e.User.Email,
+			FirebaseUid:  e.User.FirebaseUID,
+			RevenuecatId: e.User.RevenueCatID,
+			Timezone:     e.User.Timezone,
+			Locale:       e.User.Locale,
+			CreatedAt:    e.User.CreatedAt,
+			UpdatedAt:    e.User.UpdatedAt,
+		},
+		Devices:       make([]api.ExportedDevice, 0, len(e.Devices)),
+		Subscriptions: make([]api.ExportedSubscription, 0, len(e.Subscriptions)),
+		Purchases:     make([]api.ExportedPurchase, 0, len(e.PurchaseEvents)),
+		StoreEvents:   make([]api.ExportedStoreEvent, 0, len(e.StoreEvents)),
+	}
+	for _, d := range e.Devices {
+		resp.Devices = append(resp.Devices, api.ExportedDevice{
+			AppUserId: d.AppUserID,
+			LinkedAt:  d.CreatedAt,
+		})
+	}
+	for _, s := range e.Subscriptions {
+		entitlementIDs := s.EntitlementIDs
+		if entitlementIDs == nil {
+			entitlementIDs = []string{}
+		}
+		resp.Subscriptions = append(resp.Subscriptions, api.ExportedSubscription{
+			ProductId:      s.ProductID,
+			EntitlementIds: entitlementIDs,
+			Store:          s.Store,
+			Environment:    s.Environment,
+			Status:         string(s.Status),
+			PurchasedAt:    s.PurchasedAt,
+			ExpiresAt:      s.ExpiresAt,
+			CancelledAt:    s.CancelledAt,
+		})
+	}
+	for _, p := range e.PurchaseEvents {
+		resp.Purchases = append(resp.Purchases, api.ExportedPurchase{
+			EventType:                p.EventType,
+			Store:                    p.Store,
+			ProductId:                p.ProductID,
+			CountryCode:              p.CountryCode,
+			Price:                    p.Price,
+			PriceInPurchasedCurrency: p.PriceInPurchasedCurrency,
+			Currency:                 p.Currency,
+			OccurredAt:               p.OccurredAt,
+		})
+	}
+	for _, ev := range e.StoreEvents {
+		resp.StoreEvents = append(resp.StoreEvents, api.ExportedStoreEvent{
+			Provider:   ev.Provider,
+			EventType:  ev.EventType,
+			ReceivedAt: ev.ReceivedAt,
+			Payload:    handlers.JSONObject(ev.Payload),
+		})
+	}
+
+	return resp
+}
@@ -0,0 +1,42 @@
+package users
+
+import (
+	"context"
+	"net/http"
+
+	"athylps/internal/api"
+	"athylps/internal/handlers"
+	"athylps/internal/handlers/middlewares"
+	"athylps/internal/logging"
+	"athylps/internal/models"
+
+	"go.uber.org/zap"
+)
+
+type getEntitlementsUsecase interface {
+	Perform(ctx context.Context, userID string) ([]*models.Entitlement, error)
+}
+
+func HandleGetEntitlements(logger *zap.Logger, usecase getEntitlementsUsecase) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := middlewares.UserFromContext(r.Context())
+		if !ok {
+			handlers.WriteError(w, http.StatusUnauthorized, "Bearer token is required")
+			return
+		}
+
+		entitlements, err := usecase.Perform(r.Context(), user.ID)
+		if err != nil {
+			logging.FromContext(r.Context(), logger).Error("failed to get entitlements", zap.Error(err), zap.String("user_id", user.ID))
+			handlers.WriteError(w, http.StatusInternalServerError, "An unexpected error occurred")
+			return
+		}
+
+		resp := api.EntitlementsResponse{Entitlements: make([]api.Entitlement, 0, len(entitlements))}
+		for _, e := range entitlements {
+			resp.Entitlements = append(resp.Entitlements, handlers.ToApiEntitlement(e))
+		}
+
+		handlers.WriteJSON(w, http.StatusOK, resp)
+	}
+}
@@ -0,0 +1,40 @@
+package users
+
+import (
+	"net/http"
+
+	"athylps/internal/api"
+	"athylps/internal/handlers"
+	"athylps/internal/handlers/middlewares"
+	"athylps/internal/models"
+)
+
+func HandleGetMe() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := middlewares.UserFromContext(r.Context())
+		if !ok {
+			handlers.WriteError(w, http.StatusUnauthorized, "Bearer token is required")
+			return
+		}
+
+		handlers.WriteJSON(w, http.StatusOK, toApiUser(user))
+	}
+}
+
+func toApiUser(u *models.User) api.User {
+	resp := api.User{
+		Id:           u.ID,
+		Email:        u.Email,
+		RevenuecatId: u.RevenueCatID,
+		Timezone:     u.Timezone,
+		Locale:       u.Locale,
+	}
+	if u.CreatedAt != nil {
+		resp.CreatedAt = *u.CreatedAt
+	}
+	if u.UpdatedAt != nil {
+		resp.UpdatedAt = *u.UpdatedAt
+	}
+
+	return resp
+}
@@ -0,0 +1,64 @@
+package users
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"athylps/internal/api"
+	"athylps/internal/handlers"
+	"athylps/internal/handlers/middlewares"
+	"athylps/internal/logging"
+	"athylps/internal/models"
+	"athylps/internal/repositories"
+	"athylps/internal/usecases"
+
+	"go.uber.org/zap"
+)
+
+type linkRevenueCatCustomerUsecase interface {
+	Perform(ctx context.Context, params *usecases.LinkRevenueCatCustomerParams) (*models.User, error)
+}
+
+func HandleLinkRevenueCatCustomer(logger *zap.Logger, usecase linkRevenueCatCustomerUsecase) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := middlewares.UserFromContext(r.Context())
+		if !ok {
+			handlers.WriteError(w, http.StatusUnauthorized, "Bearer token is required")
+			return
+		}
+
+		var body api.LinkRevenueCatCustomerRequest
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			handlers.WriteError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		appUserID := strings.TrimSpace(body.AppUserId)
+		if appUserID == "" {
+			handlers.WriteError(w, http.StatusBadRequest, "app_user_id is required")
+			return
+		}
+
+		updated, err := usecase.Perform(r.Context(), &usecases.LinkRevenueCatCustomerParams{
+			UserID:      user.ID,
+			FirebaseUID: user.FirebaseUID,
+			AppUserID:   appUserID,
+		})
+		switch {
+		case errors.Is(err, usecases.ErrForeignAppUserID):
+			handlers.WriteError(w, http.StatusForbidden, "App user id must be the user's id or Firebase uid")
+			return
+		case errors.Is(err, repositories.ErrRevenueCatCustomerLinked):
+			handlers.WriteError(w, http.StatusConflict, "RevenueCat customer is linked to another user")
+			return
+		case err != nil:
+			logging.FromContext(r.Context(), logger).Error("failed to link revenuecat customer", zap.Error(err), zap.String("user_id", user.ID))
+			handlers.WriteError(w, http.StatusInternalServerError, "An unexpected error occurred")
+			return
+		}
+
+		handlers.WriteJSON(w, http.StatusOK, toApiUser(updated))
+	}
+}
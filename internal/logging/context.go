@@ -0,0 +1,26 @@
+// Package logging carries request-scoped loggers in the context and redacts secrets from log fields.
+package logging
+
+import (
+	"context"
+
+	"athylps/internal/tracing"
+
+	"go.uber.org/zap"
+)
+
+type loggerContextKey struct{}
+
+// WithLogger returns a copy of the context carrying the logger, e.g. one with the request id.
+func WithLogger(ctx context.Context, logger *zap.Logger) context.Context {
+	return context.WithValue(ctx, loggerContextKey{}, logger)
+}
+
+// FromContext returns the logger put into the context by WithLogger. Outside of requests it's the
+// fallback with the trace of the context, if any.
+func FromContext(ctx context.Context, fallback *zap.Logger) *zap.Logger {
+	if logger, ok := ctx.Value(loggerContextKey{}).(*zap.Logger); ok && logger != nil {
+		return logger
+	}
+	return tracing.Logger(ctx, fallback)
+}
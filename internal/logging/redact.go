@@ -0,0 +1,214 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const redacted = "[REDACTED]"
+
+// sensitiveKeys name secrets, values of fields whose name ends with one of them are always redacted,
+// e.g. auth_header, botToken or X-Api-Key, but not token_expires_at.
+var sensitiveKeys = []string{"authorization", "authheader", "token", "tokens", "secret", "password", "apikey", "cookie", "cookies"}
+
+var (
+	emailRegexp  = regexp.MustCompile(`([A-Za-z0-9._%+\-])[A-Za-z0-9._%+\-]*@([A-Za-z0-9.\-]+\.[A-Za-z]{2,})`)
+	bearerRegexp = regexp.MustCompile(`(?i)\b(bearer|basic)\s+[A-Za-z0-9\-._~+/]+=*`)
+	jwtRegexp    = regexp.MustCompile(`\beyJ[A-Za-z0-9_\-]+\.[A-Za-z0-9_\-]+\.[A-Za-z0-9_\-]*`)
+	// Telegram bot tokens leak with urls of failed Bot API requests
+	botTokenRegexp = regexp.MustCompile(`\d{6,}:[A-Za-z0-9_\-]{30,}`)
+)
+
+// Redact returns the logger masking secrets and emails in fields of every entry logged through it.
+func Redact(logger *zap.Logger) *zap.Logger {
+	return logger.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return &redactingCore{Core: core}
+	}))
+}
+
+// redactingCore masks values of fields with sensitive names and tokens and emails in string, error and
+// Stringer fields. Structs, maps and objects are masked the same way key by key.
+type redactingCore struct {
+	zapcore.Core
+}
+
+func (c *redactingCore) With(fields []zapcore.Field) zapcore.Core {
+	return &redactingCore{Core: c.Core.With(redactFields(fields))}
+}
+
+func (c *redactingCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+	return checked
+}
+
+func (c *redactingCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	entry.Message = RedactString(entry.Message)
+	return c.Core.Write(entry, redactFields(fields))
+}
+
+func redactFields(fields []zapcore.Field) []zapcore.Field {
+	redactedFields := make([]zapcore.Field, len(fields))
+	for i, field := range fields {
+		redactedFields[i] = redactField(field)
+	}
+	return redactedFields
+}
+
+func redactField(field zapcore.Field) zapcore.Field {
+	if isSensitiveKey(field.Key) {
+		return zap.String(field.Key, redacted)
+	}
+
+	switch field.Type {
+	case zapcore.StringType:
+		if s := RedactString(field.String); s != field.String {
+			return zap.String(field.Key, s)
+		}
+	case zapcore.ByteStringType:
+		if b, ok := field.Interface.([]byte); ok {
+			if s := RedactString(string(b)); s != string(b) {
+				return zap.String(field.Key, s)
+			}
+		}
+	case zapcore.ErrorType:
+		if err, ok := field.Interface.(error); ok && err != nil {
+			if s := RedactString(err.Error()); s != err.Error() {
+				return zap.String(field.Key, s)
+			}
+		}
+	case zapcore.StringerType:
+		if stringer, ok := field.Interface.(fmt.Stringer); ok && stringer != nil {
+			if s := RedactString(stringer.String()); s != stringer.String() {
+				return zap.String(field.Key, s)
+			}
+		}
+	case zapcore.ReflectType:
+		if value, ok := redactReflected(field.Interface); ok {
+			return zap.Any(field.Key, value)
+		}
+	case zapcore.ObjectMarshalerType, zapcore.ArrayMarshalerType:
+		if value, ok := redactMarshaler(field); ok {
+			return zap.Any(field.Key, value)
+		}
+	}
+
+	return field
+}
+
+// redactReflected returns the value logged with zap.Any or zap.Reflect as it's written, decoded from JSON, with
+// sensitive keys and strings masked. Returns false if it can't be encoded, the encoder reports that.
+func redactReflected(value any) (any, bool) {
+	b, err := json.Marshal(value)
+	if err != nil {
+		return nil, false
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.UseNumber()
+	var decoded any
+	if err := decoder.Decode(&decoded); err != nil {
+		return nil, false
+	}
+
+	return redactValue(decoded), true
+}
+
+// redactMarshaler returns the object or array field encoded to maps and slices with sensitive keys and strings masked.
+func redactMarshaler(field zapcore.Field) (any, bool) {
+	enc := zapcore.NewMapObjectEncoder()
+	field.AddTo(enc)
+	value, ok := enc.Fields[field.Key]
+	if !ok {
+		return nil, false
+	}
+
+	return redactValue(value), true
+}
+
+func redactValue(value any) any {
+	switch v := value.(type) {
+	case string:
+		return RedactString(v)
+	case map[string]any:
+		for key, nested := range v {
+			if isSensitiveKey(key) {
+				v[key] = redacted
+			} else {
+				v[key] = redactValue(nested)
+			}
+		}
+	case []any:
+		for i, nested := range v {
+			v[i] = redactValue(nested)
+		}
+	}
+
+	return value
+}
+
+func isSensitiveKey(key string) bool {
+	segments := keySegments(key)
+	if len(segments) == 0 {
+		return false
+	}
+
+	last := segments[len(segments)-1]
+	if slices.Contains(sensitiveKeys, last) {
+		return true
+	}
+	// Secrets named with two words, e.g. api_key
+	return len(segments) > 1 && slices.Contains(sensitiveKeys, segments[len(segments)-2]+last)
+}
+
+// keySegments splits the field name into lower case words at separators and camelCase humps, e.g. X-Api-Key
+// and apiKey into x, api, key.
+func keySegments(key string) []string {
+	var segments []string
+	var word strings.Builder
+	flush := func() {
+		if word.Len() > 0 {
+			segments = append(segments, word.String())
+			word.Reset()
+		}
+	}
+
+	prevLower := false
+	for _, r := range key {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			flush()
+			prevLower = false
+			continue
+		}
+		if unicode.IsUpper(r) && prevLower {
+			flush()
+		}
+		word.WriteRune(unicode.ToLower(r))
+		prevLower = unicode.IsLower(r) || unicode.IsDigit(r)
+	}
+	flush()
+
+	return segments
+}
+
+// RedactString masks bearer tokens, JWTs, Telegram bot tokens and emails in the string.
+// Emails keep the first letter and the domain, e.g. j***@example.com, so support can still tell users apart.
+func RedactString(s string) string {
+	if s == "" {
+		return s
+	}
+
+	s = bearerRegexp.ReplaceAllString(s, "$1 "+redacted)
+	s = jwtRegexp.ReplaceAllString(s, redacted)
+	s = botTokenRegexp.ReplaceAllString(s, redacted)
+	return emailRegexp.ReplaceAllString(s, "$1***@$2")
+}
@@ -0,0 +1,72 @@
+// Package metrics holds the Prometheus metrics of the app, they are exposed by Handler.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "athylps"
+
+var (
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by route pattern and response status.",
+	}, []string{"method", "route", "status"})
+
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Time to handle HTTP requests by route pattern.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	WebhookEvents = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "webhook_events_received_total",
+		Help:      "Webhook events received from stores, including redeliveries.",
+	}, []string{"provider", "event_type", "environment"})
+
+	WebhookAuthFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "webhook_auth_failures_total",
+		Help:      "Webhook requests rejected because of a missing or wrong token.",
+	}, []string{"provider"})
+
+	NotificationsSent = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "notifications_sent_total",
+		Help:      "Notification delivery attempts by channel and result: sent, rate_limited, rejected or failed.",
+	}, []string{"channel", "result"})
+
+	NotificationSendDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "notification_send_duration_seconds",
+		Help:      "Time to deliver a notification, including failed attempts.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"channel"})
+)
+
+// Handler returns the handler of /metrics exposing the app metrics, Go runtime and process metrics
+// and stats of the database pool.
+func Handler(pool *pgxpool.Pool) http.Handler {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		newPoolCollector(pool),
+		HTTPRequests,
+		HTTPRequestDuration,
+		WebhookEvents,
+		WebhookAuthFailures,
+		NotificationsSent,
+		NotificationSendDuration,
+	)
+
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
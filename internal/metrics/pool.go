@@ -0,0 +1,75 @@
+package metrics
+
+import (
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type poolStater interface {
+	Stat() *pgxpool.Stat
+}
+
+type poolMetric struct {
+	desc      *prometheus.Desc
+	valueType prometheus.ValueType
+	value     func(s *pgxpool.Stat) float64
+}
+
+// poolCollector reads stats of the pgx pool when metrics are scraped.
+type poolCollector struct {
+	pool    poolStater
+	metrics []poolMetric
+}
+
+func newPoolCollector(pool poolStater) *poolCollector {
+	metric := func(name string, help string, valueType prometheus.ValueType, value func(s *pgxpool.Stat) float64) poolMetric {
+		return poolMetric{
+			desc:      prometheus.NewDesc(prometheus.BuildFQName(namespace, "db_pool", name), help, nil, nil),
+			valueType: valueType,
+			value:     value,
+		}
+	}
+
+	return &poolCollector{
+		pool: pool,
+		metrics: []poolMetric{
+			metric("acquired_conns", "Connections currently in use.", prometheus.GaugeValue, func(s *pgxpool.Stat) float64 {
+				return float64(s.AcquiredConns())
+			}),
+			metric("idle_conns", "Idle connections.", prometheus.GaugeValue, func(s *pgxpool.Stat) float64 {
+				return float64(s.IdleConns())
+			}),
+			metric("total_conns", "Open connections, including ones being established.", prometheus.GaugeValue, func(s *pgxpool.Stat) float64 {
+				return float64(s.TotalConns())
+			}),
+			metric("max_conns", "Maximum size of the pool.", prometheus.GaugeValue, func(s *pgxpool.Stat) float64 {
+				return float64(s.MaxConns())
+			}),
+			metric("acquires_total", "Successful connection acquires.", prometheus.CounterValue, func(s *pgxpool.Stat) float64 {
+				return float64(s.AcquireCount())
+			}),
+			metric("acquire_duration_seconds_total", "Time spent acquiring connections.", prometheus.CounterValue, func(s *pgxpool.Stat) float64 {
+				return s.AcquireDuration().Seconds()
+			}),
+			metric("empty_acquires_total", "Acquires that had to wait for a connection because the pool was empty.", prometheus.CounterValue, func(s *pgxpool.Stat) float64 {
+				return float64(s.EmptyAcquireCount())
+			}),
+			metric("canceled_acquires_total", "Acquires cancelled by the context.", prometheus.CounterValue, func(s *pgxpool.Stat) float64 {
+				return float64(s.CanceledAcquireCount())
+			}),
+		},
+	}
+}
+
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, m := range c.metrics {
+		ch <- m.desc
+	}
+}
+
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	stat := c.pool.Stat()
+	for _, m := range c.metrics {
+		ch <- prometheus.MustNewConstMetric(m.desc, m.valueType, m.value(stat))
+	}
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// AdminAction is a mutating call of the admin API recorded for audit.
+type AdminAction struct {
+	ID        string
+	AdminUID  string
+	Method    string
+	Route     string
+	Path      string
+	Request   []byte // JSON body of the request, nil if it had none
+	Status    int
+	CreatedAt time.Time
+}
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// Donation is a donation received through DonationAlerts.
+type Donation struct {
+	ID         string
+	ExternalID int64
+	Username   *string
+	Message    *string
+	Amount     float64
+	Currency   string
+	DonatedAt  time.Time
+	CreatedAt  *time.Time
+}
+
+// OAuthToken is an OAuth token pair of a connected third-party account.
+type OAuthToken struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresAt    time.Time
+}
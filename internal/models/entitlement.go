@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// Entitlement is an access level the user currently has, granted by a subscription or purchase.
+type Entitlement struct {
+	ID                   string
+	ProductID            string
+	Store                string
+	PurchasedAt          *time.Time
+	ExpiresAt            *time.Time // nil for lifetime purchases
+	GracePeriodExpiresAt *time.Time
+	IsInGracePeriod      bool
+	WillRenew            bool
+	IsSandbox            bool
+}
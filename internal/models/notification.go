@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+type NotificationStatus string
+
+const (
+	NotificationStatusPending NotificationStatus = "pending"
+	NotificationStatusSent    NotificationStatus = "sent"
+	NotificationStatusFailed  NotificationStatus = "failed" // Gave up after retries or a permanent error
+	// Collapsed into a summary notification during a burst, not delivered on its own
+	NotificationStatusCoalesced NotificationStatus = "coalesced"
+)
+
+const (
+	NotificationChannelTelegram = "telegram"
+	NotificationChannelWebhook  = "webhook"
+	NotificationChannelEmail    = "email"
+)
+
+// NotificationTarget is where a notification is delivered.
+// Recipient is a chat id for Telegram, a configured webhook name for webhooks and an address for email.
+type NotificationTarget struct {
+	Channel   string
+	Recipient string
+}
+
+// Notification is a message waiting in the outbox to be delivered to its target.
+// Text is Telegram flavoured HTML, channels without HTML support deliver it as plain text.
+type Notification struct {
+	ID            string
+	Target        NotificationTarget
+	Text          string
+	EventType     *string  // Event of a purchase notification, others are never collapsed into summaries
+	Price         *float64 // USD
+	Locale        string   // Locale the text is rendered in, the default one if empty
+	Traceparent   string   // Trace of the request that enqueued the notification, empty if it wasn't traced
+	Status        NotificationStatus
+	Attempts      int
+	NextAttemptAt time.Time
+	LastError     *string
+	CreatedAt     *time.Time
+	SentAt        *time.Time
+}
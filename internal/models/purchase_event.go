@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// PurchaseEvent is a purchase related webhook event normalized for revenue stats.
+type PurchaseEvent struct {
+	ID             string
+	WebhookEventID string
+	EventType      string
+	Store          string
+	Environment    *string
+	ProductID      *string
+	CountryCode    *string
+	Price          *float64 // USD
+	// Price in the currency of the purchase with its ISO 4217 code
+	PriceInPurchasedCurrency *float64
+	Currency                 *string
+	UserID                   *string
+	OccurredAt               time.Time
+	CreatedAt                *time.Time
+}
+
+// PurchaseStats is the number of purchase events with the same type, store, product and country and their total price.
+type PurchaseStats struct {
+	EventType   string
+	Store       string
+	ProductID   *string
+	CountryCode *string
+	Count       int
+	Revenue     float64 // USD
+}
@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// RevenueCatCustomer is an app user id the app has logged in to RevenueCat with, linked to our user.
+// Anonymous ids are generated per installation of the app.
+type RevenueCatCustomer struct {
+	AppUserID string
+	UserID    string
+	CreatedAt *time.Time
+}
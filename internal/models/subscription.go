@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+type SubscriptionStatus string
+
+const (
+	SubscriptionStatusActive      SubscriptionStatus = "active"
+	SubscriptionStatusGracePeriod SubscriptionStatus = "grace_period"
+	SubscriptionStatusCancelled   SubscriptionStatus = "cancelled" // Cancelled, but still active until ExpiresAt
+	SubscriptionStatusPaused      SubscriptionStatus = "paused"
+	SubscriptionStatusExpired     SubscriptionStatus = "expired"
+)
+
+// StoreManual is the store of entitlements granted by admins, they are stored as subscriptions
+// so they are active together with purchased ones, but aren't revenue.
+const StoreManual = "MANUAL"
+
+// Subscription is the current state of a store subscription of an app user to a product.
+// Non-renewing purchases that grant entitlements are stored as subscriptions without expiration.
+type Subscription struct {
+	ID                   string
+	AppUserID            string
+	UserID               *string // Our user the app user id is linked to, if known
+	ProductID            string
+	EntitlementIDs       []string
+	Store                string
+	Environment          *string
+	Status               SubscriptionStatus
+	PurchasedAt          *time.Time
+	ExpiresAt            *time.Time
+	GracePeriodExpiresAt *time.Time
+	CancelledAt          *time.Time
+	LastEventType        string
+	LastEventAt          *time.Time
+	CreatedAt            *time.Time
+	UpdatedAt            *time.Time
+}
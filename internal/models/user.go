@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+type User struct {
+	ID           string
+	Email        *string
+	FirebaseUID  *string
+	RevenueCatID *string
+	Timezone     *string
+	Locale       *string
+	CreatedAt    *time.Time
+	UpdatedAt    *time.Time
+	DeletedAt    *time.Time
+	PurgeAfter   *time.Time // Personal data of the deleted account is purged after the time
+}
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+const (
+	ProviderRevenueCat = "revenuecat"
+	ProviderRuStore    = "rustore"
+)
+
+// WebhookEvent is a raw webhook delivery received from a third-party provider.
+type WebhookEvent struct {
+	ID          string
+	Provider    string
+	EventID     string
+	EventType   string
+	Environment *string
+	Payload     []byte  // Exactly as received, decrypted for RuStore
+	UserID      *string // Our user the event was attributed to during processing
+	ReceivedAt  time.Time
+	ProcessedAt *time.Time
+}
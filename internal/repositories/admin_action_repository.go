@@ -0,0 +1,138 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"athylps/internal/models"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var adminActionColumns = []string{
+	"id",
+	"admin_uid",
+	"method",
+	"route",
+	"path",
+	"request",
+	"status",
+	"created_at",
+}
+
+type AdminActionRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewAdminActionRepository(db *pgxpool.Pool) *AdminActionRepository {
+	return &AdminActionRepository{
+		db: db,
+	}
+}
+
+type CreateAdminActionParams struct {
+	AdminUID string
+	Method   string
+	Route    string
+	Path     string
+	Request  []byte // Must be valid JSON or nil
+	Status   int
+}
+
+func (repo *AdminActionRepository) Create(ctx context.Context, p *CreateAdminActionParams) error {
+	sql, args, err := sq.Insert("admin_actions").
+		Columns("admin_uid", "method", "route", "path", "request", "status").
+		Values(p.AdminUID, p.Method, p.Route, p.Path, p.Request, p.Status).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build insert admin action query: %w", err)
+	}
+
+	if _, err := conn(ctx, repo.db).Exec(ctx, sql, args...); err != nil {
+		return fmt.Errorf("failed to create admin action: %w", err)
+	}
+
+	return nil
+}
+
+type ListAdminActionsParams struct {
+	AdminUID *string
+	// Created at, From is inclusive and To is exclusive
+	From   *time.Time
+	To     *time.Time
+	Limit  uint64
+	Offset uint64
+}
+
+// List returns admin actions matching the filters, newest first.
+func (repo *AdminActionRepository) List(ctx context.Context, p *ListAdminActionsParams) ([]*models.AdminAction, error) {
+	query := sq.Select(adminActionColumns...).
+		From("admin_actions").
+		OrderBy("created_at DESC", "id DESC").
+		PlaceholderFormat(sq.Dollar)
+	if p.AdminUID != nil {
+		query = query.Where(sq.Eq{"admin_uid": *p.AdminUID})
+	}
+	if p.From != nil {
+		query = query.Where(sq.GtOrEq{"created_at": *p.From})
+	}
+	if p.To != nil {
+		query = query.Where(sq.Lt{"created_at": *p.To})
+	}
+	if p.Limit > 0 {
+		query = query.Limit(p.Limit)
+	}
+	if p.Offset > 0 {
+		query = query.Offset(p.Offset)
+	}
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build list admin actions query: %w", err)
+	}
+
+	rows, err := conn(ctx, repo.db).Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list admin actions: %w", err)
+	}
+
+	dbActions, err := pgx.CollectRows(rows, pgx.RowToStructByName[adminActionDbModel])
+	if err != nil {
+		return nil, fmt.Errorf("failed to list admin actions: %w", err)
+	}
+
+	actions := make([]*models.AdminAction, 0, len(dbActions))
+	for _, a := range dbActions {
+		actions = append(actions, a.toModel())
+	}
+
+	return actions, nil
+}
+
+type adminActionDbModel struct {
+	Id        string    `db:"id"`
+	AdminUid  string    `db:"admin_uid"`
+	Method    string    `db:"method"`
+	Route     string    `db:"route"`
+	Path      string    `db:"path"`
+	Request   []byte    `db:"request"`
+	Status    int       `db:"status"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+func (m *adminActionDbModel) toModel() *models.AdminAction {
+	return &models.AdminAction{
+		ID:        m.Id,
+		AdminUID:  m.AdminUid,
+		Method:    m.Method,
+		Route:     m.Route,
+		Path:      m.Path,
+		Request:   m.Request,
+		Status:    m.Status,
+		CreatedAt: m.CreatedAt,
+	}
+}
@@ -0,0 +1,38 @@
+package repositories
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+func Test_AdminActionRepository(t *testing.T) {
+	ctx := context.Background()
+	pool := newTestPool(t)
+	repo := NewAdminActionRepository(pool)
+
+	for _, p := range []*CreateAdminActionParams{
+		{AdminUID: "admin-1", Method: http.MethodPost, Route: "/admin/v1/users/{id}/entitlements", Path: "/admin/v1/users/1/entitlements", Request: []byte(`{"entitlement_id":"premium"}`), Status: http.StatusOK},
+		{AdminUID: "admin-2", Method: http.MethodDelete, Route: "/admin/v1/users/{id}/entitlements/{entitlementID}", Path: "/admin/v1/users/1/entitlements/premium", Status: http.StatusNotFound},
+	} {
+		if err := repo.Create(ctx, p); err != nil {
+			t.Fatalf("failed to create admin action: %v", err)
+		}
+	}
+
+	all, err := repo.List(ctx, &ListAdminActionsParams{})
+	if err != nil {
+		t.Fatalf("failed to list admin actions: %v", err)
+	}
+	if len(all) != 2 || all[0].AdminUID != "admin-2" || all[0].Request != nil {
+		t.Errorf("expected actions newest first, got %+v", all)
+	}
+
+	byAdmin, err := repo.List(ctx, &ListAdminActionsParams{AdminUID: ptr("admin-1")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(byAdmin) != 1 || string(byAdmin[0].Request) != `{"entitlement_id": "premium"}` || byAdmin[0].Status != http.StatusOK {
+		t.Errorf("expected the action of admin-1 with its request, got %+v", byAdmin)
+	}
+}
@@ -0,0 +1,34 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type DigestRunRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewDigestRunRepository(db *pgxpool.Pool) *DigestRunRepository {
+	return &DigestRunRepository{
+		db: db,
+	}
+}
+
+// Claim records that the digest for the period is sent, returns false if it has already been claimed.
+// Claimed inside a transaction, the period is locked for other replicas until it commits or rolls back.
+func (repo *DigestRunRepository) Claim(ctx context.Context, kind string, periodStart time.Time) (bool, error) {
+	tag, err := conn(ctx, repo.db).Exec(ctx, `
+		INSERT INTO digest_runs (kind, period_start) VALUES ($1, $2)
+		ON CONFLICT (kind, period_start) DO NOTHING`,
+		kind, periodStart,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim %s digest for %s: %w", kind, periodStart, err)
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
@@ -0,0 +1,157 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"athylps/internal/models"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var donationColumns = []string{
+	"id",
+	"external_id",
+	"username",
+	"message",
+	"amount",
+	"currency",
+	"donated_at",
+	"created_at",
+}
+
+type DonationRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewDonationRepository(db *pgxpool.Pool) *DonationRepository {
+	return &DonationRepository{
+		db: db,
+	}
+}
+
+type CreateDonationParams struct {
+	ExternalID int64
+	Username   *string
+	Message    *string
+	Amount     float64
+	Currency   string
+	DonatedAt  time.Time
+}
+
+// Create stores a donation. Returns ErrAlreadyExists if the donation has already been stored.
+func (repo *DonationRepository) Create(ctx context.Context, p *CreateDonationParams) (*models.Donation, error) {
+	sql, args, err := sq.Insert("donations").
+		Columns("external_id", "username", "message", "amount", "currency", "donated_at").
+		Values(p.ExternalID, p.Username, p.Message, p.Amount, p.Currency, p.DonatedAt).
+		Suffix("ON CONFLICT (external_id) DO NOTHING RETURNING " + strings.Join(donationColumns, ", ")).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build insert donation query: %w", err)
+	}
+
+	rows, err := conn(ctx, repo.db).Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert donation: %w", err)
+	}
+
+	row, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[donationDbModel])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrAlreadyExists
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert donation: %w", err)
+	}
+
+	return row.toModel(), nil
+}
+
+// HasAny reports whether at least one donation has been stored.
+func (repo *DonationRepository) HasAny(ctx context.Context) (bool, error) {
+	var exists bool
+	err := conn(ctx, repo.db).QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM donations)").Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check donations: %w", err)
+	}
+
+	return exists, nil
+}
+
+type donationDbModel struct {
+	Id         string     `db:"id"`
+	ExternalId int64      `db:"external_id"`
+	Username   *string    `db:"username"`
+	Message    *string    `db:"message"`
+	Amount     float64    `db:"amount"`
+	Currency   string     `db:"currency"`
+	DonatedAt  time.Time  `db:"donated_at"`
+	CreatedAt  *time.Time `db:"created_at"`
+}
+
+func (m *donationDbModel) toModel() *models.Donation {
+	return &models.Donation{
+		ID:         m.Id,
+		ExternalID: m.ExternalId,
+		Username:   m.Username,
+		Message:    m.Message,
+		Amount:     m.Amount,
+		Currency:   m.Currency,
+		DonatedAt:  m.DonatedAt,
+		CreatedAt:  m.CreatedAt,
+	}
+}
+
+type DonationAlertsTokenRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewDonationAlertsTokenRepository(db *pgxpool.Pool) *DonationAlertsTokenRepository {
+	return &DonationAlertsTokenRepository{
+		db: db,
+	}
+}
+
+// Get returns the token of the connected DonationAlerts account or ErrNotFound if no account is connected.
+func (repo *DonationAlertsTokenRepository) Get(ctx context.Context) (*models.OAuthToken, error) {
+	var token models.OAuthToken
+	err := conn(ctx, repo.db).QueryRow(
+		ctx,
+		"SELECT access_token, refresh_token, expires_at FROM donationalerts_tokens WHERE id = 1",
+	).Scan(&token.AccessToken, &token.RefreshToken, &token.ExpiresAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get donationalerts token: %w", err)
+	}
+
+	return &token, nil
+}
+
+func (repo *DonationAlertsTokenRepository) Save(ctx context.Context, token *models.OAuthToken) error {
+	sql, args, err := sq.Insert("donationalerts_tokens").
+		Columns("id", "access_token", "refresh_token", "expires_at").
+		Values(1, token.AccessToken, token.RefreshToken, token.ExpiresAt).
+		Suffix(`ON CONFLICT (id) DO UPDATE SET
+			access_token = excluded.access_token,
+			refresh_token = excluded.refresh_token,
+			expires_at = excluded.expires_at,
+			updated_at = now()`).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build save donationalerts token query: %w", err)
+	}
+
+	if _, err := conn(ctx, repo.db).Exec(ctx, sql, args...); err != nil {
+		return fmt.Errorf("failed to save donationalerts token: %w", err)
+	}
+
+	return nil
+}
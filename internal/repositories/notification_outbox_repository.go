@@ -0,0 +1,292 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"athylps/internal/models"
+	"athylps/internal/tracing"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var notificationColumns = []string{
+	"id",
+	"channel",
+	"recipient",
+	"text",
+	"event_type",
+	"price::float8 AS price",
+	"locale",
+	"traceparent",
+	"status",
+	"attempts",
+	"next_attempt_at",
+	"last_error",
+	"created_at",
+	"sent_at",
+}
+
+type NotificationOutboxRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewNotificationOutboxRepository(db *pgxpool.Pool) *NotificationOutboxRepository {
+	return &NotificationOutboxRepository{
+		db: db,
+	}
+}
+
+type EnqueueNotificationParams struct {
+	Target    models.NotificationTarget
+	Text      string
+	EventType *string
+	Price     *float64
+	Locale    string
+}
+
+// Enqueue writes the notification to the outbox, it's delivered once the surrounding transaction commits.
+// The trace in the context is continued when the notification is delivered.
+func (repo *NotificationOutboxRepository) Enqueue(ctx context.Context, p *EnqueueNotificationParams) error {
+	sql, args, err := sq.Insert("notifications_outbox").
+		Columns("channel", "recipient", "text", "event_type", "price", "locale", "traceparent").
+		Values(p.Target.Channel, p.Target.Recipient, p.Text, p.EventType, p.Price, p.Locale, tracing.Traceparent(ctx)).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build insert notification query: %w", err)
+	}
+
+	if _, err := conn(ctx, repo.db).Exec(ctx, sql, args...); err != nil {
+		return fmt.Errorf("failed to enqueue notification: %w", err)
+	}
+
+	return nil
+}
+
+// ClaimDue returns up to limit pending notifications that are due, oldest first, and hides them from other
+// dispatchers for the lease duration. Notifications of a dispatcher that died before reporting the result
+// become due again when the lease expires.
+func (repo *NotificationOutboxRepository) ClaimDue(ctx context.Context, limit uint64, lease time.Duration) ([]*models.Notification, error) {
+	rows, err := conn(ctx, repo.db).Query(ctx, `
+		UPDATE notifications_outbox SET next_attempt_at = now() + make_interval(secs => $2)
+		WHERE id IN (
+			SELECT id FROM notifications_outbox
+			WHERE status = 'pending' AND next_attempt_at <= now()
+			ORDER BY next_attempt_at, id
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+strings.Join(notificationColumns, ", "),
+		limit, lease.Seconds(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim notifications: %w", err)
+	}
+
+	dbNotifications, err := pgx.CollectRows(rows, pgx.RowToStructByName[notificationDbModel])
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim notifications: %w", err)
+	}
+
+	notifications := make([]*models.Notification, 0, len(dbNotifications))
+	for _, n := range dbNotifications {
+		notifications = append(notifications, n.toModel())
+	}
+	// UPDATE ... RETURNING doesn't keep the order of the subquery, ids are time ordered uuidv7
+	slices.SortFunc(notifications, func(a, b *models.Notification) int {
+		return strings.Compare(a.ID, b.ID)
+	})
+
+	return notifications, nil
+}
+
+func (repo *NotificationOutboxRepository) MarkSent(ctx context.Context, id string) error {
+	return repo.update(ctx, id, map[string]any{
+		"status":   string(models.NotificationStatusSent),
+		"attempts": sq.Expr("attempts + 1"),
+		"sent_at":  sq.Expr("now()"),
+	})
+}
+
+// MarkRetry records the failed attempt and schedules the next one.
+func (repo *NotificationOutboxRepository) MarkRetry(ctx context.Context, id string, nextAttemptAt time.Time, lastError string) error {
+	return repo.update(ctx, id, map[string]any{
+		"attempts":        sq.Expr("attempts + 1"),
+		"next_attempt_at": nextAttemptAt,
+		"last_error":      lastError,
+	})
+}
+
+// MarkFailed records the failed attempt and stops retrying the notification.
+func (repo *NotificationOutboxRepository) MarkFailed(ctx context.Context, id string, lastError string) error {
+	return repo.update(ctx, id, map[string]any{
+		"status":     string(models.NotificationStatusFailed),
+		"attempts":   sq.Expr("attempts + 1"),
+		"last_error": lastError,
+	})
+}
+
+// MarkCoalesced stops delivering pending notifications that were collapsed into a summary.
+func (repo *NotificationOutboxRepository) MarkCoalesced(ctx context.Context, ids []string) error {
+	sql, args, err := sq.Update("notifications_outbox").
+		Set("status", string(models.NotificationStatusCoalesced)).
+		Where(sq.Eq{"id": ids, "status": string(models.NotificationStatusPending)}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build coalesce notifications query: %w", err)
+	}
+
+	if _, err := conn(ctx, repo.db).Exec(ctx, sql, args...); err != nil {
+		return fmt.Errorf("failed to mark notifications coalesced: %w", err)
+	}
+
+	return nil
+}
+
+// Release makes claimed notifications due at the given time without counting an attempt,
+// e.g. when the dispatcher stops or is rate limited before sending them.
+func (repo *NotificationOutboxRepository) Release(ctx context.Context, ids []string, nextAttemptAt time.Time) error {
+	sql, args, err := sq.Update("notifications_outbox").
+		Set("next_attempt_at", nextAttemptAt).
+		Where(sq.Eq{"id": ids, "status": string(models.NotificationStatusPending)}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build release notifications query: %w", err)
+	}
+
+	if _, err := conn(ctx, repo.db).Exec(ctx, sql, args...); err != nil {
+		return fmt.Errorf("failed to release notifications: %w", err)
+	}
+
+	return nil
+}
+
+type ListNotificationsParams struct {
+	Status *models.NotificationStatus
+	Limit  uint64
+}
+
+// List returns notifications with the status, the newest first.
+func (repo *NotificationOutboxRepository) List(ctx context.Context, p *ListNotificationsParams) ([]*models.Notification, error) {
+	query := sq.Select(notificationColumns...).
+		From("notifications_outbox").
+		OrderBy("created_at DESC", "id DESC").
+		PlaceholderFormat(sq.Dollar)
+	if p.Status != nil {
+		query = query.Where(sq.Eq{"status": string(*p.Status)})
+	}
+	if p.Limit > 0 {
+		query = query.Limit(p.Limit)
+	}
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build list notifications query: %w", err)
+	}
+
+	rows, err := conn(ctx, repo.db).Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list notifications: %w", err)
+	}
+
+	dbNotifications, err := pgx.CollectRows(rows, pgx.RowToStructByName[notificationDbModel])
+	if err != nil {
+		return nil, fmt.Errorf("failed to list notifications: %w", err)
+	}
+
+	notifications := make([]*models.Notification, 0, len(dbNotifications))
+	for _, n := range dbNotifications {
+		notifications = append(notifications, n.toModel())
+	}
+
+	return notifications, nil
+}
+
+// Resend enqueues a copy of the notification to the same target, e.g. after it failed or was lost by the channel.
+// The copy is a new pending notification, returns ErrNotFound if there is no notification with the id.
+func (repo *NotificationOutboxRepository) Resend(ctx context.Context, id string) (*models.Notification, error) {
+	rows, err := conn(ctx, repo.db).Query(ctx, `
+		INSERT INTO notifications_outbox (channel, recipient, text, event_type, price, locale, traceparent)
+		SELECT channel, recipient, text, event_type, price, locale, $2 FROM notifications_outbox WHERE id::text = $1
+		RETURNING `+strings.Join(notificationColumns, ", "),
+		id, tracing.Traceparent(ctx),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resend notification %s: %w", id, err)
+	}
+
+	row, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[notificationDbModel])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("failed to resend notification %s: %w", id, ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to resend notification %s: %w", id, err)
+	}
+
+	return row.toModel(), nil
+}
+
+func (repo *NotificationOutboxRepository) update(ctx context.Context, id string, set map[string]any) error {
+	sql, args, err := sq.Update("notifications_outbox").
+		SetMap(set).
+		Where(sq.Eq{"id": id}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build update notification query: %w", err)
+	}
+
+	tag, err := conn(ctx, repo.db).Exec(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("failed to update notification %s: %w", id, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("failed to update notification %s: %w", id, ErrNotFound)
+	}
+
+	return nil
+}
+
+type notificationDbModel struct {
+	Id            string     `db:"id"`
+	Channel       string     `db:"channel"`
+	Recipient     string     `db:"recipient"`
+	Text          string     `db:"text"`
+	EventType     *string    `db:"event_type"`
+	Price         *float64   `db:"price"`
+	Locale        string     `db:"locale"`
+	Traceparent   string     `db:"traceparent"`
+	Status        string     `db:"status"`
+	Attempts      int        `db:"attempts"`
+	NextAttemptAt time.Time  `db:"next_attempt_at"`
+	LastError     *string    `db:"last_error"`
+	CreatedAt     *time.Time `db:"created_at"`
+	SentAt        *time.Time `db:"sent_at"`
+}
+
+func (m *notificationDbModel) toModel() *models.Notification {
+	return &models.Notification{
+		ID:            m.Id,
+		Target:        models.NotificationTarget{Channel: m.Channel, Recipient: m.Recipient},
+		Text:          m.Text,
+		EventType:     m.EventType,
+		Price:         m.Price,
+		Locale:        m.Locale,
+		Traceparent:   m.Traceparent,
+		Status:        models.NotificationStatus(m.Status),
+		Attempts:      m.Attempts,
+		NextAttemptAt: m.NextAttemptAt,
+		LastError:     m.LastError,
+		CreatedAt:     m.CreatedAt,
+		SentAt:        m.SentAt,
+	}
+}
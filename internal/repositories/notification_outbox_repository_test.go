@@ -0,0 +1,158 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"athylps/internal/models"
+)
+
+func Test_NotificationOutboxRepository(t *testing.T) {
+	ctx := context.Background()
+	pool := newTestPool(t)
+	outbox := NewNotificationOutboxRepository(pool)
+	transactor := NewTransactor(pool)
+
+	rollback := errors.New("rollback")
+	err := transactor.WithinTx(ctx, func(ctx context.Context) error {
+		if err := outbox.Enqueue(ctx, &EnqueueNotificationParams{Text: "rolled back"}); err != nil {
+			t.Fatal(err)
+		}
+		return rollback
+	})
+	if !errors.Is(err, rollback) {
+		t.Fatalf("expected the error of the transaction, got %v", err)
+	}
+
+	err = transactor.WithinTx(ctx, func(ctx context.Context) error {
+		for _, text := range []string{"first", "second", "third"} {
+			target := models.NotificationTarget{Channel: models.NotificationChannelEmail, Recipient: text + "@example.com"}
+			if err := outbox.Enqueue(ctx, &EnqueueNotificationParams{Target: target, Text: text}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to enqueue notifications: %v", err)
+	}
+
+	claimed, err := outbox.ClaimDue(ctx, 2, time.Minute)
+	if err != nil {
+		t.Fatalf("failed to claim notifications: %v", err)
+	}
+	if len(claimed) != 2 || claimed[0].Text != "first" || claimed[1].Text != "second" {
+		t.Fatalf("expected the oldest committed notifications to be claimed, got %+v", claimed)
+	}
+	if claimed[0].Target.Channel != models.NotificationChannelEmail || claimed[0].Target.Recipient != "first@example.com" {
+		t.Errorf("expected notification target to be stored, got %+v", claimed[0].Target)
+	}
+
+	// Claimed notifications are hidden until the lease expires
+	rest, err := outbox.ClaimDue(ctx, 10, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rest) != 1 || rest[0].Text != "third" {
+		t.Fatalf("expected only not claimed notification, got %+v", rest)
+	}
+
+	if err := outbox.MarkSent(ctx, claimed[0].ID); err != nil {
+		t.Fatalf("failed to mark notification sent: %v", err)
+	}
+	if err := outbox.MarkRetry(ctx, claimed[1].ID, time.Now().Add(-time.Second), "timeout"); err != nil {
+		t.Fatalf("failed to schedule retry: %v", err)
+	}
+	if err := outbox.MarkFailed(ctx, rest[0].ID, "bad request"); err != nil {
+		t.Fatalf("failed to mark notification failed: %v", err)
+	}
+
+	retried, err := outbox.ClaimDue(ctx, 10, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(retried) != 1 || retried[0].ID != claimed[1].ID || retried[0].Attempts != 1 || *retried[0].LastError != "timeout" {
+		t.Fatalf("expected only the retried notification to be due, got %+v", retried)
+	}
+
+	if err := outbox.Release(ctx, []string{retried[0].ID}, time.Now().Add(-time.Second)); err != nil {
+		t.Fatalf("failed to release notification: %v", err)
+	}
+	released, err := outbox.ClaimDue(ctx, 10, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(released) != 1 || released[0].Attempts != 1 || released[0].Status != models.NotificationStatusPending {
+		t.Errorf("expected released notification to be due without counting an attempt, got %+v", released)
+	}
+
+	eventType, price := "RENEWAL", 4.99
+	err = outbox.Enqueue(ctx, &EnqueueNotificationParams{Text: "renewal", EventType: &eventType, Price: &price, Locale: "en"})
+	if err != nil {
+		t.Fatalf("failed to enqueue notification: %v", err)
+	}
+	burst, err := outbox.ClaimDue(ctx, 10, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(burst) != 1 || *burst[0].EventType != eventType || *burst[0].Price != price || burst[0].Locale != "en" {
+		t.Fatalf("expected the event of the notification to be stored, got %+v", burst)
+	}
+	if err := outbox.MarkCoalesced(ctx, []string{burst[0].ID}); err != nil {
+		t.Fatalf("failed to mark notification coalesced: %v", err)
+	}
+	if err := outbox.Release(ctx, []string{burst[0].ID}, time.Now().Add(-time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if due, err := outbox.ClaimDue(ctx, 10, time.Minute); err != nil || len(due) != 0 {
+		t.Errorf("expected coalesced notification not to be delivered, got %+v, %v", due, err)
+	}
+}
+
+func Test_NotificationOutboxRepository_Resend(t *testing.T) {
+	ctx := context.Background()
+	outbox := NewNotificationOutboxRepository(newTestPool(t))
+
+	target := models.NotificationTarget{Channel: models.NotificationChannelTelegram, Recipient: "-100"}
+	if err := outbox.Enqueue(ctx, &EnqueueNotificationParams{Target: target, Text: "lost", Locale: "en"}); err != nil {
+		t.Fatal(err)
+	}
+	claimed, err := outbox.ClaimDue(ctx, 1, time.Minute)
+	if err != nil || len(claimed) != 1 {
+		t.Fatalf("failed to claim the notification: %v", err)
+	}
+	if err := outbox.MarkFailed(ctx, claimed[0].ID, "chat not found"); err != nil {
+		t.Fatal(err)
+	}
+
+	failed := models.NotificationStatusFailed
+	listed, err := outbox.List(ctx, &ListNotificationsParams{Status: &failed})
+	if err != nil {
+		t.Fatalf("failed to list notifications: %v", err)
+	}
+	if len(listed) != 1 || listed[0].ID != claimed[0].ID {
+		t.Fatalf("expected the failed notification, got %+v", listed)
+	}
+
+	resent, err := outbox.Resend(ctx, claimed[0].ID)
+	if err != nil {
+		t.Fatalf("failed to resend notification: %v", err)
+	}
+	if resent.ID == claimed[0].ID || resent.Status != models.NotificationStatusPending || resent.Text != "lost" || resent.Target != target || resent.Locale != "en" {
+		t.Errorf("expected a pending copy of the notification, got %+v", resent)
+	}
+
+	all, err := outbox.List(ctx, &ListNotificationsParams{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 2 || all[0].ID != resent.ID {
+		t.Errorf("expected the copy to be the newest notification, got %+v", all)
+	}
+
+	if _, err := outbox.Resend(ctx, "unknown"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
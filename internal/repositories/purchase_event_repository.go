@@ -0,0 +1,300 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"athylps/internal/models"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var purchaseEventColumns = []string{
+	"id",
+	"webhook_event_id",
+	"event_type",
+	"store",
+	"environment",
+	"product_id",
+	"country_code",
+	"price::float8 AS price",
+	"price_in_purchased_currency::float8 AS price_in_purchased_currency",
+	"currency",
+	"user_id",
+	"occurred_at",
+	"created_at",
+}
+
+type PurchaseEventRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewPurchaseEventRepository(db *pgxpool.Pool) *PurchaseEventRepository {
+	return &PurchaseEventRepository{
+		db: db,
+	}
+}
+
+type CreatePurchaseEventParams struct {
+	WebhookEventID string
+	EventType      string
+	Store          string
+	Environment    *string
+	ProductID      *string
+	CountryCode    *string
+	Price          *float32 // USD
+	// Price in the currency of the purchase with its ISO 4217 code
+	PriceInPurchasedCurrency *float32
+	Currency                 *string
+	UserID                   *string
+	OccurredAt               time.Time
+}
+
+// Create records the purchase event, returns ErrAlreadyExists if the webhook event is already recorded.
+// A missing price of the recorded event is filled in though, so replaying events prices ones recorded
+// before their price could be converted to USD.
+func (repo *PurchaseEventRepository) Create(ctx context.Context, p *CreatePurchaseEventParams) (*models.PurchaseEvent, error) {
+	sql, args, err := sq.Insert("purchase_events").
+		Columns(
+			"webhook_event_id",
+			"event_type",
+			"store",
+			"environment",
+			"product_id",
+			"country_code",
+			"price",
+			"price_in_purchased_currency",
+			"currency",
+			"user_id",
+			"occurred_at",
+		).
+		Values(
+			p.WebhookEventID,
+			p.EventType,
+			p.Store,
+			p.Environment,
+			p.ProductID,
+			p.CountryCode,
+			p.Price,
+			p.PriceInPurchasedCurrency,
+			p.Currency,
+			p.UserID,
+			p.OccurredAt,
+		).
+		Suffix(`ON CONFLICT (webhook_event_id) DO UPDATE SET
+			price = EXCLUDED.price,
+			price_in_purchased_currency = EXCLUDED.price_in_purchased_currency,
+			currency = EXCLUDED.currency
+			WHERE purchase_events.price IS NULL AND EXCLUDED.price IS NOT NULL
+			RETURNING ` + strings.Join(purchaseEventColumns, ", ")).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build insert purchase event query: %w", err)
+	}
+
+	rows, err := conn(ctx, repo.db).Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert purchase event: %w", err)
+	}
+
+	row, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[purchaseEventDbModel])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrAlreadyExists
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert purchase event: %w", err)
+	}
+
+	return row.toModel(), nil
+}
+
+// Stats aggregates purchase events that occurred in [from, to).
+func (repo *PurchaseEventRepository) Stats(ctx context.Context, from time.Time, to time.Time) ([]*models.PurchaseStats, error) {
+	sql, args, err := sq.Select(
+		"event_type",
+		"store",
+		"product_id",
+		"country_code",
+		"count(*) AS count",
+		"coalesce(sum(price), 0)::float8 AS revenue",
+	).
+		From("purchase_events").
+		Where(sq.GtOrEq{"occurred_at": from}).
+		Where(sq.Lt{"occurred_at": to}).
+		GroupBy("event_type", "store", "product_id", "country_code").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build purchase stats query: %w", err)
+	}
+
+	rows, err := conn(ctx, repo.db).Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get purchase stats: %w", err)
+	}
+
+	dbStats, err := pgx.CollectRows(rows, pgx.RowToStructByName[purchaseStatsDbModel])
+	if err != nil {
+		return nil, fmt.Errorf("failed to get purchase stats: %w", err)
+	}
+
+	stats := make([]*models.PurchaseStats, 0, len(dbStats))
+	for _, s := range dbStats {
+		stats = append(stats, &models.PurchaseStats{
+			EventType:   s.EventType,
+			Store:       s.Store,
+			ProductID:   s.ProductId,
+			CountryCode: s.CountryCode,
+			Count:       s.Count,
+			Revenue:     s.Revenue,
+		})
+	}
+
+	return stats, nil
+}
+
+// ListLatest returns the latest purchase events, the most recent first.
+func (repo *PurchaseEventRepository) ListLatest(ctx context.Context, limit uint64) ([]*models.PurchaseEvent, error) {
+	sql, args, err := sq.Select(purchaseEventColumns...).
+		From("purchase_events").
+		OrderBy("occurred_at DESC", "id DESC").
+		Limit(limit).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build list purchase events query: %w", err)
+	}
+
+	rows, err := conn(ctx, repo.db).Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list purchase events: %w", err)
+	}
+
+	dbEvents, err := pgx.CollectRows(rows, pgx.RowToStructByName[purchaseEventDbModel])
+	if err != nil {
+		return nil, fmt.Errorf("failed to list purchase events: %w", err)
+	}
+
+	events := make([]*models.PurchaseEvent, 0, len(dbEvents))
+	for _, e := range dbEvents {
+		events = append(events, e.toModel())
+	}
+
+	return events, nil
+}
+
+// ListByUserID returns up to limit latest purchase events of the user, the most recent first, all of them if limit is 0.
+func (repo *PurchaseEventRepository) ListByUserID(ctx context.Context, userID string, limit uint64) ([]*models.PurchaseEvent, error) {
+	query := sq.Select(purchaseEventColumns...).
+		From("purchase_events").
+		Where(sq.Eq{"user_id": userID}).
+		OrderBy("occurred_at DESC", "id DESC").
+		PlaceholderFormat(sq.Dollar)
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build list purchase events query: %w", err)
+	}
+
+	rows, err := conn(ctx, repo.db).Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list purchase events of user %s: %w", userID, err)
+	}
+
+	dbEvents, err := pgx.CollectRows(rows, pgx.RowToStructByName[purchaseEventDbModel])
+	if err != nil {
+		return nil, fmt.Errorf("failed to list purchase events of user %s: %w", userID, err)
+	}
+
+	events := make([]*models.PurchaseEvent, 0, len(dbEvents))
+	for _, e := range dbEvents {
+		events = append(events, e.toModel())
+	}
+
+	return events, nil
+}
+
+// LatestPrices returns the most recent non-zero price of each product paid in events of the types.
+// Products without such events are missing from the result.
+func (repo *PurchaseEventRepository) LatestPrices(ctx context.Context, productIDs []string, eventTypes []string) (map[string]float64, error) {
+	sql, args, err := sq.Select("DISTINCT ON (product_id) product_id", "price::float8 AS price").
+		From("purchase_events").
+		Where(sq.Eq{"product_id": productIDs, "event_type": eventTypes}).
+		Where(sq.Gt{"price": 0}).
+		OrderBy("product_id", "occurred_at DESC").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build latest prices query: %w", err)
+	}
+
+	rows, err := conn(ctx, repo.db).Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latest prices: %w", err)
+	}
+
+	prices := make(map[string]float64)
+	var productID string
+	var price float64
+	_, err = pgx.ForEachRow(rows, []any{&productID, &price}, func() error {
+		prices[productID] = price
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latest prices: %w", err)
+	}
+
+	return prices, nil
+}
+
+type purchaseEventDbModel struct {
+	Id                       string     `db:"id"`
+	WebhookEventId           string     `db:"webhook_event_id"`
+	EventType                string     `db:"event_type"`
+	Store                    string     `db:"store"`
+	Environment              *string    `db:"environment"`
+	ProductId                *string    `db:"product_id"`
+	CountryCode              *string    `db:"country_code"`
+	Price                    *float64   `db:"price"`
+	PriceInPurchasedCurrency *float64   `db:"price_in_purchased_currency"`
+	Currency                 *string    `db:"currency"`
+	UserId                   *string    `db:"user_id"`
+	OccurredAt               time.Time  `db:"occurred_at"`
+	CreatedAt                *time.Time `db:"created_at"`
+}
+
+func (m *purchaseEventDbModel) toModel() *models.PurchaseEvent {
+	return &models.PurchaseEvent{
+		ID:                       m.Id,
+		WebhookEventID:           m.WebhookEventId,
+		EventType:                m.EventType,
+		Store:                    m.Store,
+		Environment:              m.Environment,
+		ProductID:                m.ProductId,
+		CountryCode:              m.CountryCode,
+		Price:                    m.Price,
+		PriceInPurchasedCurrency: m.PriceInPurchasedCurrency,
+		Currency:                 m.Currency,
+		UserID:                   m.UserId,
+		OccurredAt:               m.OccurredAt,
+		CreatedAt:                m.CreatedAt,
+	}
+}
+
+type purchaseStatsDbModel struct {
+	EventType   string  `db:"event_type"`
+	Store       string  `db:"store"`
+	ProductId   *string `db:"product_id"`
+	CountryCode *string `db:"country_code"`
+	Count       int     `db:"count"`
+	Revenue     float64 `db:"revenue"`
+}
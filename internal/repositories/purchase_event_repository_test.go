@@ -0,0 +1,211 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"athylps/internal/models"
+)
+
+func Test_PurchaseEventRepository(t *testing.T) {
+	ctx := context.Background()
+	pool := newTestPool(t)
+	webhookEvents := NewWebhookEventRepository(pool)
+	purchases := NewPurchaseEventRepository(pool)
+
+	day := time.Date(2025, 12, 4, 0, 0, 0, 0, time.UTC)
+	events := []*CreatePurchaseEventParams{
+		{EventType: "INITIAL_PURCHASE", Store: "APP_STORE", ProductID: ptr("premium"), CountryCode: ptr("RU"), Price: ptr[float32](4.99), OccurredAt: day},
+		{EventType: "INITIAL_PURCHASE", Store: "APP_STORE", ProductID: ptr("premium"), CountryCode: ptr("RU"), Price: ptr[float32](4.99), OccurredAt: day.Add(time.Hour)},
+		{EventType: "CANCELLATION", Store: "RU_STORE", ProductID: ptr("premium"), OccurredAt: day.Add(2 * time.Hour)},
+		{EventType: "INITIAL_PURCHASE", Store: "RU_STORE", ProductID: ptr("premium"), CountryCode: ptr("RU"), Price: ptr[float32](1.86), PriceInPurchasedCurrency: ptr[float32](149), Currency: ptr("RUB"), OccurredAt: day.Add(3 * time.Hour)},
+		{EventType: "RENEWAL", Store: "APP_STORE", ProductID: ptr("premium"), Price: ptr[float32](4.99), OccurredAt: day.Add(24 * time.Hour)}, // Next day
+	}
+	for i, p := range events {
+		event, err := webhookEvents.Create(ctx, &CreateWebhookEventParams{
+			Provider:  models.ProviderRevenueCat,
+			EventID:   fmt.Sprintf("event-%d", i),
+			EventType: p.EventType,
+			Payload:   []byte(`{}`),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		p.WebhookEventID = event.ID
+
+		created, err := purchases.Create(ctx, p)
+		if err != nil {
+			t.Fatalf("failed to create purchase event: %v", err)
+		}
+		if p.Price != nil && (created.Price == nil || math.Round(*created.Price*100) != math.Round(float64(*p.Price)*100)) {
+			t.Errorf("expected price to be stored, got %v", created.Price)
+		}
+		if p.Currency != nil && (created.PriceInPurchasedCurrency == nil || *created.PriceInPurchasedCurrency != 149 || *created.Currency != "RUB") {
+			t.Errorf("expected price in the purchased currency to be stored, got %+v", created)
+		}
+	}
+
+	if _, err := purchases.Create(ctx, events[0]); !errors.Is(err, ErrAlreadyExists) {
+		t.Errorf("expected the same webhook event not to be recorded twice, got %v", err)
+	}
+
+	stats, err := purchases.Stats(ctx, day, day.Add(24*time.Hour))
+	if err != nil {
+		t.Fatalf("failed to get stats: %v", err)
+	}
+	if len(stats) != 3 {
+		t.Fatalf("expected stats of 3 groups, got %d", len(stats))
+	}
+	for _, s := range stats {
+		switch s.EventType + "/" + s.Store {
+		case "INITIAL_PURCHASE/APP_STORE":
+			if s.Count != 2 || s.Revenue != 9.98 || *s.CountryCode != "RU" {
+				t.Errorf("unexpected purchases stats: %+v", s)
+			}
+		case "INITIAL_PURCHASE/RU_STORE":
+			if s.Count != 1 || s.Revenue != 1.86 {
+				t.Errorf("expected rustore purchases to be counted in USD, got %+v", s)
+			}
+		case "CANCELLATION/RU_STORE":
+			if s.Count != 1 || s.Revenue != 0 {
+				t.Errorf("unexpected cancellations stats: %+v", s)
+			}
+		default:
+			t.Errorf("unexpected stats of another day: %+v", s)
+		}
+	}
+
+	latest, err := purchases.ListLatest(ctx, 2)
+	if err != nil {
+		t.Fatalf("failed to list latest purchase events: %v", err)
+	}
+	if len(latest) != 2 || latest[0].EventType != "RENEWAL" || latest[1].Store != "RU_STORE" {
+		t.Errorf("expected the 2 latest events, got %+v", latest)
+	}
+
+	prices, err := purchases.LatestPrices(ctx, []string{"premium", "coins"}, []string{"INITIAL_PURCHASE", "RENEWAL"})
+	if err != nil {
+		t.Fatalf("failed to get latest prices: %v", err)
+	}
+	if len(prices) != 1 || prices["premium"] != 4.99 {
+		t.Errorf("expected the price of premium only, got %v", prices)
+	}
+}
+
+func Test_PurchaseEventRepository_FillsInMissingPrice(t *testing.T) {
+	ctx := context.Background()
+	pool := newTestPool(t)
+	purchases := NewPurchaseEventRepository(pool)
+
+	event, err := NewWebhookEventRepository(pool).Create(ctx, &CreateWebhookEventParams{
+		Provider:  models.ProviderRuStore,
+		EventID:   "rustore-1",
+		EventType: "INITIAL_PURCHASE",
+		Payload:   []byte(`{}`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	unpriced := &CreatePurchaseEventParams{WebhookEventID: event.ID, EventType: "INITIAL_PURCHASE", Store: "RU_STORE", OccurredAt: time.Now()}
+	if _, err := purchases.Create(ctx, unpriced); err != nil {
+		t.Fatal(err)
+	}
+
+	// Replayed after the rate of the currency has been configured
+	priced := *unpriced
+	priced.Price, priced.PriceInPurchasedCurrency, priced.Currency = ptr[float32](1.86), ptr[float32](149), ptr("RUB")
+	filled, err := purchases.Create(ctx, &priced)
+	if err != nil {
+		t.Fatalf("expected the missing price to be filled in, got %v", err)
+	}
+	if filled.Price == nil || *filled.Price != 1.86 || *filled.Currency != "RUB" {
+		t.Errorf("expected the price to be filled in, got %+v", filled)
+	}
+
+	priced.Price = ptr[float32](2)
+	if _, err := purchases.Create(ctx, &priced); !errors.Is(err, ErrAlreadyExists) {
+		t.Errorf("expected a recorded price not to be overwritten, got %v", err)
+	}
+}
+
+func Test_PurchaseEventRepository_ListByUserID(t *testing.T) {
+	ctx := context.Background()
+	pool := newTestPool(t)
+	webhookEvents := NewWebhookEventRepository(pool)
+	purchases := NewPurchaseEventRepository(pool)
+
+	user, err := NewUserRepository(pool).CreateUser(ctx, &CreateUserParams{FirebaseUid: "firebase-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	day := time.Date(2025, 12, 4, 0, 0, 0, 0, time.UTC)
+	for i, userID := range []*string{&user.ID, nil, &user.ID} {
+		event, err := webhookEvents.Create(ctx, &CreateWebhookEventParams{
+			Provider:  models.ProviderRevenueCat,
+			EventID:   fmt.Sprintf("event-%d", i),
+			EventType: "RENEWAL",
+			Payload:   []byte(`{}`),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = purchases.Create(ctx, &CreatePurchaseEventParams{
+			WebhookEventID: event.ID,
+			EventType:      "RENEWAL",
+			Store:          "APP_STORE",
+			UserID:         userID,
+			OccurredAt:     day.Add(time.Duration(i) * time.Hour),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	events, err := purchases.ListByUserID(ctx, user.ID, 10)
+	if err != nil {
+		t.Fatalf("failed to list purchase events of the user: %v", err)
+	}
+	if len(events) != 2 || !events[0].OccurredAt.Equal(day.Add(2*time.Hour)) || *events[1].UserID != user.ID {
+		t.Errorf("expected 2 events of the user, the most recent first, got %+v", events)
+	}
+}
+
+func Test_DigestRunRepository_Claim(t *testing.T) {
+	ctx := context.Background()
+	pool := newTestPool(t)
+	runs := NewDigestRunRepository(pool)
+	transactor := NewTransactor(pool)
+
+	period := time.Date(2025, 12, 4, 0, 0, 0, 0, time.UTC)
+	rollback := errors.New("rollback")
+	err := transactor.WithinTx(ctx, func(ctx context.Context) error {
+		if claimed, err := runs.Claim(ctx, "daily", period); err != nil || !claimed {
+			t.Fatalf("expected period to be claimed, got %t, %v", claimed, err)
+		}
+		return rollback
+	})
+	if !errors.Is(err, rollback) {
+		t.Fatal(err)
+	}
+
+	// The claim is rolled back with the transaction
+	for _, want := range []bool{true, false} {
+		claimed, err := runs.Claim(ctx, "daily", period)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if claimed != want {
+			t.Errorf("expected claimed to be %t, got %t", want, claimed)
+		}
+	}
+
+	if claimed, err := runs.Claim(ctx, "weekly", period); err != nil || !claimed {
+		t.Errorf("expected another kind to be claimed independently, got %t, %v", claimed, err)
+	}
+}
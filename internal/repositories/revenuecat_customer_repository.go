@@ -0,0 +1,157 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"athylps/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErrRevenueCatCustomerLinked is returned when an app user id already belongs to another user.
+var ErrRevenueCatCustomerLinked = fmt.Errorf("revenuecat customer is linked to another user: %w", ErrAlreadyExists)
+
+type RevenueCatCustomerRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewRevenueCatCustomerRepository(db *pgxpool.Pool) *RevenueCatCustomerRepository {
+	return &RevenueCatCustomerRepository{
+		db: db,
+	}
+}
+
+// FindUserID returns the id of the user any of the app user ids belongs to or ErrNotFound.
+func (repo *RevenueCatCustomerRepository) FindUserID(ctx context.Context, appUserIDs []string) (string, error) {
+	var userID string
+	err := conn(ctx, repo.db).QueryRow(ctx, `
+		SELECT c.user_id FROM revenuecat_customers c
+		JOIN users u ON u.id = c.user_id AND u.deleted_at IS NULL
+		WHERE c.app_user_id = ANY($1)
+		ORDER BY c.created_at
+		LIMIT 1`,
+		appUserIDs,
+	).Scan(&userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to find revenuecat customer: %w", err)
+	}
+
+	return userID, nil
+}
+
+type LinkRevenueCatCustomerParams struct {
+	UserID     string
+	AppUserIDs []string
+	// RevenueCatID is stored as users.revenuecat_id when set
+	RevenueCatID *string
+}
+
+// Link attaches the app user ids and their subscriptions to the user.
+// Returns ErrRevenueCatCustomerLinked if some of the ids already belong to another user, nothing is changed then.
+func (repo *RevenueCatCustomerRepository) Link(ctx context.Context, p *LinkRevenueCatCustomerParams) error {
+	err := pgx.BeginFunc(ctx, conn(ctx, repo.db), func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO revenuecat_customers (app_user_id, user_id)
+			SELECT unnest($1::text[]), $2
+			ON CONFLICT (app_user_id) DO NOTHING`,
+			p.AppUserIDs, p.UserID,
+		)
+		if err != nil {
+			return mapUserError(err)
+		}
+
+		var linkedToOther bool
+		err = tx.QueryRow(ctx, `
+			SELECT EXISTS (SELECT 1 FROM revenuecat_customers WHERE app_user_id = ANY($1) AND user_id <> $2)`,
+			p.AppUserIDs, p.UserID,
+		).Scan(&linkedToOther)
+		if err != nil {
+			return err
+		}
+		if linkedToOther {
+			return ErrRevenueCatCustomerLinked
+		}
+
+		_, err = tx.Exec(ctx, `
+			UPDATE subscriptions SET user_id = $2, updated_at = now()
+			WHERE app_user_id = ANY($1) AND user_id IS DISTINCT FROM $2`,
+			p.AppUserIDs, p.UserID,
+		)
+		if err != nil {
+			return err
+		}
+
+		if p.RevenueCatID != nil {
+			_, err = tx.Exec(ctx, `
+				UPDATE users SET revenuecat_id = $1, updated_at = now()
+				WHERE id = $2 AND deleted_at IS NULL AND revenuecat_id IS DISTINCT FROM $1`,
+				*p.RevenueCatID, p.UserID,
+			)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to link revenuecat customer to user %s: %w", p.UserID, err)
+	}
+
+	return nil
+}
+
+// ListByUserID returns app user ids linked to the user, the earliest linked first.
+func (repo *RevenueCatCustomerRepository) ListByUserID(ctx context.Context, userID string) ([]*models.RevenueCatCustomer, error) {
+	rows, err := conn(ctx, repo.db).Query(ctx, `
+		SELECT app_user_id, user_id, created_at FROM revenuecat_customers
+		WHERE user_id = $1
+		ORDER BY created_at, app_user_id`,
+		userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list revenuecat customers of user %s: %w", userID, err)
+	}
+
+	dbCustomers, err := pgx.CollectRows(rows, pgx.RowToStructByName[revenueCatCustomerDbModel])
+	if err != nil {
+		return nil, fmt.Errorf("failed to list revenuecat customers of user %s: %w", userID, err)
+	}
+
+	customers := make([]*models.RevenueCatCustomer, 0, len(dbCustomers))
+	for _, c := range dbCustomers {
+		customers = append(customers, c.toModel())
+	}
+
+	return customers, nil
+}
+
+// UnlinkUser detaches all app user ids from the user, so they can be linked to another user.
+func (repo *RevenueCatCustomerRepository) UnlinkUser(ctx context.Context, userID string) error {
+	if _, err := conn(ctx, repo.db).Exec(ctx, `DELETE FROM revenuecat_customers WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to unlink revenuecat customers of user %s: %w", userID, err)
+	}
+
+	return nil
+}
+
+type revenueCatCustomerDbModel struct {
+	AppUserId string     `db:"app_user_id"`
+	UserId    string     `db:"user_id"`
+	CreatedAt *time.Time `db:"created_at"`
+}
+
+func (m *revenueCatCustomerDbModel) toModel() *models.RevenueCatCustomer {
+	return &models.RevenueCatCustomer{
+		AppUserID: m.AppUserId,
+		UserID:    m.UserId,
+		CreatedAt: m.CreatedAt,
+	}
+}
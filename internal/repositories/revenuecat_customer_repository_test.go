@@ -0,0 +1,82 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"athylps/internal/models"
+)
+
+func Test_RevenueCatCustomerRepository_Link(t *testing.T) {
+	ctx := context.Background()
+	pool := newTestPool(t)
+	users := NewUserRepository(pool)
+	customers := NewRevenueCatCustomerRepository(pool)
+	subscriptions := NewSubscriptionRepository(pool)
+
+	first, err := users.CreateUser(ctx, &CreateUserParams{FirebaseUid: "firebase-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := users.CreateUser(ctx, &CreateUserParams{FirebaseUid: "firebase-2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const anonymousID = "$RCAnonymousID:1"
+	_, err = subscriptions.Upsert(ctx, &models.Subscription{
+		AppUserID:     anonymousID,
+		ProductID:     "premium_monthly",
+		Store:         "APP_STORE",
+		Status:        models.SubscriptionStatusActive,
+		LastEventType: "INITIAL_PURCHASE",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := customers.FindUserID(ctx, []string{"user_42", anonymousID}); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for unknown customer, got %v", err)
+	}
+
+	err = customers.Link(ctx, &LinkRevenueCatCustomerParams{UserID: first.ID, AppUserIDs: []string{"user_42"}, RevenueCatID: ptr("user_42")})
+	if err != nil {
+		t.Fatalf("failed to link customer: %v", err)
+	}
+	linked, err := users.GetUserByID(ctx, first.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if linked.RevenueCatID == nil || *linked.RevenueCatID != "user_42" {
+		t.Errorf("expected revenuecat_id to be set, got %v", linked.RevenueCatID)
+	}
+
+	err = customers.Link(ctx, &LinkRevenueCatCustomerParams{UserID: first.ID, AppUserIDs: []string{"user_42", anonymousID}})
+	if err != nil {
+		t.Fatalf("failed to link aliases: %v", err)
+	}
+	userID, err := customers.FindUserID(ctx, []string{anonymousID})
+	if err != nil || userID != first.ID {
+		t.Errorf("expected alias to belong to %s, got %q, %v", first.ID, userID, err)
+	}
+	subscription, err := subscriptions.Get(ctx, anonymousID, "premium_monthly")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if subscription.UserID == nil || *subscription.UserID != first.ID {
+		t.Errorf("expected subscription of the alias to move to the user, got %v", subscription.UserID)
+	}
+
+	err = customers.Link(ctx, &LinkRevenueCatCustomerParams{UserID: second.ID, AppUserIDs: []string{"user_42"}, RevenueCatID: ptr("user_42")})
+	if !errors.Is(err, ErrRevenueCatCustomerLinked) {
+		t.Errorf("expected ErrRevenueCatCustomerLinked, got %v", err)
+	}
+	notLinked, err := users.GetUserByID(ctx, second.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if notLinked.RevenueCatID != nil {
+		t.Errorf("expected failed link to be rolled back, got revenuecat_id %s", *notLinked.RevenueCatID)
+	}
+}
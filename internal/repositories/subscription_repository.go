@@ -0,0 +1,277 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"athylps/internal/models"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var subscriptionColumns = []string{
+	"id",
+	"app_user_id",
+	"user_id",
+	"product_id",
+	"entitlement_ids",
+	"store",
+	"environment",
+	"status",
+	"purchased_at",
+	"expires_at",
+	"grace_period_expires_at",
+	"cancelled_at",
+	"last_event_type",
+	"last_event_at",
+	"created_at",
+	"updated_at",
+}
+
+type SubscriptionRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewSubscriptionRepository(db *pgxpool.Pool) *SubscriptionRepository {
+	return &SubscriptionRepository{
+		db: db,
+	}
+}
+
+func (repo *SubscriptionRepository) Get(ctx context.Context, appUserID string, productID string) (*models.Subscription, error) {
+	sql, args, err := sq.Select(subscriptionColumns...).
+		From("subscriptions").
+		Where(sq.Eq{"app_user_id": appUserID, "product_id": productID}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build select subscription query: %w", err)
+	}
+
+	rows, err := conn(ctx, repo.db).Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get subscription: %w", err)
+	}
+
+	row, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[subscriptionDbModel])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get subscription: %w", err)
+	}
+
+	return row.toModel(), nil
+}
+
+// ListByUserID returns all subscriptions linked to the user.
+func (repo *SubscriptionRepository) ListByUserID(ctx context.Context, userID string) ([]*models.Subscription, error) {
+	sql, args, err := sq.Select(subscriptionColumns...).
+		From("subscriptions").
+		Where(sq.Eq{"user_id": userID}).
+		OrderBy("created_at").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build list subscriptions query: %w", err)
+	}
+
+	rows, err := conn(ctx, repo.db).Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list subscriptions of user %s: %w", userID, err)
+	}
+
+	dbSubscriptions, err := pgx.CollectRows(rows, pgx.RowToStructByName[subscriptionDbModel])
+	if err != nil {
+		return nil, fmt.Errorf("failed to list subscriptions of user %s: %w", userID, err)
+	}
+
+	subscriptions := make([]*models.Subscription, 0, len(dbSubscriptions))
+	for _, s := range dbSubscriptions {
+		subscriptions = append(subscriptions, s.toModel())
+	}
+
+	return subscriptions, nil
+}
+
+// ListExpiringAfter returns not expired subscriptions that expire, or leave the grace period, after the time.
+// Purchases that never expire are not included.
+func (repo *SubscriptionRepository) ListExpiringAfter(ctx context.Context, at time.Time) ([]*models.Subscription, error) {
+	sql, args, err := sq.Select(subscriptionColumns...).
+		From("subscriptions").
+		Where(sq.NotEq{"status": string(models.SubscriptionStatusExpired)}).
+		Where(sq.Gt{"coalesce(grace_period_expires_at, expires_at)": at}).
+		OrderBy("created_at").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build list subscriptions query: %w", err)
+	}
+
+	rows, err := conn(ctx, repo.db).Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list subscriptions expiring after %s: %w", at, err)
+	}
+
+	dbSubscriptions, err := pgx.CollectRows(rows, pgx.RowToStructByName[subscriptionDbModel])
+	if err != nil {
+		return nil, fmt.Errorf("failed to list subscriptions expiring after %s: %w", at, err)
+	}
+
+	subscriptions := make([]*models.Subscription, 0, len(dbSubscriptions))
+	for _, s := range dbSubscriptions {
+		subscriptions = append(subscriptions, s.toModel())
+	}
+
+	return subscriptions, nil
+}
+
+// Upsert creates or replaces the state of the subscription identified by app user id and product id.
+func (repo *SubscriptionRepository) Upsert(ctx context.Context, s *models.Subscription) (*models.Subscription, error) {
+	entitlementIDs := s.EntitlementIDs
+	if entitlementIDs == nil {
+		entitlementIDs = []string{} // NULL is not allowed
+	}
+
+	sql, args, err := sq.Insert("subscriptions").
+		Columns(
+			"app_user_id",
+			"user_id",
+			"product_id",
+			"entitlement_ids",
+			"store",
+			"environment",
+			"status",
+			"purchased_at",
+			"expires_at",
+			"grace_period_expires_at",
+			"cancelled_at",
+			"last_event_type",
+			"last_event_at",
+		).
+		Values(
+			s.AppUserID,
+			s.UserID,
+			s.ProductID,
+			entitlementIDs,
+			s.Store,
+			s.Environment,
+			string(s.Status),
+			s.PurchasedAt,
+			s.ExpiresAt,
+			s.GracePeriodExpiresAt,
+			s.CancelledAt,
+			s.LastEventType,
+			s.LastEventAt,
+		).
+		Suffix(`ON CONFLICT (app_user_id, product_id) DO UPDATE SET
+			user_id = COALESCE(excluded.user_id, subscriptions.user_id),
+			entitlement_ids = excluded.entitlement_ids,
+			store = excluded.store,
+			environment = excluded.environment,
+			status = excluded.status,
+			purchased_at = excluded.purchased_at,
+			expires_at = excluded.expires_at,
+			grace_period_expires_at = excluded.grace_period_expires_at,
+			cancelled_at = excluded.cancelled_at,
+			last_event_type = excluded.last_event_type,
+			last_event_at = excluded.last_event_at,
+			updated_at = now()
+			RETURNING ` + strings.Join(subscriptionColumns, ", ")).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build upsert subscription query: %w", err)
+	}
+
+	rows, err := conn(ctx, repo.db).Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to upsert subscription: %w", err)
+	}
+
+	row, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[subscriptionDbModel])
+	if err != nil {
+		return nil, fmt.Errorf("failed to upsert subscription: %w", err)
+	}
+
+	return row.toModel(), nil
+}
+
+// Transfer moves all subscriptions of the given app user ids to another app user id and the user it is linked to.
+// Subscriptions the target already has for the same products are replaced.
+func (repo *SubscriptionRepository) Transfer(ctx context.Context, fromAppUserIDs []string, toAppUserID string, toUserID *string) (int64, error) {
+	var transferred int64
+	err := pgx.BeginFunc(ctx, conn(ctx, repo.db), func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, `
+			DELETE FROM subscriptions
+			WHERE app_user_id = $1
+			AND product_id IN (SELECT product_id FROM subscriptions WHERE app_user_id = ANY($2))`,
+			toAppUserID, fromAppUserIDs,
+		)
+		if err != nil {
+			return err
+		}
+
+		tag, err := tx.Exec(ctx, `
+			UPDATE subscriptions SET app_user_id = $1, user_id = $3, updated_at = now()
+			WHERE app_user_id = ANY($2)`,
+			toAppUserID, fromAppUserIDs, toUserID,
+		)
+		if err != nil {
+			return err
+		}
+		transferred = tag.RowsAffected()
+
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to transfer subscriptions to %s: %w", toAppUserID, err)
+	}
+
+	return transferred, nil
+}
+
+type subscriptionDbModel struct {
+	Id                   string     `db:"id"`
+	AppUserId            string     `db:"app_user_id"`
+	UserId               *string    `db:"user_id"`
+	ProductId            string     `db:"product_id"`
+	EntitlementIds       []string   `db:"entitlement_ids"`
+	Store                string     `db:"store"`
+	Environment          *string    `db:"environment"`
+	Status               string     `db:"status"`
+	PurchasedAt          *time.Time `db:"purchased_at"`
+	ExpiresAt            *time.Time `db:"expires_at"`
+	GracePeriodExpiresAt *time.Time `db:"grace_period_expires_at"`
+	CancelledAt          *time.Time `db:"cancelled_at"`
+	LastEventType        string     `db:"last_event_type"`
+	LastEventAt          *time.Time `db:"last_event_at"`
+	CreatedAt            *time.Time `db:"created_at"`
+	UpdatedAt            *time.Time `db:"updated_at"`
+}
+
+func (m *subscriptionDbModel) toModel() *models.Subscription {
+	return &models.Subscription{
+		ID:                   m.Id,
+		AppUserID:            m.AppUserId,
+		UserID:               m.UserId,
+		ProductID:            m.ProductId,
+		EntitlementIDs:       m.EntitlementIds,
+		Store:                m.Store,
+		Environment:          m.Environment,
+		Status:               models.SubscriptionStatus(m.Status),
+		PurchasedAt:          m.PurchasedAt,
+		ExpiresAt:            m.ExpiresAt,
+		GracePeriodExpiresAt: m.GracePeriodExpiresAt,
+		CancelledAt:          m.CancelledAt,
+		LastEventType:        m.LastEventType,
+		LastEventAt:          m.LastEventAt,
+		CreatedAt:            m.CreatedAt,
+		UpdatedAt:            m.UpdatedAt,
+	}
+}
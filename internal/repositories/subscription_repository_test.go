@@ -0,0 +1,97 @@
+package repositories
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"athylps/internal/models"
+)
+
+func Test_SubscriptionRepository_ListByUserID(t *testing.T) {
+	ctx := context.Background()
+	pool := newTestPool(t)
+	users := NewUserRepository(pool)
+	subscriptions := NewSubscriptionRepository(pool)
+
+	user, err := users.CreateUser(ctx, &CreateUserParams{FirebaseUid: "firebase-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, s := range []*models.Subscription{
+		{AppUserID: "user_42", UserID: &user.ID, ProductID: "premium_monthly", EntitlementIDs: []string{"premium"}},
+		{AppUserID: "user_42", UserID: &user.ID, ProductID: "coins_100"},
+		{AppUserID: "user_43", ProductID: "premium_monthly", EntitlementIDs: []string{"premium"}},
+	} {
+		s.Store = "APP_STORE"
+		s.Status = models.SubscriptionStatusActive
+		s.LastEventType = "INITIAL_PURCHASE"
+		if _, err := subscriptions.Upsert(ctx, s); err != nil {
+			t.Fatalf("failed to upsert subscription: %v", err)
+		}
+	}
+
+	// Events without user id must not detach the subscription from the user
+	_, err = subscriptions.Upsert(ctx, &models.Subscription{
+		AppUserID:      "user_42",
+		ProductID:      "premium_monthly",
+		EntitlementIDs: []string{"premium"},
+		Store:          "APP_STORE",
+		Status:         models.SubscriptionStatusCancelled,
+		LastEventType:  "CANCELLATION",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := subscriptions.ListByUserID(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("failed to list subscriptions: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("expected 2 subscriptions of the user, got %d", len(got))
+	}
+	if got[0].ProductID != "premium_monthly" || got[0].Status != models.SubscriptionStatusCancelled ||
+		!slices.Equal(got[0].EntitlementIDs, []string{"premium"}) {
+		t.Errorf("unexpected subscription: %+v", got[0])
+	}
+	if got[1].EntitlementIDs == nil || len(got[1].EntitlementIDs) != 0 {
+		t.Errorf("expected empty entitlements, got %v", got[1].EntitlementIDs)
+	}
+}
+
+func Test_SubscriptionRepository_ListExpiringAfter(t *testing.T) {
+	ctx := context.Background()
+	pool := newTestPool(t)
+	subscriptions := NewSubscriptionRepository(pool)
+
+	now := time.Now()
+	for _, s := range []*models.Subscription{
+		{ProductID: "active", Status: models.SubscriptionStatusActive, ExpiresAt: ptr(now.Add(time.Hour))},
+		{ProductID: "grace", Status: models.SubscriptionStatusGracePeriod, ExpiresAt: ptr(now.Add(-time.Hour)), GracePeriodExpiresAt: ptr(now.Add(time.Hour))},
+		{ProductID: "lapsed", Status: models.SubscriptionStatusActive, ExpiresAt: ptr(now.Add(-time.Hour))},
+		{ProductID: "expired", Status: models.SubscriptionStatusExpired, ExpiresAt: ptr(now.Add(time.Hour))},
+		{ProductID: "lifetime", Status: models.SubscriptionStatusActive},
+	} {
+		s.AppUserID = "user_42"
+		s.Store = "APP_STORE"
+		s.LastEventType = "INITIAL_PURCHASE"
+		if _, err := subscriptions.Upsert(ctx, s); err != nil {
+			t.Fatalf("failed to upsert subscription: %v", err)
+		}
+	}
+
+	got, err := subscriptions.ListExpiringAfter(ctx, now)
+	if err != nil {
+		t.Fatalf("failed to list subscriptions: %v", err)
+	}
+	var products []string
+	for _, s := range got {
+		products = append(products, s.ProductID)
+	}
+	if !slices.Equal(products, []string{"active", "grace"}) {
+		t.Errorf("expected active and grace period subscriptions, got %v", products)
+	}
+}
@@ -0,0 +1,73 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/pressly/goose/v3"
+)
+
+// Integration tests run against a real Postgres and are skipped unless TEST_DATABASE_URL is set,
+// e.g. `make test-integration` with the database from compose.yaml.
+// The database is migrated once and all tables are truncated before every test, never point it to real data.
+const testDatabaseURLEnv = "TEST_DATABASE_URL"
+
+var migrateOnce sync.Once
+
+func newTestPool(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+
+	url := os.Getenv(testDatabaseURLEnv)
+	if url == "" {
+		t.Skipf("%s is not set, skipping integration test", testDatabaseURLEnv)
+	}
+
+	var migrateErr error
+	migrateOnce.Do(func() {
+		db, err := sql.Open("pgx", url)
+		if err != nil {
+			migrateErr = err
+			return
+		}
+		defer db.Close()
+
+		goose.SetLogger(goose.NopLogger())
+		if err := goose.SetDialect("pgx"); err != nil {
+			migrateErr = err
+			return
+		}
+		migrateErr = goose.Up(db, "../../migrations")
+	})
+	if migrateErr != nil {
+		t.Fatalf("failed to migrate test database: %v", migrateErr)
+	}
+
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, url)
+	if err != nil {
+		t.Fatalf("failed to connect to test database: %v", err)
+	}
+	t.Cleanup(pool.Close)
+
+	rows, err := pool.Query(ctx, "SELECT tablename FROM pg_tables WHERE schemaname = 'public' AND tablename <> 'goose_db_version'")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tables, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, table := range tables {
+		if _, err := pool.Exec(ctx, "TRUNCATE "+pgx.Identifier{table}.Sanitize()+" CASCADE"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return pool
+}
@@ -2,49 +2,539 @@ package repositories
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
+	"athylps/internal/models"
+
 	sq "github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+const (
+	pgUniqueViolationCode      = "23505"
+	pgInvalidTextRepresentCode = "22P02" // e.g. malformed uuid
+	usersEmailUniqueConstraint = "users_email_key"
+)
+
+// ErrEmailTaken is returned when another user already has the email.
+var ErrEmailTaken = fmt.Errorf("email is taken: %w", ErrAlreadyExists)
+
+var userColumns = []string{
+	"id",
+	"email",
+	"firebase_uid",
+	"revenuecat_id",
+	"timezone",
+	"locale",
+	"created_at",
+	"updated_at",
+	"deleted_at",
+	"purge_after",
+}
+
 type UserRepository struct {
 	db *pgxpool.Pool
 }
 
-func NewUserRepository() *UserRepository {
+func NewUserRepository(db *pgxpool.Pool) *UserRepository {
 	return &UserRepository{
-		db: nil,
+		db: db,
 	}
 }
 
 type CreateUserParams struct {
 	FirebaseUid  string
-	Email        string
+	Email        *string
 	RevenueCatId *string
 	Timezone     *string
 	Locale       *string
 }
 
-func (repo *UserRepository) CreateUser(ctx context.Context, p *CreateUserParams) {
-	sql, args, _ := sq.Insert("users").
-		Columns("email, firebase_uid, revenuecat_id, timezone, locale").
+func (repo *UserRepository) CreateUser(ctx context.Context, p *CreateUserParams) (*models.User, error) {
+	sql, args, err := sq.Insert("users").
+		Columns("email", "firebase_uid", "revenuecat_id", "timezone", "locale").
 		Values(p.Email, p.FirebaseUid, p.RevenueCatId, p.Timezone, p.Locale).
-		Suffix("returning *").
+		Suffix("RETURNING " + strings.Join(userColumns, ", ")).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build insert user query: %w", err)
+	}
+
+	user, err := repo.queryOne(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+
+	return user, nil
+}
+
+func (repo *UserRepository) GetUserByID(ctx context.Context, id string) (*models.User, error) {
+	return repo.getUserBy(ctx, sq.Eq{"id": id})
+}
+
+// GetUserByIDWithDeleted returns the user by id even if the user has been deleted, or ErrNotFound.
+func (repo *UserRepository) GetUserByIDWithDeleted(ctx context.Context, id string) (*models.User, error) {
+	sql, args, err := sq.Select(userColumns...).
+		From("users").
+		Where(sq.Eq{"id": id}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build select user query: %w", err)
+	}
+
+	user, err := repo.queryOne(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	return user, nil
+}
+
+// GetUserByFirebaseUID returns a not deleted user by Firebase uid or ErrNotFound.
+func (repo *UserRepository) GetUserByFirebaseUID(ctx context.Context, firebaseUID string) (*models.User, error) {
+	return repo.getUserBy(ctx, sq.Eq{"firebase_uid": firebaseUID})
+}
+
+func (repo *UserRepository) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	return repo.getUserBy(ctx, sq.Eq{"email": email})
+}
+
+func (repo *UserRepository) GetUserByRevenueCatID(ctx context.Context, revenueCatID string) (*models.User, error) {
+	return repo.getUserBy(ctx, sq.Eq{"revenuecat_id": revenueCatID})
+}
+
+func (repo *UserRepository) getUserBy(ctx context.Context, where sq.Eq) (*models.User, error) {
+	where["deleted_at"] = nil
+	sql, args, err := sq.Select(userColumns...).
+		From("users").
+		Where(where).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build select user query: %w", err)
+	}
+
+	user, err := repo.queryOne(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	return user, nil
+}
+
+type UpsertUserParams struct {
+	FirebaseUID string
+	Email       *string
+	Timezone    *string
+	Locale      *string
+}
+
+// UpsertUserByFirebaseUID creates a user with the Firebase uid or updates the existing one.
+// Nil fields don't override stored values and updated_at changes only when some field actually changes.
+// Returns ErrNotFound if the user with this Firebase uid has been deleted.
+func (repo *UserRepository) UpsertUserByFirebaseUID(ctx context.Context, p *UpsertUserParams) (*models.User, error) {
+	sql, args, err := sq.Insert("users").
+		Columns("firebase_uid", "email", "timezone", "locale").
+		Values(p.FirebaseUID, p.Email, p.Timezone, p.Locale).
+		Suffix(`ON CONFLICT (firebase_uid) DO UPDATE SET
+			email = COALESCE(excluded.email, users.email),
+			timezone = COALESCE(excluded.timezone, users.timezone),
+			locale = COALESCE(excluded.locale, users.locale),
+			updated_at = now()
+			WHERE users.deleted_at IS NULL AND (
+				users.email IS DISTINCT FROM COALESCE(excluded.email, users.email) OR
+				users.timezone IS DISTINCT FROM COALESCE(excluded.timezone, users.timezone) OR
+				users.locale IS DISTINCT FROM COALESCE(excluded.locale, users.locale)
+			)
+			RETURNING ` + strings.Join(userColumns, ", ")).
 		PlaceholderFormat(sq.Dollar).
 		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build upsert user query: %w", err)
+	}
 
-	err := repo.db.QueryRow(ctx, sql, args).Scan()
+	user, err := repo.queryOne(ctx, sql, args...)
+	if errors.Is(err, ErrNotFound) {
+		// Nothing has changed (or the user is deleted), so the upsert didn't return the row
+		return repo.GetUserByFirebaseUID(ctx, p.FirebaseUID)
+	}
 	if err != nil {
-		fmt.Println(err)
-		return
+		return nil, fmt.Errorf("failed to upsert user: %w", err)
 	}
+
+	return user, nil
+}
+
+// UpdateUserParams contains profile fields to update, nil fields are left unchanged.
+type UpdateUserParams struct {
+	Email    *string
+	Timezone *string
+	Locale   *string
+}
+
+func (repo *UserRepository) UpdateUser(ctx context.Context, id string, p *UpdateUserParams) (*models.User, error) {
+	query := sq.Update("users").
+		Set("updated_at", sq.Expr("now()")).
+		Where(sq.Eq{"id": id, "deleted_at": nil}).
+		Suffix("RETURNING " + strings.Join(userColumns, ", ")).
+		PlaceholderFormat(sq.Dollar)
+	if p.Email != nil {
+		query = query.Set("email", *p.Email)
+	}
+	if p.Timezone != nil {
+		query = query.Set("timezone", *p.Timezone)
+	}
+	if p.Locale != nil {
+		query = query.Set("locale", *p.Locale)
+	}
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build update user query: %w", err)
+	}
+
+	user, err := repo.queryOne(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update user %s: %w", id, err)
+	}
+
+	return user, nil
+}
+
+// DeleteUser soft deletes the user, deleted users are not returned by other methods unless requested explicitly.
+func (repo *UserRepository) DeleteUser(ctx context.Context, id string) error {
+	sql, args, err := sq.Update("users").
+		Set("deleted_at", sq.Expr("now()")).
+		Set("updated_at", sq.Expr("now()")).
+		Where(sq.Eq{"id": id, "deleted_at": nil}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build delete user query: %w", err)
+	}
+
+	tag, err := conn(ctx, repo.db).Exec(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("failed to delete user %s: %w", id, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("failed to delete user %s: %w", id, ErrNotFound)
+	}
+
+	return nil
+}
+
+// DeleteAccount soft deletes the user on their request, detaches the RevenueCat id and schedules the purge
+// of personal data, see PurgeUser. Returns ErrNotFound if the user doesn't exist or is already deleted.
+func (repo *UserRepository) DeleteAccount(ctx context.Context, id string, purgeAfter time.Time) (*models.User, error) {
+	sql, args, err := sq.Update("users").
+		Set("deleted_at", sq.Expr("now()")).
+		Set("purge_after", purgeAfter).
+		Set("revenuecat_id", nil).
+		Set("updated_at", sq.Expr("now()")).
+		Where(sq.Eq{"id": id, "deleted_at": nil}).
+		Suffix("RETURNING " + strings.Join(userColumns, ", ")).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build delete account query: %w", err)
+	}
+
+	user, err := repo.queryOne(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete account of user %s: %w", id, err)
+	}
+
+	return user, nil
+}
+
+// ListPurgeDue returns up to limit deleted users whose purge is due at the time, the longest due first.
+func (repo *UserRepository) ListPurgeDue(ctx context.Context, at time.Time, limit uint64) ([]*models.User, error) {
+	sql, args, err := sq.Select(userColumns...).
+		From("users").
+		Where(sq.NotEq{"deleted_at": nil}).
+		Where(sq.LtOrEq{"purge_after": at}).
+		OrderBy("purge_after", "id").
+		Limit(limit).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build list purge due users query: %w", err)
+	}
+
+	rows, err := conn(ctx, repo.db).Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list purge due users: %w", err)
+	}
+
+	dbUsers, err := pgx.CollectRows(rows, pgx.RowToStructByName[userDbModel])
+	if err != nil {
+		return nil, fmt.Errorf("failed to list purge due users: %w", err)
+	}
+
+	users := make([]*models.User, 0, len(dbUsers))
+	for _, u := range dbUsers {
+		users = append(users, u.toModel())
+	}
+
+	return users, nil
+}
+
+// PurgeUser hard deletes the user if the purge is due at the time: removes grants made by admins and deletes
+// the user row. Subscriptions, purchase and webhook events are kept for revenue stats, but identifiers of the user
+// in them are replaced with a pseudonym:
+//   - app user ids of the user's subscriptions;
+//   - app user ids, aliases, transfers and subscriber attributes, like email and name, in the user's RevenueCat events;
+//   - the developer payload, which is our user id, in the user's RuStore events;
+//   - the user id in texts of notifications.
+//
+// Rewritten payloads are no longer byte for byte what the provider sent. Linked RevenueCat customers go with the row.
+// Returns ErrNotFound if the user has already been purged or restored, so purging again does nothing.
+func (repo *UserRepository) PurgeUser(ctx context.Context, id string, at time.Time) error {
+	pseudonym := "deleted:" + uuid.NewString()
+	err := pgx.BeginFunc(ctx, conn(ctx, repo.db), func(tx pgx.Tx) error {
+		// Locks the user, so a concurrent restore waits for the purge
+		var firebaseUID *string
+		err := tx.QueryRow(ctx, `
+			SELECT firebase_uid FROM users WHERE id = $1 AND deleted_at IS NOT NULL AND purge_after <= $2 FOR UPDATE`,
+			id, at,
+		).Scan(&firebaseUID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return mapUserError(err)
+		}
+
+		_, err = tx.Exec(ctx, `DELETE FROM subscriptions WHERE user_id = $1 AND store = $2`, id, models.StoreManual)
+		if err != nil {
+			return err
+		}
+
+		// The subscription id keeps app user ids unique per product
+		appUserIDs := []string{id}
+		if firebaseUID != nil {
+			appUserIDs = append(appUserIDs, *firebaseUID)
+		}
+		_, err = tx.Exec(ctx, `
+			UPDATE subscriptions SET app_user_id = 'deleted:' || id, updated_at = now()
+			WHERE user_id = $1 OR app_user_id = ANY($2)`,
+			id, appUserIDs,
+		)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, `
+			UPDATE webhook_events SET payload = convert_to(jsonb_set(jsonb_set(
+				convert_from(payload, 'UTF8')::jsonb
+					#- '{event,subscriber_attributes}'
+					#- '{event,aliases}'
+					#- '{event,transferred_from}'
+					#- '{event,transferred_to}',
+				'{event,app_user_id}', to_jsonb($3::text), false),
+				'{event,original_app_user_id}', to_jsonb($3::text), false)::text, 'UTF8')
+			WHERE user_id = $1 AND provider = $2`,
+			id, models.ProviderRevenueCat, pseudonym,
+		)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, `
+			UPDATE webhook_events SET payload = convert_to(jsonb_set(
+				convert_from(payload, 'UTF8')::jsonb, '{developerPayload}', to_jsonb($3::text), false)::text, 'UTF8')
+			WHERE user_id = $1 AND provider = $2`,
+			id, models.ProviderRuStore, pseudonym,
+		)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, `UPDATE notifications_outbox SET text = replace(text, $1, $2) WHERE strpos(text, $1) > 0`, id, pseudonym)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, `DELETE FROM users WHERE id = $1`, id)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to purge user %s: %w", id, err)
+	}
+
+	return nil
+}
+
+// RestoreUser undoes the soft delete and cancels the purge, returns ErrNotFound if the user doesn't exist or isn't deleted.
+func (repo *UserRepository) RestoreUser(ctx context.Context, id string) (*models.User, error) {
+	sql, args, err := sq.Update("users").
+		Set("deleted_at", nil).
+		Set("purge_after", nil).
+		Set("updated_at", sq.Expr("now()")).
+		Where(sq.Eq{"id": id}).
+		Where(sq.NotEq{"deleted_at": nil}).
+		Suffix("RETURNING " + strings.Join(userColumns, ", ")).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build restore user query: %w", err)
+	}
+
+	user, err := repo.queryOne(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore user %s: %w", id, err)
+	}
+
+	return user, nil
+}
+
+// FindUsers returns users, deleted ones included, whose id, email, Firebase uid or RevenueCat id is the query,
+// or who have been linked to it as a RevenueCat app user id.
+func (repo *UserRepository) FindUsers(ctx context.Context, query string) ([]*models.User, error) {
+	sql, args, err := sq.Select(userColumns...).
+		From("users").
+		Where(sq.Or{
+			sq.Eq{"id::text": query},
+			sq.Eq{"lower(email)": strings.ToLower(query)},
+			sq.Eq{"firebase_uid": query},
+			sq.Eq{"revenuecat_id": query},
+			sq.Expr("id IN (SELECT user_id FROM revenuecat_customers WHERE app_user_id = ?)", query),
+		}).
+		OrderBy("created_at DESC", "id DESC").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build find users query: %w", err)
+	}
+
+	rows, err := conn(ctx, repo.db).Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find users: %w", err)
+	}
+
+	dbUsers, err := pgx.CollectRows(rows, pgx.RowToStructByName[userDbModel])
+	if err != nil {
+		return nil, fmt.Errorf("failed to find users: %w", err)
+	}
+
+	users := make([]*models.User, 0, len(dbUsers))
+	for _, u := range dbUsers {
+		users = append(users, u.toModel())
+	}
+
+	return users, nil
+}
+
+type ListUsersParams struct {
+	EmailContains  *string
+	Locale         *string
+	CreatedAfter   *time.Time
+	CreatedBefore  *time.Time
+	IncludeDeleted bool
+	Limit          uint64
+	Offset         uint64
+}
+
+// ListUsers returns users matching the filters, newest first.
+func (repo *UserRepository) ListUsers(ctx context.Context, p *ListUsersParams) ([]*models.User, error) {
+	query := sq.Select(userColumns...).
+		From("users").
+		OrderBy("created_at DESC", "id DESC").
+		PlaceholderFormat(sq.Dollar)
+	if p.EmailContains != nil {
+		query = query.Where(sq.ILike{"email": "%" + escapeLike(*p.EmailContains) + "%"})
+	}
+	if p.Locale != nil {
+		query = query.Where(sq.Eq{"locale": *p.Locale})
+	}
+	if p.CreatedAfter != nil {
+		query = query.Where(sq.GtOrEq{"created_at": *p.CreatedAfter})
+	}
+	if p.CreatedBefore != nil {
+		query = query.Where(sq.Lt{"created_at": *p.CreatedBefore})
+	}
+	if !p.IncludeDeleted {
+		query = query.Where(sq.Eq{"deleted_at": nil})
+	}
+	if p.Limit > 0 {
+		query = query.Limit(p.Limit)
+	}
+	if p.Offset > 0 {
+		query = query.Offset(p.Offset)
+	}
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build list users query: %w", err)
+	}
+
+	rows, err := conn(ctx, repo.db).Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+
+	dbUsers, err := pgx.CollectRows(rows, pgx.RowToStructByName[userDbModel])
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+
+	users := make([]*models.User, 0, len(dbUsers))
+	for _, u := range dbUsers {
+		users = append(users, u.toModel())
+	}
+
+	return users, nil
+}
+
+func (repo *UserRepository) queryOne(ctx context.Context, sql string, args ...any) (*models.User, error) {
+	rows, err := conn(ctx, repo.db).Query(ctx, sql, args...)
+	if err != nil {
+		return nil, mapUserError(err)
+	}
+
+	row, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[userDbModel])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, mapUserError(err)
+	}
+
+	return row.toModel(), nil
+}
+
+func mapUserError(err error) error {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return err
+	}
+
+	switch {
+	case pgErr.Code == pgUniqueViolationCode && pgErr.ConstraintName == usersEmailUniqueConstraint:
+		return ErrEmailTaken
+	case pgErr.Code == pgInvalidTextRepresentCode:
+		return ErrNotFound
+	}
+
+	return err
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
 }
 
 type userDbModel struct {
 	Id           string     `db:"id"`
-	Email        string     `db:"email"`
+	Email        *string    `db:"email"`
 	FirebaseUid  *string    `db:"firebase_uid"`
 	RevenueCatId *string    `db:"revenuecat_id"`
 	Timezone     *string    `db:"timezone"`
@@ -52,4 +542,20 @@ type userDbModel struct {
 	CreatedAt    *time.Time `db:"created_at"`
 	UpdatedAt    *time.Time `db:"updated_at"`
 	DeletedAt    *time.Time `db:"deleted_at"`
+	PurgeAfter   *time.Time `db:"purge_after"`
+}
+
+func (m *userDbModel) toModel() *models.User {
+	return &models.User{
+		ID:           m.Id,
+		Email:        m.Email,
+		FirebaseUID:  m.FirebaseUid,
+		RevenueCatID: m.RevenueCatId,
+		Timezone:     m.Timezone,
+		Locale:       m.Locale,
+		CreatedAt:    m.CreatedAt,
+		UpdatedAt:    m.UpdatedAt,
+		DeletedAt:    m.DeletedAt,
+		PurgeAfter:   m.PurgeAfter,
+	}
 }
@@ -0,0 +1,427 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"athylps/internal/models"
+)
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func idOf(u *models.User) string {
+	if u == nil {
+		return ""
+	}
+	return u.ID
+}
+
+func Test_UserRepository_CRUD(t *testing.T) {
+	ctx := context.Background()
+	repo := NewUserRepository(newTestPool(t))
+
+	created, err := repo.CreateUser(ctx, &CreateUserParams{
+		FirebaseUid:  "firebase-1",
+		Email:        ptr("user@example.com"),
+		RevenueCatId: ptr("rc-1"),
+		Locale:       ptr("ru-RU"),
+	})
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	if created.ID == "" || created.CreatedAt == nil || created.DeletedAt != nil {
+		t.Fatalf("unexpected created user: %+v", created)
+	}
+
+	for name, get := range map[string]func() (string, error){
+		"id":           func() (string, error) { u, err := repo.GetUserByID(ctx, created.ID); return idOf(u), err },
+		"firebase_uid": func() (string, error) { u, err := repo.GetUserByFirebaseUID(ctx, "firebase-1"); return idOf(u), err },
+		"email":        func() (string, error) { u, err := repo.GetUserByEmail(ctx, "user@example.com"); return idOf(u), err },
+		"revenuecat":   func() (string, error) { u, err := repo.GetUserByRevenueCatID(ctx, "rc-1"); return idOf(u), err },
+	} {
+		id, err := get()
+		if err != nil || id != created.ID {
+			t.Errorf("get by %s: got %q, %v", name, id, err)
+		}
+	}
+
+	if _, err := repo.GetUserByID(ctx, "not-a-uuid"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound for malformed id, got %v", err)
+	}
+
+	_, err = repo.CreateUser(ctx, &CreateUserParams{FirebaseUid: "firebase-2", Email: ptr("user@example.com")})
+	if !errors.Is(err, ErrEmailTaken) || !errors.Is(err, ErrAlreadyExists) {
+		t.Errorf("expected ErrEmailTaken, got %v", err)
+	}
+
+	updated, err := repo.UpdateUser(ctx, created.ID, &UpdateUserParams{Timezone: ptr("Europe/Moscow")})
+	if err != nil {
+		t.Fatalf("failed to update user: %v", err)
+	}
+	if *updated.Timezone != "Europe/Moscow" || *updated.Locale != "ru-RU" || *updated.Email != "user@example.com" {
+		t.Errorf("unexpected updated user: %+v", updated)
+	}
+	if !updated.UpdatedAt.After(*created.UpdatedAt) {
+		t.Errorf("expected updated_at to change")
+	}
+
+	if err := repo.DeleteUser(ctx, created.ID); err != nil {
+		t.Fatalf("failed to delete user: %v", err)
+	}
+	if err := repo.DeleteUser(ctx, created.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound on second delete, got %v", err)
+	}
+	if _, err := repo.GetUserByID(ctx, created.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected deleted user not to be found, got %v", err)
+	}
+	if _, err := repo.UpdateUser(ctx, created.ID, &UpdateUserParams{Locale: ptr("en")}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected deleted user not to be updated, got %v", err)
+	}
+}
+
+func Test_UserRepository_ListUsers(t *testing.T) {
+	ctx := context.Background()
+	repo := NewUserRepository(newTestPool(t))
+
+	for _, p := range []*CreateUserParams{
+		{FirebaseUid: "1", Email: ptr("anna@example.com"), Locale: ptr("ru-RU")},
+		{FirebaseUid: "2", Email: ptr("boris@example.com"), Locale: ptr("en-US")},
+		{FirebaseUid: "3", Email: ptr("anna_k@test.dev"), Locale: ptr("ru-RU")},
+	} {
+		if _, err := repo.CreateUser(ctx, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	deleted, err := repo.GetUserByEmail(ctx, "boris@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.DeleteUser(ctx, deleted.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		params *ListUsersParams
+		want   []string
+	}{
+		{name: "all active", params: &ListUsersParams{}, want: []string{"anna_k@test.dev", "anna@example.com"}},
+		{name: "with deleted", params: &ListUsersParams{IncludeDeleted: true}, want: []string{"anna_k@test.dev", "boris@example.com", "anna@example.com"}},
+		{name: "email", params: &ListUsersParams{EmailContains: ptr("ANNA")}, want: []string{"anna_k@test.dev", "anna@example.com"}},
+		{name: "email with wildcard", params: &ListUsersParams{EmailContains: ptr("a_k")}, want: []string{"anna_k@test.dev"}},
+		{name: "locale", params: &ListUsersParams{Locale: ptr("en-US"), IncludeDeleted: true}, want: []string{"boris@example.com"}},
+		{name: "paginated", params: &ListUsersParams{Limit: 1, Offset: 1}, want: []string{"anna@example.com"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users, err := repo.ListUsers(ctx, tt.params)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var emails []string
+			for _, u := range users {
+				emails = append(emails, *u.Email)
+			}
+			if len(emails) != len(tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, emails)
+			}
+			for i := range emails {
+				if emails[i] != tt.want[i] {
+					t.Fatalf("expected %v, got %v", tt.want, emails)
+				}
+			}
+		})
+	}
+}
+
+func Test_UserRepository_UpsertUserByFirebaseUID(t *testing.T) {
+	ctx := context.Background()
+	repo := NewUserRepository(newTestPool(t))
+
+	created, err := repo.UpsertUserByFirebaseUID(ctx, &UpsertUserParams{FirebaseUID: "firebase-1", Locale: ptr("ru-RU")})
+	if err != nil {
+		t.Fatalf("failed to provision user: %v", err)
+	}
+	if created.Email != nil || *created.Locale != "ru-RU" {
+		t.Fatalf("unexpected provisioned user: %+v", created)
+	}
+
+	same, err := repo.UpsertUserByFirebaseUID(ctx, &UpsertUserParams{FirebaseUID: "firebase-1", Locale: ptr("ru-RU")})
+	if err != nil {
+		t.Fatalf("failed to upsert user: %v", err)
+	}
+	if same.ID != created.ID || !same.UpdatedAt.Equal(*created.UpdatedAt) {
+		t.Errorf("expected unchanged user to keep updated_at, got %+v", same)
+	}
+
+	changed, err := repo.UpsertUserByFirebaseUID(ctx, &UpsertUserParams{FirebaseUID: "firebase-1", Email: ptr("user@example.com")})
+	if err != nil {
+		t.Fatalf("failed to upsert user: %v", err)
+	}
+	if changed.ID != created.ID || *changed.Email != "user@example.com" || *changed.Locale != "ru-RU" {
+		t.Errorf("unexpected changed user: %+v", changed)
+	}
+	if !changed.UpdatedAt.After(*created.UpdatedAt) {
+		t.Errorf("expected updated_at to change")
+	}
+
+	_, err = repo.UpsertUserByFirebaseUID(ctx, &UpsertUserParams{FirebaseUID: "firebase-2", Email: ptr("user@example.com")})
+	if !errors.Is(err, ErrEmailTaken) {
+		t.Errorf("expected ErrEmailTaken, got %v", err)
+	}
+
+	if err := repo.DeleteUser(ctx, created.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.UpsertUserByFirebaseUID(ctx, &UpsertUserParams{FirebaseUID: "firebase-1"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected deleted user not to be provisioned again, got %v", err)
+	}
+}
+
+func Test_UserRepository_FindAndRestore(t *testing.T) {
+	ctx := context.Background()
+	pool := newTestPool(t)
+	repo := NewUserRepository(pool)
+
+	user, err := repo.CreateUser(ctx, &CreateUserParams{FirebaseUid: "firebase-1", Email: ptr("User@Example.com"), RevenueCatId: ptr("rc-1")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = NewRevenueCatCustomerRepository(pool).Link(ctx, &LinkRevenueCatCustomerParams{UserID: user.ID, AppUserIDs: []string{"$RCAnonymousID:1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.DeleteUser(ctx, user.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, query := range []string{user.ID, "user@example.com", "firebase-1", "rc-1", "$RCAnonymousID:1"} {
+		found, err := repo.FindUsers(ctx, query)
+		if err != nil {
+			t.Fatalf("failed to find users by %q: %v", query, err)
+		}
+		if len(found) != 1 || found[0].ID != user.ID || found[0].DeletedAt == nil {
+			t.Errorf("expected deleted user to be found by %q, got %+v", query, found)
+		}
+	}
+	if found, err := repo.FindUsers(ctx, "unknown"); err != nil || len(found) != 0 {
+		t.Errorf("expected no users, got %+v, %v", found, err)
+	}
+	if deleted, err := repo.GetUserByIDWithDeleted(ctx, user.ID); err != nil || deleted.DeletedAt == nil {
+		t.Errorf("expected deleted user to be got by id, got %+v, %v", deleted, err)
+	}
+	if _, err := repo.GetUserByIDWithDeleted(ctx, "not-a-uuid"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound for a malformed id, got %v", err)
+	}
+
+	restored, err := repo.RestoreUser(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("failed to restore user: %v", err)
+	}
+	if restored.DeletedAt != nil {
+		t.Errorf("expected user not to be deleted, got %+v", restored)
+	}
+	if _, err := repo.GetUserByID(ctx, user.ID); err != nil {
+		t.Errorf("expected restored user to be found, got %v", err)
+	}
+	if _, err := repo.RestoreUser(ctx, user.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound restoring a not deleted user, got %v", err)
+	}
+}
+
+func Test_UserRepository_DeleteAccountAndPurge(t *testing.T) {
+	ctx := context.Background()
+	pool := newTestPool(t)
+	repo := NewUserRepository(pool)
+	customers := NewRevenueCatCustomerRepository(pool)
+	subscriptions := NewSubscriptionRepository(pool)
+	webhookEvents := NewWebhookEventRepository(pool)
+
+	user, err := repo.CreateUser(ctx, &CreateUserParams{FirebaseUid: "firebase-1", Email: ptr("user@example.com")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = customers.Link(ctx, &LinkRevenueCatCustomerParams{UserID: user.ID, AppUserIDs: []string{"user_42"}, RevenueCatID: ptr("user_42")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	kept, err := subscriptions.Upsert(ctx, &models.Subscription{
+		AppUserID:     "user_42",
+		UserID:        &user.ID,
+		ProductID:     "APP_STORE_premium",
+		Store:         "APP_STORE",
+		Status:        models.SubscriptionStatusActive,
+		LastEventType: "INITIAL_PURCHASE",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Not attributed to the user, but its app user id is the user's Firebase uid
+	byFirebaseUID, err := subscriptions.Upsert(ctx, &models.Subscription{
+		AppUserID:     "firebase-1",
+		ProductID:     "PLAY_STORE_premium",
+		Store:         "PLAY_STORE",
+		Status:        models.SubscriptionStatusActive,
+		LastEventType: "INITIAL_PURCHASE",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = subscriptions.Upsert(ctx, &models.Subscription{
+		AppUserID:     "user_42",
+		UserID:        &user.ID,
+		ProductID:     models.StoreManual + "_premium",
+		Store:         models.StoreManual,
+		Status:        models.SubscriptionStatusActive,
+		LastEventType: "INITIAL_PURCHASE",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	payloads := map[string]string{
+		models.ProviderRevenueCat: `{"event": {"app_user_id": "user_42", "original_app_user_id": "firebase-1", "aliases": ["user_42", "firebase-1"],
+			"transferred_from": ["firebase-1"], "transferred_to": ["user_42"], "subscriber_attributes": {"$email": {"value": "user@example.com"}}}}`,
+		models.ProviderRuStore: fmt.Sprintf(`{"notificationId": "n-1", "developerPayload": %q}`, user.ID),
+	}
+	var events []*models.WebhookEvent
+	for provider, payload := range payloads {
+		event, err := webhookEvents.Create(ctx, &CreateWebhookEventParams{
+			Provider:  provider,
+			EventID:   "event-1",
+			EventType: "INITIAL_PURCHASE",
+			Payload:   []byte(payload),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := webhookEvents.MarkProcessed(ctx, event.ID, &user.ID); err != nil {
+			t.Fatal(err)
+		}
+		events = append(events, event)
+	}
+	notifications := NewNotificationOutboxRepository(pool)
+	err = notifications.Enqueue(ctx, &EnqueueNotificationParams{
+		Target: models.NotificationTarget{Channel: models.NotificationChannelTelegram},
+		Text:   fmt.Sprintf("💵 New purchase\nUser: <code>%s</code>", user.ID),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	purgeAfter := time.Now().Add(time.Hour)
+	deleted, err := repo.DeleteAccount(ctx, user.ID, purgeAfter)
+	if err != nil {
+		t.Fatalf("failed to delete account: %v", err)
+	}
+	if deleted.DeletedAt == nil || deleted.PurgeAfter == nil || deleted.RevenueCatID != nil {
+		t.Errorf("expected deleted user with purge scheduled and revenuecat id detached, got %+v", deleted)
+	}
+	if _, err := repo.DeleteAccount(ctx, user.ID, purgeAfter); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound deleting the account again, got %v", err)
+	}
+	if err := customers.UnlinkUser(ctx, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	if linked, err := customers.ListByUserID(ctx, user.ID); err != nil || len(linked) != 0 {
+		t.Errorf("expected customers to be unlinked, got %+v, %v", linked, err)
+	}
+
+	if due, err := repo.ListPurgeDue(ctx, time.Now(), 10); err != nil || len(due) != 0 {
+		t.Errorf("expected no users due before the retention ends, got %+v, %v", due, err)
+	}
+	if err := repo.PurgeUser(ctx, user.ID, time.Now()); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected the purge not to be due yet, got %v", err)
+	}
+
+	due, err := repo.ListPurgeDue(ctx, purgeAfter, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(due) != 1 || due[0].ID != user.ID {
+		t.Fatalf("expected the user to be due, got %+v", due)
+	}
+	if err := repo.PurgeUser(ctx, user.ID, purgeAfter); err != nil {
+		t.Fatalf("failed to purge user: %v", err)
+	}
+	if err := repo.PurgeUser(ctx, user.ID, purgeAfter); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound purging the user again, got %v", err)
+	}
+
+	if found, err := repo.FindUsers(ctx, user.ID); err != nil || len(found) != 0 {
+		t.Errorf("expected the user to be gone, got %+v, %v", found, err)
+	}
+	if _, err := subscriptions.Get(ctx, "user_42", models.StoreManual+"_premium"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected the manual grant to be removed, got %v", err)
+	}
+	if _, err := subscriptions.Get(ctx, "user_42", "APP_STORE_premium"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected the app user id of the store subscription to be replaced, got %v", err)
+	}
+	kept, err = subscriptions.Get(ctx, "deleted:"+kept.ID, "APP_STORE_premium")
+	if err != nil {
+		t.Fatalf("expected the store subscription to be kept: %v", err)
+	}
+	if kept.UserID != nil {
+		t.Errorf("expected the store subscription to be detached, got user %v", *kept.UserID)
+	}
+	if _, err := subscriptions.Get(ctx, "deleted:"+byFirebaseUID.ID, "PLAY_STORE_premium"); err != nil {
+		t.Errorf("expected the app user id equal to the Firebase uid to be replaced: %v", err)
+	}
+
+	identifiers := []string{user.ID, "firebase-1", "user_42", "user@example.com"}
+	for _, event := range events {
+		scrubbed, err := webhookEvents.GetByID(ctx, event.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if scrubbed.UserID != nil {
+			t.Errorf("expected the %s event to be detached", scrubbed.Provider)
+		}
+		for _, identifier := range identifiers {
+			if strings.Contains(string(scrubbed.Payload), identifier) {
+				t.Errorf("expected %s to be removed from the %s event, got %s", identifier, scrubbed.Provider, scrubbed.Payload)
+			}
+		}
+		if !strings.Contains(string(scrubbed.Payload), "deleted:") {
+			t.Errorf("expected the ids in the %s event to be replaced with a pseudonym, got %s", scrubbed.Provider, scrubbed.Payload)
+		}
+	}
+
+	sent, err := notifications.List(ctx, &ListNotificationsParams{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sent) != 1 || strings.Contains(sent[0].Text, user.ID) || !strings.Contains(sent[0].Text, "User: <code>deleted:") {
+		t.Errorf("expected the user id in the notification to be replaced, got %+v", sent)
+	}
+}
+
+func Test_UserRepository_RestoreCancelsPurge(t *testing.T) {
+	ctx := context.Background()
+	repo := NewUserRepository(newTestPool(t))
+
+	user, err := repo.CreateUser(ctx, &CreateUserParams{FirebaseUid: "firebase-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	purgeAfter := time.Now()
+	if _, err := repo.DeleteAccount(ctx, user.ID, purgeAfter); err != nil {
+		t.Fatal(err)
+	}
+	restored, err := repo.RestoreUser(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.PurgeAfter != nil {
+		t.Errorf("expected the purge to be cancelled, got %v", restored.PurgeAfter)
+	}
+	if err := repo.PurgeUser(ctx, user.ID, purgeAfter.Add(time.Hour)); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected a restored user not to be purged, got %v", err)
+	}
+}
@@ -0,0 +1,247 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"athylps/internal/models"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var webhookEventColumns = []string{
+	"id",
+	"provider",
+	"event_id",
+	"event_type",
+	"environment",
+	"payload",
+	"user_id",
+	"received_at",
+	"processed_at",
+}
+
+type WebhookEventRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewWebhookEventRepository(db *pgxpool.Pool) *WebhookEventRepository {
+	return &WebhookEventRepository{
+		db: db,
+	}
+}
+
+type CreateWebhookEventParams struct {
+	Provider    string
+	EventID     string
+	EventType   string
+	Environment *string
+	Payload     []byte
+}
+
+// Create stores a newly received webhook event.
+// Returns ErrAlreadyExists if the provider has already delivered an event with the same id.
+func (repo *WebhookEventRepository) Create(ctx context.Context, p *CreateWebhookEventParams) (*models.WebhookEvent, error) {
+	sql, args, err := sq.Insert("webhook_events").
+		Columns("provider", "event_id", "event_type", "environment", "payload").
+		Values(p.Provider, p.EventID, p.EventType, p.Environment, p.Payload).
+		Suffix("ON CONFLICT (provider, event_id) DO NOTHING RETURNING " + strings.Join(webhookEventColumns, ", ")).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build insert webhook event query: %w", err)
+	}
+
+	event, err := repo.queryOne(ctx, sql, args...)
+	if errors.Is(err, ErrNotFound) {
+		return nil, ErrAlreadyExists
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert webhook event: %w", err)
+	}
+
+	return event, nil
+}
+
+// GetByID returns the event by its internal id or ErrNotFound, also if the id is not a valid uuid.
+func (repo *WebhookEventRepository) GetByID(ctx context.Context, id string) (*models.WebhookEvent, error) {
+	sql, args, err := sq.Select(webhookEventColumns...).
+		From("webhook_events").
+		Where(sq.Eq{"id": id}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build select webhook event query: %w", err)
+	}
+
+	event, err := repo.queryOne(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook event %s: %w", id, err)
+	}
+
+	return event, nil
+}
+
+func (repo *WebhookEventRepository) GetByEventID(ctx context.Context, provider string, eventID string) (*models.WebhookEvent, error) {
+	sql, args, err := sq.Select(webhookEventColumns...).
+		From("webhook_events").
+		Where(sq.Eq{"provider": provider, "event_id": eventID}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build select webhook event query: %w", err)
+	}
+
+	event, err := repo.queryOne(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook event %s/%s: %w", provider, eventID, err)
+	}
+
+	return event, nil
+}
+
+// MarkProcessed marks the event processed and attributes it to the user, if known.
+// Returns ErrNotFound if the event doesn't exist or has already been processed.
+// Within a transaction it locks the event, so concurrent deliveries of the same event are processed once.
+func (repo *WebhookEventRepository) MarkProcessed(ctx context.Context, id string, userID *string) error {
+	sql, args, err := sq.Update("webhook_events").
+		Set("processed_at", sq.Expr("now()")).
+		Set("user_id", userID).
+		Where(sq.Eq{"id": id, "processed_at": nil}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build update webhook event query: %w", err)
+	}
+
+	tag, err := conn(ctx, repo.db).Exec(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("failed to mark webhook event %s processed: %w", id, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("failed to mark webhook event %s processed: %w", id, ErrNotFound)
+	}
+
+	return nil
+}
+
+type ListWebhookEventsParams struct {
+	IDs        []string
+	Provider   *string
+	EventTypes []string
+	UserID     *string
+	// Received at, From is inclusive and To is exclusive
+	From   *time.Time
+	To     *time.Time
+	Limit  uint64
+	Offset uint64
+	// Return the most recently received events first
+	NewestFirst bool
+}
+
+// List returns webhook events matching the filters in the order they were received, or the other way round.
+func (repo *WebhookEventRepository) List(ctx context.Context, p *ListWebhookEventsParams) ([]*models.WebhookEvent, error) {
+	query := sq.Select(webhookEventColumns...).
+		From("webhook_events").
+		PlaceholderFormat(sq.Dollar)
+	if p.NewestFirst {
+		query = query.OrderBy("received_at DESC", "id DESC")
+	} else {
+		query = query.OrderBy("received_at", "id")
+	}
+	if len(p.IDs) > 0 {
+		query = query.Where(sq.Eq{"id": p.IDs})
+	}
+	if p.Provider != nil {
+		query = query.Where(sq.Eq{"provider": *p.Provider})
+	}
+	if len(p.EventTypes) > 0 {
+		query = query.Where(sq.Eq{"event_type": p.EventTypes})
+	}
+	if p.UserID != nil {
+		// Compared as text, so a malformed id from a request matches nothing instead of failing
+		query = query.Where(sq.Eq{"user_id::text": *p.UserID})
+	}
+	if p.From != nil {
+		query = query.Where(sq.GtOrEq{"received_at": *p.From})
+	}
+	if p.To != nil {
+		query = query.Where(sq.Lt{"received_at": *p.To})
+	}
+	if p.Limit > 0 {
+		query = query.Limit(p.Limit)
+	}
+	if p.Offset > 0 {
+		query = query.Offset(p.Offset)
+	}
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build list webhook events query: %w", err)
+	}
+
+	rows, err := conn(ctx, repo.db).Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook events: %w", err)
+	}
+
+	dbEvents, err := pgx.CollectRows(rows, pgx.RowToStructByName[webhookEventDbModel])
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook events: %w", err)
+	}
+
+	events := make([]*models.WebhookEvent, 0, len(dbEvents))
+	for _, e := range dbEvents {
+		events = append(events, e.toModel())
+	}
+
+	return events, nil
+}
+
+func (repo *WebhookEventRepository) queryOne(ctx context.Context, sql string, args ...any) (*models.WebhookEvent, error) {
+	rows, err := conn(ctx, repo.db).Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	row, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[webhookEventDbModel])
+	if errors.Is(err, pgx.ErrNoRows) || isInvalidTextRepresentation(err) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return row.toModel(), nil
+}
+
+type webhookEventDbModel struct {
+	Id          string     `db:"id"`
+	Provider    string     `db:"provider"`
+	EventId     string     `db:"event_id"`
+	EventType   string     `db:"event_type"`
+	Environment *string    `db:"environment"`
+	Payload     []byte     `db:"payload"`
+	UserId      *string    `db:"user_id"`
+	ReceivedAt  time.Time  `db:"received_at"`
+	ProcessedAt *time.Time `db:"processed_at"`
+}
+
+func (m *webhookEventDbModel) toModel() *models.WebhookEvent {
+	return &models.WebhookEvent{
+		ID:          m.Id,
+		Provider:    m.Provider,
+		EventID:     m.EventId,
+		EventType:   m.EventType,
+		Environment: m.Environment,
+		Payload:     m.Payload,
+		UserID:      m.UserId,
+		ReceivedAt:  m.ReceivedAt,
+		ProcessedAt: m.ProcessedAt,
+	}
+}
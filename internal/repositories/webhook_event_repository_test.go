@@ -0,0 +1,122 @@
+package repositories
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"athylps/internal/models"
+)
+
+func Test_WebhookEventRepository_List(t *testing.T) {
+	ctx := context.Background()
+	pool := newTestPool(t)
+	repo := NewWebhookEventRepository(pool)
+
+	created := make([]*models.WebhookEvent, 0, 3)
+	for i, p := range []*CreateWebhookEventParams{
+		{Provider: models.ProviderRevenueCat, EventType: "INITIAL_PURCHASE"},
+		{Provider: models.ProviderRuStore, EventType: "RENEWAL"},
+		{Provider: models.ProviderRevenueCat, EventType: "CANCELLATION"},
+	} {
+		p.EventID = fmt.Sprintf("event-%d", i)
+		p.Payload = []byte(`{}`)
+		event, err := repo.Create(ctx, p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		created = append(created, event)
+	}
+
+	all, err := repo.List(ctx, &ListWebhookEventsParams{})
+	if err != nil {
+		t.Fatalf("failed to list webhook events: %v", err)
+	}
+	if len(all) != 3 || all[0].ID != created[0].ID || all[2].ID != created[2].ID {
+		t.Errorf("expected all events in the order they were received, got %+v", all)
+	}
+
+	revenueCat, err := repo.List(ctx, &ListWebhookEventsParams{Provider: ptr(models.ProviderRevenueCat), EventTypes: []string{"CANCELLATION"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revenueCat) != 1 || revenueCat[0].ID != created[2].ID {
+		t.Errorf("expected events filtered by provider and type, got %+v", revenueCat)
+	}
+
+	byID, err := repo.List(ctx, &ListWebhookEventsParams{IDs: []string{created[1].ID}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(byID) != 1 || byID[0].Provider != models.ProviderRuStore {
+		t.Errorf("expected the event by id, got %+v", byID)
+	}
+
+	future := time.Now().Add(time.Hour)
+	none, err := repo.List(ctx, &ListWebhookEventsParams{From: &future})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(none) != 0 {
+		t.Errorf("expected no events received after %v, got %d", future, len(none))
+	}
+
+	limited, err := repo.List(ctx, &ListWebhookEventsParams{Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(limited) != 2 {
+		t.Errorf("expected 2 events, got %d", len(limited))
+	}
+
+	newest, err := repo.List(ctx, &ListWebhookEventsParams{NewestFirst: true, Limit: 1, Offset: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(newest) != 1 || newest[0].ID != created[1].ID {
+		t.Errorf("expected the second newest event, got %+v", newest)
+	}
+
+	if err := repo.MarkProcessed(ctx, created[0].ID, nil); err != nil {
+		t.Fatal(err)
+	}
+	got, err := repo.GetByID(ctx, created[0].ID)
+	if err != nil {
+		t.Fatalf("failed to get webhook event: %v", err)
+	}
+	if got.EventID != "event-0" || got.ProcessedAt == nil {
+		t.Errorf("expected the processed event, got %+v", got)
+	}
+	if _, err := repo.GetByID(ctx, "not-a-uuid"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound for a malformed id, got %v", err)
+	}
+}
+
+func Test_WebhookEventRepository_KeepsRawPayload(t *testing.T) {
+	ctx := context.Background()
+	pool := newTestPool(t)
+	repo := NewWebhookEventRepository(pool)
+
+	// Key order, whitespace and duplicate keys would be lost in jsonb
+	payload := []byte("{\"event\": {\"type\": \"RENEWAL\",\n  \"id\": \"event-1\", \"id\": \"event-1\"},\"api_version\":\"1.0\"}")
+	created, err := repo.Create(ctx, &CreateWebhookEventParams{
+		Provider:  models.ProviderRevenueCat,
+		EventID:   "event-1",
+		EventType: "RENEWAL",
+		Payload:   payload,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	event, err := repo.GetByID(ctx, created.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(event.Payload, payload) {
+		t.Errorf("expected the payload as received, got %s", event.Payload)
+	}
+}
@@ -0,0 +1,90 @@
+package services
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Money is an amount in an ISO 4217 currency.
+type Money struct {
+	Amount   float64
+	Currency string
+}
+
+type currencyFormat struct {
+	symbol      string
+	symbolAfter bool // The symbol follows the amount, e.g. 499 ₽
+	digits      int  // ISO 4217 minor unit digits
+}
+
+// currencyFormats of currencies payments come in, others are written as 4.99 XYZ with 2 digits.
+var currencyFormats = map[string]currencyFormat{
+	"USD": {symbol: "$", digits: 2},
+	"EUR": {symbol: "€", digits: 2},
+	"GBP": {symbol: "£", digits: 2},
+	"CAD": {symbol: "CA$", digits: 2},
+	"AUD": {symbol: "A$", digits: 2},
+	"BRL": {symbol: "R$", digits: 2},
+	"MXN": {symbol: "MX$", digits: 2},
+	"CNY": {symbol: "CN¥", digits: 2},
+	"INR": {symbol: "₹", digits: 2},
+	"ILS": {symbol: "₪", digits: 2},
+	"PHP": {symbol: "₱", digits: 2},
+	"NGN": {symbol: "₦", digits: 2},
+	"THB": {symbol: "฿", digits: 2},
+	"JPY": {symbol: "¥", digits: 0},
+	"KRW": {symbol: "₩", digits: 0},
+	"RUB": {symbol: "₽", symbolAfter: true, digits: 2},
+	"UAH": {symbol: "₴", symbolAfter: true, digits: 2},
+	"KZT": {symbol: "₸", symbolAfter: true, digits: 2},
+	"BYN": {symbol: "Br", symbolAfter: true, digits: 2},
+	"GEL": {symbol: "₾", symbolAfter: true, digits: 2},
+	"AMD": {symbol: "֏", symbolAfter: true, digits: 2},
+	"AZN": {symbol: "₼", symbolAfter: true, digits: 2},
+	"TRY": {symbol: "₺", symbolAfter: true, digits: 2},
+	"PLN": {symbol: "zł", symbolAfter: true, digits: 2},
+	"VND": {symbol: "₫", symbolAfter: true, digits: 0},
+	"CLP": {symbol: "CLP", symbolAfter: true, digits: 0},
+	"ISK": {symbol: "kr", symbolAfter: true, digits: 0},
+	"HUF": {symbol: "Ft", symbolAfter: true, digits: 2},
+	"IDR": {symbol: "Rp", digits: 2},
+	"KWD": {symbol: "KWD", symbolAfter: true, digits: 3},
+	"BHD": {symbol: "BHD", symbolAfter: true, digits: 3},
+	"OMR": {symbol: "OMR", symbolAfter: true, digits: 3},
+	"JOD": {symbol: "JOD", symbolAfter: true, digits: 3},
+	"TND": {symbol: "TND", symbolAfter: true, digits: 3},
+}
+
+func currencyFormatOf(code string) currencyFormat {
+	code = strings.ToUpper(code)
+	if f, ok := currencyFormats[code]; ok {
+		return f
+	}
+	return currencyFormat{symbol: code, symbolAfter: true, digits: 2}
+}
+
+// MinorUnitsToAmount converts an amount in minor units of the currency, e.g. kopecks, to major units.
+func MinorUnitsToAmount(minorUnits int64, currency string) float64 {
+	return float64(minorUnits) / math.Pow10(currencyFormatOf(currency).digits)
+}
+
+// formatMoney writes the amount with the currency symbol, rounded to the currency minor units,
+// e.g. 499 ₽, €4.99 or ¥500. Whole amounts are written without the fraction.
+func formatMoney(m Money) string {
+	f := currencyFormatOf(m.Currency)
+
+	amount := strconv.FormatFloat(math.Abs(m.Amount), 'f', f.digits, 64)
+	if whole, fraction, ok := strings.Cut(amount, "."); ok && strings.Trim(fraction, "0") == "" {
+		amount = whole
+	}
+
+	sign := ""
+	if m.Amount < 0 && strings.Trim(amount, "0.") != "" {
+		sign = "-"
+	}
+	if f.symbolAfter {
+		return sign + amount + " " + f.symbol
+	}
+	return sign + f.symbol + amount
+}
@@ -0,0 +1,33 @@
+package services
+
+import "testing"
+
+func Test_FormatMoney(t *testing.T) {
+	tests := []struct {
+		money Money
+		want  string
+	}{
+		{Money{Amount: 499, Currency: "RUB"}, "499 ₽"},
+		{Money{Amount: 499.5, Currency: "RUB"}, "499.50 ₽"},
+		{Money{Amount: 4.99, Currency: "EUR"}, "€4.99"},
+		{Money{Amount: 500.4, Currency: "JPY"}, "¥500"},
+		{Money{Amount: 1.2346, Currency: "KWD"}, "1.235 KWD"},
+		{Money{Amount: -5.4, Currency: "USD"}, "-$5.40"},
+		{Money{Amount: 12.3, Currency: "xyz"}, "12.30 XYZ"},
+	}
+
+	for _, tt := range tests {
+		if got := formatMoney(tt.money); got != tt.want {
+			t.Errorf("expected %+v to be formatted as %q, got %q", tt.money, tt.want, got)
+		}
+	}
+}
+
+func Test_MinorUnitsToAmount(t *testing.T) {
+	if got := MinorUnitsToAmount(49900, "RUB"); got != 499 {
+		t.Errorf("expected 49900 kopecks to be 499 rubles, got %v", got)
+	}
+	if got := MinorUnitsToAmount(500, "JPY"); got != 500 {
+		t.Errorf("expected yen to have no minor units, got %v", got)
+	}
+}
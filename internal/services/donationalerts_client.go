@@ -0,0 +1,169 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"athylps/internal/config"
+	"athylps/internal/models"
+)
+
+const donationAlertsTimeLayout = "2006-01-02 15:04:05"
+
+// DonationAlertsDonation is a donation as returned by the DonationAlerts API.
+type DonationAlertsDonation struct {
+	ID        int64
+	Username  *string
+	Message   *string
+	Amount    float64
+	Currency  string
+	CreatedAt time.Time
+}
+
+// DonationAlertsClient is a client for the DonationAlerts OAuth and REST API.
+type DonationAlertsClient struct {
+	httpClient   *http.Client
+	baseURL      string
+	clientID     string
+	clientSecret string
+	redirectURL  string
+}
+
+func NewDonationAlertsClient(cfg *config.DonationAlertsConfig, httpClient *http.Client) *DonationAlertsClient {
+	return &DonationAlertsClient{
+		httpClient:   httpClient,
+		baseURL:      strings.TrimSuffix(cfg.BaseURL, "/"),
+		clientID:     cfg.ClientID,
+		clientSecret: cfg.ClientSecret,
+		redirectURL:  cfg.RedirectURL,
+	}
+}
+
+// AuthorizeURL returns the page the account owner authorizes the app on, DonationAlerts redirects back to the
+// redirect url with the code and the state.
+func (c *DonationAlertsClient) AuthorizeURL(state string) string {
+	return c.baseURL + "/oauth/authorize?" + url.Values{
+		"client_id":     {c.clientID},
+		"redirect_uri":  {c.redirectURL},
+		"response_type": {"code"},
+		"scope":         {"oauth-donation-index"},
+		"state":         {state},
+	}.Encode()
+}
+
+// ExchangeCode exchanges an authorization code received by the OAuth callback for a token pair.
+func (c *DonationAlertsClient) ExchangeCode(ctx context.Context, code string) (*models.OAuthToken, error) {
+	return c.requestToken(ctx, url.Values{
+		"grant_type":    {"authorization_code"},
+		"client_id":     {c.clientID},
+		"client_secret": {c.clientSecret},
+		"redirect_uri":  {c.redirectURL},
+		"code":          {code},
+	})
+}
+
+func (c *DonationAlertsClient) RefreshToken(ctx context.Context, refreshToken string) (*models.OAuthToken, error) {
+	return c.requestToken(ctx, url.Values{
+		"grant_type":    {"refresh_token"},
+		"client_id":     {c.clientID},
+		"client_secret": {c.clientSecret},
+		"refresh_token": {refreshToken},
+	})
+}
+
+type donationAlertsTokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+func (c *DonationAlertsClient) requestToken(ctx context.Context, form url.Values) (*models.OAuthToken, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/oauth/token", strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create donationalerts token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	var resp donationAlertsTokenResponse
+	if err := c.do(req, &resp); err != nil {
+		return nil, fmt.Errorf("failed to request donationalerts token: %w", err)
+	}
+
+	return &models.OAuthToken{
+		AccessToken:  resp.AccessToken,
+		RefreshToken: resp.RefreshToken,
+		ExpiresAt:    time.Now().Add(time.Duration(resp.ExpiresIn) * time.Second),
+	}, nil
+}
+
+type donationAlertsDonationsResponse struct {
+	Data []struct {
+		ID        int64   `json:"id"`
+		Username  *string `json:"username"`
+		Message   *string `json:"message"`
+		Amount    float64 `json:"amount"`
+		Currency  string  `json:"currency"`
+		CreatedAt string  `json:"created_at"`
+	} `json:"data"`
+}
+
+// GetDonations returns a page of donations of the authorized user, newest first. Pages start at 1.
+func (c *DonationAlertsClient) GetDonations(ctx context.Context, accessToken string, page int) ([]DonationAlertsDonation, error) {
+	endpoint := c.baseURL + "/api/v1/alerts/donations?page=" + strconv.Itoa(page)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create donationalerts donations request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+
+	var resp donationAlertsDonationsResponse
+	if err := c.do(req, &resp); err != nil {
+		return nil, fmt.Errorf("failed to get donationalerts donations: %w", err)
+	}
+
+	donations := make([]DonationAlertsDonation, 0, len(resp.Data))
+	for _, d := range resp.Data {
+		createdAt, err := time.Parse(donationAlertsTimeLayout, d.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse donation %d created_at: %w", d.ID, err)
+		}
+		donations = append(donations, DonationAlertsDonation{
+			ID:        d.ID,
+			Username:  d.Username,
+			Message:   d.Message,
+			Amount:    d.Amount,
+			Currency:  d.Currency,
+			CreatedAt: createdAt,
+		})
+	}
+
+	return donations, nil
+}
+
+func (c *DonationAlertsClient) do(req *http.Request, v any) error {
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, body)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,87 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"athylps/internal/config"
+)
+
+func Test_DonationAlertsClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/oauth/token":
+			if err := r.ParseForm(); err != nil {
+				t.Fatal(err)
+			}
+			if r.PostForm.Get("grant_type") != "authorization_code" || r.PostForm.Get("code") != "code-1" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			if r.PostForm.Get("client_id") != "client" || r.PostForm.Get("redirect_uri") != "http://localhost/callback" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte(`{"token_type":"Bearer","access_token":"access","refresh_token":"refresh","expires_in":3600}`))
+		case "/api/v1/alerts/donations":
+			if r.Header.Get("Authorization") != "Bearer access" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte(`{"data":[{"id":30,"name":"donation","username":"Ivan","message_type":"text","message":"Hi!","amount":150.5,"currency":"RUB","is_shown":1,"created_at":"2025-11-20 09:00:00","shown_at":null}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := NewDonationAlertsClient(&config.DonationAlertsConfig{
+		BaseURL:      server.URL,
+		ClientID:     "client",
+		ClientSecret: "secret",
+		RedirectURL:  "http://localhost/callback",
+	}, server.Client())
+
+	token, err := client.ExchangeCode(context.Background(), "code-1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if token.AccessToken != "access" || token.RefreshToken != "refresh" {
+		t.Errorf("unexpected token: %+v", token)
+	}
+	if time.Until(token.ExpiresAt) < 59*time.Minute {
+		t.Errorf("unexpected token expiration: %s", token.ExpiresAt)
+	}
+
+	donations, err := client.GetDonations(context.Background(), token.AccessToken, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(donations) != 1 {
+		t.Fatalf("expected 1 donation, got %d", len(donations))
+	}
+	d := donations[0]
+	if d.ID != 30 || d.Amount != 150.5 || d.Currency != "RUB" || *d.Username != "Ivan" || *d.Message != "Hi!" {
+		t.Errorf("unexpected donation: %+v", d)
+	}
+	if !d.CreatedAt.Equal(time.Date(2025, 11, 20, 9, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected donation time: %s", d.CreatedAt)
+	}
+
+	if _, err := client.GetDonations(context.Background(), "expired", 1); err == nil {
+		t.Errorf("expected error for unauthorized request")
+	}
+
+	authorizeURL, err := url.Parse(client.AuthorizeURL("state-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := authorizeURL.Query()
+	if authorizeURL.Path != "/oauth/authorize" || query.Get("state") != "state-1" || query.Get("redirect_uri") != "http://localhost/callback" || query.Get("response_type") != "code" {
+		t.Errorf("unexpected authorize url: %s", authorizeURL)
+	}
+}
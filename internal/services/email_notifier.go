@@ -0,0 +1,125 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"athylps/internal/config"
+)
+
+// EmailNotifierService sends notifications as plain text emails over SMTP, using STARTTLS when the server offers it.
+type EmailNotifierService struct {
+	cfg *config.SMTPConfig
+	now func() time.Time
+}
+
+func NewEmailNotifierService(cfg *config.SMTPConfig) *EmailNotifierService {
+	return &EmailNotifierService{
+		cfg: cfg,
+		now: time.Now,
+	}
+}
+
+// Send emails the message to the address, its first line becomes the subject.
+func (s *EmailNotifierService) Send(ctx context.Context, to string, message string) error {
+	if _, err := mail.ParseAddress(to); err != nil {
+		return fmt.Errorf("%w: invalid email address %q: %w", ErrNotificationRejected, to, err)
+	}
+
+	if err := s.send(ctx, to, buildEmail(s.cfg.From, to, message, s.now())); err != nil {
+		return fmt.Errorf("failed to send email: %w", smtpError(err))
+	}
+
+	return nil
+}
+
+func (s *EmailNotifierService) send(ctx context.Context, to string, email []byte) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(s.cfg.Host, s.cfg.Port))
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, s.cfg.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: s.cfg.Host}); err != nil {
+			return err
+		}
+	}
+	if s.cfg.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", s.cfg.Username, s.cfg.Password, s.cfg.Host)); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(s.cfg.From); err != nil {
+		return err
+	}
+	if err := client.Rcpt(to); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(email); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
+
+// smtpError marks permanent SMTP failures (5xx replies) as rejected.
+func smtpError(err error) error {
+	var protoErr *textproto.Error
+	if errors.As(err, &protoErr) && protoErr.Code >= 500 {
+		return fmt.Errorf("%w: %w", ErrNotificationRejected, err)
+	}
+	return err
+}
+
+func buildEmail(from string, to string, message string, date time.Time) []byte {
+	text := plainText(message)
+	subject, _, _ := strings.Cut(text, "\n")
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", to)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.BEncoding.Encode("UTF-8", strings.TrimSpace(subject)))
+	fmt.Fprintf(&b, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: base64\r\n\r\n")
+
+	encoded := base64.StdEncoding.EncodeToString([]byte(strings.ReplaceAll(text, "\n", "\r\n")))
+	for len(encoded) > 76 {
+		b.WriteString(encoded[:76] + "\r\n")
+		encoded = encoded[76:]
+	}
+	b.WriteString(encoded + "\r\n")
+
+	return b.Bytes()
+}
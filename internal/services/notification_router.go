@@ -0,0 +1,150 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"athylps/internal/config"
+	"athylps/internal/models"
+)
+
+// testEventType is the type of events RevenueCat sends from the dashboard to check the integration.
+const testEventType = "TEST"
+
+type RouteNotificationParams struct {
+	EventType   string
+	Store       string
+	Environment *string
+	Price       *float32
+}
+
+// RoutedTarget is a target a notification is routed to and the locale to render it in, the default one if empty.
+type RoutedTarget struct {
+	Target models.NotificationTarget
+	Locale string
+}
+
+// NotificationRouter decides where notifications about an event go according to the configured routes.
+// Sandbox and TEST events only match routes that list their environment and go to the sandbox chat otherwise.
+type NotificationRouter struct {
+	routes        []config.NotificationRoute
+	defaultTarget RoutedTarget
+	sandboxTarget *RoutedTarget
+}
+
+// NewNotificationRouter validates the routes against the configured channels.
+func NewNotificationRouter(cfg *config.Config) (*NotificationRouter, error) {
+	for i, route := range cfg.Notify.Routes {
+		for _, target := range route.Targets {
+			if err := validateNotificationTarget(cfg, &target); err != nil {
+				return nil, fmt.Errorf("invalid notification route %d: %w", i, err)
+			}
+		}
+	}
+
+	router := &NotificationRouter{
+		routes: cfg.Notify.Routes,
+		defaultTarget: RoutedTarget{
+			Target: models.NotificationTarget{Channel: models.NotificationChannelTelegram, Recipient: cfg.Telegram.NotifyChatID},
+		},
+	}
+	if cfg.Notify.SandboxChatID != "" {
+		router.sandboxTarget = &RoutedTarget{
+			Target: models.NotificationTarget{Channel: models.NotificationChannelTelegram, Recipient: cfg.Notify.SandboxChatID},
+		}
+	}
+
+	return router, nil
+}
+
+func validateNotificationTarget(cfg *config.Config, target *config.NotificationTarget) error {
+	switch target.Channel {
+	case models.NotificationChannelTelegram:
+		return nil // Empty chat id is the default chat
+	case models.NotificationChannelWebhook:
+		if _, ok := cfg.Notify.Webhooks[target.To]; !ok {
+			return fmt.Errorf("webhook %q is not configured", target.To)
+		}
+		return nil
+	case models.NotificationChannelEmail:
+		if !cfg.Notify.SMTP.Enabled() {
+			return errors.New("email channel requires SMTP to be configured")
+		}
+		if target.To == "" {
+			return errors.New("email target requires an address")
+		}
+		return nil
+	}
+
+	return fmt.Errorf("unknown channel %q", target.Channel)
+}
+
+// Route returns the targets of all routes matching the event, the default chat if none matches.
+// Unmatched sandbox events go to the sandbox chat or are discarded if it isn't configured,
+// except TEST events, which always produce a confirmation.
+func (r *NotificationRouter) Route(p *RouteNotificationParams) []RoutedTarget {
+	targets := []RoutedTarget{}
+	matched := false
+	for i := range r.routes {
+		route := &r.routes[i]
+		if !routeMatches(route, p) {
+			continue
+		}
+
+		matched = true
+		for _, t := range route.Targets {
+			target := RoutedTarget{
+				Target: models.NotificationTarget{Channel: t.Channel, Recipient: t.To},
+				Locale: t.Locale,
+			}
+			if !slices.Contains(targets, target) {
+				targets = append(targets, target)
+			}
+		}
+	}
+
+	if matched {
+		return targets
+	}
+
+	switch {
+	case !p.IsSandbox():
+		return []RoutedTarget{r.defaultTarget}
+	case r.sandboxTarget != nil:
+		return []RoutedTarget{*r.sandboxTarget}
+	case p.EventType == testEventType:
+		return []RoutedTarget{r.defaultTarget}
+	}
+
+	return targets
+}
+
+// IsSandbox reports whether the event isn't about real money: it's a TEST event or comes from a non-production environment.
+func (p *RouteNotificationParams) IsSandbox() bool {
+	return p.EventType == testEventType || (p.Environment != nil && !strings.EqualFold(*p.Environment, "PRODUCTION"))
+}
+
+func routeMatches(route *config.NotificationRoute, p *RouteNotificationParams) bool {
+	if len(route.EventTypes) > 0 && !slices.Contains(route.EventTypes, p.EventType) {
+		return false
+	}
+	if len(route.Stores) > 0 && !slices.Contains(route.Stores, p.Store) {
+		return false
+	}
+	if len(route.Environments) > 0 && (p.Environment == nil || !slices.Contains(route.Environments, *p.Environment)) {
+		return false
+	}
+	if len(route.Environments) == 0 && p.IsSandbox() {
+		return false
+	}
+	if route.MinPrice != nil && (p.Price == nil || *p.Price < *route.MinPrice) {
+		return false
+	}
+	if route.MaxPrice != nil && (p.Price == nil || *p.Price > *route.MaxPrice) {
+		return false
+	}
+
+	return true
+}
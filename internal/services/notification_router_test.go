@@ -0,0 +1,169 @@
+package services
+
+import (
+	"slices"
+	"testing"
+
+	"athylps/internal/config"
+	"athylps/internal/models"
+)
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func Test_NotificationRouter(t *testing.T) {
+	cfg := &config.Config{
+		Telegram: config.TelegramConfig{NotifyChatID: "main"},
+		Notify: config.NotifyConfig{
+			Webhooks: map[string]string{"finance": "http://localhost/finance"},
+			SMTP:     config.SMTPConfig{Host: "localhost"},
+			Routes: config.NotificationRoutes{
+				{
+					EventTypes: []string{"REFUND"},
+					Targets: []config.NotificationTarget{
+						{Channel: models.NotificationChannelTelegram, To: "finance"},
+						{Channel: models.NotificationChannelWebhook, To: "finance"},
+					},
+				},
+				{
+					EventTypes: []string{"RENEWAL"},
+					Targets:    []config.NotificationTarget{{Channel: models.NotificationChannelTelegram, To: "muted"}},
+				},
+				{
+					MinPrice: ptr[float32](100),
+					Targets: []config.NotificationTarget{
+						{Channel: models.NotificationChannelEmail, To: "ceo@example.com", Locale: "en"},
+						{Channel: models.NotificationChannelTelegram, To: "main"},
+					},
+				},
+				{
+					Environments: []string{"SANDBOX"},
+					EventTypes:   []string{"RENEWAL"},
+					Targets:      []config.NotificationTarget{}, // Discarded
+				},
+				{
+					Stores:   []string{"RU_STORE"},
+					MaxPrice: ptr[float32](1),
+					Targets: []config.NotificationTarget{
+						{Channel: models.NotificationChannelWebhook, To: "finance"},
+						{Channel: models.NotificationChannelWebhook, To: "finance"},
+					},
+				},
+			},
+		},
+	}
+	router, err := NewNotificationRouter(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	mainChat := RoutedTarget{Target: models.NotificationTarget{Channel: models.NotificationChannelTelegram, Recipient: "main"}}
+	mutedChat := RoutedTarget{Target: models.NotificationTarget{Channel: models.NotificationChannelTelegram, Recipient: "muted"}}
+	financeChat := RoutedTarget{Target: models.NotificationTarget{Channel: models.NotificationChannelTelegram, Recipient: "finance"}}
+	financeWebhook := RoutedTarget{Target: models.NotificationTarget{Channel: models.NotificationChannelWebhook, Recipient: "finance"}}
+	ceoEmail := RoutedTarget{
+		Target: models.NotificationTarget{Channel: models.NotificationChannelEmail, Recipient: "ceo@example.com"},
+		Locale: "en",
+	}
+
+	tests := []struct {
+		name   string
+		params *RouteNotificationParams
+		want   []RoutedTarget
+	}{
+		{
+			name:   "no route matches",
+			params: &RouteNotificationParams{EventType: "INITIAL_PURCHASE", Store: "APP_STORE", Price: ptr[float32](5)},
+			want:   []RoutedTarget{mainChat},
+		},
+		{
+			name:   "by event type",
+			params: &RouteNotificationParams{EventType: "REFUND", Store: "APP_STORE"},
+			want:   []RoutedTarget{financeChat, financeWebhook},
+		},
+		{
+			name:   "several routes",
+			params: &RouteNotificationParams{EventType: "RENEWAL", Store: "APP_STORE", Price: ptr[float32](120)},
+			want:   []RoutedTarget{mutedChat, ceoEmail, mainChat},
+		},
+		{
+			name:   "price condition without price",
+			params: &RouteNotificationParams{EventType: "CANCELLATION", Store: "RU_STORE"},
+			want:   []RoutedTarget{mainChat},
+		},
+		{
+			name:   "discarded",
+			params: &RouteNotificationParams{EventType: "RENEWAL", Store: "APP_STORE", Environment: ptr("SANDBOX")},
+			want:   []RoutedTarget{},
+		},
+		{
+			name:   "sandbox events skip routes without environments",
+			params: &RouteNotificationParams{EventType: "REFUND", Store: "APP_STORE", Environment: ptr("SANDBOX"), Price: ptr[float32](120)},
+			want:   []RoutedTarget{},
+		},
+		{
+			name:   "test event",
+			params: &RouteNotificationParams{EventType: "TEST", Store: "APP_STORE", Environment: ptr("PRODUCTION")},
+			want:   []RoutedTarget{mainChat},
+		},
+		{
+			name:   "duplicate targets",
+			params: &RouteNotificationParams{EventType: "INITIAL_PURCHASE", Store: "RU_STORE", Price: ptr[float32](0.99)},
+			want:   []RoutedTarget{financeWebhook},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := router.Route(tt.params)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func Test_NotificationRouter_SandboxChat(t *testing.T) {
+	router, err := NewNotificationRouter(&config.Config{
+		Telegram: config.TelegramConfig{NotifyChatID: "main"},
+		Notify:   config.NotifyConfig{SandboxChatID: "dev"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	devChat := RoutedTarget{Target: models.NotificationTarget{Channel: models.NotificationChannelTelegram, Recipient: "dev"}}
+	for _, p := range []*RouteNotificationParams{
+		{EventType: "INITIAL_PURCHASE", Store: "APP_STORE", Environment: ptr("SANDBOX")},
+		{EventType: "TEST", Store: "APP_STORE"},
+	} {
+		if got := router.Route(p); !slices.Equal(got, []RoutedTarget{devChat}) {
+			t.Errorf("expected %s to go to the sandbox chat, got %v", p.EventType, got)
+		}
+	}
+}
+
+func Test_NewNotificationRouter_ValidatesTargets(t *testing.T) {
+	tests := []struct {
+		name   string
+		target config.NotificationTarget
+	}{
+		{name: "unknown channel", target: config.NotificationTarget{Channel: "sms", To: "+79990000000"}},
+		{name: "unknown webhook", target: config.NotificationTarget{Channel: models.NotificationChannelWebhook, To: "ops"}},
+		{name: "smtp not configured", target: config.NotificationTarget{Channel: models.NotificationChannelEmail, To: "ceo@example.com"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{
+				Notify: config.NotifyConfig{
+					Routes: config.NotificationRoutes{{Targets: []config.NotificationTarget{tt.target}}},
+				},
+			}
+			if _, err := NewNotificationRouter(cfg); err == nil {
+				t.Error("expected invalid route to be rejected")
+			}
+		})
+	}
+}
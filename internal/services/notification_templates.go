@@ -0,0 +1,425 @@
+package services
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"maps"
+	"math"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"athylps/internal/config"
+	"athylps/internal/models"
+
+	"github.com/biter777/countries"
+)
+
+//go:embed all:templates/notifications
+var embeddedNotificationTemplates embed.FS
+
+const (
+	defaultNotificationTemplate = "default"
+	digestTemplate              = "digest"
+	burstTemplate               = "burst"
+	donationTemplate            = "donation"
+	mrrTemplate                 = "mrr"     // Reply to /mrr
+	userSummaryTemplate         = "user"    // Reply to /user
+	latestPurchasesTemplate     = "latest"  // Reply to /last
+	sandboxLabelTemplate        = "sandbox" // Block prepended to notifications about sandbox events
+)
+
+// Templates that aren't about an event, Render never picks them by the event type
+var reservedTemplates = []string{digestTemplate, burstTemplate, donationTemplate, mrrTemplate, userSummaryTemplate, latestPurchasesTemplate}
+
+const (
+	DigestDaily  = "daily"
+	DigestWeekly = "weekly"
+)
+
+var storeNames = map[string]string{
+	"APP_STORE":   "App Store",
+	"PLAY_STORE":  "Google Play",
+	"STRIPE":      "Stripe",
+	"PROMOTIONAL": "RC Manual",
+	"RU_STORE":    "RuStore",
+}
+
+// PurchaseNotificationData is what purchase notification templates are rendered with.
+type PurchaseNotificationData struct {
+	EventType     string
+	Store         string
+	Sandbox       bool // Not real money, the notification is labelled
+	UserID        *string
+	CountryCode   *string
+	Price         *float32 // USD
+	LocalPrice    *Money   // In the currency of the purchase, if it isn't USD
+	ProductID     *string
+	RenewalNumber *int
+	ExpiresAt     *time.Time
+}
+
+// DigestData is what the digest template is rendered with, amounts are in USD.
+type DigestData struct {
+	Kind          string    // DigestDaily or DigestWeekly
+	From          time.Time // First day of the period
+	Until         time.Time // Last day of the period
+	Revenue       float64   // Purchases and renewals
+	Purchases     DigestTotal
+	Renewals      DigestTotal
+	Cancellations int
+	Refunds       DigestTotal
+	// Revenue breakdowns ordered by revenue, the name is empty if unknown
+	ByStore   []DigestTotal
+	ByProduct []DigestTotal
+	ByCountry []DigestTotal
+}
+
+type DigestTotal struct {
+	Name    string
+	Count   int
+	Revenue float64
+}
+
+// BurstData is what the burst template is rendered with, a summary of notifications
+// collapsed because too many of them were sent to a target within Window.
+type BurstData struct {
+	Window      time.Duration
+	Count       int
+	Revenue     float64       // Purchases and renewals, USD
+	ByEventType []DigestTotal // Ordered by count
+}
+
+// DonationNotificationData is what the donation template is rendered with.
+type DonationNotificationData struct {
+	Amount   *Money
+	Username string // Empty for anonymous donations
+	Message  string
+}
+
+// MRRData is what the mrr template is rendered with.
+type MRRData struct {
+	Amount        float64 // USD
+	Subscriptions int     // Active subscriptions
+	Unpriced      int     // Active subscriptions of products without a known price, not included in Amount
+}
+
+// UserSummaryData is what the user template is rendered with.
+type UserSummaryData struct {
+	User          *models.User
+	Subscriptions []*models.Subscription
+	Entitlements  []*models.Entitlement
+}
+
+// LatestPurchasesData is what the latest template is rendered with, purchases are the newest first.
+type LatestPurchasesData struct {
+	Purchases []LatestPurchase
+}
+
+type LatestPurchase struct {
+	OccurredAt  time.Time // In the time zone of the reply
+	EventType   string
+	Store       string
+	ProductID   *string
+	CountryCode *string
+	Price       *float64 // USD
+	LocalPrice  *Money   // In the currency of the purchase, if it isn't USD
+}
+
+// NotificationTemplates renders notifications from templates/notifications/<locale>/<event_type>.tmpl.
+// Templates are HTML templates, so provider supplied values are escaped. Files starting with _ hold
+// shared {{define}} blocks, default.tmpl is used for event types without a template of their own
+// digest.tmpl renders revenue digests, donation.tmpl donations, mrr.tmpl, user.tmpl and latest.tmpl bot replies.
+// Templates missing in a locale fall back to the default locale.
+type NotificationTemplates struct {
+	locales       map[string]*template.Template
+	defaultLocale string
+	now           func() time.Time
+}
+
+// NewNotificationTemplates loads the embedded templates, files in the configured directory
+// replace embedded ones with the same path or add new locales and event types.
+func NewNotificationTemplates(cfg *config.NotifyConfig) (*NotificationTemplates, error) {
+	embedded, err := fs.Sub(embeddedNotificationTemplates, "templates/notifications")
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := readNotificationTemplates(embedded, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read embedded notification templates: %w", err)
+	}
+	if cfg.TemplatesDir != "" {
+		files, err = readNotificationTemplates(os.DirFS(cfg.TemplatesDir), files)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read notification templates from %s: %w", cfg.TemplatesDir, err)
+		}
+	}
+
+	t := &NotificationTemplates{
+		locales:       make(map[string]*template.Template),
+		defaultLocale: cfg.Locale,
+		now:           time.Now,
+	}
+	if _, ok := files[t.defaultLocale]; !ok {
+		return nil, fmt.Errorf("no notification templates for default locale %q", t.defaultLocale)
+	}
+
+	for locale := range files {
+		// The locale only has to override files that differ from the default locale
+		localeFiles := maps.Clone(files[t.defaultLocale])
+		maps.Copy(localeFiles, files[locale])
+
+		tmpl := template.New(locale).Funcs(t.funcs(locale))
+		for _, name := range slices.Sorted(maps.Keys(localeFiles)) {
+			if _, err := tmpl.New(name).Parse(localeFiles[name]); err != nil {
+				return nil, fmt.Errorf("failed to parse notification template %s/%s: %w", locale, name, err)
+			}
+		}
+		t.locales[locale] = tmpl
+	}
+
+	// Catch mistakes like unknown fields at startup rather than when a purchase happens
+	for locale, tmpl := range t.locales {
+		for _, name := range templateNames(tmpl) {
+			var data any = &PurchaseNotificationData{EventType: strings.ToUpper(name)}
+			switch name {
+			case digestTemplate:
+				data = &DigestData{Kind: DigestDaily}
+			case burstTemplate:
+				data = &BurstData{ByEventType: []DigestTotal{{Name: "RENEWAL"}}}
+			case donationTemplate:
+				data = &DonationNotificationData{Amount: &Money{Currency: "USD"}}
+			case mrrTemplate:
+				data = &MRRData{}
+			case userSummaryTemplate:
+				data = &UserSummaryData{User: &models.User{}}
+			case latestPurchasesTemplate:
+				data = &LatestPurchasesData{Purchases: []LatestPurchase{{LocalPrice: &Money{Currency: "RUB"}}}}
+			}
+			if _, err := t.execute(locale, name, data); err != nil {
+				return nil, err
+			}
+		}
+		if _, err := t.Render(locale, &PurchaseNotificationData{Sandbox: true}); err != nil {
+			return nil, err
+		}
+	}
+
+	return t, nil
+}
+
+// Render renders the notification about the event in the locale, the default locale if it's empty or unknown.
+func (t *NotificationTemplates) Render(locale string, data *PurchaseNotificationData) (string, error) {
+	name := strings.ToLower(data.EventType)
+	if t.template(locale).Lookup(name) == nil || slices.Contains(reservedTemplates, name) {
+		name = defaultNotificationTemplate
+	}
+
+	text, err := t.execute(locale, name, data)
+	if err != nil || !data.Sandbox {
+		return text, err
+	}
+
+	label, err := t.execute(locale, sandboxLabelTemplate, data)
+	if err != nil {
+		return "", err
+	}
+
+	return label + "\n" + text, nil
+}
+
+// RenderDigest renders the revenue digest in the locale, the default locale if it's empty or unknown.
+func (t *NotificationTemplates) RenderDigest(locale string, data *DigestData) (string, error) {
+	return t.execute(locale, digestTemplate, data)
+}
+
+// RenderBurst renders the summary of collapsed notifications in the locale, the default locale if it's empty or unknown.
+func (t *NotificationTemplates) RenderBurst(locale string, data *BurstData) (string, error) {
+	return t.execute(locale, burstTemplate, data)
+}
+
+// RenderDonation renders the notification about a donation in the locale, the default locale if it's empty or unknown.
+func (t *NotificationTemplates) RenderDonation(locale string, data *DonationNotificationData) (string, error) {
+	return t.execute(locale, donationTemplate, data)
+}
+
+// RenderMRR renders the reply to /mrr in the locale, the default locale if it's empty or unknown.
+func (t *NotificationTemplates) RenderMRR(locale string, data *MRRData) (string, error) {
+	return t.execute(locale, mrrTemplate, data)
+}
+
+// RenderUserSummary renders the reply to /user in the locale, the default locale if it's empty or unknown.
+func (t *NotificationTemplates) RenderUserSummary(locale string, data *UserSummaryData) (string, error) {
+	return t.execute(locale, userSummaryTemplate, data)
+}
+
+// RenderLatestPurchases renders the reply to /last in the locale, the default locale if it's empty or unknown.
+func (t *NotificationTemplates) RenderLatestPurchases(locale string, data *LatestPurchasesData) (string, error) {
+	return t.execute(locale, latestPurchasesTemplate, data)
+}
+
+func (t *NotificationTemplates) template(locale string) *template.Template {
+	if tmpl, ok := t.locales[locale]; ok {
+		return tmpl
+	}
+	return t.locales[t.defaultLocale]
+}
+
+func (t *NotificationTemplates) execute(locale string, name string, data any) (string, error) {
+	if _, ok := t.locales[locale]; !ok {
+		locale = t.defaultLocale
+	}
+	tmpl := t.locales[locale]
+
+	var b bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&b, name, data); err != nil {
+		return "", fmt.Errorf("failed to render notification template %s/%s: %w", locale, name, err)
+	}
+
+	return strings.TrimSpace(b.String()), nil
+}
+
+// readNotificationTemplates adds <locale>/<name>.tmpl files to files keyed by locale and name.
+func readNotificationTemplates(fsys fs.FS, files map[string]map[string]string) (map[string]map[string]string, error) {
+	if files == nil {
+		files = make(map[string]map[string]string)
+	}
+
+	paths, err := fs.Glob(fsys, "*/*.tmpl")
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range paths {
+		content, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return nil, err
+		}
+		locale, file := path.Split(p)
+		locale = strings.TrimSuffix(locale, "/")
+		if files[locale] == nil {
+			files[locale] = make(map[string]string)
+		}
+		files[locale][strings.TrimSuffix(file, ".tmpl")] = string(content)
+	}
+
+	return files, nil
+}
+
+// templateNames returns names of event templates, excluding shared files and {{define}} blocks.
+func templateNames(tmpl *template.Template) []string {
+	var names []string
+	for _, t := range tmpl.Templates() {
+		name := t.Name()
+		if strings.HasPrefix(name, "_") || t.Tree == nil || t.Tree.ParseName != name {
+			continue
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+func (t *NotificationTemplates) funcs(locale string) template.FuncMap {
+	return template.FuncMap{
+		"price": func(price float32) string {
+			return fmt.Sprintf("$%.2f", price)
+		},
+		"money": func(amount float64) string {
+			return fmt.Sprintf("$%.2f", amount)
+		},
+		"amount": func(m *Money) string {
+			return formatMoney(*m)
+		},
+		"store": func(store string) string {
+			if name, ok := storeNames[store]; ok {
+				return name
+			}
+			return store
+		},
+		"country": func(countryCode string) string {
+			return countryName(countryCode, locale)
+		},
+		"date": func(at time.Time) string {
+			if locale == "ru" {
+				return at.Format("02.01.2006")
+			}
+			return at.Format("Jan 2, 2006")
+		},
+		"datetime": func(at time.Time) string {
+			if locale == "ru" {
+				return at.Format("02.01 15:04")
+			}
+			return at.Format("Jan 2 15:04")
+		},
+		"relative": func(at time.Time) string {
+			return relativeTime(at.Sub(t.now()), locale)
+		},
+		"duration": func(d time.Duration) string {
+			return durationText(d, locale)
+		},
+	}
+}
+
+func countryName(countryCode string, locale string) string {
+	country := countries.ByName(countryCode)
+	name := country.String()
+	if locale == "ru" {
+		name = country.StringRus()
+	}
+	flag := country.Emoji()
+
+	return fmt.Sprintf("%s %s", flag, name)
+}
+
+// relativeTime formats the duration from now, positive durations are in the future.
+func relativeTime(d time.Duration, locale string) string {
+	abs := d.Abs()
+	if abs < time.Minute {
+		if locale == "ru" {
+			return "только что"
+		}
+		return "just now"
+	}
+
+	text := durationText(abs, locale)
+	if locale == "ru" {
+		if d > 0 {
+			return "через " + text
+		}
+		return text + " назад"
+	}
+
+	if d > 0 {
+		return "in " + text
+	}
+	return text + " ago"
+}
+
+// durationText formats the duration rounded to the largest unit that fits, e.g. 3 ч. or 3 hours.
+func durationText(d time.Duration, locale string) string {
+	var amount int
+	var ruUnit, enUnit string
+	switch abs := d.Abs(); {
+	case abs < time.Minute:
+		amount, ruUnit, enUnit = int(math.Round(abs.Seconds())), "сек.", "second"
+	case abs < time.Hour:
+		amount, ruUnit, enUnit = int(math.Round(abs.Minutes())), "мин.", "minute"
+	case abs < 48*time.Hour:
+		amount, ruUnit, enUnit = int(math.Round(abs.Hours())), "ч.", "hour"
+	default:
+		amount, ruUnit, enUnit = int(math.Round(abs.Hours()/24)), "дн.", "day"
+	}
+
+	if locale == "ru" {
+		return fmt.Sprintf("%d %s", amount, ruUnit)
+	}
+	if amount != 1 {
+		enUnit += "s"
+	}
+	return fmt.Sprintf("%d %s", amount, enUnit)
+}
@@ -0,0 +1,411 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"athylps/internal/config"
+)
+
+func Test_CountryCode(t *testing.T) {
+	data := []string{"BY", "RU", "GB", "SE", "UA"}
+	for _, c := range data {
+		fmt.Println(countryName(c, "ru"), countryName(c, "en"))
+	}
+}
+
+func newTestNotificationTemplates(t *testing.T, cfg *config.NotifyConfig) *NotificationTemplates {
+	t.Helper()
+
+	if cfg.Locale == "" {
+		cfg.Locale = "ru"
+	}
+	templates, err := NewNotificationTemplates(cfg)
+	if err != nil {
+		t.Fatalf("failed to load notification templates: %v", err)
+	}
+	templates.now = func() time.Time { return time.Date(2025, 12, 1, 10, 0, 0, 0, time.UTC) }
+	return templates
+}
+
+func Test_NotificationTemplates_Render(t *testing.T) {
+	templates := newTestNotificationTemplates(t, &config.NotifyConfig{})
+	expiresAt := time.Date(2025, 12, 31, 10, 0, 0, 0, time.UTC)
+	data := &PurchaseNotificationData{
+		EventType:     "INITIAL_PURCHASE",
+		Store:         "APP_STORE",
+		UserID:        ptr("0193a6f0-0000-7000-8000-000000000000"),
+		CountryCode:   ptr("RU"),
+		Price:         ptr[float32](4.99),
+		ProductID:     ptr("premium_<b>monthly</b>"),
+		RenewalNumber: ptr(0),
+		ExpiresAt:     &expiresAt,
+	}
+
+	tests := []struct {
+		name      string
+		locale    string
+		eventType string
+		local     *Money
+		sandbox   bool
+		want      string
+	}{
+		{
+			name:      "initial purchase",
+			locale:    "ru",
+			eventType: "INITIAL_PURCHASE",
+			want: "💵 Совершена покупка в <b>App Store</b> 💵\n\n" +
+				"Стоимость: $4.99\n" +
+				"Действует до: 31.12.2025 (через 30 дн.)\n" +
+				"Страна: 🇷🇺 Россия\n" +
+				"Продукт: premium_&lt;b&gt;monthly&lt;/b&gt;\n" +
+				"Кол-во продлений: 0\n" +
+				"Пользователь: <code>0193a6f0-0000-7000-8000-000000000000</code>",
+		},
+		{
+			name:      "english",
+			locale:    "en",
+			eventType: "RENEWAL",
+			want: "🔁 Subscription renewed in <b>App Store</b> 🔁\n\n" +
+				"Price: $4.99\n" +
+				"Active until: Dec 31, 2025 (in 30 days)\n" +
+				"Country: 🇷🇺 Russian Federation\n" +
+				"Product: premium_&lt;b&gt;monthly&lt;/b&gt;\n" +
+				"Renewals: 0\n" +
+				"User: <code>0193a6f0-0000-7000-8000-000000000000</code>",
+		},
+		{
+			name:      "local currency",
+			locale:    "ru",
+			eventType: "NON_RENEWING_PURCHASE",
+			local:     &Money{Amount: 499, Currency: "RUB"},
+			want: "💵 Совершена разовая покупка в <b>App Store</b> 💵\n\n" +
+				"Стоимость: 499 ₽ (~$4.99)\n" +
+				"Страна: 🇷🇺 Россия\n" +
+				"Продукт: premium_&lt;b&gt;monthly&lt;/b&gt;\n" +
+				"Кол-во продлений: 0\n" +
+				"Пользователь: <code>0193a6f0-0000-7000-8000-000000000000</code>",
+		},
+		{
+			name:      "unknown locale falls back to default",
+			locale:    "de",
+			eventType: "REFUND",
+			want: "↩️ Оформлен возврат в <b>App Store</b> ↩️\n\n" +
+				"Страна: 🇷🇺 Россия\n" +
+				"Продукт: premium_&lt;b&gt;monthly&lt;/b&gt;\n" +
+				"Кол-во продлений: 0\n" +
+				"Пользователь: <code>0193a6f0-0000-7000-8000-000000000000</code>",
+		},
+		{
+			name:      "sandbox",
+			locale:    "en",
+			eventType: "REFUND",
+			sandbox:   true,
+			want: "🧪 <b>SANDBOX</b> – test event, not real money\n" +
+				"↩️ Refund issued in <b>App Store</b> ↩️\n\n" +
+				"Country: 🇷🇺 Russian Federation\n" +
+				"Product: premium_&lt;b&gt;monthly&lt;/b&gt;\n" +
+				"Renewals: 0\n" +
+				"User: <code>0193a6f0-0000-7000-8000-000000000000</code>",
+		},
+		{
+			name:      "test event",
+			locale:    "ru",
+			eventType: "TEST",
+			want:      "✅ Получено тестовое событие RevenueCat, интеграция работает",
+		},
+		{
+			name:      "event type without template",
+			locale:    "en",
+			eventType: "BILLING_ISSUE",
+			want:      "Event: BILLING_ISSUE",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := *data
+			d.EventType = tt.eventType
+			d.LocalPrice = tt.local
+			d.Sandbox = tt.sandbox
+			got, err := templates.Render(tt.locale, &d)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected\n%q\ngot\n%q", tt.want, got)
+			}
+		})
+	}
+}
+
+func Test_NotificationTemplates_Override(t *testing.T) {
+	dir := t.TempDir()
+	writeTemplate := func(path string, content string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(dir, path)), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, path), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeTemplate("ru/refund.tmpl", `Возврат: {{.ProductID}}`)
+	writeTemplate("de/renewal.tmpl", `Verlängert in {{store .Store}}`)
+
+	templates := newTestNotificationTemplates(t, &config.NotifyConfig{TemplatesDir: dir})
+	render := func(locale string, eventType string) string {
+		t.Helper()
+		text, err := templates.Render(locale, &PurchaseNotificationData{EventType: eventType, Store: "RU_STORE", ProductID: ptr("<i>x</i>")})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return text
+	}
+
+	if got := render("ru", "REFUND"); got != "Возврат: &lt;i&gt;x&lt;/i&gt;" {
+		t.Errorf("expected overridden template, got %q", got)
+	}
+	if got := render("ru", "RENEWAL"); !strings.HasPrefix(got, "🔁 Подписка продлена в <b>RuStore</b>") {
+		t.Errorf("expected embedded template, got %q", got)
+	}
+	if got := render("de", "RENEWAL"); got != "Verlängert in RuStore" {
+		t.Errorf("expected added locale, got %q", got)
+	}
+	if got := render("de", "CANCELLATION"); !strings.HasPrefix(got, "✖︎ Совершена отмена подписки") {
+		t.Errorf("expected added locale to fall back to default locale, got %q", got)
+	}
+}
+
+func Test_NewNotificationTemplates_ValidatesTemplates(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{name: "syntax error", content: `{{if .Price}}`},
+		{name: "unknown field", content: `{{.Amount}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.MkdirAll(filepath.Join(dir, "en"), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(dir, "en", "renewal.tmpl"), []byte(tt.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := NewNotificationTemplates(&config.NotifyConfig{TemplatesDir: dir, Locale: "ru"}); err == nil {
+				t.Error("expected broken template to be rejected")
+			}
+		})
+	}
+}
+
+func Test_RelativeTime(t *testing.T) {
+	tests := []struct {
+		d      time.Duration
+		locale string
+		want   string
+	}{
+		{d: 30 * time.Second, locale: "ru", want: "только что"},
+		{d: 5 * time.Minute, locale: "ru", want: "через 5 мин."},
+		{d: -3 * time.Hour, locale: "ru", want: "3 ч. назад"},
+		{d: 72 * time.Hour, locale: "ru", want: "через 3 дн."},
+		{d: -time.Minute, locale: "en", want: "1 minute ago"},
+		{d: 36 * time.Hour, locale: "en", want: "in 36 hours"},
+		{d: -10 * 24 * time.Hour, locale: "en", want: "10 days ago"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := relativeTime(tt.d, tt.locale); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func Test_DurationText(t *testing.T) {
+	tests := []struct {
+		d      time.Duration
+		locale string
+		want   string
+	}{
+		{d: 30 * time.Second, locale: "ru", want: "30 сек."},
+		{d: time.Minute, locale: "en", want: "1 minute"},
+		{d: 90 * time.Minute, locale: "en", want: "2 hours"},
+		{d: 72 * time.Hour, locale: "ru", want: "3 дн."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := durationText(tt.d, tt.locale); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func Test_NotificationTemplates_RenderBurst(t *testing.T) {
+	templates := newTestNotificationTemplates(t, &config.NotifyConfig{})
+	data := &BurstData{
+		Window:  time.Minute,
+		Count:   12,
+		Revenue: 54.1,
+		ByEventType: []DigestTotal{
+			{Name: "RENEWAL", Count: 10, Revenue: 54.1},
+			{Name: "CANCELLATION", Count: 1},
+			{Name: "BILLING_ISSUE", Count: 1},
+		},
+	}
+
+	tests := []struct {
+		locale string
+		want   string
+	}{
+		{
+			locale: "en",
+			want: "⚡️ <b>12 events in 1 minute</b>, $54.10 total\n\n" +
+				"Renewals: 10 – $54.10\n" +
+				"Cancellations: 1\n" +
+				"BILLING_ISSUE: 1",
+		},
+		{
+			locale: "ru",
+			want: "⚡️ <b>Событий за 1 мин.: 12</b>, выручка $54.10\n\n" +
+				"Продления: 10 – $54.10\n" +
+				"Отмены: 1\n" +
+				"BILLING_ISSUE: 1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.locale, func(t *testing.T) {
+			got, err := templates.RenderBurst(tt.locale, data)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected\n%q\ngot\n%q", tt.want, got)
+			}
+		})
+	}
+}
+
+func Test_NotificationTemplates_RenderDonation(t *testing.T) {
+	templates := newTestNotificationTemplates(t, &config.NotifyConfig{})
+
+	tests := []struct {
+		locale string
+		data   *DonationNotificationData
+		want   string
+	}{
+		{
+			locale: "ru",
+			data:   &DonationNotificationData{Amount: &Money{Amount: 100, Currency: "RUB"}, Username: "Ivan", Message: "<b>hello</b>"},
+			want:   "🍩 Новый донат на <b>DonationAlerts</b> 🍩\n\nСумма: 100 ₽\nОт: Ivan\nСообщение: &lt;b&gt;hello&lt;/b&gt;",
+		},
+		{
+			locale: "en",
+			data:   &DonationNotificationData{Amount: &Money{Amount: 5, Currency: "USD"}},
+			want:   "🍩 New donation on <b>DonationAlerts</b> 🍩\n\nAmount: $5\nFrom: Anonymous",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.locale, func(t *testing.T) {
+			got, err := templates.RenderDonation(tt.locale, tt.data)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected\n%q\ngot\n%q", tt.want, got)
+			}
+		})
+	}
+}
+
+func Test_NotificationTemplates_RenderLatestPurchases(t *testing.T) {
+	templates := newTestNotificationTemplates(t, &config.NotifyConfig{})
+	product, country := "premium", "DE"
+	price, localPrice := 3.74, 299.0
+	data := &LatestPurchasesData{Purchases: []LatestPurchase{
+		{
+			OccurredAt:  time.Date(2025, 12, 8, 10, 30, 0, 0, time.UTC),
+			EventType:   "RENEWAL",
+			Store:       "RU_STORE",
+			ProductID:   &product,
+			Price:       &price,
+			LocalPrice:  &Money{Amount: localPrice, Currency: "RUB"},
+			CountryCode: &country,
+		},
+		{OccurredAt: time.Date(2025, 12, 8, 9, 0, 0, 0, time.UTC), EventType: "CANCELLATION", Store: "APP_STORE"},
+	}}
+
+	tests := []struct {
+		locale string
+		data   *LatestPurchasesData
+		want   string
+	}{
+		{
+			locale: "en",
+			data:   data,
+			want:   "🧾 <b>Latest events</b>\n\nDec 8 10:30 RENEWAL RuStore premium 299 ₽ (~$3.74) DE\nDec 8 09:00 CANCELLATION App Store",
+		},
+		{locale: "ru", data: &LatestPurchasesData{}, want: "Покупок пока не было"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.locale, func(t *testing.T) {
+			got, err := templates.RenderLatestPurchases(tt.locale, tt.data)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected\n%q\ngot\n%q", tt.want, got)
+			}
+		})
+	}
+}
+
+func Test_NotificationTemplates_RenderDigest(t *testing.T) {
+	templates := newTestNotificationTemplates(t, &config.NotifyConfig{})
+	data := &DigestData{
+		Kind:          DigestWeekly,
+		From:          time.Date(2025, 12, 1, 0, 0, 0, 0, time.UTC),
+		Until:         time.Date(2025, 12, 7, 0, 0, 0, 0, time.UTC),
+		Revenue:       24.95,
+		Purchases:     DigestTotal{Count: 3, Revenue: 14.97},
+		Renewals:      DigestTotal{Count: 2, Revenue: 9.98},
+		Cancellations: 1,
+		ByStore:       []DigestTotal{{Name: "APP_STORE", Count: 5, Revenue: 24.95}},
+		ByProduct:     []DigestTotal{{Name: "premium", Count: 5, Revenue: 24.95}},
+		ByCountry:     []DigestTotal{{Name: "SE", Count: 5, Revenue: 24.95}},
+	}
+
+	got, err := templates.RenderDigest("en", data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "📊 <b>Week Dec 1, 2025 – Dec 7, 2025</b>\n\n" +
+		"Revenue: <b>$24.95</b>\n" +
+		"New purchases: 3 ($14.97)\n" +
+		"Renewals: 2 ($9.98)\n" +
+		"Cancellations: 1\n" +
+		"Refunds: 0\n\n" +
+		"<b>By store</b>\n" +
+		"App Store: 5 – $24.95\n\n" +
+		"<b>By product</b>\n" +
+		"premium: 5 – $24.95\n\n" +
+		"<b>By country</b>\n" +
+		"🇸🇪 Sweden: 5 – $24.95"
+	if got != want {
+		t.Errorf("expected\n%q\ngot\n%q", want, got)
+	}
+}
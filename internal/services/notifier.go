@@ -0,0 +1,84 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+	"time"
+
+	"athylps/internal/metrics"
+	"athylps/internal/models"
+)
+
+// ErrNotificationRejected means the channel won't ever accept the notification, e.g. it's malformed
+// or the recipient doesn't exist, so there is no point in retrying it.
+var ErrNotificationRejected = errors.New("notification rejected")
+
+// RateLimitedError means the channel accepts no more notifications until RetryAfter passes.
+type RateLimitedError struct {
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *RateLimitedError) Error() string {
+	return fmt.Sprintf("rate limited, retry after %s: %v", e.RetryAfter, e.Err)
+}
+
+func (e *RateLimitedError) Unwrap() error {
+	return e.Err
+}
+
+// Notifier delivers notifications over a single channel.
+type Notifier interface {
+	Send(ctx context.Context, recipient string, message string) error
+}
+
+// NotifierService delivers notifications over the channel of their target.
+type NotifierService struct {
+	channels map[string]Notifier
+}
+
+func NewNotifierService(channels map[string]Notifier) *NotifierService {
+	return &NotifierService{
+		channels: channels,
+	}
+}
+
+func (s *NotifierService) Send(ctx context.Context, target models.NotificationTarget, message string) error {
+	notifier, ok := s.channels[target.Channel]
+	if !ok {
+		return fmt.Errorf("%w: channel %q is not configured", ErrNotificationRejected, target.Channel)
+	}
+
+	start := time.Now()
+	err := notifier.Send(ctx, target.Recipient, message)
+	metrics.NotificationsSent.WithLabelValues(target.Channel, notificationResult(err)).Inc()
+	metrics.NotificationSendDuration.WithLabelValues(target.Channel).Observe(time.Since(start).Seconds())
+
+	return err
+}
+
+// notificationResult labels the result of a delivery attempt in metrics.
+func notificationResult(err error) string {
+	var rateLimited *RateLimitedError
+	switch {
+	case err == nil:
+		return "sent"
+	case errors.As(err, &rateLimited):
+		return "rate_limited"
+	case errors.Is(err, ErrNotificationRejected):
+		return "rejected"
+	default:
+		return "failed"
+	}
+}
+
+var htmlTagRegexp = regexp.MustCompile(`<[^>]*>`)
+
+// plainText converts a Telegram HTML message to plain text for channels that don't support HTML.
+func plainText(message string) string {
+	return strings.TrimSpace(html.UnescapeString(htmlTagRegexp.ReplaceAllString(message, "")))
+}
@@ -0,0 +1,152 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+
+	"athylps/internal/models"
+
+	tgbot "github.com/go-telegram/bot"
+)
+
+const testNotificationMessage = "💵 Совершена покупка в <b>App Store</b> 💵\n\nСтоимость: $4.99\nПользователь: <code>a&amp;b</code>\n"
+
+func Test_WebhookNotifierService(t *testing.T) {
+	var received webhookMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok":
+			if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+				t.Fatal(err)
+			}
+		case "/limited":
+			w.Header().Set("Retry-After", "30")
+			w.WriteHeader(http.StatusTooManyRequests)
+		case "/gone":
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer server.Close()
+
+	notifier := NewWebhookNotifierService(map[string]string{
+		"ok":      server.URL + "/ok",
+		"limited": server.URL + "/limited",
+		"gone":    server.URL + "/gone",
+		"down":    server.URL + "/down",
+	}, server.Client())
+	ctx := context.Background()
+
+	if err := notifier.Send(ctx, "ok", testNotificationMessage); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "💵 Совершена покупка в App Store 💵\n\nСтоимость: $4.99\nПользователь: a&b"
+	if received.Text != want || received.Content != want {
+		t.Errorf("expected plain text message, got %+v", received)
+	}
+
+	var rateLimited *RateLimitedError
+	if err := notifier.Send(ctx, "limited", "hi"); !errors.As(err, &rateLimited) || rateLimited.RetryAfter != 30*time.Second {
+		t.Errorf("expected rate limited error, got %v", err)
+	}
+	for _, name := range []string{"gone", "unknown"} {
+		if err := notifier.Send(ctx, name, "hi"); !errors.Is(err, ErrNotificationRejected) {
+			t.Errorf("expected %s webhook to reject notification, got %v", name, err)
+		}
+	}
+	if err := notifier.Send(ctx, "down", "hi"); err == nil || errors.Is(err, ErrNotificationRejected) {
+		t.Errorf("expected retryable error, got %v", err)
+	}
+}
+
+func Test_NotifierService_UnknownChannel(t *testing.T) {
+	notifier := NewNotifierService(map[string]Notifier{})
+	err := notifier.Send(context.Background(), models.NotificationTarget{Channel: models.NotificationChannelEmail}, "hi")
+	if !errors.Is(err, ErrNotificationRejected) {
+		t.Errorf("expected notification to an unconfigured channel to be rejected, got %v", err)
+	}
+}
+
+func Test_NotificationResult(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{err: nil, want: "sent"},
+		{err: fmt.Errorf("failed to send: %w", &RateLimitedError{RetryAfter: time.Second}), want: "rate_limited"},
+		{err: fmt.Errorf("%w: chat not found", ErrNotificationRejected), want: "rejected"},
+		{err: errors.New("timeout"), want: "failed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := notificationResult(tt.err); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func Test_TelegramError(t *testing.T) {
+	var rateLimited *RateLimitedError
+	if err := telegramError(&tgbot.TooManyRequestsError{RetryAfter: 17}); !errors.As(err, &rateLimited) || rateLimited.RetryAfter != 17*time.Second {
+		t.Errorf("expected rate limited error, got %v", err)
+	}
+	for _, err := range []error{tgbot.ErrorBadRequest, tgbot.ErrorForbidden} {
+		if got := telegramError(fmt.Errorf("%w, chat not found", err)); !errors.Is(got, ErrNotificationRejected) || !errors.Is(got, err) {
+			t.Errorf("expected %v to be rejected, got %v", err, got)
+		}
+	}
+	if err := telegramError(errors.New("timeout")); errors.Is(err, ErrNotificationRejected) {
+		t.Errorf("expected other errors to be retryable, got %v", err)
+	}
+}
+
+func Test_SMTPError(t *testing.T) {
+	if err := smtpError(&textproto.Error{Code: 550, Msg: "mailbox unavailable"}); !errors.Is(err, ErrNotificationRejected) {
+		t.Errorf("expected permanent failure to be rejected, got %v", err)
+	}
+	if err := smtpError(&textproto.Error{Code: 451, Msg: "try again later"}); errors.Is(err, ErrNotificationRejected) {
+		t.Errorf("expected transient failure to be retryable, got %v", err)
+	}
+}
+
+func Test_BuildEmail(t *testing.T) {
+	date := time.Date(2025, 12, 3, 10, 0, 0, 0, time.UTC)
+	email := string(buildEmail("bot@example.com", "ceo@example.com", testNotificationMessage, date))
+
+	headers, body, ok := strings.Cut(email, "\r\n\r\n")
+	if !ok {
+		t.Fatalf("expected headers and body, got %q", email)
+	}
+	for _, header := range []string{
+		"From: bot@example.com",
+		"To: ceo@example.com",
+		"Subject: =?UTF-8?b?",
+		"Date: Wed, 03 Dec 2025 10:00:00 +0000",
+		"Content-Type: text/plain; charset=UTF-8",
+	} {
+		if !strings.Contains(headers, header) {
+			t.Errorf("expected header %q in %q", header, headers)
+		}
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(body, "\r\n", ""))
+	if err != nil {
+		t.Fatalf("failed to decode body: %v", err)
+	}
+	want := "💵 Совершена покупка в App Store 💵\r\n\r\nСтоимость: $4.99\r\nПользователь: a&b"
+	if string(decoded) != want {
+		t.Errorf("expected body %q, got %q", want, decoded)
+	}
+}
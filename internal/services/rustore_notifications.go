@@ -0,0 +1,162 @@
+package services
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"athylps/internal/config"
+)
+
+var (
+	ErrInvalidRustorePayload = errors.New("invalid rustore notification payload")
+	ErrRustoreSecretNotSet   = errors.New("rustore notify secret is not configured")
+)
+
+type RustoreNotificationType string
+
+const (
+	RustoreNotificationPurchase            RustoreNotificationType = "PURCHASE"
+	RustoreNotificationSubscriptionRenewal RustoreNotificationType = "SUBSCRIPTION_RENEWAL"
+	RustoreNotificationRefund              RustoreNotificationType = "REFUND"
+	RustoreNotificationCancellation        RustoreNotificationType = "CANCELLATION"
+)
+
+// RustoreNotification is the decrypted payload of a RuStore payment notification.
+type RustoreNotification struct {
+	NotificationID string                  `json:"notificationId"`
+	Type           RustoreNotificationType `json:"type"`
+	PurchaseID     string                  `json:"purchaseId"`
+	ProductID      string                  `json:"productId"`
+	// DeveloperPayload is set by the app when the purchase is started, we use it to pass app user id
+	DeveloperPayload *string    `json:"developerPayload,omitempty"`
+	Amount           int64      `json:"amount"` // In minor units of the currency, e.g. kopecks
+	Currency         string     `json:"currency"`
+	CountryCode      *string    `json:"countryCode,omitempty"`
+	PurchaseTime     time.Time  `json:"purchaseTime"`
+	ExpirationTime   *time.Time `json:"expirationTime,omitempty"` // Only for subscriptions
+	// EventTime is when the renewal, refund or cancellation happened, if the notification has it
+	EventTime *time.Time `json:"eventTime,omitempty"`
+	Sandbox   bool       `json:"sandbox"`
+}
+
+func (n *RustoreNotification) IsSubscription() bool {
+	return n.ExpirationTime != nil
+}
+
+// Price returns the amount in major units of the currency, e.g. rubles.
+func (n *RustoreNotification) Price() float64 {
+	return MinorUnitsToAmount(n.Amount, n.Currency)
+}
+
+func (n *RustoreNotification) validate() error {
+	switch n.Type {
+	case RustoreNotificationPurchase, RustoreNotificationSubscriptionRenewal, RustoreNotificationRefund, RustoreNotificationCancellation:
+	default:
+		return fmt.Errorf("unknown notification type %q", n.Type)
+	}
+
+	if n.NotificationID == "" {
+		return errors.New("notificationId is required")
+	}
+	if n.ProductID == "" {
+		return errors.New("productId is required")
+	}
+	if n.PurchaseTime.IsZero() {
+		return errors.New("purchaseTime is required")
+	}
+	if n.Amount < 0 {
+		return fmt.Errorf("amount must not be negative, got %d", n.Amount)
+	}
+	if len(n.Currency) != 3 {
+		return fmt.Errorf("currency must be an ISO 4217 code, got %q", n.Currency)
+	}
+	if n.Type == RustoreNotificationSubscriptionRenewal && n.ExpirationTime == nil {
+		return errors.New("expirationTime is required for subscription renewals")
+	}
+
+	return nil
+}
+
+// RustoreNotificationDecoder decrypts and parses payloads of RuStore payment notifications.
+//
+// The payload is base64 encoded AES-GCM ciphertext prefixed with a 12 byte nonce,
+// the key is the base64 encoded notify secret from the RuStore console.
+type RustoreNotificationDecoder struct {
+	aead cipher.AEAD
+}
+
+// NewRustoreNotificationDecoder returns a decoder for the configured notify secret.
+// An empty secret is allowed for local development, such decoder rejects every payload.
+func NewRustoreNotificationDecoder(cfg *config.RustoreConfig) (*RustoreNotificationDecoder, error) {
+	if cfg.NotifySecret == "" {
+		return &RustoreNotificationDecoder{}, nil
+	}
+
+	key, err := base64.StdEncoding.DecodeString(cfg.NotifySecret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode rustore notify secret: %w", err)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create rustore payload cipher: %w", err)
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create rustore payload cipher: %w", err)
+	}
+
+	return &RustoreNotificationDecoder{
+		aead: aead,
+	}, nil
+}
+
+// Decode returns the decrypted notification together with its plaintext JSON representation.
+func (d *RustoreNotificationDecoder) Decode(payload string) (*RustoreNotification, []byte, error) {
+	if d.aead == nil {
+		return nil, nil, ErrRustoreSecretNotSet
+	}
+
+	data, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", ErrInvalidRustorePayload, err)
+	}
+
+	nonceSize := d.aead.NonceSize()
+	if len(data) < nonceSize+d.aead.Overhead() {
+		return nil, nil, fmt.Errorf("%w: payload is too short", ErrInvalidRustorePayload)
+	}
+
+	plaintext, err := d.aead.Open(nil, data[:nonceSize], data[nonceSize:], nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", ErrInvalidRustorePayload, err)
+	}
+
+	notification, err := ParseRustoreNotification(plaintext)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return notification, plaintext, nil
+}
+
+// ParseRustoreNotification parses and validates the plaintext JSON of a decrypted notification,
+// it's how notifications are stored to be replayed.
+func ParseRustoreNotification(plaintext []byte) (*RustoreNotification, error) {
+	var notification RustoreNotification
+	if err := json.Unmarshal(plaintext, &notification); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidRustorePayload, err)
+	}
+
+	if err := notification.validate(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidRustorePayload, err)
+	}
+
+	return &notification, nil
+}
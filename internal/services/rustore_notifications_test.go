@@ -0,0 +1,123 @@
+package services
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	"athylps/internal/config"
+)
+
+func newTestRustoreSecret(t *testing.T) (string, []byte) {
+	t.Helper()
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	return base64.StdEncoding.EncodeToString(key), key
+}
+
+func encryptRustorePayload(t *testing.T, key []byte, plaintext string) string {
+	t.Helper()
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		t.Fatal(err)
+	}
+	return base64.StdEncoding.EncodeToString(aead.Seal(nonce, nonce, []byte(plaintext), nil))
+}
+
+const testRustoreRenewal = `{
+	"notificationId": "b7d0c1c4-4c1b-4c87-9a5e-5d1f3c2d9a10",
+	"type": "SUBSCRIPTION_RENEWAL",
+	"purchaseId": "purchase-1",
+	"productId": "premium_monthly",
+	"developerPayload": "user_42",
+	"amount": 49900,
+	"currency": "RUB",
+	"purchaseTime": "2025-11-20T10:00:00Z",
+	"expirationTime": "2025-12-20T10:00:00Z",
+	"eventTime": "2025-11-20T10:00:05Z",
+	"sandbox": false
+}`
+
+func Test_RustoreNotificationDecoder_Decode(t *testing.T) {
+	secret, key := newTestRustoreSecret(t)
+	decoder, err := NewRustoreNotificationDecoder(&config.RustoreConfig{NotifySecret: secret})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	notification, plaintext, err := decoder.Decode(encryptRustorePayload(t, key, testRustoreRenewal))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if string(plaintext) != testRustoreRenewal {
+		t.Errorf("unexpected plaintext: %s", plaintext)
+	}
+	if notification.Type != RustoreNotificationSubscriptionRenewal {
+		t.Errorf("unexpected type: %s", notification.Type)
+	}
+	if notification.Amount != 49900 || notification.Currency != "RUB" {
+		t.Errorf("unexpected amount: %d %s", notification.Amount, notification.Currency)
+	}
+	if !notification.IsSubscription() {
+		t.Errorf("expected subscription notification")
+	}
+	if notification.EventTime == nil || !notification.EventTime.Equal(time.Date(2025, 11, 20, 10, 0, 5, 0, time.UTC)) {
+		t.Errorf("unexpected event time: %v", notification.EventTime)
+	}
+}
+
+func Test_RustoreNotificationDecoder_RejectsInvalidPayloads(t *testing.T) {
+	secret, key := newTestRustoreSecret(t)
+	_, otherKey := newTestRustoreSecret(t)
+	decoder, err := NewRustoreNotificationDecoder(&config.RustoreConfig{NotifySecret: secret})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	valid := encryptRustorePayload(t, key, testRustoreRenewal)
+	tampered := []byte(valid)
+	tampered[len(tampered)-5] ^= 1
+
+	tests := map[string]string{
+		"not base64":       "%%%",
+		"too short":        base64.StdEncoding.EncodeToString([]byte("short")),
+		"tampered":         string(tampered),
+		"wrong key":        encryptRustorePayload(t, otherKey, testRustoreRenewal),
+		"not json":         encryptRustorePayload(t, key, "not json"),
+		"unknown type":     encryptRustorePayload(t, key, `{"notificationId":"1","type":"UNKNOWN","productId":"p","purchaseTime":"2025-11-20T10:00:00Z","currency":"RUB"}`),
+		"missing product":  encryptRustorePayload(t, key, `{"notificationId":"1","type":"PURCHASE","purchaseTime":"2025-11-20T10:00:00Z","currency":"RUB"}`),
+		"renewal w/o exp.": encryptRustorePayload(t, key, `{"notificationId":"1","type":"SUBSCRIPTION_RENEWAL","productId":"p","purchaseTime":"2025-11-20T10:00:00Z","currency":"RUB"}`),
+	}
+
+	for name, payload := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, _, err := decoder.Decode(payload)
+			if !errors.Is(err, ErrInvalidRustorePayload) {
+				t.Errorf("expected ErrInvalidRustorePayload, got %v", err)
+			}
+		})
+	}
+}
+
+func Test_NewRustoreNotificationDecoder_RejectsInvalidSecret(t *testing.T) {
+	for _, secret := range []string{"not base64!", base64.StdEncoding.EncodeToString([]byte("short"))} {
+		if _, err := NewRustoreNotificationDecoder(&config.RustoreConfig{NotifySecret: secret}); err == nil {
+			t.Errorf("expected error for secret %q", secret)
+		}
+	}
+}
@@ -2,12 +2,16 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"time"
 
 	"athylps/internal/config"
 
 	tgbot "github.com/go-telegram/bot"
 	tgmodels "github.com/go-telegram/bot/models"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 	"go.uber.org/zap"
 )
 
@@ -21,7 +25,9 @@ func NewTgNotifierService(
 	cfg *config.TelegramConfig,
 	logger *zap.Logger,
 ) *TgNotifierService {
-	bot, err := tgbot.New(cfg.BotToken)
+	// Traced, so the Telegram request shows up in the trace of the notification
+	client := &http.Client{Timeout: time.Minute, Transport: otelhttp.NewTransport(http.DefaultTransport)}
+	bot, err := tgbot.New(cfg.BotToken, tgbot.WithHTTPClient(time.Minute, client))
 	if err != nil {
 		logger.Fatal("failed to create telegram bot", zap.Error(err))
 	}
@@ -32,17 +38,42 @@ func NewTgNotifierService(
 	}
 }
 
+// Notify sends the message to the default notification chat.
 func (s *TgNotifierService) Notify(ctx context.Context, message string) error {
+	return s.Send(ctx, s.notifyChatID, message)
+}
+
+// Send sends the message to the chat, the default notification chat if it's empty.
+func (s *TgNotifierService) Send(ctx context.Context, chatID string, message string) error {
+	if chatID == "" {
+		chatID = s.notifyChatID
+	}
+
 	msg, err := s.bot.SendMessage(ctx, &tgbot.SendMessageParams{
 		Text:      message,
-		ChatID:    s.notifyChatID,
+		ChatID:    chatID,
 		ParseMode: tgmodels.ParseModeHTML,
 	})
 	if err != nil {
-		return fmt.Errorf("failed to send telegram message: %w", err)
+		return fmt.Errorf("failed to send telegram message: %w", telegramError(err))
 	}
 
-	s.logger.Info("sent telegram message", zap.Any("msg", msg), zap.String("chat_id", s.notifyChatID))
+	s.logger.Info("sent telegram message", zap.Int("message_id", msg.ID), zap.String("chat_id", chatID))
 
 	return nil
 }
+
+// telegramError maps Bot API errors to the errors notifiers report.
+func telegramError(err error) error {
+	var tooManyRequests *tgbot.TooManyRequestsError
+	if errors.As(err, &tooManyRequests) {
+		return &RateLimitedError{RetryAfter: time.Duration(tooManyRequests.RetryAfter) * time.Second, Err: err}
+	}
+
+	// The message is malformed, the chat doesn't exist or the bot was removed from it
+	if errors.Is(err, tgbot.ErrorBadRequest) || errors.Is(err, tgbot.ErrorForbidden) {
+		return fmt.Errorf("%w: %w", ErrNotificationRejected, err)
+	}
+
+	return err
+}
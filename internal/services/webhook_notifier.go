@@ -0,0 +1,74 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// webhookMessage is understood by Slack and Mattermost incoming webhooks (text)
+// and Discord webhooks (content), unknown fields are ignored by all of them.
+type webhookMessage struct {
+	Text    string `json:"text"`
+	Content string `json:"content"`
+}
+
+// WebhookNotifierService posts notifications as plain text to JSON webhooks configured by name.
+type WebhookNotifierService struct {
+	httpClient *http.Client
+	webhooks   map[string]string
+}
+
+func NewWebhookNotifierService(webhooks map[string]string, httpClient *http.Client) *WebhookNotifierService {
+	return &WebhookNotifierService{
+		httpClient: httpClient,
+		webhooks:   webhooks,
+	}
+}
+
+// Send posts the message to the webhook with the given name.
+func (s *WebhookNotifierService) Send(ctx context.Context, name string, message string) error {
+	url, ok := s.webhooks[name]
+	if !ok {
+		return fmt.Errorf("%w: webhook %q is not configured", ErrNotificationRejected, name)
+	}
+
+	text := plainText(message)
+	body, err := json.Marshal(&webhookMessage{Text: text, Content: text})
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook message: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("%w: failed to create webhook %q request: %w", ErrNotificationRejected, name, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call webhook %q: %w", name, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	err = fmt.Errorf("webhook %q responded with status %d: %s", name, resp.StatusCode, respBody)
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		retryAfter, _ := strconv.Atoi(resp.Header.Get("Retry-After"))
+		return &RateLimitedError{RetryAfter: time.Duration(retryAfter) * time.Second, Err: err}
+	case resp.StatusCode >= 400 && resp.StatusCode < 500:
+		return fmt.Errorf("%w: %w", ErrNotificationRejected, err)
+	}
+
+	return err
+}
@@ -0,0 +1,52 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func Test_Traceparent(t *testing.T) {
+	ctx, span := sdktrace.NewTracerProvider().Tracer("test").Start(context.Background(), "webhook")
+	defer span.End()
+
+	traceparent := Traceparent(ctx)
+	if traceparent == "" {
+		t.Fatal("expected traceparent of the span")
+	}
+
+	continued := trace.SpanContextFromContext(ContextWithTraceparent(context.Background(), traceparent))
+	if continued.TraceID() != span.SpanContext().TraceID() || continued.SpanID() != span.SpanContext().SpanID() {
+		t.Errorf("expected the trace to be continued, got %v", continued)
+	}
+
+	if got := Traceparent(context.Background()); got != "" {
+		t.Errorf("expected no traceparent without a span, got %q", got)
+	}
+	if ctx := ContextWithTraceparent(context.Background(), ""); trace.SpanContextFromContext(ctx).IsValid() {
+		t.Error("expected no trace to be continued without a traceparent")
+	}
+}
+
+func Test_Logger(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	logger := zap.New(core)
+
+	ctx, span := sdktrace.NewTracerProvider().Tracer("test").Start(context.Background(), "webhook")
+	defer span.End()
+
+	Logger(ctx, logger).Info("traced")
+	Logger(context.Background(), logger).Info("untraced")
+
+	entries := logs.All()
+	if got := entries[0].ContextMap()["trace_id"]; got != span.SpanContext().TraceID().String() {
+		t.Errorf("expected trace_id of the span, got %v", got)
+	}
+	if _, ok := entries[1].ContextMap()["trace_id"]; ok {
+		t.Error("expected no trace_id without a span")
+	}
+}
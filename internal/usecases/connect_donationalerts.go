@@ -0,0 +1,144 @@
+package usecases
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"athylps/internal/logging"
+	"athylps/internal/models"
+
+	"go.uber.org/zap"
+)
+
+// donationAlertsStateTTL is how long the owner has to authorize the app after the authorization has been started.
+const donationAlertsStateTTL = 15 * time.Minute
+
+// ErrInvalidOAuthState is returned for callbacks of authorizations we haven't started, or started too long ago.
+var ErrInvalidOAuthState = errors.New("invalid or expired oauth state")
+
+type donationAlertsAuthClient interface {
+	AuthorizeURL(state string) string
+	ExchangeCode(ctx context.Context, code string) (*models.OAuthToken, error)
+}
+
+type donationAlertsTokenRepository interface {
+	Get(ctx context.Context) (*models.OAuthToken, error)
+	Save(ctx context.Context, token *models.OAuthToken) error
+}
+
+type ConnectDonationAlertsParams struct {
+	Code  string
+	State string
+}
+
+// DonationAlertsAuthorization is a started authorization, the owner opens the url to connect the account.
+type DonationAlertsAuthorization struct {
+	URL       string
+	ExpiresAt time.Time
+}
+
+// ConnectDonationAlertsUsecase runs the DonationAlerts OAuth authorization-code flow. The state is signed with
+// the key rather than stored, so the callback is accepted by any replica and authorizations can be started
+// from athylpsctl.
+type ConnectDonationAlertsUsecase struct {
+	stateKey []byte
+	client   donationAlertsAuthClient
+	tokens   donationAlertsTokenRepository
+	logger   *zap.Logger
+	now      func() time.Time
+}
+
+func NewConnectDonationAlertsUsecase(
+	stateKey string,
+	client donationAlertsAuthClient,
+	tokens donationAlertsTokenRepository,
+	logger *zap.Logger,
+) *ConnectDonationAlertsUsecase {
+	return &ConnectDonationAlertsUsecase{
+		stateKey: []byte(stateKey),
+		client:   client,
+		tokens:   tokens,
+		logger:   logger,
+		now:      time.Now,
+	}
+}
+
+// Authorize starts the authorization.
+func (u *ConnectDonationAlertsUsecase) Authorize() (*DonationAlertsAuthorization, error) {
+	if len(u.stateKey) == 0 {
+		return nil, errors.New("donationalerts client secret is not configured")
+	}
+
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate oauth state: %w", err)
+	}
+	expiresAt := u.now().Add(donationAlertsStateTTL).Truncate(time.Second)
+	payload := base64.RawURLEncoding.EncodeToString(nonce) + "." + strconv.FormatInt(expiresAt.Unix(), 10)
+
+	return &DonationAlertsAuthorization{
+		URL:       u.client.AuthorizeURL(payload + "." + u.sign(payload)),
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// Perform completes the authorization, returns ErrInvalidOAuthState if the state isn't one Authorize has issued
+// or has expired. Otherwise anyone could connect their own account and get the donations polled from it.
+func (u *ConnectDonationAlertsUsecase) Perform(ctx context.Context, params *ConnectDonationAlertsParams) error {
+	if !u.validState(params.State) {
+		return ErrInvalidOAuthState
+	}
+
+	token, err := u.client.ExchangeCode(ctx, params.Code)
+	if err != nil {
+		return fmt.Errorf("failed to exchange donationalerts authorization code: %w", err)
+	}
+
+	if err := u.tokens.Save(ctx, token); err != nil {
+		return err
+	}
+
+	logging.FromContext(ctx, u.logger).Info("connected donationalerts account", zap.Time("token_expires_at", token.ExpiresAt))
+
+	return nil
+}
+
+func (u *ConnectDonationAlertsUsecase) validState(state string) bool {
+	if len(u.stateKey) == 0 {
+		return false
+	}
+
+	payload, signature, ok := cutLast(state, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(u.sign(payload))) {
+		return false
+	}
+	_, expiresAt, ok := cutLast(payload, ".")
+	if !ok {
+		return false
+	}
+	unix, err := strconv.ParseInt(expiresAt, 10, 64)
+
+	return err == nil && u.now().Before(time.Unix(unix, 0))
+}
+
+func (u *ConnectDonationAlertsUsecase) sign(payload string) string {
+	mac := hmac.New(sha256.New, u.stateKey)
+	mac.Write([]byte("donationalerts-oauth-state:" + payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func cutLast(s, sep string) (before, after string, found bool) {
+	i := strings.LastIndex(s, sep)
+	if i < 0 {
+		return s, "", false
+	}
+	return s[:i], s[i+len(sep):], true
+}
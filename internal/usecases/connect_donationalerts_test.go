@@ -0,0 +1,86 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"athylps/internal/models"
+
+	"go.uber.org/zap"
+)
+
+type fakeDonationAlertsAuthClient struct {
+	exchanged []string
+}
+
+func (c *fakeDonationAlertsAuthClient) AuthorizeURL(state string) string {
+	return "https://www.donationalerts.com/oauth/authorize?" + url.Values{"state": {state}}.Encode()
+}
+
+func (c *fakeDonationAlertsAuthClient) ExchangeCode(_ context.Context, code string) (*models.OAuthToken, error) {
+	c.exchanged = append(c.exchanged, code)
+	return &models.OAuthToken{AccessToken: "access-" + code}, nil
+}
+
+func Test_ConnectDonationAlerts(t *testing.T) {
+	ctx := context.Background()
+	client := &fakeDonationAlertsAuthClient{}
+	tokens := &fakeDonationAlertsTokenRepository{}
+	now := time.Date(2025, 12, 1, 12, 0, 0, 0, time.UTC)
+	usecase := NewConnectDonationAlertsUsecase("secret", client, tokens, zap.NewNop())
+	usecase.now = func() time.Time { return now }
+
+	authorization, err := usecase.Authorize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	authorizeURL, err := url.Parse(authorization.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state := authorizeURL.Query().Get("state")
+
+	other := NewConnectDonationAlertsUsecase("other-secret", client, tokens, zap.NewNop())
+	otherAuthorization, err := other.Authorize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherURL, _ := url.Parse(otherAuthorization.URL)
+
+	for _, tt := range []struct {
+		name  string
+		state string
+	}{
+		{name: "missing state", state: ""},
+		{name: "tampered state", state: state + "x"},
+		{name: "state signed with another key", state: otherURL.Query().Get("state")},
+		{name: "forged state", state: "nonce.9999999999.signature"},
+	} {
+		if err := usecase.Perform(ctx, &ConnectDonationAlertsParams{Code: "attacker", State: tt.state}); !errors.Is(err, ErrInvalidOAuthState) {
+			t.Errorf("%s: expected ErrInvalidOAuthState, got %v", tt.name, err)
+		}
+	}
+	if len(client.exchanged) != 0 || tokens.token != nil {
+		t.Fatalf("expected no code to be exchanged for invalid states, got %v", client.exchanged)
+	}
+
+	if err := usecase.Perform(ctx, &ConnectDonationAlertsParams{Code: "owner", State: state}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if tokens.token == nil || tokens.token.AccessToken != "access-owner" {
+		t.Errorf("expected the token to be saved, got %+v", tokens.token)
+	}
+
+	now = authorization.ExpiresAt
+	if err := usecase.Perform(ctx, &ConnectDonationAlertsParams{Code: "late", State: state}); !errors.Is(err, ErrInvalidOAuthState) {
+		t.Errorf("expected the expired state to be rejected, got %v", err)
+	}
+
+	unconfigured := NewConnectDonationAlertsUsecase("", client, tokens, zap.NewNop())
+	if _, err := unconfigured.Authorize(); err == nil {
+		t.Errorf("expected authorization to require the client secret")
+	}
+}
@@ -0,0 +1,88 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"athylps/internal/logging"
+	"athylps/internal/models"
+
+	"go.uber.org/zap"
+)
+
+type accountDeleter interface {
+	DeleteAccount(ctx context.Context, id string, purgeAfter time.Time) (*models.User, error)
+}
+
+type revenueCatCustomerUnlinker interface {
+	UnlinkUser(ctx context.Context, userID string) error
+}
+
+type sessionRevoker interface {
+	RevokeRefreshTokens(ctx context.Context, uid string) error
+}
+
+// DeleteAccountUsecase deletes the account on the user's request: signs the user out of all devices,
+// soft deletes the user and detaches RevenueCat customers, so the purchases can be restored to another account.
+// Personal data is purged after the retention by PurgeDeletedUsersUsecase.
+type DeleteAccountUsecase struct {
+	retention time.Duration
+	tx        transactor
+	users     accountDeleter
+	customers revenueCatCustomerUnlinker
+	sessions  sessionRevoker
+	logger    *zap.Logger
+	now       func() time.Time
+}
+
+func NewDeleteAccountUsecase(
+	retention time.Duration,
+	tx transactor,
+	users accountDeleter,
+	customers revenueCatCustomerUnlinker,
+	sessions sessionRevoker,
+	logger *zap.Logger,
+) *DeleteAccountUsecase {
+	return &DeleteAccountUsecase{
+		retention: retention,
+		tx:        tx,
+		users:     users,
+		customers: customers,
+		sessions:  sessions,
+		logger:    logger,
+		now:       time.Now,
+	}
+}
+
+// Perform returns the deleted user, repositories.ErrNotFound if the user has already been deleted.
+func (u *DeleteAccountUsecase) Perform(ctx context.Context, user *models.User) (*models.User, error) {
+	// Sessions are revoked first, if it fails the user is still there to retry
+	if user.FirebaseUID != nil {
+		if err := u.sessions.RevokeRefreshTokens(ctx, *user.FirebaseUID); err != nil {
+			return nil, fmt.Errorf("failed to revoke firebase sessions: %w", err)
+		}
+	}
+
+	var deleted *models.User
+	err := u.tx.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		deleted, err = u.users.DeleteAccount(ctx, user.ID, u.now().Add(u.retention))
+		if err != nil {
+			return fmt.Errorf("failed to delete user: %w", err)
+		}
+
+		if err := u.customers.UnlinkUser(ctx, user.ID); err != nil {
+			return fmt.Errorf("failed to detach revenuecat customers: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logging.FromContext(ctx, u.logger).Info("deleted account", zap.String("user_id", user.ID), zap.Timep("purge_after", deleted.PurgeAfter))
+
+	return deleted, nil
+}
@@ -0,0 +1,75 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"athylps/internal/models"
+
+	"go.uber.org/zap"
+)
+
+type fakeAccountDeleter struct {
+	deleted map[string]time.Time
+}
+
+func (d *fakeAccountDeleter) DeleteAccount(_ context.Context, id string, purgeAfter time.Time) (*models.User, error) {
+	d.deleted[id] = purgeAfter
+	return &models.User{ID: id, DeletedAt: &purgeAfter, PurgeAfter: &purgeAfter}, nil
+}
+
+type fakeRevenueCatCustomerUnlinker struct {
+	unlinked []string
+}
+
+func (u *fakeRevenueCatCustomerUnlinker) UnlinkUser(_ context.Context, userID string) error {
+	u.unlinked = append(u.unlinked, userID)
+	return nil
+}
+
+type fakeSessionRevoker struct {
+	revoked []string
+	err     error
+}
+
+func (r *fakeSessionRevoker) RevokeRefreshTokens(_ context.Context, uid string) error {
+	if r.err != nil {
+		return r.err
+	}
+	r.revoked = append(r.revoked, uid)
+	return nil
+}
+
+func Test_DeleteAccount(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 12, 12, 10, 0, 0, 0, time.UTC)
+	users := &fakeAccountDeleter{deleted: map[string]time.Time{}}
+	customers := &fakeRevenueCatCustomerUnlinker{}
+	sessions := &fakeSessionRevoker{}
+	usecase := NewDeleteAccountUsecase(30*24*time.Hour, fakeTransactor{}, users, customers, sessions, zap.NewNop())
+	usecase.now = func() time.Time { return now }
+
+	deleted, err := usecase.Perform(ctx, &models.User{ID: "1", FirebaseUID: ptr("firebase-1")})
+	if err != nil {
+		t.Fatalf("failed to delete account: %v", err)
+	}
+	if want := now.Add(30 * 24 * time.Hour); !users.deleted["1"].Equal(want) || !deleted.PurgeAfter.Equal(want) {
+		t.Errorf("expected the purge to be scheduled at %v, got %v", want, users.deleted["1"])
+	}
+	if len(sessions.revoked) != 1 || sessions.revoked[0] != "firebase-1" {
+		t.Errorf("expected firebase sessions to be revoked, got %v", sessions.revoked)
+	}
+	if len(customers.unlinked) != 1 || customers.unlinked[0] != "1" {
+		t.Errorf("expected revenuecat customers to be detached, got %v", customers.unlinked)
+	}
+
+	sessions.err = errors.New("firebase is unavailable")
+	if _, err := usecase.Perform(ctx, &models.User{ID: "2", FirebaseUID: ptr("firebase-2")}); !errors.Is(err, sessions.err) {
+		t.Errorf("expected the revocation error, got %v", err)
+	}
+	if _, ok := users.deleted["2"]; ok {
+		t.Error("expected the user not to be deleted while sessions are alive")
+	}
+}
@@ -0,0 +1,383 @@
+package usecases
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"athylps/internal/logging"
+	"athylps/internal/models"
+	"athylps/internal/repositories"
+	"athylps/internal/services"
+	"athylps/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+const (
+	notificationBatchSize      = 20
+	notificationLease          = 2 * time.Minute
+	notificationSendTimeout    = 30 * time.Second
+	notificationMaxAttempts    = 10
+	notificationRetryBaseDelay = 5 * time.Second
+	notificationRetryMaxDelay  = time.Hour
+	notificationMinRetryAfter  = time.Second
+	notificationReleaseTimeout = 5 * time.Second
+	notificationMaxErrorLength = 1000
+)
+
+// NotificationLimits configures how many notifications a target gets, zero values disable the limits.
+type NotificationLimits struct {
+	MinInterval time.Duration // Between two notifications to a target
+	// More than BurstThreshold purchase notifications to a target within BurstWindow are collapsed into a summary
+	BurstThreshold int
+	BurstWindow    time.Duration
+}
+
+// targetThrottle is what a target was sent recently.
+type targetThrottle struct {
+	windowStart time.Time
+	sent        int       // Within the burst window
+	nextSendAt  time.Time // Not before MinInterval or retry_after pass
+}
+
+type notificationDispatchOutbox interface {
+	Enqueue(ctx context.Context, p *repositories.EnqueueNotificationParams) error
+	ClaimDue(ctx context.Context, limit uint64, lease time.Duration) ([]*models.Notification, error)
+	MarkSent(ctx context.Context, id string) error
+	MarkRetry(ctx context.Context, id string, nextAttemptAt time.Time, lastError string) error
+	MarkFailed(ctx context.Context, id string, lastError string) error
+	MarkCoalesced(ctx context.Context, ids []string) error
+	Release(ctx context.Context, ids []string, nextAttemptAt time.Time) error
+}
+
+type notificationSender interface {
+	Send(ctx context.Context, target models.NotificationTarget, message string) error
+}
+
+type burstRenderer interface {
+	RenderBurst(locale string, data *services.BurstData) (string, error)
+}
+
+// DispatchNotificationsUsecase delivers notifications from the outbox to their targets.
+// Failed deliveries are retried with exponential backoff, a rate limited target is paused
+// for the requested retry_after. Several dispatchers can run concurrently, e.g. one per replica.
+//
+// Targets get a notification at most every MinInterval. When purchase notifications to a target exceed
+// the burst threshold within the window, e.g. on a RevenueCat backlog replay, the claimed ones are
+// collapsed into a single summary, and ones claimed after the threshold is reached wait for the next window.
+// Limits are tracked per dispatcher, so with several replicas a target can get a multiple of them.
+type DispatchNotificationsUsecase struct {
+	limits    *NotificationLimits
+	tx        transactor
+	outbox    notificationDispatchOutbox
+	notifier  notificationSender
+	templates burstRenderer
+	logger    *zap.Logger
+	now       func() time.Time
+	throttles map[models.NotificationTarget]*targetThrottle
+}
+
+func NewDispatchNotificationsUsecase(
+	limits *NotificationLimits,
+	tx transactor,
+	outbox notificationDispatchOutbox,
+	notifier notificationSender,
+	templates burstRenderer,
+	logger *zap.Logger,
+) *DispatchNotificationsUsecase {
+	return &DispatchNotificationsUsecase{
+		limits:    limits,
+		tx:        tx,
+		outbox:    outbox,
+		notifier:  notifier,
+		templates: templates,
+		logger:    logger,
+		now:       time.Now,
+		throttles: make(map[models.NotificationTarget]*targetThrottle),
+	}
+}
+
+// Perform sends all due notifications and returns when there are none left or the context is cancelled.
+// A notification being sent when the context is cancelled is still sent, claimed but not sent ones are released.
+func (u *DispatchNotificationsUsecase) Perform(ctx context.Context) error {
+	for ctx.Err() == nil {
+		notifications, err := u.outbox.ClaimDue(ctx, notificationBatchSize, notificationLease)
+		if err != nil {
+			return fmt.Errorf("failed to claim notifications: %w", err)
+		}
+		if len(notifications) == 0 {
+			return nil
+		}
+
+		// Notifications to a throttled target are postponed, a rate limited one would reject them too
+		postponed := make(map[time.Time][]*models.Notification)
+		notifications, err = u.coalesceBursts(ctx, notifications, postponed)
+		if err != nil {
+			return errors.Join(err, u.releasePostponed(ctx, postponed))
+		}
+
+		for i, n := range notifications {
+			if ctx.Err() != nil {
+				return errors.Join(u.release(ctx, notifications[i:], u.now()), u.releasePostponed(ctx, postponed))
+			}
+
+			throttle := u.throttle(n.Target)
+			if u.now().Before(throttle.nextSendAt) {
+				postponed[throttle.nextSendAt] = append(postponed[throttle.nextSendAt], n)
+				continue
+			}
+
+			retryAfter, err := u.dispatch(ctx, n)
+			if err != nil {
+				return err
+			}
+			throttle.sent++
+			throttle.nextSendAt = u.now().Add(max(retryAfter, u.limits.MinInterval))
+		}
+
+		if err := u.releasePostponed(ctx, postponed); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// dispatch sends the notification and records the result, returning how long to wait if the target is rate limited.
+func (u *DispatchNotificationsUsecase) dispatch(ctx context.Context, n *models.Notification) (time.Duration, error) {
+	// Don't abort the request in the middle on shutdown, the message could be delivered but not marked sent
+	sendCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), notificationSendTimeout)
+	defer cancel()
+
+	// Continues the trace of the request that enqueued the notification, e.g. a webhook
+	sendCtx, span := tracing.Start(tracing.ContextWithTraceparent(sendCtx, n.Traceparent), "DispatchNotificationsUsecase.dispatch", trace.WithAttributes(
+		attribute.String("notification.id", n.ID),
+		attribute.String("notification.channel", n.Target.Channel),
+		attribute.Int("notification.attempt", n.Attempts+1),
+	))
+	defer span.End()
+
+	sendErr := u.notifier.Send(sendCtx, n.Target, n.Text)
+	tracing.RecordError(span, sendErr)
+	if sendErr == nil {
+		if err := u.outbox.MarkSent(sendCtx, n.ID); err != nil {
+			return 0, fmt.Errorf("failed to mark notification sent: %w", err)
+		}
+		return 0, nil
+	}
+
+	logger := logging.FromContext(sendCtx, u.logger).With(
+		zap.String("notification_id", n.ID),
+		zap.String("channel", n.Target.Channel),
+		zap.Int("attempt", n.Attempts+1),
+		zap.Error(sendErr),
+	)
+	lastError := truncate(sendErr.Error(), notificationMaxErrorLength)
+
+	delay, retry := notificationRetryDelay(n.Attempts+1, sendErr)
+	if !retry {
+		logger.Error("failed to deliver notification, giving up")
+		if err := u.outbox.MarkFailed(sendCtx, n.ID, lastError); err != nil {
+			return 0, fmt.Errorf("failed to mark notification failed: %w", err)
+		}
+		return 0, nil
+	}
+
+	logger.Warn("failed to deliver notification, will retry", zap.Duration("retry_in", delay))
+	if err := u.outbox.MarkRetry(sendCtx, n.ID, u.now().Add(delay), lastError); err != nil {
+		return 0, fmt.Errorf("failed to schedule notification retry: %w", err)
+	}
+
+	var rateLimited *services.RateLimitedError
+	if errors.As(sendErr, &rateLimited) {
+		return delay, nil
+	}
+
+	return 0, nil
+}
+
+// coalesceBursts collapses purchase notifications that would exceed the burst threshold of their target
+// into a summary. Notifications to targets that reached the threshold are added to postponed until the
+// window ends, the rest are returned to be sent.
+func (u *DispatchNotificationsUsecase) coalesceBursts(
+	ctx context.Context,
+	notifications []*models.Notification,
+	postponed map[time.Time][]*models.Notification,
+) ([]*models.Notification, error) {
+	if u.limits.BurstThreshold <= 0 {
+		return notifications, nil
+	}
+
+	bursts := make(map[models.NotificationTarget][]*models.Notification)
+	for _, n := range notifications {
+		if n.EventType != nil {
+			bursts[n.Target] = append(bursts[n.Target], n)
+		}
+	}
+
+	collapsed := make(map[string]bool)
+	for target, burst := range bursts {
+		throttle := u.throttle(target)
+		free := u.limits.BurstThreshold - throttle.sent
+		if len(burst) <= free {
+			continue
+		}
+
+		for _, n := range burst {
+			collapsed[n.ID] = true
+		}
+		if free <= 0 {
+			windowEnd := throttle.windowStart.Add(u.limits.BurstWindow)
+			postponed[windowEnd] = append(postponed[windowEnd], burst...)
+			continue
+		}
+
+		if err := u.collapse(ctx, target, burst); err != nil {
+			return nil, err
+		}
+		// The rest of the window is left for the summary and notifications that aren't collapsible
+		throttle.sent = u.limits.BurstThreshold
+		logging.FromContext(ctx, u.logger).Info("collapsed notification burst", zap.String("channel", target.Channel), zap.Int("count", len(burst)))
+	}
+
+	return slices.DeleteFunc(notifications, func(n *models.Notification) bool {
+		return collapsed[n.ID]
+	}), nil
+}
+
+// collapse replaces the notifications with a summary, which is delivered like any other notification.
+func (u *DispatchNotificationsUsecase) collapse(ctx context.Context, target models.NotificationTarget, burst []*models.Notification) error {
+	locale := burst[0].Locale
+	text, err := u.templates.RenderBurst(locale, buildBurstData(u.limits.BurstWindow, burst))
+	if err != nil {
+		return err
+	}
+
+	ids := make([]string, 0, len(burst))
+	for _, n := range burst {
+		ids = append(ids, n.ID)
+	}
+
+	err = u.tx.WithinTx(ctx, func(ctx context.Context) error {
+		if err := u.outbox.MarkCoalesced(ctx, ids); err != nil {
+			return err
+		}
+		return u.outbox.Enqueue(ctx, &repositories.EnqueueNotificationParams{Target: target, Text: text, Locale: locale})
+	})
+	if err != nil {
+		return fmt.Errorf("failed to collapse notifications to %s: %w", target.Channel, err)
+	}
+
+	return nil
+}
+
+// throttle returns the throttle of the target, starting a new burst window if the previous one is over.
+func (u *DispatchNotificationsUsecase) throttle(target models.NotificationTarget) *targetThrottle {
+	now := u.now()
+	throttle, ok := u.throttles[target]
+	if !ok {
+		throttle = &targetThrottle{windowStart: now}
+		u.throttles[target] = throttle
+	}
+	if !now.Before(throttle.windowStart.Add(u.limits.BurstWindow)) {
+		throttle.windowStart = now
+		throttle.sent = 0
+	}
+
+	return throttle
+}
+
+func (u *DispatchNotificationsUsecase) releasePostponed(ctx context.Context, postponed map[time.Time][]*models.Notification) error {
+	for _, until := range slices.SortedFunc(maps.Keys(postponed), time.Time.Compare) {
+		if err := u.release(ctx, postponed[until], until); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (u *DispatchNotificationsUsecase) release(ctx context.Context, notifications []*models.Notification, nextAttemptAt time.Time) error {
+	if len(notifications) == 0 {
+		return nil
+	}
+
+	ids := make([]string, 0, len(notifications))
+	for _, n := range notifications {
+		ids = append(ids, n.ID)
+	}
+
+	releaseCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), notificationReleaseTimeout)
+	defer cancel()
+
+	if err := u.outbox.Release(releaseCtx, ids, nextAttemptAt); err != nil {
+		return fmt.Errorf("failed to release notifications: %w", err)
+	}
+
+	return nil
+}
+
+func buildBurstData(window time.Duration, burst []*models.Notification) *services.BurstData {
+	data := &services.BurstData{Window: window, Count: len(burst)}
+	byEventType := make(map[string]*services.DigestTotal)
+	for _, n := range burst {
+		total, ok := byEventType[*n.EventType]
+		if !ok {
+			total = &services.DigestTotal{Name: *n.EventType}
+			byEventType[*n.EventType] = total
+		}
+		total.Count++
+
+		switch *n.EventType {
+		case typeInitialPurchase, typeNonRenewingPurchase, typeRenewal:
+			if n.Price != nil {
+				total.Revenue += *n.Price
+				data.Revenue += *n.Price
+			}
+		}
+	}
+
+	for _, total := range byEventType {
+		data.ByEventType = append(data.ByEventType, *total)
+	}
+	slices.SortFunc(data.ByEventType, func(a, b services.DigestTotal) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), cmp.Compare(a.Name, b.Name))
+	})
+
+	return data
+}
+
+// notificationRetryDelay returns when to retry the attempt that failed with the error, false if it must not be retried.
+func notificationRetryDelay(attempt int, err error) (time.Duration, bool) {
+	var rateLimited *services.RateLimitedError
+	if errors.As(err, &rateLimited) {
+		// Rate limits are temporary by definition, they are retried regardless of the attempts limit
+		return max(rateLimited.RetryAfter, notificationMinRetryAfter), true
+	}
+
+	if errors.Is(err, services.ErrNotificationRejected) || attempt >= notificationMaxAttempts {
+		return 0, false
+	}
+
+	delay := notificationRetryBaseDelay << (attempt - 1)
+	if delay <= 0 || delay > notificationRetryMaxDelay {
+		delay = notificationRetryMaxDelay
+	}
+
+	return delay, true
+}
+
+func truncate(s string, length int) string {
+	runes := []rune(s)
+	if len(runes) <= length {
+		return s
+	}
+	return string(runes[:length])
+}
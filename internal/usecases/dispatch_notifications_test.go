@@ -0,0 +1,448 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"athylps/internal/models"
+	"athylps/internal/repositories"
+	"athylps/internal/services"
+
+	"go.uber.org/zap"
+)
+
+type fakeNotificationOutbox struct {
+	now           func() time.Time
+	notifications []*models.Notification
+	released      []string
+}
+
+var testNotificationTarget = models.NotificationTarget{Channel: models.NotificationChannelTelegram}
+
+func (o *fakeNotificationOutbox) add(texts ...string) {
+	o.addTo(testNotificationTarget, texts...)
+}
+
+func (o *fakeNotificationOutbox) addTo(target models.NotificationTarget, texts ...string) {
+	for _, text := range texts {
+		o.notifications = append(o.notifications, &models.Notification{
+			ID:            fmt.Sprintf("%d", len(o.notifications)+1),
+			Target:        target,
+			Text:          text,
+			Status:        models.NotificationStatusPending,
+			NextAttemptAt: o.now(),
+		})
+	}
+}
+
+// addPurchases adds purchase notifications of the event type priced in USD.
+func (o *fakeNotificationOutbox) addPurchases(eventType string, prices ...float64) {
+	for _, price := range prices {
+		o.add(fmt.Sprintf("%s $%.2f", eventType, price))
+		n := o.notifications[len(o.notifications)-1]
+		n.EventType = &eventType
+		n.Price = &price
+		n.Locale = "en"
+	}
+}
+
+func (o *fakeNotificationOutbox) Enqueue(_ context.Context, p *repositories.EnqueueNotificationParams) error {
+	o.addTo(p.Target, p.Text)
+	n := o.notifications[len(o.notifications)-1]
+	n.EventType = p.EventType
+	n.Price = p.Price
+	n.Locale = p.Locale
+	return nil
+}
+
+func (o *fakeNotificationOutbox) get(id string) *models.Notification {
+	for _, n := range o.notifications {
+		if n.ID == id {
+			return n
+		}
+	}
+	return nil
+}
+
+func (o *fakeNotificationOutbox) ClaimDue(_ context.Context, limit uint64, lease time.Duration) ([]*models.Notification, error) {
+	var claimed []*models.Notification
+	for _, n := range o.notifications {
+		if uint64(len(claimed)) == limit {
+			break
+		}
+		if n.Status == models.NotificationStatusPending && !n.NextAttemptAt.After(o.now()) {
+			n.NextAttemptAt = o.now().Add(lease)
+			copied := *n
+			claimed = append(claimed, &copied)
+		}
+	}
+	return claimed, nil
+}
+
+func (o *fakeNotificationOutbox) MarkSent(_ context.Context, id string) error {
+	n := o.get(id)
+	n.Status = models.NotificationStatusSent
+	n.Attempts++
+	return nil
+}
+
+func (o *fakeNotificationOutbox) MarkRetry(_ context.Context, id string, nextAttemptAt time.Time, lastError string) error {
+	n := o.get(id)
+	n.Attempts++
+	n.NextAttemptAt = nextAttemptAt
+	n.LastError = &lastError
+	return nil
+}
+
+func (o *fakeNotificationOutbox) MarkFailed(_ context.Context, id string, lastError string) error {
+	n := o.get(id)
+	n.Status = models.NotificationStatusFailed
+	n.Attempts++
+	n.LastError = &lastError
+	return nil
+}
+
+func (o *fakeNotificationOutbox) MarkCoalesced(_ context.Context, ids []string) error {
+	for _, id := range ids {
+		if n := o.get(id); n.Status == models.NotificationStatusPending {
+			n.Status = models.NotificationStatusCoalesced
+		}
+	}
+	return nil
+}
+
+func (o *fakeNotificationOutbox) Release(_ context.Context, ids []string, nextAttemptAt time.Time) error {
+	for _, id := range ids {
+		o.get(id).NextAttemptAt = nextAttemptAt
+	}
+	o.released = append(o.released, ids...)
+	return nil
+}
+
+// scriptedNotifier fails with the scripted errors in order, then succeeds.
+type scriptedNotifier struct {
+	errs   []error
+	sent   []string
+	onSend func(ctx context.Context)
+}
+
+func (n *scriptedNotifier) Send(ctx context.Context, _ models.NotificationTarget, message string) error {
+	if n.onSend != nil {
+		n.onSend(ctx)
+	}
+	if len(n.errs) > 0 {
+		err := n.errs[0]
+		n.errs = n.errs[1:]
+		if err != nil {
+			return fmt.Errorf("failed to send telegram message: %w", err)
+		}
+	}
+	n.sent = append(n.sent, message)
+	return nil
+}
+
+type fakeBurstRenderer struct{}
+
+func (fakeBurstRenderer) RenderBurst(locale string, data *services.BurstData) (string, error) {
+	text := fmt.Sprintf("%s: %d in %s, $%.2f", locale, data.Count, data.Window, data.Revenue)
+	for _, total := range data.ByEventType {
+		text += fmt.Sprintf(", %s %d", total.Name, total.Count)
+	}
+	return text, nil
+}
+
+func newTestDispatcher(notifier notificationSender) (*DispatchNotificationsUsecase, *fakeNotificationOutbox, *time.Time) {
+	return newLimitedTestDispatcher(notifier, &NotificationLimits{})
+}
+
+func newLimitedTestDispatcher(notifier notificationSender, limits *NotificationLimits) (*DispatchNotificationsUsecase, *fakeNotificationOutbox, *time.Time) {
+	now := time.Date(2025, 12, 1, 10, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+	outbox := &fakeNotificationOutbox{now: clock}
+	usecase := NewDispatchNotificationsUsecase(limits, fakeTransactor{}, outbox, notifier, fakeBurstRenderer{}, zap.NewNop())
+	usecase.now = clock
+	return usecase, outbox, &now
+}
+
+func Test_DispatchNotifications_SendsDueNotifications(t *testing.T) {
+	notifier := &scriptedNotifier{}
+	usecase, outbox, _ := newTestDispatcher(notifier)
+	for i := range notificationBatchSize + 5 {
+		outbox.add(fmt.Sprintf("message %d", i))
+	}
+
+	if err := usecase.Perform(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(notifier.sent) != notificationBatchSize+5 || notifier.sent[0] != "message 0" {
+		t.Fatalf("expected all notifications to be sent in order, got %v", notifier.sent)
+	}
+	for _, n := range outbox.notifications {
+		if n.Status != models.NotificationStatusSent || n.Attempts != 1 {
+			t.Errorf("expected notification %s to be sent, got %s after %d attempts", n.ID, n.Status, n.Attempts)
+		}
+	}
+}
+
+func Test_DispatchNotifications_RetriesWithBackoff(t *testing.T) {
+	notifier := &scriptedNotifier{errs: []error{errors.New("connection reset"), errors.New("connection reset")}}
+	usecase, outbox, now := newTestDispatcher(notifier)
+	outbox.add("purchase")
+
+	if err := usecase.Perform(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	n := outbox.get("1")
+	if n.Attempts != 1 || !n.NextAttemptAt.Equal(now.Add(notificationRetryBaseDelay)) {
+		t.Fatalf("expected retry after %s, got %+v", notificationRetryBaseDelay, n)
+	}
+
+	// Not due yet
+	if err := usecase.Perform(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n.Attempts != 1 {
+		t.Fatalf("expected notification not to be retried before it's due")
+	}
+
+	*now = n.NextAttemptAt
+	if err := usecase.Perform(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n.Attempts != 2 || !n.NextAttemptAt.Equal(now.Add(2*notificationRetryBaseDelay)) {
+		t.Fatalf("expected retry after %s, got %+v", 2*notificationRetryBaseDelay, n)
+	}
+
+	*now = n.NextAttemptAt
+	if err := usecase.Perform(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n.Status != models.NotificationStatusSent || len(notifier.sent) != 1 {
+		t.Errorf("expected notification to be sent on the third attempt, got %+v", n)
+	}
+}
+
+func Test_DispatchNotifications_HonoursRetryAfter(t *testing.T) {
+	notifier := &scriptedNotifier{errs: []error{&services.RateLimitedError{RetryAfter: 42 * time.Second, Err: errors.New("too many requests")}}}
+	usecase, outbox, now := newTestDispatcher(notifier)
+	outbox.add("first", "second", "third")
+
+	if err := usecase.Perform(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(notifier.sent) != 0 {
+		t.Fatalf("expected dispatching to pause after rate limit, sent %v", notifier.sent)
+	}
+	retryAt := now.Add(42 * time.Second)
+	for _, n := range outbox.notifications {
+		if n.Status != models.NotificationStatusPending || !n.NextAttemptAt.Equal(retryAt) {
+			t.Errorf("expected notification %s to be postponed until %s, got %+v", n.ID, retryAt, n)
+		}
+	}
+	if !slices.Equal(outbox.released, []string{"2", "3"}) {
+		t.Errorf("expected not attempted notifications to be released, got %v", outbox.released)
+	}
+
+	*now = retryAt
+	if err := usecase.Perform(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !slices.Equal(notifier.sent, []string{"first", "second", "third"}) {
+		t.Errorf("expected all notifications to be sent after retry_after, got %v", notifier.sent)
+	}
+}
+
+// targetNotifier is rate limited for one target only.
+type targetNotifier struct {
+	limited models.NotificationTarget
+	sent    []string
+}
+
+func (n *targetNotifier) Send(_ context.Context, target models.NotificationTarget, message string) error {
+	if target == n.limited {
+		return &services.RateLimitedError{RetryAfter: time.Minute}
+	}
+	n.sent = append(n.sent, message)
+	return nil
+}
+
+func Test_DispatchNotifications_PausesOnlyRateLimitedTarget(t *testing.T) {
+	finance := models.NotificationTarget{Channel: models.NotificationChannelWebhook, Recipient: "finance"}
+	notifier := &targetNotifier{limited: finance}
+	usecase, outbox, now := newTestDispatcher(notifier)
+	outbox.addTo(finance, "refund 1", "refund 2")
+	outbox.add("purchase")
+
+	if err := usecase.Perform(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !slices.Equal(notifier.sent, []string{"purchase"}) {
+		t.Errorf("expected other targets to be notified, sent %v", notifier.sent)
+	}
+	if n := outbox.get("1"); n.Attempts != 1 || !n.NextAttemptAt.Equal(now.Add(time.Minute)) {
+		t.Errorf("expected rate limited notification to be retried after a minute, got %+v", n)
+	}
+	if n := outbox.get("2"); n.Attempts != 0 || !n.NextAttemptAt.Equal(now.Add(time.Minute)) {
+		t.Errorf("expected notification to the rate limited target to be postponed without an attempt, got %+v", n)
+	}
+}
+
+func Test_DispatchNotifications_GivesUp(t *testing.T) {
+	notifier := &scriptedNotifier{errs: []error{fmt.Errorf("%w: can't parse entities", services.ErrNotificationRejected)}}
+	usecase, outbox, _ := newTestDispatcher(notifier)
+	outbox.add("<b>broken")
+
+	if err := usecase.Perform(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	n := outbox.get("1")
+	if n.Status != models.NotificationStatusFailed || n.LastError == nil {
+		t.Errorf("expected malformed notification to fail without retries, got %+v", n)
+	}
+}
+
+func Test_DispatchNotifications_StopsCleanly(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Shutdown is requested while the first message is being sent
+	notifier := &scriptedNotifier{onSend: func(sendCtx context.Context) {
+		cancel()
+		if sendCtx.Err() != nil {
+			t.Errorf("expected message being sent not to be aborted")
+		}
+	}}
+	usecase, outbox, now := newTestDispatcher(notifier)
+	outbox.add("first", "second", "third")
+
+	if err := usecase.Perform(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !slices.Equal(notifier.sent, []string{"first"}) {
+		t.Fatalf("expected only the message in flight to be sent, got %v", notifier.sent)
+	}
+	if outbox.get("1").Status != models.NotificationStatusSent {
+		t.Errorf("expected message in flight to be marked sent")
+	}
+	for _, id := range []string{"2", "3"} {
+		if n := outbox.get(id); n.Status != models.NotificationStatusPending || !n.NextAttemptAt.Equal(*now) {
+			t.Errorf("expected notification %s to be released for other dispatchers, got %+v", id, n)
+		}
+	}
+}
+
+func Test_DispatchNotifications_KeepsMinInterval(t *testing.T) {
+	finance := models.NotificationTarget{Channel: models.NotificationChannelWebhook, Recipient: "finance"}
+	notifier := &targetNotifier{}
+	usecase, outbox, now := newLimitedTestDispatcher(notifier, &NotificationLimits{MinInterval: 3 * time.Second})
+	outbox.add("first", "second", "third")
+	outbox.addTo(finance, "refund")
+
+	if err := usecase.Perform(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !slices.Equal(notifier.sent, []string{"first", "refund"}) {
+		t.Fatalf("expected one notification per target, sent %v", notifier.sent)
+	}
+	for _, id := range []string{"2", "3"} {
+		if n := outbox.get(id); n.Attempts != 0 || !n.NextAttemptAt.Equal(now.Add(3*time.Second)) {
+			t.Errorf("expected notification %s to be postponed by the min interval, got %+v", id, n)
+		}
+	}
+
+	for range 2 {
+		*now = now.Add(3 * time.Second)
+		if err := usecase.Perform(context.Background()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if !slices.Equal(notifier.sent, []string{"first", "refund", "second", "third"}) {
+		t.Errorf("expected a notification every 3 seconds, sent %v", notifier.sent)
+	}
+}
+
+func Test_DispatchNotifications_CollapsesBursts(t *testing.T) {
+	notifier := &scriptedNotifier{}
+	limits := &NotificationLimits{BurstThreshold: 3, BurstWindow: time.Minute}
+	usecase, outbox, now := newLimitedTestDispatcher(notifier, limits)
+
+	// Within the threshold notifications are sent as they are
+	outbox.addPurchases(typeRenewal, 4.99, 4.99)
+	outbox.add("digest")
+	if err := usecase.Perform(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !slices.Equal(notifier.sent, []string{"RENEWAL $4.99", "RENEWAL $4.99", "digest"}) {
+		t.Fatalf("expected notifications within the threshold to be sent, got %v", notifier.sent)
+	}
+
+	// The threshold is reached, so the burst waits for the next window
+	notifier.sent = nil
+	*now = now.Add(10 * time.Second)
+	outbox.addPurchases(typeRenewal, 4.99, 9.99, 4.99)
+	outbox.addPurchases(typeInitialPurchase, 19.99)
+	outbox.addPurchases(typeCancellation, 0)
+	if err := usecase.Perform(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(notifier.sent) != 0 {
+		t.Fatalf("expected burst to be postponed, sent %v", notifier.sent)
+	}
+	windowEnd := now.Add(50 * time.Second)
+	for _, id := range []string{"4", "5", "6", "7", "8"} {
+		if n := outbox.get(id); n.Status != models.NotificationStatusPending || !n.NextAttemptAt.Equal(windowEnd) {
+			t.Errorf("expected notification %s to be postponed until the window ends, got %+v", id, n)
+		}
+	}
+
+	*now = windowEnd
+	if err := usecase.Perform(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "en: 5 in 1m0s, $39.96, RENEWAL 3, CANCELLATION 1, INITIAL_PURCHASE 1"
+	if !slices.Equal(notifier.sent, []string{want}) {
+		t.Fatalf("expected a single summary, got %v", notifier.sent)
+	}
+	for _, id := range []string{"4", "5", "6", "7", "8"} {
+		if n := outbox.get(id); n.Status != models.NotificationStatusCoalesced {
+			t.Errorf("expected notification %s to be coalesced, got %s", id, n.Status)
+		}
+	}
+}
+
+func Test_NotificationRetryDelay(t *testing.T) {
+	tests := []struct {
+		name      string
+		attempt   int
+		err       error
+		wantDelay time.Duration
+		wantRetry bool
+	}{
+		{name: "first failure", attempt: 1, err: errors.New("timeout"), wantDelay: 5 * time.Second, wantRetry: true},
+		{name: "exponential", attempt: 4, err: errors.New("timeout"), wantDelay: 40 * time.Second, wantRetry: true},
+		{name: "last retry", attempt: notificationMaxAttempts - 1, err: errors.New("timeout"), wantDelay: 21*time.Minute + 20*time.Second, wantRetry: true},
+		{name: "attempts exhausted", attempt: notificationMaxAttempts, err: errors.New("timeout")},
+		{name: "rejected", attempt: 1, err: fmt.Errorf("%w: chat not found", services.ErrNotificationRejected)},
+		{name: "retry after", attempt: 3, err: &services.RateLimitedError{RetryAfter: 17 * time.Second}, wantDelay: 17 * time.Second, wantRetry: true},
+		{name: "retry after beyond attempts", attempt: 20, err: &services.RateLimitedError{RetryAfter: time.Second}, wantDelay: time.Second, wantRetry: true},
+		{name: "retry after without value", attempt: 1, err: &services.RateLimitedError{}, wantDelay: time.Second, wantRetry: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			delay, retry := notificationRetryDelay(tt.attempt, tt.err)
+			if delay != tt.wantDelay || retry != tt.wantRetry {
+				t.Errorf("expected (%s, %t), got (%s, %t)", tt.wantDelay, tt.wantRetry, delay, retry)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"athylps/internal/models"
+	"athylps/internal/repositories"
+)
+
+// UserDataExport is everything we hold about the user.
+type UserDataExport struct {
+	ExportedAt time.Time
+	User       *models.User
+	// App user ids the app has logged in to RevenueCat with, anonymous ones identify installations of the app
+	Devices        []*models.RevenueCatCustomer
+	Subscriptions  []*models.Subscription
+	PurchaseEvents []*models.PurchaseEvent
+	// Events received from stores about the user's purchases, as the stores sent them
+	StoreEvents []*models.WebhookEvent
+}
+
+type revenueCatCustomerLister interface {
+	ListByUserID(ctx context.Context, userID string) ([]*models.RevenueCatCustomer, error)
+}
+
+// ExportUserDataUsecase collects the user's personal data for a data export request.
+type ExportUserDataUsecase struct {
+	customers     revenueCatCustomerLister
+	subscriptions userSubscriptionsLister
+	purchases     userPurchaseEventsLister
+	events        webhookEventLister
+	now           func() time.Time
+}
+
+func NewExportUserDataUsecase(
+	customers revenueCatCustomerLister,
+	subscriptions userSubscriptionsLister,
+	purchases userPurchaseEventsLister,
+	events webhookEventLister,
+) *ExportUserDataUsecase {
+	return &ExportUserDataUsecase{
+		customers:     customers,
+		subscriptions: subscriptions,
+		purchases:     purchases,
+		events:        events,
+		now:           time.Now,
+	}
+}
+
+func (u *ExportUserDataUsecase) Perform(ctx context.Context, user *models.User) (*UserDataExport, error) {
+	customers, err := u.customers.ListByUserID(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load revenuecat customers: %w", err)
+	}
+
+	subscriptions, err := u.subscriptions.ListByUserID(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load subscriptions: %w", err)
+	}
+
+	purchases, err := u.purchases.ListByUserID(ctx, user.ID, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load purchase events: %w", err)
+	}
+
+	events, err := u.events.List(ctx, &repositories.ListWebhookEventsParams{UserID: &user.ID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load webhook events: %w", err)
+	}
+
+	return &UserDataExport{
+		ExportedAt:     u.now(),
+		User:           user,
+		Devices:        customers,
+		Subscriptions:  subscriptions,
+		PurchaseEvents: purchases,
+		StoreEvents:    events,
+	}, nil
+}
@@ -0,0 +1,115 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"athylps/internal/models"
+)
+
+type userSubscriptionsLister interface {
+	ListByUserID(ctx context.Context, userID string) ([]*models.Subscription, error)
+}
+
+// GetEntitlementsUsecase computes entitlements the user has right now from the stored subscriptions.
+type GetEntitlementsUsecase struct {
+	subscriptions userSubscriptionsLister
+	now           func() time.Time
+}
+
+func NewGetEntitlementsUsecase(subscriptions userSubscriptionsLister) *GetEntitlementsUsecase {
+	return &GetEntitlementsUsecase{
+		subscriptions: subscriptions,
+		now:           time.Now,
+	}
+}
+
+func (u *GetEntitlementsUsecase) Perform(ctx context.Context, userID string) ([]*models.Entitlement, error) {
+	subscriptions, err := u.subscriptions.ListByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load subscriptions: %w", err)
+	}
+
+	return activeEntitlements(subscriptions, u.now()), nil
+}
+
+// activeEntitlements returns entitlements granted by active subscriptions sorted by id.
+// If several subscriptions grant the same entitlement, the one that lasts longer wins.
+func activeEntitlements(subscriptions []*models.Subscription, now time.Time) []*models.Entitlement {
+	byID := map[string]*models.Entitlement{}
+	for _, s := range subscriptions {
+		activeUntil, ok := subscriptionActiveUntil(s, now)
+		if !ok {
+			continue
+		}
+
+		for _, id := range s.EntitlementIDs {
+			current, exists := byID[id]
+			if exists && !lastsLonger(activeUntil, entitlementActiveUntil(current)) {
+				continue
+			}
+
+			entitlement := &models.Entitlement{
+				ID:              id,
+				ProductID:       s.ProductID,
+				Store:           s.Store,
+				PurchasedAt:     s.PurchasedAt,
+				ExpiresAt:       s.ExpiresAt,
+				IsInGracePeriod: s.Status == models.SubscriptionStatusGracePeriod,
+				WillRenew:       s.Status == models.SubscriptionStatusActive && s.ExpiresAt != nil,
+				IsSandbox:       isSandbox(s.Environment),
+			}
+			if entitlement.IsInGracePeriod {
+				entitlement.GracePeriodExpiresAt = s.GracePeriodExpiresAt
+			}
+			byID[id] = entitlement
+		}
+	}
+
+	entitlements := make([]*models.Entitlement, 0, len(byID))
+	for _, e := range byID {
+		entitlements = append(entitlements, e)
+	}
+	slices.SortFunc(entitlements, func(a, b *models.Entitlement) int {
+		return strings.Compare(a.ID, b.ID)
+	})
+
+	return entitlements
+}
+
+// subscriptionActiveUntil reports whether the subscription is active and returns its end, nil if it never ends.
+func subscriptionActiveUntil(s *models.Subscription, now time.Time) (*time.Time, bool) {
+	switch s.Status {
+	case models.SubscriptionStatusActive, models.SubscriptionStatusCancelled:
+		if s.ExpiresAt == nil {
+			return nil, true
+		}
+		return s.ExpiresAt, s.ExpiresAt.After(now)
+	case models.SubscriptionStatusGracePeriod:
+		end := s.GracePeriodExpiresAt
+		if end == nil {
+			end = s.ExpiresAt
+		}
+		return end, end != nil && end.After(now)
+	}
+
+	return nil, false
+}
+
+func entitlementActiveUntil(e *models.Entitlement) *time.Time {
+	if e.IsInGracePeriod && e.GracePeriodExpiresAt != nil {
+		return e.GracePeriodExpiresAt
+	}
+	return e.ExpiresAt
+}
+
+// lastsLonger reports whether the end a is later than b, nil means it never ends.
+func lastsLonger(a *time.Time, b *time.Time) bool {
+	if b == nil {
+		return false
+	}
+	return a == nil || a.After(*b)
+}
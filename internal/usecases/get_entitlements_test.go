@@ -0,0 +1,140 @@
+package usecases
+
+import (
+	"testing"
+	"time"
+
+	"athylps/internal/models"
+)
+
+func Test_ActiveEntitlements(t *testing.T) {
+	now := time.Date(2025, 11, 29, 12, 0, 0, 0, time.UTC)
+	at := func(days int) *time.Time {
+		t := now.AddDate(0, 0, days)
+		return &t
+	}
+	subscription := func(productID string, status models.SubscriptionStatus, expiresAt *time.Time) *models.Subscription {
+		return &models.Subscription{
+			ProductID:      productID,
+			EntitlementIDs: []string{"premium"},
+			Store:          "APP_STORE",
+			Environment:    ptr("PRODUCTION"),
+			Status:         status,
+			ExpiresAt:      expiresAt,
+		}
+	}
+
+	inGrace := subscription("premium_monthly", models.SubscriptionStatusGracePeriod, at(-1))
+	inGrace.GracePeriodExpiresAt = at(6)
+	graceEnded := subscription("premium_monthly", models.SubscriptionStatusGracePeriod, at(-10))
+	graceEnded.GracePeriodExpiresAt = at(-3)
+	sandbox := subscription("premium_monthly", models.SubscriptionStatusActive, at(1))
+	sandbox.Environment = ptr("SANDBOX")
+	// Any environment but PRODUCTION isn't real money, not only SANDBOX
+	rustoreTest := subscription("premium_monthly", models.SubscriptionStatusActive, at(1))
+	rustoreTest.Environment = ptr("TEST")
+	production := subscription("premium_monthly", models.SubscriptionStatusActive, at(1))
+	production.Environment = ptr("PRODUCTION")
+	noEntitlements := subscription("coins_100", models.SubscriptionStatusActive, nil)
+	noEntitlements.EntitlementIDs = nil
+
+	tests := []struct {
+		name          string
+		subscriptions []*models.Subscription
+		want          *models.Entitlement
+	}{
+		{
+			name:          "active subscription",
+			subscriptions: []*models.Subscription{subscription("premium_monthly", models.SubscriptionStatusActive, at(10))},
+			want:          &models.Entitlement{ID: "premium", ProductID: "premium_monthly", WillRenew: true},
+		},
+		{
+			name:          "cancelled but not expired yet",
+			subscriptions: []*models.Subscription{subscription("premium_monthly", models.SubscriptionStatusCancelled, at(10))},
+			want:          &models.Entitlement{ID: "premium", ProductID: "premium_monthly"},
+		},
+		{
+			name:          "expired by time",
+			subscriptions: []*models.Subscription{subscription("premium_monthly", models.SubscriptionStatusActive, at(-1))},
+		},
+		{
+			name:          "expired status",
+			subscriptions: []*models.Subscription{subscription("premium_monthly", models.SubscriptionStatusExpired, at(10))},
+		},
+		{
+			name:          "paused",
+			subscriptions: []*models.Subscription{subscription("premium_monthly", models.SubscriptionStatusPaused, at(10))},
+		},
+		{
+			name:          "in grace period",
+			subscriptions: []*models.Subscription{inGrace},
+			want:          &models.Entitlement{ID: "premium", ProductID: "premium_monthly", IsInGracePeriod: true},
+		},
+		{
+			name:          "grace period ended",
+			subscriptions: []*models.Subscription{graceEnded},
+		},
+		{
+			name:          "lifetime purchase",
+			subscriptions: []*models.Subscription{subscription("premium_lifetime", models.SubscriptionStatusActive, nil)},
+			want:          &models.Entitlement{ID: "premium", ProductID: "premium_lifetime"},
+		},
+		{
+			name: "longest subscription wins",
+			subscriptions: []*models.Subscription{
+				subscription("premium_monthly", models.SubscriptionStatusActive, at(10)),
+				subscription("premium_yearly", models.SubscriptionStatusCancelled, at(200)),
+				subscription("premium_weekly", models.SubscriptionStatusActive, at(3)),
+			},
+			want: &models.Entitlement{ID: "premium", ProductID: "premium_yearly"},
+		},
+		{
+			name: "lifetime wins over subscription",
+			subscriptions: []*models.Subscription{
+				subscription("premium_lifetime", models.SubscriptionStatusActive, nil),
+				subscription("premium_yearly", models.SubscriptionStatusActive, at(200)),
+			},
+			want: &models.Entitlement{ID: "premium", ProductID: "premium_lifetime"},
+		},
+		{
+			name:          "sandbox",
+			subscriptions: []*models.Subscription{sandbox},
+			want:          &models.Entitlement{ID: "premium", ProductID: "premium_monthly", WillRenew: true, IsSandbox: true},
+		},
+		{
+			name:          "non-production environment",
+			subscriptions: []*models.Subscription{rustoreTest},
+			want:          &models.Entitlement{ID: "premium", ProductID: "premium_monthly", WillRenew: true, IsSandbox: true},
+		},
+		{
+			name:          "production",
+			subscriptions: []*models.Subscription{production},
+			want:          &models.Entitlement{ID: "premium", ProductID: "premium_monthly", WillRenew: true},
+		},
+		{
+			name:          "product without entitlements",
+			subscriptions: []*models.Subscription{noEntitlements},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := activeEntitlements(tt.subscriptions, now)
+
+			if tt.want == nil {
+				if len(got) != 0 {
+					t.Fatalf("expected no entitlements, got %+v", got[0])
+				}
+				return
+			}
+			if len(got) != 1 {
+				t.Fatalf("expected 1 entitlement, got %d", len(got))
+			}
+			e := got[0]
+			if e.ID != tt.want.ID || e.ProductID != tt.want.ProductID || e.IsInGracePeriod != tt.want.IsInGracePeriod ||
+				e.WillRenew != tt.want.WillRenew || e.IsSandbox != tt.want.IsSandbox {
+				t.Errorf("expected %+v, got %+v", tt.want, e)
+			}
+		})
+	}
+}
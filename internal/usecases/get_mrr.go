@@ -0,0 +1,105 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"athylps/internal/models"
+)
+
+// averageMonth is the length of a month subscription periods are normalized to.
+const averageMonth = time.Duration(365.25 / 12 * 24 * float64(time.Hour))
+
+// MRR is the monthly recurring revenue of active subscriptions in USD.
+type MRR struct {
+	Amount        float64
+	Subscriptions int // Active subscriptions
+	Unpriced      int // Active subscriptions of products without a known price, not included in Amount
+}
+
+type activeSubscriptionsLister interface {
+	ListExpiringAfter(ctx context.Context, at time.Time) ([]*models.Subscription, error)
+}
+
+type productPricesGetter interface {
+	LatestPrices(ctx context.Context, productIDs []string, eventTypes []string) (map[string]float64, error)
+}
+
+// GetMRRUsecase estimates MRR from the stored subscriptions and prices of recorded purchase events.
+// Each active subscription contributes the latest price of its product normalized by the length of its
+// current period, so yearly subscriptions contribute a twelfth of their price. Sandbox subscriptions
+// are only counted if configured.
+type GetMRRUsecase struct {
+	subscriptions  activeSubscriptionsLister
+	prices         productPricesGetter
+	includeSandbox bool
+	now            func() time.Time
+}
+
+func NewGetMRRUsecase(subscriptions activeSubscriptionsLister, prices productPricesGetter, includeSandbox bool) *GetMRRUsecase {
+	return &GetMRRUsecase{
+		subscriptions:  subscriptions,
+		prices:         prices,
+		includeSandbox: includeSandbox,
+		now:            time.Now,
+	}
+}
+
+func (u *GetMRRUsecase) Perform(ctx context.Context) (*MRR, error) {
+	now := u.now()
+	subscriptions, err := u.subscriptions.ListExpiringAfter(ctx, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list active subscriptions: %w", err)
+	}
+
+	var active []*models.Subscription
+	var productIDs []string
+	for _, s := range subscriptions {
+		if _, ok := subscriptionActiveUntil(s, now); !ok {
+			continue
+		}
+		if isSandbox(s.Environment) && !u.includeSandbox {
+			continue
+		}
+		if s.Store == models.StoreManual {
+			continue
+		}
+		active = append(active, s)
+		if !slices.Contains(productIDs, s.ProductID) {
+			productIDs = append(productIDs, s.ProductID)
+		}
+	}
+
+	mrr := &MRR{Subscriptions: len(active)}
+	if len(active) == 0 {
+		return mrr, nil
+	}
+
+	prices, err := u.prices.LatestPrices(ctx, productIDs, []string{typeInitialPurchase, typeRenewal})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get product prices: %w", err)
+	}
+
+	for _, s := range active {
+		price, ok := prices[s.ProductID]
+		if !ok {
+			mrr.Unpriced++
+			continue
+		}
+		mrr.Amount += monthlyPrice(price, s)
+	}
+
+	return mrr, nil
+}
+
+// monthlyPrice normalizes the price of the subscription period to a month.
+// Subscriptions without a known period are assumed to be monthly.
+func monthlyPrice(price float64, s *models.Subscription) float64 {
+	if s.PurchasedAt == nil || s.ExpiresAt == nil || !s.ExpiresAt.After(*s.PurchasedAt) {
+		return price
+	}
+
+	return price * float64(averageMonth) / float64(s.ExpiresAt.Sub(*s.PurchasedAt))
+}
@@ -0,0 +1,65 @@
+package usecases
+
+import (
+	"context"
+	"math"
+	"slices"
+	"testing"
+	"time"
+
+	"athylps/internal/models"
+)
+
+type fakeActiveSubscriptionsLister []*models.Subscription
+
+func (l fakeActiveSubscriptionsLister) ListExpiringAfter(context.Context, time.Time) ([]*models.Subscription, error) {
+	return l, nil
+}
+
+type fakeProductPrices struct {
+	prices     map[string]float64
+	productIDs []string
+}
+
+func (p *fakeProductPrices) LatestPrices(_ context.Context, productIDs []string, _ []string) (map[string]float64, error) {
+	p.productIDs = productIDs
+	return p.prices, nil
+}
+
+func Test_GetMRR(t *testing.T) {
+	now := time.Date(2025, 12, 8, 12, 0, 0, 0, time.UTC)
+	subscription := func(productID string, status models.SubscriptionStatus, purchasedAt time.Time, expiresAt time.Time) *models.Subscription {
+		return &models.Subscription{ProductID: productID, Status: status, PurchasedAt: &purchasedAt, ExpiresAt: &expiresAt}
+	}
+	subscriptions := fakeActiveSubscriptionsLister{
+		subscription("monthly", models.SubscriptionStatusActive, now.Add(-averageMonth/2), now.Add(averageMonth/2)),
+		subscription("monthly", models.SubscriptionStatusCancelled, now.Add(-averageMonth/2), now.Add(averageMonth/2)),
+		subscription("yearly", models.SubscriptionStatusActive, now.AddDate(-1, 0, 1), now.AddDate(0, 0, 1)),
+		subscription("weekly", models.SubscriptionStatusActive, now.AddDate(0, 0, -1), now.AddDate(0, 0, 6)),
+		subscription("monthly", models.SubscriptionStatusPaused, now.Add(-averageMonth/2), now.Add(averageMonth/2)),
+		subscription("monthly", models.SubscriptionStatusActive, now.Add(-averageMonth/2), now.Add(averageMonth/2)),
+	}
+	subscriptions[len(subscriptions)-1].Environment = ptr("SANDBOX")
+	manual := subscription("manual:premium", models.SubscriptionStatusActive, now.AddDate(0, 0, -1), now.AddDate(0, 1, 0))
+	manual.Store = models.StoreManual
+	subscriptions = append(subscriptions, manual)
+	prices := &fakeProductPrices{prices: map[string]float64{"monthly": 5, "yearly": 48}}
+	usecase := NewGetMRRUsecase(subscriptions, prices, false)
+	usecase.now = func() time.Time { return now }
+
+	mrr, err := usecase.Perform(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !slices.Equal(prices.productIDs, []string{"monthly", "yearly", "weekly"}) {
+		t.Errorf("expected prices of active products, got %v", prices.productIDs)
+	}
+	if mrr.Subscriptions != 4 || mrr.Unpriced != 1 {
+		t.Errorf("expected 4 active subscriptions with 1 unpriced, got %+v", mrr)
+	}
+	// Two monthly subscriptions and the 365 days long yearly one normalized to a month
+	if want := 5 + 5 + 48*float64(averageMonth)/float64(365*24*time.Hour); math.Abs(mrr.Amount-want) > 0.001 {
+		t.Errorf("expected MRR %.3f, got %.3f", want, mrr.Amount)
+	}
+}
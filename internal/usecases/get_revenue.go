@@ -0,0 +1,37 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"athylps/internal/services"
+)
+
+var ErrInvalidPeriod = errors.New("period must end after it starts")
+
+// GetRevenueUsecase totals purchase events of an arbitrary period the way digests do.
+type GetRevenueUsecase struct {
+	purchases purchaseStatsRepository
+}
+
+func NewGetRevenueUsecase(purchases purchaseStatsRepository) *GetRevenueUsecase {
+	return &GetRevenueUsecase{
+		purchases: purchases,
+	}
+}
+
+// Perform returns totals of events that occurred from the start of the period till its end, exclusive.
+func (u *GetRevenueUsecase) Perform(ctx context.Context, from time.Time, to time.Time) (*services.DigestData, error) {
+	if !to.After(from) {
+		return nil, ErrInvalidPeriod
+	}
+
+	stats, err := u.purchases.Stats(ctx, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get revenue stats: %w", err)
+	}
+
+	return buildDigestData("", from, to, stats), nil
+}
@@ -0,0 +1,40 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// GetRevenueReportUsecase renders the revenue digest of the current day or week so far.
+type GetRevenueReportUsecase struct {
+	location  *time.Location
+	purchases purchaseStatsRepository
+	templates digestRenderer
+	now       func() time.Time
+}
+
+func NewGetRevenueReportUsecase(
+	location *time.Location,
+	purchases purchaseStatsRepository,
+	templates digestRenderer,
+) *GetRevenueReportUsecase {
+	return &GetRevenueReportUsecase{
+		location:  location,
+		purchases: purchases,
+		templates: templates,
+		now:       time.Now,
+	}
+}
+
+// Perform returns the report of the kind, services.DigestDaily or services.DigestWeekly.
+func (u *GetRevenueReportUsecase) Perform(ctx context.Context, kind string) (string, error) {
+	from, to := currentPeriod(kind, u.now().In(u.location))
+
+	stats, err := u.purchases.Stats(ctx, from, to)
+	if err != nil {
+		return "", fmt.Errorf("failed to get %s revenue report: %w", kind, err)
+	}
+
+	return u.templates.RenderDigest("", buildDigestData(kind, from, to, stats))
+}
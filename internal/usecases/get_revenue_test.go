@@ -0,0 +1,39 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"athylps/internal/models"
+)
+
+func Test_GetRevenue(t *testing.T) {
+	from := time.Date(2025, 12, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, 7)
+	stats := &fakePurchaseStats{stats: []*models.PurchaseStats{
+		{EventType: typeInitialPurchase, Store: "APP_STORE", Count: 2, Revenue: 9.98},
+		{EventType: typeRenewal, Store: "RU_STORE", Count: 1, Revenue: 3},
+		{EventType: typeRefund, Store: "APP_STORE", Count: 1, Revenue: 4.99},
+	}}
+	usecase := NewGetRevenueUsecase(stats)
+
+	revenue, err := usecase.Perform(context.Background(), from, to)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(stats.requests) != 1 || stats.requests[0] != [2]time.Time{from, to} {
+		t.Errorf("expected stats of the period, got %v", stats.requests)
+	}
+	if revenue.Revenue != 12.98 || revenue.Purchases.Count != 2 || revenue.Refunds.Revenue != 4.99 || len(revenue.ByStore) != 2 {
+		t.Errorf("unexpected revenue: %+v", revenue)
+	}
+	if !revenue.Until.Equal(from.AddDate(0, 0, 6)) {
+		t.Errorf("expected the last day of the period to be %v, got %v", from.AddDate(0, 0, 6), revenue.Until)
+	}
+
+	if _, err := usecase.Perform(context.Background(), to, from); !errors.Is(err, ErrInvalidPeriod) {
+		t.Errorf("expected ErrInvalidPeriod, got %v", err)
+	}
+}
@@ -0,0 +1,74 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"athylps/internal/models"
+)
+
+const maxUserPurchaseEvents = 100
+
+// UserDetails is everything admins need to sort out a user's purchases.
+type UserDetails struct {
+	User *models.User
+	// All subscriptions of the user, expired ones and manual grants included
+	Subscriptions  []*models.Subscription
+	Entitlements   []*models.Entitlement
+	PurchaseEvents []*models.PurchaseEvent // The latest ones, the most recent first
+}
+
+type userWithDeletedGetter interface {
+	GetUserByIDWithDeleted(ctx context.Context, id string) (*models.User, error)
+}
+
+type userPurchaseEventsLister interface {
+	ListByUserID(ctx context.Context, userID string, limit uint64) ([]*models.PurchaseEvent, error)
+}
+
+// GetUserDetailsUsecase returns the user, deleted or not, with the subscription history.
+type GetUserDetailsUsecase struct {
+	users         userWithDeletedGetter
+	subscriptions userSubscriptionsLister
+	purchases     userPurchaseEventsLister
+	now           func() time.Time
+}
+
+func NewGetUserDetailsUsecase(
+	users userWithDeletedGetter,
+	subscriptions userSubscriptionsLister,
+	purchases userPurchaseEventsLister,
+) *GetUserDetailsUsecase {
+	return &GetUserDetailsUsecase{
+		users:         users,
+		subscriptions: subscriptions,
+		purchases:     purchases,
+		now:           time.Now,
+	}
+}
+
+// Perform returns repositories.ErrNotFound if there is no user with the id.
+func (u *GetUserDetailsUsecase) Perform(ctx context.Context, userID string) (*UserDetails, error) {
+	user, err := u.users.GetUserByIDWithDeleted(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	subscriptions, err := u.subscriptions.ListByUserID(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load subscriptions: %w", err)
+	}
+
+	purchases, err := u.purchases.ListByUserID(ctx, user.ID, maxUserPurchaseEvents)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load purchase events: %w", err)
+	}
+
+	return &UserDetails{
+		User:           user,
+		Subscriptions:  subscriptions,
+		Entitlements:   activeEntitlements(subscriptions, u.now()),
+		PurchaseEvents: purchases,
+	}, nil
+}
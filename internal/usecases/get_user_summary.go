@@ -0,0 +1,55 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"athylps/internal/models"
+)
+
+// UserSummary is what support needs to know about a user at a glance.
+type UserSummary struct {
+	User          *models.User
+	Subscriptions []*models.Subscription
+	Entitlements  []*models.Entitlement
+}
+
+type userByEmailGetter interface {
+	GetUserByEmail(ctx context.Context, email string) (*models.User, error)
+}
+
+// GetUserSummaryUsecase finds the user by email with their subscriptions and active entitlements.
+type GetUserSummaryUsecase struct {
+	users         userByEmailGetter
+	subscriptions userSubscriptionsLister
+	now           func() time.Time
+}
+
+func NewGetUserSummaryUsecase(users userByEmailGetter, subscriptions userSubscriptionsLister) *GetUserSummaryUsecase {
+	return &GetUserSummaryUsecase{
+		users:         users,
+		subscriptions: subscriptions,
+		now:           time.Now,
+	}
+}
+
+// Perform returns repositories.ErrNotFound if there is no user with the email.
+func (u *GetUserSummaryUsecase) Perform(ctx context.Context, email string) (*UserSummary, error) {
+	user, err := u.users.GetUserByEmail(ctx, strings.TrimSpace(email))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user by email: %w", err)
+	}
+
+	subscriptions, err := u.subscriptions.ListByUserID(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load subscriptions: %w", err)
+	}
+
+	return &UserSummary{
+		User:          user,
+		Subscriptions: subscriptions,
+		Entitlements:  activeEntitlements(subscriptions, u.now()),
+	}, nil
+}
@@ -0,0 +1,96 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"athylps/internal/logging"
+	"athylps/internal/models"
+
+	"go.uber.org/zap"
+)
+
+const (
+	typeManualGrant  = "MANUAL_GRANT"
+	typeManualRevoke = "MANUAL_REVOKE"
+)
+
+var ErrGrantExpired = errors.New("entitlement grant must expire in the future")
+
+type GrantEntitlementParams struct {
+	UserID        string
+	EntitlementID string
+	ExpiresAt     *time.Time // nil grants the entitlement forever
+	GrantedBy     string     // Admin who grants the entitlement
+}
+
+type manualSubscriptionRepository interface {
+	Get(ctx context.Context, appUserID string, productID string) (*models.Subscription, error)
+	Upsert(ctx context.Context, s *models.Subscription) (*models.Subscription, error)
+}
+
+// GrantEntitlementUsecase grants an entitlement to the user by hand, e.g. to compensate an outage or for testers.
+// The grant is a subscription of StoreManual, granting the same entitlement again replaces its expiration.
+type GrantEntitlementUsecase struct {
+	users         userGetter
+	subscriptions manualSubscriptionRepository
+	logger        *zap.Logger
+	now           func() time.Time
+}
+
+func NewGrantEntitlementUsecase(users userGetter, subscriptions manualSubscriptionRepository, logger *zap.Logger) *GrantEntitlementUsecase {
+	return &GrantEntitlementUsecase{
+		users:         users,
+		subscriptions: subscriptions,
+		logger:        logger,
+		now:           time.Now,
+	}
+}
+
+// Perform returns repositories.ErrNotFound if the user doesn't exist or has been deleted.
+func (u *GrantEntitlementUsecase) Perform(ctx context.Context, params *GrantEntitlementParams) (*models.Subscription, error) {
+	now := u.now()
+	if params.ExpiresAt != nil && !params.ExpiresAt.After(now) {
+		return nil, ErrGrantExpired
+	}
+
+	user, err := u.users.GetUserByID(ctx, params.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	appUserID, productID := manualSubscriptionKey(user.ID, params.EntitlementID)
+	granted, err := u.subscriptions.Upsert(ctx, &models.Subscription{
+		AppUserID:      appUserID,
+		UserID:         &user.ID,
+		ProductID:      productID,
+		EntitlementIDs: []string{params.EntitlementID},
+		Store:          models.StoreManual,
+		Status:         models.SubscriptionStatusActive,
+		PurchasedAt:    &now,
+		ExpiresAt:      params.ExpiresAt,
+		LastEventType:  typeManualGrant,
+		LastEventAt:    &now,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to grant entitlement: %w", err)
+	}
+
+	logging.FromContext(ctx, u.logger).Info(
+		"granted entitlement",
+		zap.String("user_id", user.ID),
+		zap.String("entitlement_id", params.EntitlementID),
+		zap.Timep("expires_at", params.ExpiresAt),
+		zap.String("granted_by", params.GrantedBy),
+	)
+
+	return granted, nil
+}
+
+// manualSubscriptionKey returns the app user id and product id of the subscription granting the entitlement by hand,
+// they never clash with store ones.
+func manualSubscriptionKey(userID string, entitlementID string) (string, string) {
+	return "manual:" + userID, "manual:" + entitlementID
+}
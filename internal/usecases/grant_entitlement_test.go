@@ -0,0 +1,85 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"athylps/internal/models"
+	"athylps/internal/repositories"
+
+	"go.uber.org/zap"
+)
+
+func Test_GrantAndRevokeEntitlement(t *testing.T) {
+	now := time.Date(2025, 12, 8, 12, 0, 0, 0, time.UTC)
+	userID := "019a7b5e-4f2c-7c1d-9a3e-2b1f0c9d8e7a"
+	subscriptions := newFakeSubscriptionRepository()
+	grant := NewGrantEntitlementUsecase(fakeUserGetter{userID: {ID: userID}}, subscriptions, zap.NewNop())
+	grant.now = func() time.Time { return now }
+	revoke := NewRevokeEntitlementUsecase(subscriptions, zap.NewNop())
+	revoke.now = func() time.Time { return now.Add(time.Hour) }
+	entitlements := func(at time.Time) []*models.Entitlement {
+		var all []*models.Subscription
+		for _, s := range subscriptions.subscriptions {
+			all = append(all, s)
+		}
+		return activeEntitlements(all, at)
+	}
+
+	until := now.AddDate(0, 1, 0)
+	_, err := grant.Perform(context.Background(), &GrantEntitlementParams{UserID: userID, EntitlementID: "premium", ExpiresAt: &until, GrantedBy: "admin"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := entitlements(now); len(got) != 1 || got[0].ID != "premium" || got[0].Store != models.StoreManual || !got[0].ExpiresAt.Equal(until) {
+		t.Errorf("expected premium to be granted until %v, got %+v", until, got)
+	}
+	if got := entitlements(until.Add(time.Second)); len(got) != 0 {
+		t.Errorf("expected the grant to expire, got %+v", got)
+	}
+
+	// Granting again replaces the expiration instead of adding another grant
+	if _, err := grant.Perform(context.Background(), &GrantEntitlementParams{UserID: userID, EntitlementID: "premium"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(subscriptions.subscriptions) != 1 {
+		t.Errorf("expected one grant, got %d", len(subscriptions.subscriptions))
+	}
+	if got := entitlements(now.AddDate(10, 0, 0)); len(got) != 1 || got[0].ExpiresAt != nil {
+		t.Errorf("expected premium to be granted forever, got %+v", got)
+	}
+
+	revoked, err := revoke.Perform(context.Background(), &RevokeEntitlementParams{UserID: userID, EntitlementID: "premium", RevokedBy: "admin"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if revoked.Status != models.SubscriptionStatusExpired || revoked.LastEventType != typeManualRevoke {
+		t.Errorf("expected the grant to expire, got %+v", revoked)
+	}
+	if got := entitlements(now.Add(2 * time.Hour)); len(got) != 0 {
+		t.Errorf("expected no entitlements after revoking, got %+v", got)
+	}
+	if _, err := revoke.Perform(context.Background(), &RevokeEntitlementParams{UserID: userID, EntitlementID: "premium"}); err != nil {
+		t.Errorf("expected revoking twice to succeed, got %v", err)
+	}
+}
+
+func Test_GrantEntitlement_Errors(t *testing.T) {
+	now := time.Date(2025, 12, 8, 12, 0, 0, 0, time.UTC)
+	grant := NewGrantEntitlementUsecase(fakeUserGetter{}, newFakeSubscriptionRepository(), zap.NewNop())
+	grant.now = func() time.Time { return now }
+	revoke := NewRevokeEntitlementUsecase(newFakeSubscriptionRepository(), zap.NewNop())
+
+	past := now.Add(-time.Minute)
+	if _, err := grant.Perform(context.Background(), &GrantEntitlementParams{UserID: "1", EntitlementID: "premium", ExpiresAt: &past}); !errors.Is(err, ErrGrantExpired) {
+		t.Errorf("expected grant expiring in the past to be rejected, got %v", err)
+	}
+	if _, err := grant.Perform(context.Background(), &GrantEntitlementParams{UserID: "unknown", EntitlementID: "premium"}); !errors.Is(err, repositories.ErrNotFound) {
+		t.Errorf("expected grant to unknown user to fail with ErrNotFound, got %v", err)
+	}
+	if _, err := revoke.Perform(context.Background(), &RevokeEntitlementParams{UserID: "1", EntitlementID: "premium"}); !errors.Is(err, repositories.ErrNotFound) {
+		t.Errorf("expected revoking a never granted entitlement to fail with ErrNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,75 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"athylps/internal/logging"
+	"athylps/internal/models"
+	"athylps/internal/repositories"
+
+	"go.uber.org/zap"
+)
+
+// ErrForeignAppUserID is returned when the app tries to link an app user id the user doesn't own.
+// The app logs in to RevenueCat with the user's id or Firebase uid, any other id, anonymous ones included,
+// is linked only through aliases reported by RevenueCat.
+var ErrForeignAppUserID = errors.New("app user id must be the user's id or firebase uid")
+
+type LinkRevenueCatCustomerParams struct {
+	UserID      string
+	FirebaseUID *string
+	AppUserID   string
+}
+
+type revenueCatCustomerLinker interface {
+	Link(ctx context.Context, p *repositories.LinkRevenueCatCustomerParams) error
+}
+
+type userGetter interface {
+	GetUserByID(ctx context.Context, id string) (*models.User, error)
+}
+
+// LinkRevenueCatCustomerUsecase links the app user id the app has logged in to RevenueCat with to our user.
+type LinkRevenueCatCustomerUsecase struct {
+	customers revenueCatCustomerLinker
+	users     userGetter
+	logger    *zap.Logger
+}
+
+func NewLinkRevenueCatCustomerUsecase(customers revenueCatCustomerLinker, users userGetter, logger *zap.Logger) *LinkRevenueCatCustomerUsecase {
+	return &LinkRevenueCatCustomerUsecase{
+		customers: customers,
+		users:     users,
+		logger:    logger,
+	}
+}
+
+// Perform returns the updated user, ErrForeignAppUserID if the app user id isn't the user's own,
+// repositories.ErrRevenueCatCustomerLinked if it belongs to another user.
+func (u *LinkRevenueCatCustomerUsecase) Perform(ctx context.Context, params *LinkRevenueCatCustomerParams) (*models.User, error) {
+	// The client can't be trusted with other ids, claiming one would grant its entitlements
+	owned := params.AppUserID == params.UserID || (params.FirebaseUID != nil && params.AppUserID == *params.FirebaseUID)
+	if !owned {
+		return nil, ErrForeignAppUserID
+	}
+
+	err := u.customers.Link(ctx, &repositories.LinkRevenueCatCustomerParams{
+		UserID:       params.UserID,
+		AppUserIDs:   []string{params.AppUserID},
+		RevenueCatID: &params.AppUserID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to link revenuecat customer: %w", err)
+	}
+
+	logging.FromContext(ctx, u.logger).Info("linked revenuecat customer", zap.String("user_id", params.UserID), zap.String("app_user_id", params.AppUserID))
+
+	user, err := u.users.GetUserByID(ctx, params.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get linked user: %w", err)
+	}
+
+	return user, nil
+}
@@ -0,0 +1,53 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func Test_LinkRevenueCatCustomer(t *testing.T) {
+	ctx := context.Background()
+	const userID = "8f14e45f-ceea-467f-a0e6-7a3f3b1e2c9d"
+	customers := &fakeRevenueCatCustomerRepository{customers: map[string]string{"firebase-other": "other-user"}}
+	usecase := NewLinkRevenueCatCustomerUsecase(customers, fakeUserGetter{userID: {ID: userID}}, zap.NewNop())
+
+	for _, tt := range []struct {
+		name      string
+		appUserID string
+		wantErr   error
+	}{
+		{name: "user id", appUserID: userID},
+		{name: "firebase uid", appUserID: "firebase-uid"},
+		{name: "someone else's id", appUserID: "firebase-other", wantErr: ErrForeignAppUserID},
+		{name: "unlinked id", appUserID: "user_42", wantErr: ErrForeignAppUserID},
+		{name: "anonymous id", appUserID: "$RCAnonymousID:992ed7dce7d442838082445dfb1bacbe", wantErr: ErrForeignAppUserID},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			user, err := usecase.Perform(ctx, &LinkRevenueCatCustomerParams{
+				UserID:      userID,
+				FirebaseUID: ptr("firebase-uid"),
+				AppUserID:   tt.appUserID,
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			linkedTo, linked := customers.customers[tt.appUserID]
+			if tt.wantErr != nil {
+				if linked && linkedTo == userID {
+					t.Errorf("expected %s not to be linked to the user", tt.appUserID)
+				}
+				return
+			}
+			if !linked || linkedTo != userID || user.ID != userID {
+				t.Errorf("expected %s to be linked to the user, got %v", tt.appUserID, customers.customers)
+			}
+		})
+	}
+
+	if _, err := usecase.Perform(ctx, &LinkRevenueCatCustomerParams{UserID: userID, AppUserID: "firebase-uid"}); !errors.Is(err, ErrForeignAppUserID) {
+		t.Errorf("expected a firebase uid to be rejected for a user without one, got %v", err)
+	}
+}
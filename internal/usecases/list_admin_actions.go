@@ -0,0 +1,39 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+
+	"athylps/internal/models"
+	"athylps/internal/repositories"
+)
+
+const maxListedAdminActions = 100
+
+type adminActionLister interface {
+	List(ctx context.Context, p *repositories.ListAdminActionsParams) ([]*models.AdminAction, error)
+}
+
+// ListAdminActionsUsecase returns the audit log of the admin API.
+type ListAdminActionsUsecase struct {
+	actions adminActionLister
+}
+
+func NewListAdminActionsUsecase(actions adminActionLister) *ListAdminActionsUsecase {
+	return &ListAdminActionsUsecase{
+		actions: actions,
+	}
+}
+
+// Perform returns actions matching the filter, newest first. The limit is clamped to [1, 100].
+func (u *ListAdminActionsUsecase) Perform(ctx context.Context, params *repositories.ListAdminActionsParams) ([]*models.AdminAction, error) {
+	filter := *params
+	filter.Limit = max(1, min(filter.Limit, maxListedAdminActions))
+
+	actions, err := u.actions.List(ctx, &filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list admin actions: %w", err)
+	}
+
+	return actions, nil
+}
@@ -0,0 +1,37 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+
+	"athylps/internal/models"
+)
+
+const maxLatestPurchases = 50
+
+type latestPurchasesLister interface {
+	ListLatest(ctx context.Context, limit uint64) ([]*models.PurchaseEvent, error)
+}
+
+// ListLatestPurchasesUsecase returns the most recent purchase events.
+type ListLatestPurchasesUsecase struct {
+	purchases latestPurchasesLister
+}
+
+func NewListLatestPurchasesUsecase(purchases latestPurchasesLister) *ListLatestPurchasesUsecase {
+	return &ListLatestPurchasesUsecase{
+		purchases: purchases,
+	}
+}
+
+// Perform returns up to limit events, the limit is clamped to [1, 50].
+func (u *ListLatestPurchasesUsecase) Perform(ctx context.Context, limit int) ([]*models.PurchaseEvent, error) {
+	limit = max(1, min(limit, maxLatestPurchases))
+
+	events, err := u.purchases.ListLatest(ctx, uint64(limit))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list latest purchases: %w", err)
+	}
+
+	return events, nil
+}
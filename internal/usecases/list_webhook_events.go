@@ -0,0 +1,52 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+
+	"athylps/internal/models"
+	"athylps/internal/repositories"
+)
+
+const maxListedWebhookEvents = 100
+
+type webhookEventBrowser interface {
+	webhookEventLister
+	GetByID(ctx context.Context, id string) (*models.WebhookEvent, error)
+}
+
+// ListWebhookEventsUsecase lets admins browse received webhook events, e.g. to find what a store has sent about a user.
+type ListWebhookEventsUsecase struct {
+	events webhookEventBrowser
+}
+
+func NewListWebhookEventsUsecase(events webhookEventBrowser) *ListWebhookEventsUsecase {
+	return &ListWebhookEventsUsecase{
+		events: events,
+	}
+}
+
+// Perform returns events matching the filter, the most recently received first.
+// The limit is clamped to [1, 100].
+func (u *ListWebhookEventsUsecase) Perform(ctx context.Context, params *repositories.ListWebhookEventsParams) ([]*models.WebhookEvent, error) {
+	filter := *params
+	filter.NewestFirst = true
+	filter.Limit = max(1, min(filter.Limit, maxListedWebhookEvents))
+
+	events, err := u.events.List(ctx, &filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook events: %w", err)
+	}
+
+	return events, nil
+}
+
+// Get returns the event with its payload or repositories.ErrNotFound.
+func (u *ListWebhookEventsUsecase) Get(ctx context.Context, id string) (*models.WebhookEvent, error) {
+	event, err := u.events.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook event: %w", err)
+	}
+
+	return event, nil
+}
@@ -0,0 +1,214 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"athylps/internal/models"
+	"athylps/internal/repositories"
+	"athylps/internal/services"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// Refresh the token a bit earlier, so it doesn't expire in the middle of a poll
+	donationAlertsTokenRefreshMargin = 5 * time.Minute
+	// Limits how deep we look into the donations history on every poll
+	donationAlertsMaxPages = 5
+	// Donations are routed as events of this store
+	donationStore = "DONATIONALERTS"
+)
+
+type donationAlertsClient interface {
+	RefreshToken(ctx context.Context, refreshToken string) (*models.OAuthToken, error)
+	GetDonations(ctx context.Context, accessToken string, page int) ([]services.DonationAlertsDonation, error)
+}
+
+// typeDonation is the event type of donation notifications, routes can select them by it.
+var typeDonation = "DONATION"
+
+type donationRepository interface {
+	Create(ctx context.Context, p *repositories.CreateDonationParams) (*models.Donation, error)
+	HasAny(ctx context.Context) (bool, error)
+}
+
+type donationRenderer interface {
+	RenderDonation(locale string, data *services.DonationNotificationData) (string, error)
+}
+
+// PollDonationsUsecase pulls new donations from DonationAlerts, stores them and writes a notification
+// about every new one to the outbox in the same transaction, they are delivered by DispatchNotificationsUsecase.
+type PollDonationsUsecase struct {
+	client    donationAlertsClient
+	tokens    donationAlertsTokenRepository
+	tx        transactor
+	donations donationRepository
+	outbox    notificationOutbox
+	router    notificationRouter
+	templates donationRenderer
+	logger    *zap.Logger
+}
+
+func NewPollDonationsUsecase(
+	client donationAlertsClient,
+	tokens donationAlertsTokenRepository,
+	tx transactor,
+	donations donationRepository,
+	outbox notificationOutbox,
+	router notificationRouter,
+	templates donationRenderer,
+	logger *zap.Logger,
+) *PollDonationsUsecase {
+	return &PollDonationsUsecase{
+		client:    client,
+		tokens:    tokens,
+		tx:        tx,
+		donations: donations,
+		outbox:    outbox,
+		router:    router,
+		templates: templates,
+		logger:    logger,
+	}
+}
+
+func (u *PollDonationsUsecase) Perform(ctx context.Context) error {
+	token, err := u.tokens.Get(ctx)
+	if errors.Is(err, repositories.ErrNotFound) {
+		u.logger.Debug("donationalerts account is not connected, skipping poll")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if time.Until(token.ExpiresAt) < donationAlertsTokenRefreshMargin {
+		token, err = u.refreshToken(ctx, token)
+		if err != nil {
+			return err
+		}
+	}
+
+	// On the very first poll we only remember the existing donations, so the chat isn't flooded with history
+	hasAny, err := u.donations.HasAny(ctx)
+	if err != nil {
+		return err
+	}
+	notify := hasAny
+
+	for page := 1; page <= donationAlertsMaxPages; page++ {
+		donations, err := u.client.GetDonations(ctx, token.AccessToken, page)
+		if err != nil {
+			return err
+		}
+
+		reachedKnown, err := u.storeDonations(ctx, donations, notify)
+		if err != nil {
+			return err
+		}
+		if reachedKnown || len(donations) == 0 || !notify {
+			break
+		}
+	}
+
+	return nil
+}
+
+func (u *PollDonationsUsecase) refreshToken(ctx context.Context, token *models.OAuthToken) (*models.OAuthToken, error) {
+	refreshed, err := u.client.RefreshToken(ctx, token.RefreshToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to refresh donationalerts token: %w", err)
+	}
+
+	if err := u.tokens.Save(ctx, refreshed); err != nil {
+		return nil, err
+	}
+
+	u.logger.Info("refreshed donationalerts token", zap.Time("token_expires_at", refreshed.ExpiresAt))
+
+	return refreshed, nil
+}
+
+// storeDonations stores a page of donations, newest first.
+// Returns true once it reaches a donation that has already been stored before.
+func (u *PollDonationsUsecase) storeDonations(
+	ctx context.Context,
+	donations []services.DonationAlertsDonation,
+	notify bool,
+) (bool, error) {
+	for _, d := range donations {
+		err := u.tx.WithinTx(ctx, func(ctx context.Context) error {
+			donation, err := u.donations.Create(ctx, &repositories.CreateDonationParams{
+				ExternalID: d.ID,
+				Username:   d.Username,
+				Message:    d.Message,
+				Amount:     d.Amount,
+				Currency:   d.Currency,
+				DonatedAt:  d.CreatedAt,
+			})
+			if err != nil {
+				return err
+			}
+
+			if !notify {
+				return nil
+			}
+			return u.enqueueNotifications(ctx, donation)
+		})
+		if errors.Is(err, repositories.ErrAlreadyExists) {
+			return true, nil
+		}
+		if err != nil {
+			return false, err
+		}
+
+		u.logger.Info("received donation", zap.Int64("external_id", d.ID))
+	}
+
+	return false, nil
+}
+
+// enqueueNotifications writes the notification about the donation to the outbox, one per routed target.
+func (u *PollDonationsUsecase) enqueueNotifications(ctx context.Context, d *models.Donation) error {
+	targets := u.router.Route(&services.RouteNotificationParams{EventType: typeDonation, Store: donationStore})
+	if len(targets) == 0 {
+		u.logger.Info("donation notification is discarded by routes", zap.Int64("external_id", d.ExternalID))
+		return nil
+	}
+
+	data := &services.DonationNotificationData{Amount: &services.Money{Amount: d.Amount, Currency: strings.ToUpper(d.Currency)}}
+	if d.Username != nil {
+		data.Username = *d.Username
+	}
+	if d.Message != nil {
+		data.Message = *d.Message
+	}
+
+	texts := make(map[string]string)
+	for _, routed := range targets {
+		text, ok := texts[routed.Locale]
+		if !ok {
+			var err error
+			text, err = u.templates.RenderDonation(routed.Locale, data)
+			if err != nil {
+				return fmt.Errorf("failed to render donation notification: %w", err)
+			}
+			texts[routed.Locale] = text
+		}
+
+		err := u.outbox.Enqueue(ctx, &repositories.EnqueueNotificationParams{
+			Target:    routed.Target,
+			Text:      text,
+			EventType: &typeDonation,
+			Locale:    routed.Locale,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to enqueue donation notification to %s: %w", routed.Target.Channel, err)
+		}
+	}
+
+	return nil
+}
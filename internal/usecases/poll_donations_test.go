@@ -0,0 +1,150 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"athylps/internal/config"
+	"athylps/internal/models"
+	"athylps/internal/repositories"
+	"athylps/internal/services"
+
+	"go.uber.org/zap"
+)
+
+type fakeDonationAlertsAPI struct {
+	mu        sync.Mutex
+	donations []string
+	refreshed int
+}
+
+func (api *fakeDonationAlertsAPI) add(id int, username string, message string) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	donation := fmt.Sprintf(
+		`{"id":%d,"username":%q,"message":%q,"amount":100,"currency":"RUB","created_at":"2025-11-20 09:00:00"}`,
+		id, username, message,
+	)
+	api.donations = append([]string{donation}, api.donations...)
+}
+
+func (api *fakeDonationAlertsAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	switch r.URL.Path {
+	case "/oauth/token":
+		api.refreshed++
+		w.Write([]byte(`{"access_token":"fresh","refresh_token":"refresh-2","expires_in":3600}`))
+	case "/api/v1/alerts/donations":
+		if r.Header.Get("Authorization") != "Bearer fresh" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Query().Get("page") != "1" {
+			w.Write([]byte(`{"data":[]}`))
+			return
+		}
+		w.Write([]byte(`{"data":[` + strings.Join(api.donations, ",") + `]}`))
+	}
+}
+
+type fakeDonationAlertsTokenRepository struct {
+	token *models.OAuthToken
+}
+
+func (r *fakeDonationAlertsTokenRepository) Get(context.Context) (*models.OAuthToken, error) {
+	if r.token == nil {
+		return nil, repositories.ErrNotFound
+	}
+	return r.token, nil
+}
+
+func (r *fakeDonationAlertsTokenRepository) Save(_ context.Context, token *models.OAuthToken) error {
+	r.token = token
+	return nil
+}
+
+type fakeDonationRepository struct {
+	donations map[int64]*models.Donation
+}
+
+func (r *fakeDonationRepository) Create(_ context.Context, p *repositories.CreateDonationParams) (*models.Donation, error) {
+	if _, ok := r.donations[p.ExternalID]; ok {
+		return nil, repositories.ErrAlreadyExists
+	}
+	d := &models.Donation{ExternalID: p.ExternalID, Username: p.Username, Message: p.Message, Amount: p.Amount, Currency: p.Currency}
+	r.donations[p.ExternalID] = d
+	return d, nil
+}
+
+func (r *fakeDonationRepository) HasAny(context.Context) (bool, error) {
+	return len(r.donations) > 0, nil
+}
+
+func Test_PollDonations(t *testing.T) {
+	api := &fakeDonationAlertsAPI{}
+	server := httptest.NewServer(api)
+	defer server.Close()
+
+	client := services.NewDonationAlertsClient(&config.DonationAlertsConfig{BaseURL: server.URL}, server.Client())
+	tokens := &fakeDonationAlertsTokenRepository{}
+	donations := &fakeDonationRepository{donations: map[int64]*models.Donation{}}
+	templates, err := services.NewNotificationTemplates(&config.NotifyConfig{Locale: "ru"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	outbox := &fakeEnqueueOutbox{}
+	router := &fakeNotificationRouter{targets: []services.RoutedTarget{
+		{Target: models.NotificationTarget{Channel: models.NotificationChannelTelegram}, Locale: "ru"},
+	}}
+	usecase := NewPollDonationsUsecase(client, tokens, fakeTransactor{}, donations, outbox, router, templates, zap.NewNop())
+
+	// Nothing happens until the account is connected
+	if err := usecase.Perform(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Expired token is refreshed, existing donations are stored without notifications
+	tokens.token = &models.OAuthToken{AccessToken: "stale", RefreshToken: "refresh", ExpiresAt: time.Now().Add(-time.Hour)}
+	api.add(1, "Old", "history")
+	if err := usecase.Perform(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if api.refreshed != 1 || tokens.token.AccessToken != "fresh" {
+		t.Errorf("expected token to be refreshed")
+	}
+	if len(donations.donations) != 1 || len(outbox.enqueued) != 0 {
+		t.Fatalf("expected history to be stored silently, got %d donations and %d notifications", len(donations.donations), len(outbox.enqueued))
+	}
+
+	// New donations are stored and notified exactly once
+	api.add(2, "Ivan", "<b>hello</b>")
+	for range 2 {
+		if err := usecase.Perform(context.Background()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if api.refreshed != 1 {
+		t.Errorf("expected valid token not to be refreshed, refreshed %d times", api.refreshed)
+	}
+	if len(outbox.enqueued) != 1 {
+		t.Fatalf("expected 1 notification, got %d", len(outbox.enqueued))
+	}
+	if router.params.EventType != "DONATION" || router.params.Store != "DONATIONALERTS" {
+		t.Errorf("unexpected route params: %+v", router.params)
+	}
+	n := outbox.enqueued[0]
+	if n.Target.Channel != models.NotificationChannelTelegram || n.EventType == nil || *n.EventType != "DONATION" {
+		t.Errorf("unexpected notification: %+v", n)
+	}
+	msg := n.Text
+	if !strings.Contains(msg, "100 ₽") || !strings.Contains(msg, "Ivan") || !strings.Contains(msg, "&lt;b&gt;hello&lt;/b&gt;") {
+		t.Errorf("unexpected message: %s", msg)
+	}
+}
@@ -0,0 +1,370 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"athylps/internal/logging"
+	"athylps/internal/models"
+	"athylps/internal/repositories"
+	"athylps/internal/tracing"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+var errWebhookEventAlreadyProcessed = errors.New("webhook event has already been processed")
+
+type ProcessWebhookEventParams struct {
+	Provider          string
+	EventID           string
+	EventType         string
+	Environment       *string
+	Payload           []byte
+	Store             string
+	AppUserID         *string
+	OriginalAppUserID *string
+	Aliases           []string
+	CountryCode       *string
+	Price             *float32 // USD
+	// Price in the currency of the purchase with its ISO 4217 code
+	PriceInPurchasedCurrency *float32
+	Currency                 *string
+	ProductID                *string
+	EntitlementIDs           []string
+	RenewalNumber            *int
+	EventAt                  *time.Time
+	PurchasedAt              *time.Time
+	ExpiresAt                *time.Time
+	GracePeriodExpiresAt     *time.Time
+	TransferredFrom          []string
+	TransferredTo            []string
+}
+
+type webhookEventRepository interface {
+	Create(ctx context.Context, p *repositories.CreateWebhookEventParams) (*models.WebhookEvent, error)
+	GetByEventID(ctx context.Context, provider string, eventID string) (*models.WebhookEvent, error)
+	MarkProcessed(ctx context.Context, id string, userID *string) error
+}
+
+type revenueCatCustomerResolver interface {
+	Perform(ctx context.Context, params *ResolveRevenueCatCustomerParams) (*string, error)
+}
+
+type purchaseNotificationSender interface {
+	Perform(ctx context.Context, params *SendPurchaseNotificationParams) error
+}
+
+type transactor interface {
+	WithinTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type subscriptionStateUpdater interface {
+	Perform(ctx context.Context, params *UpdateSubscriptionStateParams) error
+}
+
+type purchaseEventRecorder interface {
+	Create(ctx context.Context, p *repositories.CreatePurchaseEventParams) (*models.PurchaseEvent, error)
+}
+
+// ProcessWebhookEventUsecase persists incoming webhook events and runs them through
+// the processing pipeline exactly once, skipping redeliveries of already processed events.
+// State changes and notifications of an event are committed in one transaction.
+type ProcessWebhookEventUsecase struct {
+	tx            transactor
+	events        webhookEventRepository
+	customers     revenueCatCustomerResolver
+	users         userGetter
+	subscriptions subscriptionStateUpdater
+	purchases     purchaseEventRecorder
+	notification  purchaseNotificationSender
+	// Whether sandbox purchase events are recorded for revenue stats
+	includeSandbox bool
+	logger         *zap.Logger
+	now            func() time.Time
+}
+
+func NewProcessWebhookEventUsecase(
+	tx transactor,
+	events webhookEventRepository,
+	customers revenueCatCustomerResolver,
+	users userGetter,
+	subscriptions subscriptionStateUpdater,
+	purchases purchaseEventRecorder,
+	notification purchaseNotificationSender,
+	includeSandbox bool,
+	logger *zap.Logger,
+) *ProcessWebhookEventUsecase {
+	return &ProcessWebhookEventUsecase{
+		tx:             tx,
+		events:         events,
+		customers:      customers,
+		users:          users,
+		subscriptions:  subscriptions,
+		purchases:      purchases,
+		notification:   notification,
+		includeSandbox: includeSandbox,
+		logger:         logger,
+		now:            time.Now,
+	}
+}
+
+func (u *ProcessWebhookEventUsecase) Perform(ctx context.Context, params *ProcessWebhookEventParams) error {
+	ctx, span := tracing.Start(ctx, "ProcessWebhookEventUsecase.Perform", trace.WithAttributes(
+		attribute.String("webhook.provider", params.Provider),
+		attribute.String("webhook.event_id", params.EventID),
+		attribute.String("webhook.event_type", params.EventType),
+	))
+	err := u.perform(ctx, params)
+	tracing.End(span, err)
+
+	return err
+}
+
+func (u *ProcessWebhookEventUsecase) perform(ctx context.Context, params *ProcessWebhookEventParams) error {
+	logger := logging.FromContext(ctx, u.logger).With(
+		zap.String("provider", params.Provider),
+		zap.String("event_id", params.EventID),
+		zap.String("event_type", params.EventType),
+	)
+
+	event, err := u.events.Create(ctx, &repositories.CreateWebhookEventParams{
+		Provider:    params.Provider,
+		EventID:     params.EventID,
+		EventType:   params.EventType,
+		Environment: params.Environment,
+		Payload:     params.Payload,
+	})
+	if errors.Is(err, repositories.ErrAlreadyExists) {
+		event, err = u.events.GetByEventID(ctx, params.Provider, params.EventID)
+		if err != nil {
+			return fmt.Errorf("failed to load redelivered webhook event: %w", err)
+		}
+		if event.ProcessedAt != nil {
+			logger.Info("skipping already processed webhook event")
+			return nil
+		}
+		logger.Info("retrying processing of previously received webhook event")
+	} else if err != nil {
+		return fmt.Errorf("failed to store webhook event: %w", err)
+	}
+
+	err = u.tx.WithinTx(ctx, func(ctx context.Context) error {
+		return u.process(ctx, event, params)
+	})
+	if errors.Is(err, errWebhookEventAlreadyProcessed) {
+		logger.Info("webhook event has been processed concurrently")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	logger.Info("processed webhook event")
+
+	return nil
+}
+
+// Replay runs a stored event through the pipeline again, e.g. after fixing a bug in it.
+// State projections are idempotent, notifications are sent again unless notify is false.
+// Events that have never been processed successfully are marked processed.
+func (u *ProcessWebhookEventUsecase) Replay(ctx context.Context, event *models.WebhookEvent, params *ProcessWebhookEventParams, notify bool) error {
+	ctx, span := tracing.Start(ctx, "ProcessWebhookEventUsecase.Replay", trace.WithAttributes(
+		attribute.String("webhook.provider", event.Provider),
+		attribute.String("webhook.event_id", event.EventID),
+		attribute.String("webhook.event_type", event.EventType),
+		attribute.Bool("webhook.notify", notify),
+	))
+	err := u.tx.WithinTx(ctx, func(ctx context.Context) error {
+		userID, err := u.resolveUserID(ctx, params)
+		if err != nil {
+			return err
+		}
+
+		if event.ProcessedAt == nil {
+			err = u.events.MarkProcessed(ctx, event.ID, userID)
+			if err != nil && !errors.Is(err, repositories.ErrNotFound) {
+				return fmt.Errorf("failed to mark webhook event processed: %w", err)
+			}
+		}
+
+		return u.apply(ctx, event, params, userID, notify)
+	})
+	tracing.End(span, err)
+
+	return err
+}
+
+func (u *ProcessWebhookEventUsecase) process(ctx context.Context, event *models.WebhookEvent, params *ProcessWebhookEventParams) error {
+	userID, err := u.resolveUserID(ctx, params)
+	if err != nil {
+		return err
+	}
+
+	// Marking goes first to lock the event, the mark is rolled back if processing fails
+	err = u.events.MarkProcessed(ctx, event.ID, userID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return errWebhookEventAlreadyProcessed
+	}
+	if err != nil {
+		return fmt.Errorf("failed to mark webhook event processed: %w", err)
+	}
+
+	return u.apply(ctx, event, params, userID, true)
+}
+
+// apply updates state projections of the event and notifies about it.
+func (u *ProcessWebhookEventUsecase) apply(
+	ctx context.Context,
+	event *models.WebhookEvent,
+	params *ProcessWebhookEventParams,
+	userID *string,
+	notify bool,
+) error {
+	// Events without their own time, like most RuStore ones, are ordered by the time they have been received,
+	// which is kept for redeliveries and replays, so a late initial purchase doesn't override a cancellation
+	if params.EventAt == nil {
+		withTime := *params
+		withTime.EventAt = &event.ReceivedAt
+		params = &withTime
+	}
+
+	err := u.subscriptions.Perform(ctx, &UpdateSubscriptionStateParams{
+		EventType:            params.EventType,
+		AppUserID:            valueOrEmpty(params.AppUserID),
+		UserID:               userID,
+		ProductID:            valueOrEmpty(params.ProductID),
+		EntitlementIDs:       params.EntitlementIDs,
+		Store:                params.Store,
+		Environment:          params.Environment,
+		EventAt:              params.EventAt,
+		PurchasedAt:          params.PurchasedAt,
+		ExpiresAt:            params.ExpiresAt,
+		GracePeriodExpiresAt: params.GracePeriodExpiresAt,
+		TransferredFrom:      params.TransferredFrom,
+		TransferredTo:        params.TransferredTo,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update subscription state: %w", err)
+	}
+
+	if err := u.recordPurchaseEvent(ctx, event, params, userID); err != nil {
+		return err
+	}
+
+	if !notify {
+		return nil
+	}
+
+	err = u.notification.Perform(ctx, purchaseNotificationParams(params, userID))
+	if err != nil {
+		return fmt.Errorf("failed to send purchase notification: %w", err)
+	}
+
+	return nil
+}
+
+func purchaseNotificationParams(params *ProcessWebhookEventParams, userID *string) *SendPurchaseNotificationParams {
+	return &SendPurchaseNotificationParams{
+		EventType:                params.EventType,
+		UserID:                   userID,
+		Store:                    params.Store,
+		Environment:              params.Environment,
+		CountryCode:              params.CountryCode,
+		Price:                    params.Price,
+		PriceInPurchasedCurrency: params.PriceInPurchasedCurrency,
+		Currency:                 params.Currency,
+		ProductID:                params.ProductID,
+		RenewalNumber:            params.RenewalNumber,
+		ExpiresAt:                params.ExpiresAt,
+	}
+}
+
+// recordPurchaseEvent stores purchase related events for revenue stats.
+// Sandbox events aren't real money and are only stored if configured.
+func (u *ProcessWebhookEventUsecase) recordPurchaseEvent(
+	ctx context.Context,
+	event *models.WebhookEvent,
+	params *ProcessWebhookEventParams,
+	userID *string,
+) error {
+	if !slices.Contains(supportedEventTypes, params.EventType) {
+		return nil
+	}
+	if isSandbox(params.Environment) && !u.includeSandbox {
+		return nil
+	}
+
+	occurredAt := u.now()
+	if params.EventAt != nil {
+		occurredAt = *params.EventAt
+	}
+
+	_, err := u.purchases.Create(ctx, &repositories.CreatePurchaseEventParams{
+		WebhookEventID:           event.ID,
+		EventType:                params.EventType,
+		Store:                    params.Store,
+		Environment:              params.Environment,
+		ProductID:                params.ProductID,
+		CountryCode:              params.CountryCode,
+		Price:                    params.Price,
+		PriceInPurchasedCurrency: params.PriceInPurchasedCurrency,
+		Currency:                 params.Currency,
+		UserID:                   userID,
+		OccurredAt:               occurredAt,
+	})
+	if err != nil && !errors.Is(err, repositories.ErrAlreadyExists) {
+		return fmt.Errorf("failed to record purchase event: %w", err)
+	}
+
+	return nil
+}
+
+// resolveUserID returns our user the event belongs to, nil if it's unknown.
+func (u *ProcessWebhookEventUsecase) resolveUserID(ctx context.Context, params *ProcessWebhookEventParams) (*string, error) {
+	switch params.Provider {
+	case models.ProviderRevenueCat:
+		userID, err := u.customers.Perform(ctx, &ResolveRevenueCatCustomerParams{
+			EventType:         params.EventType,
+			AppUserID:         params.AppUserID,
+			OriginalAppUserID: params.OriginalAppUserID,
+			Aliases:           params.Aliases,
+			TransferredTo:     params.TransferredTo,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve revenuecat customer: %w", err)
+		}
+		return userID, nil
+	case models.ProviderRuStore:
+		// The app passes our user id as the developer payload of RuStore purchases. Anything else isn't looked up,
+		// a malformed uuid fails the query and with it the transaction the event is processed in.
+		if params.AppUserID == nil {
+			return nil, nil
+		}
+		if _, err := uuid.Parse(*params.AppUserID); err != nil {
+			return nil, nil
+		}
+		user, err := u.users.GetUserByID(ctx, *params.AppUserID)
+		if errors.Is(err, repositories.ErrNotFound) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve rustore user: %w", err)
+		}
+		return &user.ID, nil
+	}
+
+	return nil, nil
+}
+
+func valueOrEmpty(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,333 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"athylps/internal/models"
+	"athylps/internal/repositories"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+type fakeWebhookEventRepository struct {
+	events    map[string]*models.WebhookEvent
+	processed int
+}
+
+type fakeTransactor struct{}
+
+func (fakeTransactor) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+type fakeRevenueCatCustomerResolver struct {
+	userID *string
+}
+
+func (r fakeRevenueCatCustomerResolver) Perform(context.Context, *ResolveRevenueCatCustomerParams) (*string, error) {
+	return r.userID, nil
+}
+
+func newFakeWebhookEventRepository() *fakeWebhookEventRepository {
+	return &fakeWebhookEventRepository{events: map[string]*models.WebhookEvent{}}
+}
+
+func (r *fakeWebhookEventRepository) Create(_ context.Context, p *repositories.CreateWebhookEventParams) (*models.WebhookEvent, error) {
+	key := p.Provider + "/" + p.EventID
+	if _, ok := r.events[key]; ok {
+		return nil, repositories.ErrAlreadyExists
+	}
+	// Events are received a minute apart
+	receivedAt := subscriptionTestStart.Add(time.Duration(len(r.events)) * time.Minute)
+	event := &models.WebhookEvent{ID: key, Provider: p.Provider, EventID: p.EventID, EventType: p.EventType, ReceivedAt: receivedAt}
+	r.events[key] = event
+	return event, nil
+}
+
+func (r *fakeWebhookEventRepository) GetByEventID(_ context.Context, provider string, eventID string) (*models.WebhookEvent, error) {
+	event, ok := r.events[provider+"/"+eventID]
+	if !ok {
+		return nil, repositories.ErrNotFound
+	}
+	return event, nil
+}
+
+func (r *fakeWebhookEventRepository) MarkProcessed(_ context.Context, id string, userID *string) error {
+	if r.events[id].ProcessedAt != nil {
+		return repositories.ErrNotFound
+	}
+	now := time.Now()
+	r.events[id].ProcessedAt = &now
+	r.events[id].UserID = userID
+	r.processed++
+	return nil
+}
+
+type fakeUserGetter map[string]*models.User
+
+func (g fakeUserGetter) GetUserByID(_ context.Context, id string) (*models.User, error) {
+	user, ok := g[id]
+	if !ok {
+		return nil, repositories.ErrNotFound
+	}
+	return user, nil
+}
+
+// strictUserGetter fails lookups by malformed ids like Postgres does, which aborts the transaction.
+type strictUserGetter struct {
+	fakeUserGetter
+}
+
+func (g strictUserGetter) GetUserByID(ctx context.Context, id string) (*models.User, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, fmt.Errorf("invalid input syntax for type uuid: %q", id)
+	}
+	return g.fakeUserGetter.GetUserByID(ctx, id)
+}
+
+type fakeSubscriptionStateUpdater struct{}
+
+func (fakeSubscriptionStateUpdater) Perform(context.Context, *UpdateSubscriptionStateParams) error {
+	return nil
+}
+
+type fakePurchaseEventRecorder struct {
+	recorded []*repositories.CreatePurchaseEventParams
+}
+
+func (r *fakePurchaseEventRecorder) Create(_ context.Context, p *repositories.CreatePurchaseEventParams) (*models.PurchaseEvent, error) {
+	r.recorded = append(r.recorded, p)
+	return &models.PurchaseEvent{WebhookEventID: p.WebhookEventID}, nil
+}
+
+type fakePurchaseNotificationSender struct {
+	sent []*SendPurchaseNotificationParams
+}
+
+func (s *fakePurchaseNotificationSender) Perform(_ context.Context, params *SendPurchaseNotificationParams) error {
+	s.sent = append(s.sent, params)
+	return nil
+}
+
+func Test_ProcessWebhookEvent_SkipsRedeliveredEvents(t *testing.T) {
+	repo := newFakeWebhookEventRepository()
+	purchases := &fakePurchaseEventRecorder{}
+	sender := &fakePurchaseNotificationSender{}
+	usecase := NewProcessWebhookEventUsecase(fakeTransactor{}, repo, fakeRevenueCatCustomerResolver{}, fakeUserGetter{}, fakeSubscriptionStateUpdater{}, purchases, sender, false, zap.NewNop())
+
+	params := &ProcessWebhookEventParams{
+		Provider:  models.ProviderRevenueCat,
+		EventID:   "CD489E0E-5D52-4E03-966B-A7F17788E432",
+		EventType: typeInitialPurchase,
+		Store:     "APP_STORE",
+	}
+	for range 3 {
+		if err := usecase.Perform(context.Background(), params); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if len(sender.sent) != 1 {
+		t.Errorf("expected 1 notification, got %d", len(sender.sent))
+	}
+	if repo.processed != 1 {
+		t.Errorf("expected event to be marked processed once, got %d", repo.processed)
+	}
+	if len(purchases.recorded) != 1 || purchases.recorded[0].EventType != typeInitialPurchase || purchases.recorded[0].Store != "APP_STORE" {
+		t.Errorf("expected purchase event to be recorded once, got %+v", purchases.recorded)
+	}
+}
+
+func Test_ProcessWebhookEvent_RetriesUnprocessedEvents(t *testing.T) {
+	repo := newFakeWebhookEventRepository()
+	repo.events["revenuecat/1"] = &models.WebhookEvent{ID: "revenuecat/1", Provider: models.ProviderRevenueCat, EventID: "1"}
+	purchases := &fakePurchaseEventRecorder{}
+	sender := &fakePurchaseNotificationSender{}
+	usecase := NewProcessWebhookEventUsecase(fakeTransactor{}, repo, fakeRevenueCatCustomerResolver{}, fakeUserGetter{}, fakeSubscriptionStateUpdater{}, purchases, sender, false, zap.NewNop())
+
+	err := usecase.Perform(context.Background(), &ProcessWebhookEventParams{
+		Provider:  models.ProviderRevenueCat,
+		EventID:   "1",
+		EventType: typeRenewal,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(sender.sent) != 1 {
+		t.Errorf("expected previously unprocessed event to be notified, got %d notifications", len(sender.sent))
+	}
+}
+
+func Test_ProcessWebhookEvent_AttributesEventsToUser(t *testing.T) {
+	repo := newFakeWebhookEventRepository()
+	purchases := &fakePurchaseEventRecorder{}
+	sender := &fakePurchaseNotificationSender{}
+	userID := "019a7b5e-4f2c-7c1d-9a3e-2b1f0c9d8e7a"
+	users := strictUserGetter{fakeUserGetter{userID: {ID: userID}}}
+	usecase := NewProcessWebhookEventUsecase(fakeTransactor{}, repo, fakeRevenueCatCustomerResolver{userID: &userID}, users, fakeSubscriptionStateUpdater{}, purchases, sender, false, zap.NewNop())
+
+	events := []*ProcessWebhookEventParams{
+		{Provider: models.ProviderRevenueCat, EventID: "1", EventType: typeInitialPurchase, AppUserID: ptr("user_42")},
+		{Provider: models.ProviderRuStore, EventID: "1", EventType: typeInitialPurchase, AppUserID: &userID},
+		{Provider: models.ProviderRuStore, EventID: "2", EventType: typeInitialPurchase, AppUserID: ptr("unknown")},
+		{Provider: models.ProviderRuStore, EventID: "3", EventType: typeInitialPurchase, AppUserID: ptr("019a7b5e-0000-7000-8000-000000000000")},
+	}
+	for _, params := range events {
+		if err := usecase.Perform(context.Background(), params); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	for _, key := range []string{"revenuecat/1", "rustore/1"} {
+		if got := repo.events[key].UserID; got == nil || *got != userID {
+			t.Errorf("expected %s to be attributed to %s, got %v", key, userID, got)
+		}
+	}
+	if got := sender.sent[0].UserID; got == nil || *got != userID {
+		t.Errorf("expected notification to reference %s, got %v", userID, got)
+	}
+	for _, key := range []string{"rustore/2", "rustore/3"} {
+		if got := repo.events[key].UserID; got != nil {
+			t.Errorf("expected %s of unknown user not to be attributed, got %s", key, *got)
+		}
+	}
+}
+
+func Test_ProcessWebhookEvent_SandboxEvents(t *testing.T) {
+	events := []*ProcessWebhookEventParams{
+		{Provider: models.ProviderRevenueCat, EventID: "1", EventType: typeInitialPurchase, Environment: ptr("SANDBOX"), Price: ptr[float32](4.99)},
+		{Provider: models.ProviderRevenueCat, EventID: "2", EventType: typeTest, Environment: ptr("SANDBOX")},
+		{Provider: models.ProviderRevenueCat, EventID: "3", EventType: typeRenewal, Environment: ptr("PRODUCTION"), Price: ptr[float32](4.99)},
+	}
+
+	for _, includeSandbox := range []bool{false, true} {
+		purchases := &fakePurchaseEventRecorder{}
+		sender := &fakePurchaseNotificationSender{}
+		usecase := NewProcessWebhookEventUsecase(fakeTransactor{}, newFakeWebhookEventRepository(), fakeRevenueCatCustomerResolver{}, fakeUserGetter{}, fakeSubscriptionStateUpdater{}, purchases, sender, includeSandbox, zap.NewNop())
+		for _, params := range events {
+			if err := usecase.Perform(context.Background(), params); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+
+		if len(sender.sent) != 3 {
+			t.Errorf("expected every event to be passed on for notification, got %d", len(sender.sent))
+		}
+		wantRecorded := 1
+		if includeSandbox {
+			wantRecorded = 2
+		}
+		if len(purchases.recorded) != wantRecorded {
+			t.Errorf("expected %d purchase events to be recorded with includeSandbox=%t, got %d", wantRecorded, includeSandbox, len(purchases.recorded))
+		}
+	}
+}
+
+func Test_ProcessWebhookEvent_Replay(t *testing.T) {
+	repo := newFakeWebhookEventRepository()
+	purchases := &fakePurchaseEventRecorder{}
+	sender := &fakePurchaseNotificationSender{}
+	usecase := NewProcessWebhookEventUsecase(fakeTransactor{}, repo, fakeRevenueCatCustomerResolver{}, fakeUserGetter{}, fakeSubscriptionStateUpdater{}, purchases, sender, false, zap.NewNop())
+
+	params := &ProcessWebhookEventParams{Provider: models.ProviderRevenueCat, EventID: "1", EventType: typeRenewal}
+	if err := usecase.Perform(context.Background(), params); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	event := repo.events["revenuecat/1"]
+
+	if err := usecase.Replay(context.Background(), event, params, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(sender.sent) != 1 {
+		t.Errorf("expected suppressed replay not to notify, got %d notifications", len(sender.sent))
+	}
+	if len(purchases.recorded) != 2 {
+		t.Errorf("expected replay to rebuild purchase events, got %d", len(purchases.recorded))
+	}
+
+	if err := usecase.Replay(context.Background(), event, params, true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(sender.sent) != 2 {
+		t.Errorf("expected replay to notify again, got %d notifications", len(sender.sent))
+	}
+	if repo.processed != 1 {
+		t.Errorf("expected processed event not to be marked again, got %d marks", repo.processed)
+	}
+
+	unprocessed := &models.WebhookEvent{ID: "revenuecat/2", Provider: models.ProviderRevenueCat, EventID: "2"}
+	repo.events[unprocessed.ID] = unprocessed
+	err := usecase.Replay(context.Background(), unprocessed, &ProcessWebhookEventParams{Provider: models.ProviderRevenueCat, EventID: "2", EventType: typeRenewal}, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if unprocessed.ProcessedAt == nil {
+		t.Error("expected replayed event to be marked processed")
+	}
+}
+
+func Test_ProcessWebhookEvent_OrdersRuStoreEventsByReceipt(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeWebhookEventRepository()
+	subscriptions := newFakeSubscriptionRepository()
+	usecase := NewProcessWebhookEventUsecase(
+		fakeTransactor{},
+		repo,
+		fakeRevenueCatCustomerResolver{},
+		fakeUserGetter{},
+		NewUpdateSubscriptionStateUsecase(subscriptions, zap.NewNop()),
+		&fakePurchaseEventRecorder{},
+		&fakePurchaseNotificationSender{},
+		false,
+		zap.NewNop(),
+	)
+
+	expiresAt := subscriptionTestStart.AddDate(0, 1, 0)
+	event := func(id string, eventType string, eventAt *time.Time) *ProcessWebhookEventParams {
+		return &ProcessWebhookEventParams{
+			Provider:    models.ProviderRuStore,
+			EventID:     id,
+			EventType:   eventType,
+			Store:       "RU_STORE",
+			AppUserID:   ptr("user"),
+			ProductID:   ptr("premium_monthly"),
+			EventAt:     eventAt,
+			PurchasedAt: &subscriptionTestStart,
+			ExpiresAt:   &expiresAt,
+		}
+	}
+	status := func() models.SubscriptionStatus {
+		s, err := subscriptions.Get(ctx, "user", "premium_monthly")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s.Status
+	}
+
+	purchase := event("1", typeInitialPurchase, nil)
+	for _, params := range []*ProcessWebhookEventParams{purchase, event("2", typeCancellation, nil)} {
+		if err := usecase.Perform(ctx, params); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if err := usecase.Replay(ctx, repo.events["rustore/1"], purchase, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := status(); got != models.SubscriptionStatusCancelled {
+		t.Errorf("expected replayed purchase not to override the later cancellation, got %s", got)
+	}
+
+	// Delivered late, but happened before the cancellation
+	purchasedAt := subscriptionTestStart
+	if err := usecase.Perform(ctx, event("3", typeRenewal, &purchasedAt)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := status(); got != models.SubscriptionStatusCancelled {
+		t.Errorf("expected the late event not to override the cancellation, got %s", got)
+	}
+}
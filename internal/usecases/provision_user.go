@@ -0,0 +1,61 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"athylps/internal/logging"
+	"athylps/internal/models"
+	"athylps/internal/repositories"
+
+	"go.uber.org/zap"
+)
+
+type ProvisionUserParams struct {
+	FirebaseUID string
+	Email       *string
+	Timezone    *string
+	Locale      *string
+}
+
+type userUpserter interface {
+	UpsertUserByFirebaseUID(ctx context.Context, p *repositories.UpsertUserParams) (*models.User, error)
+}
+
+// ProvisionUserUsecase makes sure an authenticated Firebase user has a users row,
+// creating it on the first request and keeping the profile fields up to date.
+type ProvisionUserUsecase struct {
+	users  userUpserter
+	logger *zap.Logger
+}
+
+func NewProvisionUserUsecase(users userUpserter, logger *zap.Logger) *ProvisionUserUsecase {
+	return &ProvisionUserUsecase{
+		users:  users,
+		logger: logger,
+	}
+}
+
+// Perform returns the provisioned user, repositories.ErrNotFound if the user has been deleted.
+func (u *ProvisionUserUsecase) Perform(ctx context.Context, params *ProvisionUserParams) (*models.User, error) {
+	upsert := &repositories.UpsertUserParams{
+		FirebaseUID: params.FirebaseUID,
+		Email:       params.Email,
+		Timezone:    params.Timezone,
+		Locale:      params.Locale,
+	}
+
+	user, err := u.users.UpsertUserByFirebaseUID(ctx, upsert)
+	if errors.Is(err, repositories.ErrEmailTaken) {
+		// Another account already uses this email, e.g. it was registered with a different sign-in provider
+		logging.FromContext(ctx, u.logger).Warn("email is taken by another user, provisioning without email", zap.String("firebase_uid", params.FirebaseUID))
+		upsert.Email = nil
+		user, err = u.users.UpsertUserByFirebaseUID(ctx, upsert)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to provision user: %w", err)
+	}
+
+	return user, nil
+}
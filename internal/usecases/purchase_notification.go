@@ -5,16 +5,29 @@ import (
 	"fmt"
 	"slices"
 	"strings"
+	"time"
+
+	"athylps/internal/logging"
+	"athylps/internal/repositories"
+	"athylps/internal/services"
 
-	"github.com/biter777/countries"
 	"go.uber.org/zap"
 )
 
 var (
-	typeInitialPurchase     = "INITIAL_PURCHASE"
-	typeNonRenewingPurchase = "NON_RENEWING_PURCHASE"
-	typeRenewal             = "RENEWAL"
-	typeCancellation        = "CANCELLATION"
+	typeInitialPurchase      = "INITIAL_PURCHASE"
+	typeNonRenewingPurchase  = "NON_RENEWING_PURCHASE"
+	typeRenewal              = "RENEWAL"
+	typeCancellation         = "CANCELLATION"
+	typeUncancellation       = "UNCANCELLATION"
+	typeProductChange        = "PRODUCT_CHANGE"
+	typeBillingIssue         = "BILLING_ISSUE"
+	typeSubscriptionPaused   = "SUBSCRIPTION_PAUSED"
+	typeSubscriptionExtended = "SUBSCRIPTION_EXTENDED"
+	typeExpiration           = "EXPIRATION"
+	typeTransfer             = "TRANSFER"
+	typeRefund               = "REFUND" // Not a RevenueCat type, RuStore refunds are mapped to it
+	typeTest                 = "TEST"   // Sent from the RevenueCat dashboard to check the integration
 )
 
 var supportedEventTypes = []string{
@@ -22,101 +35,154 @@ var supportedEventTypes = []string{
 	typeNonRenewingPurchase,
 	typeRenewal,
 	typeCancellation,
+	typeRefund,
 }
 
-var mapStoreNames = map[string]string{
-	"APP_STORE":   "App Store",
-	"PLAY_STORE":  "Google Play",
-	"STRIPE":      "Stripe",
-	"PROMOTIONAL": "RC Manual",
-	"RU_STORE":    "RuStore",
-}
+// notificationEventTypes are supported event types and TEST, which is confirmed with a notification.
+var notificationEventTypes = append(slices.Clone(supportedEventTypes), typeTest)
 
 type SendPurchaseNotificationParams struct {
-	EventType     string
-	Store         string
-	CountryCode   *string
-	Price         *float32
-	ProductID     *string
-	RenewalNumber *int
+	EventType   string
+	UserID      *string
+	Store       string
+	Environment *string
+	CountryCode *string
+	Price       *float32 // USD
+	// Price in the currency of the purchase with its ISO 4217 code
+	PriceInPurchasedCurrency *float32
+	Currency                 *string
+	ProductID                *string
+	RenewalNumber            *int
+	ExpiresAt                *time.Time
+}
+
+type notificationOutbox interface {
+	Enqueue(ctx context.Context, p *repositories.EnqueueNotificationParams) error
 }
 
-type tgNotifier interface {
-	Notify(ctx context.Context, message string) error
+type notificationRouter interface {
+	Route(p *services.RouteNotificationParams) []services.RoutedTarget
 }
 
+type notificationRenderer interface {
+	Render(locale string, data *services.PurchaseNotificationData) (string, error)
+}
+
+// SendPurchaseNotificationUsecase writes purchase notifications to the outbox, one per routed target
+// rendered in the target's locale, they are delivered by DispatchNotificationsUsecase.
 type SendPurchaseNotificationUsecase struct {
-	notifier tgNotifier
-	logger   *zap.Logger
+	outbox    notificationOutbox
+	router    notificationRouter
+	templates notificationRenderer
+	logger    *zap.Logger
 }
 
-func NewSendPurchaseNotificationUsecase(notifier tgNotifier, logger *zap.Logger) *SendPurchaseNotificationUsecase {
+func NewSendPurchaseNotificationUsecase(
+	outbox notificationOutbox,
+	router notificationRouter,
+	templates notificationRenderer,
+	logger *zap.Logger,
+) *SendPurchaseNotificationUsecase {
 	return &SendPurchaseNotificationUsecase{
-		notifier: notifier,
-		logger:   logger,
+		outbox:    outbox,
+		router:    router,
+		templates: templates,
+		logger:    logger,
 	}
 }
 
-func (u *SendPurchaseNotificationUsecase) Perform(ctx context.Context, params *SendPurchaseNotificationParams) {
-	u.logger.Info("Sending purchase notification")
-
-	if !slices.Contains(supportedEventTypes, params.EventType) {
-		u.logger.Info("ignoring event type", zap.String("event_type", params.EventType))
-		return
+func (u *SendPurchaseNotificationUsecase) Perform(ctx context.Context, params *SendPurchaseNotificationParams) error {
+	notifications, err := u.build(ctx, params)
+	if err != nil {
+		return err
 	}
 
-	msg := buildNotificationMessage(params)
-	err := u.notifier.Notify(ctx, msg)
-	if err != nil {
-		u.logger.Error("failed to perform notify", zap.Error(err))
+	for _, n := range notifications {
+		if err := u.outbox.Enqueue(ctx, n); err != nil {
+			return fmt.Errorf("failed to enqueue purchase notification to %s: %w", n.Target.Channel, err)
+		}
 	}
+
+	return nil
 }
 
-func buildNotificationMessage(p *SendPurchaseNotificationParams) string {
-	var sb strings.Builder
+// Preview returns the notifications Perform would enqueue for the event without enqueuing them.
+func (u *SendPurchaseNotificationUsecase) Preview(ctx context.Context, params *SendPurchaseNotificationParams) ([]*repositories.EnqueueNotificationParams, error) {
+	return u.build(ctx, params)
+}
 
-	storeName, ok := mapStoreNames[p.Store]
-	if !ok {
-		storeName = p.Store
+func (u *SendPurchaseNotificationUsecase) build(ctx context.Context, params *SendPurchaseNotificationParams) ([]*repositories.EnqueueNotificationParams, error) {
+	if !slices.Contains(notificationEventTypes, params.EventType) {
+		logging.FromContext(ctx, u.logger).Info("ignoring event type", zap.String("event_type", params.EventType))
+		return nil, nil
 	}
 
-	switch p.EventType {
-	case typeInitialPurchase:
-		sb.WriteString(fmt.Sprintf("💵 Совершена покупка в <b>%s</b> 💵\n\n", storeName))
-	case typeNonRenewingPurchase:
-		sb.WriteString(fmt.Sprintf("💵 Совершена разовая покупка в <b>%s</b> 💵\n\n", storeName))
-	case typeRenewal:
-		sb.WriteString(fmt.Sprintf("🔁 Подписка продлена в <b>%s</b> 🔁\n\n", storeName))
-	case typeCancellation:
-		sb.WriteString(fmt.Sprintf("✖︎ Совершена отмена подписки в <b>%s</b> ✖︎\n\n", storeName))
-	default:
-		sb.WriteString(fmt.Sprintf("Произошло событие: %s", p.EventType))
-		return sb.String()
+	route := &services.RouteNotificationParams{
+		EventType:   params.EventType,
+		Store:       params.Store,
+		Environment: params.Environment,
+		Price:       params.Price,
 	}
-
-	if p.Price != nil && p.EventType != typeCancellation {
-		sb.WriteString(fmt.Sprintf("Стоимость: $%.2f\n", *p.Price))
+	targets := u.router.Route(route)
+	if len(targets) == 0 {
+		logging.FromContext(ctx, u.logger).Info("purchase notification is discarded by routes", zap.String("event_type", params.EventType))
+		return nil, nil
 	}
 
-	if p.CountryCode != nil {
-		sb.WriteString(fmt.Sprintf("Страна: %s\n", countryName(*p.CountryCode)))
+	data := &services.PurchaseNotificationData{
+		EventType:     params.EventType,
+		Store:         params.Store,
+		Sandbox:       route.IsSandbox(),
+		UserID:        params.UserID,
+		CountryCode:   params.CountryCode,
+		Price:         params.Price,
+		LocalPrice:    localPrice(params),
+		ProductID:     params.ProductID,
+		RenewalNumber: params.RenewalNumber,
+		ExpiresAt:     params.ExpiresAt,
 	}
-
-	if p.ProductID != nil {
-		sb.WriteString(fmt.Sprintf("Продукт: %s\n", *p.ProductID))
+	// Kept with the notification to summarize a burst of them
+	var price *float64
+	if params.Price != nil {
+		p := float64(*params.Price)
+		price = &p
 	}
 
-	if p.RenewalNumber != nil {
-		sb.WriteString(fmt.Sprintf("Кол-во продлений: %d\n", *p.RenewalNumber))
+	texts := make(map[string]string)
+	notifications := make([]*repositories.EnqueueNotificationParams, 0, len(targets))
+	for _, routed := range targets {
+		text, ok := texts[routed.Locale]
+		if !ok {
+			var err error
+			text, err = u.templates.Render(routed.Locale, data)
+			if err != nil {
+				return nil, fmt.Errorf("failed to render purchase notification: %w", err)
+			}
+			texts[routed.Locale] = text
+		}
+
+		notifications = append(notifications, &repositories.EnqueueNotificationParams{
+			Target:    routed.Target,
+			Text:      text,
+			EventType: &params.EventType,
+			Price:     price,
+			Locale:    routed.Locale,
+		})
 	}
 
-	return sb.String()
+	return notifications, nil
+}
+
+// isSandbox reports whether the event comes from a non-production environment, events without one are production.
+func isSandbox(environment *string) bool {
+	return environment != nil && !strings.EqualFold(*environment, "PRODUCTION")
 }
 
-func countryName(countryCode string) string {
-	country := countries.ByName(countryCode)
-	name := country.StringRus()
-	flag := country.Emoji()
+// localPrice returns the price in the currency of the purchase, nil if it's unknown or USD, which Price already is.
+func localPrice(params *SendPurchaseNotificationParams) *services.Money {
+	if params.PriceInPurchasedCurrency == nil || params.Currency == nil || strings.EqualFold(*params.Currency, "USD") {
+		return nil
+	}
 
-	return fmt.Sprintf("%s %s", flag, name)
+	return &services.Money{Amount: float64(*params.PriceInPurchasedCurrency), Currency: strings.ToUpper(*params.Currency)}
 }
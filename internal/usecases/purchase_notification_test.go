@@ -1,13 +1,102 @@
 package usecases
 
 import (
+	"context"
 	"fmt"
+	"slices"
 	"testing"
+
+	"athylps/internal/models"
+	"athylps/internal/repositories"
+	"athylps/internal/services"
+
+	"go.uber.org/zap"
 )
 
-func Test_CountryCode(t *testing.T) {
-	data := []string{"BY", "RU", "GB", "SE", "UA"}
-	for _, c := range data {
-		fmt.Println(countryName(c))
+type fakeEnqueueOutbox struct {
+	enqueued []*repositories.EnqueueNotificationParams
+}
+
+func (o *fakeEnqueueOutbox) Enqueue(_ context.Context, p *repositories.EnqueueNotificationParams) error {
+	o.enqueued = append(o.enqueued, p)
+	return nil
+}
+
+type fakeNotificationRouter struct {
+	targets []services.RoutedTarget
+	params  *services.RouteNotificationParams
+}
+
+func (r *fakeNotificationRouter) Route(p *services.RouteNotificationParams) []services.RoutedTarget {
+	r.params = p
+	return r.targets
+}
+
+type fakeNotificationRenderer struct {
+	rendered int
+	data     *services.PurchaseNotificationData
+}
+
+func (r *fakeNotificationRenderer) Render(locale string, data *services.PurchaseNotificationData) (string, error) {
+	r.rendered++
+	r.data = data
+	return fmt.Sprintf("%s %s in %s", locale, data.EventType, data.Store), nil
+}
+
+func Test_SendPurchaseNotification_EnqueuesPerTarget(t *testing.T) {
+	targets := []services.RoutedTarget{
+		{Target: models.NotificationTarget{Channel: models.NotificationChannelTelegram, Recipient: "finance"}, Locale: "ru"},
+		{Target: models.NotificationTarget{Channel: models.NotificationChannelWebhook, Recipient: "finance"}, Locale: "ru"},
+		{Target: models.NotificationTarget{Channel: models.NotificationChannelEmail, Recipient: "ceo@example.com"}, Locale: "en"},
+	}
+	outbox := &fakeEnqueueOutbox{}
+	router := &fakeNotificationRouter{targets: targets}
+	renderer := &fakeNotificationRenderer{}
+	usecase := NewSendPurchaseNotificationUsecase(outbox, router, renderer, zap.NewNop())
+
+	params := &SendPurchaseNotificationParams{
+		EventType:   typeRefund,
+		Store:       "APP_STORE",
+		Environment: ptr("PRODUCTION"),
+		Price:       ptr[float32](4.99),
+		// Lowercase codes are normalized
+		PriceInPurchasedCurrency: ptr[float32](499),
+		Currency:                 ptr("rub"),
+	}
+	if err := usecase.Perform(context.Background(), params); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if router.params.EventType != typeRefund || router.params.Store != "APP_STORE" || *router.params.Environment != "PRODUCTION" || *router.params.Price != 4.99 {
+		t.Errorf("expected the event to be routed, got %+v", router.params)
+	}
+	var enqueued []services.RoutedTarget
+	for _, p := range outbox.enqueued {
+		enqueued = append(enqueued, services.RoutedTarget{Target: p.Target, Locale: p.Locale})
+		if p.Text != p.Locale+" REFUND in APP_STORE" {
+			t.Errorf("unexpected notification text %q", p.Text)
+		}
+		if *p.EventType != typeRefund || float32(*p.Price) != 4.99 {
+			t.Errorf("expected the event to be kept with the notification, got %s %v", *p.EventType, *p.Price)
+		}
+	}
+	if !slices.Equal(enqueued, targets) {
+		t.Errorf("expected a notification per target in its locale, got %v", enqueued)
+	}
+	if local := renderer.data.LocalPrice; local == nil || *local != (services.Money{Amount: 499, Currency: "RUB"}) {
+		t.Errorf("expected local price of 499 RUB, got %+v", local)
+	}
+	if renderer.rendered != 2 {
+		t.Errorf("expected notification to be rendered once per locale, rendered %d times", renderer.rendered)
+	}
+
+	// Discarded by routes
+	outbox.enqueued = nil
+	router.targets = []services.RoutedTarget{}
+	if err := usecase.Perform(context.Background(), params); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(outbox.enqueued) != 0 {
+		t.Errorf("expected discarded notification not to be enqueued, got %d", len(outbox.enqueued))
 	}
 }
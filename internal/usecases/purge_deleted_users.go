@@ -0,0 +1,101 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"athylps/internal/logging"
+	"athylps/internal/models"
+	"athylps/internal/repositories"
+
+	"firebase.google.com/go/v4/auth"
+	"go.uber.org/zap"
+)
+
+const purgeBatchSize = 100
+
+type deletedUserPurger interface {
+	ListPurgeDue(ctx context.Context, at time.Time, limit uint64) ([]*models.User, error)
+	PurgeUser(ctx context.Context, id string, at time.Time) error
+}
+
+type identityDeleter interface {
+	DeleteUser(ctx context.Context, uid string) error
+}
+
+// PurgeDeletedUsersUsecase hard deletes personal data of accounts deleted longer than the retention ago,
+// the Firebase user with its email, name and providers included, so signing in again starts a new account.
+// Users are purged one by one, a failed purge is retried by the next run, purging an already purged user does nothing,
+// so several replicas can run it at once.
+type PurgeDeletedUsersUsecase struct {
+	tx         transactor
+	users      deletedUserPurger
+	identities identityDeleter
+	logger     *zap.Logger
+	now        func() time.Time
+}
+
+func NewPurgeDeletedUsersUsecase(
+	tx transactor,
+	users deletedUserPurger,
+	identities identityDeleter,
+	logger *zap.Logger,
+) *PurgeDeletedUsersUsecase {
+	return &PurgeDeletedUsersUsecase{
+		tx:         tx,
+		users:      users,
+		identities: identities,
+		logger:     logger,
+		now:        time.Now,
+	}
+}
+
+// Perform purges up to 100 users due, the rest is purged by the next runs.
+func (u *PurgeDeletedUsersUsecase) Perform(ctx context.Context) error {
+	now := u.now()
+	users, err := u.users.ListPurgeDue(ctx, now, purgeBatchSize)
+	if err != nil {
+		return fmt.Errorf("failed to list users to purge: %w", err)
+	}
+
+	logger := logging.FromContext(ctx, u.logger)
+	var errs []error
+	for _, user := range users {
+		err := u.tx.WithinTx(ctx, func(ctx context.Context) error {
+			if err := u.users.PurgeUser(ctx, user.ID, now); err != nil {
+				return err
+			}
+
+			// Deleted last, the purge is rolled back to be retried if it fails. The user stays locked meanwhile,
+			// so it can't be restored after the Firebase user is gone
+			return u.deleteIdentity(ctx, user)
+		})
+		if errors.Is(err, repositories.ErrNotFound) {
+			// Purged by another replica or restored meanwhile
+			continue
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		logger.Info("purged deleted user", zap.String("user_id", user.ID), zap.Timep("deleted_at", user.DeletedAt))
+	}
+
+	return errors.Join(errs...)
+}
+
+// deleteIdentity deletes the Firebase user, one that is already gone, e.g. deleted by the previous attempt, is fine.
+func (u *PurgeDeletedUsersUsecase) deleteIdentity(ctx context.Context, user *models.User) error {
+	if user.FirebaseUID == nil {
+		return nil
+	}
+
+	err := u.identities.DeleteUser(ctx, *user.FirebaseUID)
+	if err != nil && !auth.IsUserNotFound(err) {
+		return fmt.Errorf("failed to delete firebase user of user %s: %w", user.ID, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,195 @@
+package usecases
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"athylps/internal/models"
+	"athylps/internal/repositories"
+
+	firebase "firebase.google.com/go/v4"
+	"firebase.google.com/go/v4/auth"
+	"go.uber.org/zap"
+)
+
+// fakeDeletedUserPurger purges like the repository: only deleted users whose purge is due, once.
+// Purges are kept pending until the transaction they were made in commits, see purgeTransactor.
+type fakeDeletedUserPurger struct {
+	users   map[string]*models.User
+	failing map[string]bool
+	// Users restored after they have been listed, e.g. by an admin at the same time
+	restored map[string]bool
+	pending  []string
+	purged   []string
+}
+
+func (p *fakeDeletedUserPurger) ListPurgeDue(_ context.Context, at time.Time, limit uint64) ([]*models.User, error) {
+	var due []*models.User
+	for _, u := range p.users {
+		if u.DeletedAt != nil && u.PurgeAfter != nil && !u.PurgeAfter.After(at) {
+			due = append(due, u)
+		}
+	}
+	slices.SortFunc(due, func(a, b *models.User) int { return a.PurgeAfter.Compare(*b.PurgeAfter) })
+	return due[:min(len(due), int(limit))], nil
+}
+
+func (p *fakeDeletedUserPurger) PurgeUser(_ context.Context, id string, at time.Time) error {
+	if p.failing[id] {
+		return errors.New("connection reset")
+	}
+	user, ok := p.users[id]
+	if !ok || p.restored[id] || user.PurgeAfter.After(at) {
+		return repositories.ErrNotFound
+	}
+	p.pending = append(p.pending, id)
+	return nil
+}
+
+// purgeTransactor commits pending purges if the function succeeds and rolls them back otherwise.
+type purgeTransactor struct {
+	purger *fakeDeletedUserPurger
+}
+
+func (t purgeTransactor) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	err := fn(ctx)
+	if err == nil {
+		for _, id := range t.purger.pending {
+			delete(t.purger.users, id)
+			t.purger.purged = append(t.purger.purged, id)
+		}
+	}
+	t.purger.pending = nil
+	return err
+}
+
+// fakeAuthEmulator serves user deletion of the Firebase Auth emulator API, the real client reports its errors.
+type fakeAuthEmulator struct {
+	users   map[string]bool
+	failing map[string]bool
+}
+
+func (e *fakeAuthEmulator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		LocalID string `json:"localId"`
+	}
+	if !strings.HasSuffix(r.URL.Path, "/accounts:delete") || json.NewDecoder(r.Body).Decode(&body) != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	switch {
+	case e.failing[body.LocalID]:
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"error":{"message":"PERMISSION_DENIED"}}`))
+	case !e.users[body.LocalID]:
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":{"message":"USER_NOT_FOUND"}}`))
+	default:
+		delete(e.users, body.LocalID)
+		w.Write([]byte(`{}`))
+	}
+}
+
+func newTestAuthClient(t *testing.T, emulator *fakeAuthEmulator) *auth.Client {
+	server := httptest.NewServer(emulator)
+	t.Cleanup(server.Close)
+	t.Setenv("FIREBASE_AUTH_EMULATOR_HOST", strings.TrimPrefix(server.URL, "http://"))
+
+	app, err := firebase.NewApp(context.Background(), &firebase.Config{ProjectID: "athylps-test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := app.Auth(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func Test_PurgeDeletedUsers(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 12, 12, 10, 0, 0, 0, time.UTC)
+	deleted := func(id string, purgeAfter time.Time) *models.User {
+		deletedAt := purgeAfter.Add(-30 * 24 * time.Hour)
+		uid := "firebase-" + id
+		return &models.User{ID: id, FirebaseUID: &uid, DeletedAt: &deletedAt, PurgeAfter: &purgeAfter}
+	}
+	activeAt := now.Add(-time.Hour)
+	purger := &fakeDeletedUserPurger{
+		users: map[string]*models.User{
+			"due":      deleted("due", now.Add(-time.Hour)),
+			"flaky":    deleted("flaky", now.Add(-2*time.Hour)),
+			"restored": deleted("restored", now.Add(-3*time.Hour)),
+			"retained": deleted("retained", now.Add(time.Hour)),
+			// The Firebase user has already been deleted, e.g. by an attempt whose commit failed
+			"gone": deleted("gone", now.Add(-4*time.Hour)),
+			// Firebase refuses to delete the user, the purge is rolled back
+			"denied": deleted("denied", now.Add(-5*time.Hour)),
+			"active": {ID: "active", CreatedAt: &activeAt},
+		},
+		failing:  map[string]bool{"flaky": true},
+		restored: map[string]bool{"restored": true},
+	}
+	emulator := &fakeAuthEmulator{
+		users: map[string]bool{
+			"firebase-due": true, "firebase-flaky": true, "firebase-restored": true,
+			"firebase-retained": true, "firebase-denied": true, "firebase-active": true,
+		},
+		failing: map[string]bool{"firebase-denied": true},
+	}
+	usecase := NewPurgeDeletedUsersUsecase(purgeTransactor{purger}, purger, newTestAuthClient(t, emulator), zap.NewNop())
+	usecase.now = func() time.Time { return now }
+
+	if err := usecase.Perform(ctx); err == nil {
+		t.Error("expected the failed purges to be reported")
+	}
+	if !slices.Equal(purger.purged, []string{"gone", "due"}) {
+		t.Errorf("expected due users to be purged despite the failures, got %v", purger.purged)
+	}
+	if _, ok := purger.users["denied"]; !ok {
+		t.Error("expected the purge to be rolled back when the Firebase user isn't deleted")
+	}
+	if emulator.users["firebase-due"] || !emulator.users["firebase-flaky"] {
+		t.Errorf("expected only Firebase users of purged users to be deleted, got %v", emulator.users)
+	}
+
+	// The next run retries the failed purges, the purged users aren't purged again
+	purger.failing = nil
+	emulator.failing = nil
+	for range 2 {
+		if err := usecase.Perform(ctx); err != nil {
+			t.Fatalf("failed to purge deleted users: %v", err)
+		}
+	}
+	if !slices.Equal(purger.purged, []string{"gone", "due", "denied", "flaky"}) {
+		t.Errorf("expected every due user to be purged once, got %v", purger.purged)
+	}
+	for _, id := range []string{"retained", "active", "restored"} {
+		if _, ok := purger.users[id]; !ok {
+			t.Errorf("expected %s user to be kept", id)
+		}
+		if !emulator.users["firebase-"+id] {
+			t.Errorf("expected the Firebase user of %s user to be kept", id)
+		}
+	}
+
+	// The retention of the last user ends
+	usecase.now = func() time.Time { return now.Add(2 * time.Hour) }
+	if err := usecase.Perform(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(purger.purged, []string{"gone", "due", "denied", "flaky", "retained"}) {
+		t.Errorf("expected the user to be purged once the retention ends, got %v", purger.purged)
+	}
+	if emulator.users["firebase-retained"] {
+		t.Error("expected the Firebase user to be deleted with the purge")
+	}
+}
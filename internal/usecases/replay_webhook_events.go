@@ -0,0 +1,161 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"athylps/internal/logging"
+	"athylps/internal/models"
+	"athylps/internal/repositories"
+
+	"go.uber.org/zap"
+)
+
+// MaxReplayedWebhookEvents is the most events replayed at once, the rest is replayed by the next call.
+const MaxReplayedWebhookEvents = 1000
+
+var ErrReplayScopeRequired = errors.New("event ids or a time range are required to replay webhook events")
+
+type ReplayWebhookEventsParams struct {
+	Filter *repositories.ListWebhookEventsParams
+	// Show notifications that would be sent without changing anything
+	DryRun bool
+	// Rebuild state without sending notifications again
+	SuppressNotifications bool
+}
+
+// ReplayedWebhookEvent is the outcome of replaying an event. In dry run Notifications are
+// the notifications that would be sent, the user is the one the event was attributed to when it was processed.
+type ReplayedWebhookEvent struct {
+	Event         *models.WebhookEvent
+	Notifications []*repositories.EnqueueNotificationParams
+	Err           error
+}
+
+type ReplayWebhookEventsResult struct {
+	Events []*ReplayedWebhookEvent
+	Failed int
+	// More events match the filter than have been replayed
+	Truncated bool
+}
+
+type webhookEventLister interface {
+	List(ctx context.Context, p *repositories.ListWebhookEventsParams) ([]*models.WebhookEvent, error)
+}
+
+type webhookEventDecoder interface {
+	Decode(event *models.WebhookEvent) (*ProcessWebhookEventParams, error)
+}
+
+type webhookEventReplayer interface {
+	Replay(ctx context.Context, event *models.WebhookEvent, params *ProcessWebhookEventParams, notify bool) error
+}
+
+type purchaseNotificationPreviewer interface {
+	Preview(ctx context.Context, params *SendPurchaseNotificationParams) ([]*repositories.EnqueueNotificationParams, error)
+}
+
+// ReplayWebhookEventsUsecase re-runs stored webhook events through the processing pipeline in the order
+// they were received, e.g. after fixing a bug or adding a new projection. Every event is replayed in its own
+// transaction, a failed event doesn't stop the replay and is reported in the result.
+type ReplayWebhookEventsUsecase struct {
+	events   webhookEventLister
+	decoder  webhookEventDecoder
+	replayer webhookEventReplayer
+	previews purchaseNotificationPreviewer
+	logger   *zap.Logger
+}
+
+func NewReplayWebhookEventsUsecase(
+	events webhookEventLister,
+	decoder webhookEventDecoder,
+	replayer webhookEventReplayer,
+	previews purchaseNotificationPreviewer,
+	logger *zap.Logger,
+) *ReplayWebhookEventsUsecase {
+	return &ReplayWebhookEventsUsecase{
+		events:   events,
+		decoder:  decoder,
+		replayer: replayer,
+		previews: previews,
+		logger:   logger,
+	}
+}
+
+// Perform returns ErrReplayScopeRequired if the filter has neither ids nor a time range,
+// so the whole history isn't replayed by mistake.
+func (u *ReplayWebhookEventsUsecase) Perform(ctx context.Context, params *ReplayWebhookEventsParams) (*ReplayWebhookEventsResult, error) {
+	filter := *params.Filter
+	if len(filter.IDs) == 0 && filter.From == nil && filter.To == nil {
+		return nil, ErrReplayScopeRequired
+	}
+	if filter.Limit == 0 || filter.Limit > MaxReplayedWebhookEvents {
+		filter.Limit = MaxReplayedWebhookEvents
+	}
+	limit := filter.Limit
+	// One more event tells whether there are events left
+	filter.Limit++
+
+	events, err := u.events.List(ctx, &filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook events: %w", err)
+	}
+
+	result := &ReplayWebhookEventsResult{}
+	if uint64(len(events)) > limit {
+		events = events[:limit]
+		result.Truncated = true
+	}
+
+	logger := logging.FromContext(ctx, u.logger)
+	for _, event := range events {
+		replayed := &ReplayedWebhookEvent{Event: event}
+		replayed.Notifications, replayed.Err = u.replay(ctx, event, params)
+		if replayed.Err != nil {
+			result.Failed++
+			logger.Warn(
+				"failed to replay webhook event",
+				zap.Error(replayed.Err),
+				zap.String("provider", event.Provider),
+				zap.String("event_id", event.EventID),
+			)
+		}
+		result.Events = append(result.Events, replayed)
+	}
+
+	logger.Info(
+		"replayed webhook events",
+		zap.Int("count", len(result.Events)),
+		zap.Int("failed", result.Failed),
+		zap.Bool("dry_run", params.DryRun),
+		zap.Bool("suppress_notifications", params.SuppressNotifications),
+	)
+
+	return result, nil
+}
+
+func (u *ReplayWebhookEventsUsecase) replay(
+	ctx context.Context,
+	event *models.WebhookEvent,
+	params *ReplayWebhookEventsParams,
+) ([]*repositories.EnqueueNotificationParams, error) {
+	decoded, err := u.decoder.Decode(event)
+	if err != nil {
+		return nil, err
+	}
+
+	if !params.DryRun {
+		return nil, u.replayer.Replay(ctx, event, decoded, !params.SuppressNotifications)
+	}
+	if params.SuppressNotifications {
+		return nil, nil
+	}
+
+	notifications, err := u.previews.Preview(ctx, purchaseNotificationParams(decoded, event.UserID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to preview purchase notification: %w", err)
+	}
+
+	return notifications, nil
+}
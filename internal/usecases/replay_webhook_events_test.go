@@ -0,0 +1,148 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"athylps/internal/models"
+	"athylps/internal/repositories"
+
+	"go.uber.org/zap"
+)
+
+type fakeWebhookEventLister struct {
+	events []*models.WebhookEvent
+	filter *repositories.ListWebhookEventsParams
+}
+
+func (l *fakeWebhookEventLister) List(_ context.Context, p *repositories.ListWebhookEventsParams) ([]*models.WebhookEvent, error) {
+	l.filter = p
+	if uint64(len(l.events)) > p.Limit {
+		return l.events[:p.Limit], nil
+	}
+	return l.events, nil
+}
+
+type fakeWebhookEventDecoder struct{}
+
+func (fakeWebhookEventDecoder) Decode(event *models.WebhookEvent) (*ProcessWebhookEventParams, error) {
+	if string(event.Payload) == "invalid" {
+		return nil, errors.New("invalid payload")
+	}
+	return &ProcessWebhookEventParams{Provider: event.Provider, EventID: event.EventID, EventType: event.EventType, Store: "APP_STORE"}, nil
+}
+
+type fakeWebhookEventReplayer struct {
+	replayed []string
+	notified int
+}
+
+func (r *fakeWebhookEventReplayer) Replay(_ context.Context, event *models.WebhookEvent, _ *ProcessWebhookEventParams, notify bool) error {
+	r.replayed = append(r.replayed, event.ID)
+	if notify {
+		r.notified++
+	}
+	return nil
+}
+
+type fakePurchaseNotificationPreviewer struct{}
+
+func (fakePurchaseNotificationPreviewer) Preview(_ context.Context, params *SendPurchaseNotificationParams) ([]*repositories.EnqueueNotificationParams, error) {
+	return []*repositories.EnqueueNotificationParams{{
+		Target:    models.NotificationTarget{Channel: models.NotificationChannelTelegram, Recipient: "-100"},
+		Text:      params.EventType + " " + valueOrEmpty(params.UserID),
+		EventType: &params.EventType,
+	}}, nil
+}
+
+func Test_ReplayWebhookEvents(t *testing.T) {
+	from := time.Date(2025, 12, 1, 0, 0, 0, 0, time.UTC)
+	userID := "019a7b5e-4f2c-7c1d-9a3e-2b1f0c9d8e7a"
+	events := []*models.WebhookEvent{
+		{ID: "1", Provider: models.ProviderRevenueCat, EventID: "a", EventType: typeInitialPurchase, UserID: &userID},
+		{ID: "2", Provider: models.ProviderRevenueCat, EventID: "b", EventType: typeRenewal, Payload: []byte("invalid")},
+		{ID: "3", Provider: models.ProviderRuStore, EventID: "c", EventType: typeCancellation},
+	}
+
+	tests := []struct {
+		name                  string
+		dryRun                bool
+		suppressNotifications bool
+		wantReplayed          int
+		wantNotified          int
+		wantPreviews          int
+	}{
+		{name: "replays and notifies", wantReplayed: 2, wantNotified: 2},
+		{name: "suppresses notifications", suppressNotifications: true, wantReplayed: 2},
+		{name: "dry run previews notifications", dryRun: true, wantPreviews: 2},
+		{name: "dry run without notifications", dryRun: true, suppressNotifications: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			replayer := &fakeWebhookEventReplayer{}
+			usecase := NewReplayWebhookEventsUsecase(&fakeWebhookEventLister{events: events}, fakeWebhookEventDecoder{}, replayer, fakePurchaseNotificationPreviewer{}, zap.NewNop())
+
+			result, err := usecase.Perform(context.Background(), &ReplayWebhookEventsParams{
+				Filter:                &repositories.ListWebhookEventsParams{From: &from},
+				DryRun:                tt.dryRun,
+				SuppressNotifications: tt.suppressNotifications,
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(result.Events) != 3 || result.Failed != 1 || result.Events[1].Err == nil {
+				t.Errorf("expected the undecodable event to fail and the others to be replayed, got %+v", result)
+			}
+			if len(replayer.replayed) != tt.wantReplayed || replayer.notified != tt.wantNotified {
+				t.Errorf("expected %d replayed and %d notified events, got %v and %d", tt.wantReplayed, tt.wantNotified, replayer.replayed, replayer.notified)
+			}
+			previews := 0
+			for _, e := range result.Events {
+				previews += len(e.Notifications)
+			}
+			if previews != tt.wantPreviews {
+				t.Errorf("expected %d previewed notifications, got %d", tt.wantPreviews, previews)
+			}
+			if tt.wantPreviews > 0 && result.Events[0].Notifications[0].Text != typeInitialPurchase+" "+userID {
+				t.Errorf("expected preview to reference the attributed user, got %q", result.Events[0].Notifications[0].Text)
+			}
+		})
+	}
+}
+
+func Test_ReplayWebhookEvents_Scope(t *testing.T) {
+	lister := &fakeWebhookEventLister{events: []*models.WebhookEvent{
+		{ID: "1", Provider: models.ProviderRevenueCat, EventType: typeRenewal},
+		{ID: "2", Provider: models.ProviderRevenueCat, EventType: typeRenewal},
+	}}
+	usecase := NewReplayWebhookEventsUsecase(lister, fakeWebhookEventDecoder{}, &fakeWebhookEventReplayer{}, fakePurchaseNotificationPreviewer{}, zap.NewNop())
+
+	_, err := usecase.Perform(context.Background(), &ReplayWebhookEventsParams{
+		Filter: &repositories.ListWebhookEventsParams{Provider: ptr(models.ProviderRevenueCat)},
+	})
+	if !errors.Is(err, ErrReplayScopeRequired) {
+		t.Errorf("expected replay without ids or time range to be rejected, got %v", err)
+	}
+
+	result, err := usecase.Perform(context.Background(), &ReplayWebhookEventsParams{
+		Filter: &repositories.ListWebhookEventsParams{IDs: []string{"1", "2"}, Limit: 1},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(result.Events) != 1 || !result.Truncated {
+		t.Errorf("expected replay to stop at the limit and report the rest, got %d events, truncated %v", len(result.Events), result.Truncated)
+	}
+
+	if _, err := usecase.Perform(context.Background(), &ReplayWebhookEventsParams{
+		Filter: &repositories.ListWebhookEventsParams{IDs: []string{"1"}, Limit: 1 << 20},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if lister.filter.Limit != MaxReplayedWebhookEvents+1 {
+		t.Errorf("expected limit to be capped at %d, got %d", MaxReplayedWebhookEvents, lister.filter.Limit-1)
+	}
+}
@@ -0,0 +1,99 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"athylps/internal/logging"
+	"athylps/internal/repositories"
+
+	"go.uber.org/zap"
+)
+
+var typeSubscriberAlias = "SUBSCRIBER_ALIAS"
+
+type ResolveRevenueCatCustomerParams struct {
+	EventType         string
+	AppUserID         *string
+	OriginalAppUserID *string
+	Aliases           []string
+	TransferredTo     []string
+}
+
+type revenueCatCustomerRepository interface {
+	FindUserID(ctx context.Context, appUserIDs []string) (string, error)
+	Link(ctx context.Context, p *repositories.LinkRevenueCatCustomerParams) error
+}
+
+// ResolveRevenueCatCustomerUsecase finds our user behind the app user ids of a RevenueCat event.
+// Once any of the customer's ids is linked to a user, all its other ids (e.g. anonymous ones) are linked too,
+// so subscriptions bought before logging in move to the user.
+type ResolveRevenueCatCustomerUsecase struct {
+	customers revenueCatCustomerRepository
+	logger    *zap.Logger
+}
+
+func NewResolveRevenueCatCustomerUsecase(customers revenueCatCustomerRepository, logger *zap.Logger) *ResolveRevenueCatCustomerUsecase {
+	return &ResolveRevenueCatCustomerUsecase{
+		customers: customers,
+		logger:    logger,
+	}
+}
+
+// Perform returns the id of the user or nil if the customer isn't linked to any user yet.
+func (u *ResolveRevenueCatCustomerUsecase) Perform(ctx context.Context, params *ResolveRevenueCatCustomerParams) (*string, error) {
+	appUserIDs := customerAppUserIDs(params)
+	if len(appUserIDs) == 0 {
+		return nil, nil
+	}
+
+	userID, err := u.customers.FindUserID(ctx, appUserIDs)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find revenuecat customer: %w", err)
+	}
+
+	if len(appUserIDs) > 1 {
+		err = u.customers.Link(ctx, &repositories.LinkRevenueCatCustomerParams{
+			UserID:     userID,
+			AppUserIDs: appUserIDs,
+		})
+		if errors.Is(err, repositories.ErrRevenueCatCustomerLinked) {
+			// RevenueCat merged customers we consider different users, keep both as they are
+			logging.FromContext(ctx, u.logger).Warn(
+				"revenuecat aliases are linked to different users",
+				zap.String("user_id", userID),
+				zap.Strings("app_user_ids", appUserIDs),
+			)
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to link revenuecat aliases: %w", err)
+		}
+	}
+
+	return &userID, nil
+}
+
+// customerAppUserIDs returns all known ids of the customer the event belongs to.
+// Transfer events belong to the customer the purchases were transferred to.
+func customerAppUserIDs(p *ResolveRevenueCatCustomerParams) []string {
+	var ids []string
+	if p.EventType == typeTransfer {
+		ids = slices.Clone(p.TransferredTo)
+	} else {
+		if p.AppUserID != nil {
+			ids = append(ids, *p.AppUserID)
+		}
+		if p.OriginalAppUserID != nil {
+			ids = append(ids, *p.OriginalAppUserID)
+		}
+		ids = append(ids, p.Aliases...)
+	}
+
+	ids = slices.DeleteFunc(ids, func(id string) bool { return id == "" })
+	slices.Sort(ids)
+	return slices.Compact(ids)
+}
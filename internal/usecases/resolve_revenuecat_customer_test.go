@@ -0,0 +1,126 @@
+package usecases
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"athylps/internal/repositories"
+
+	"go.uber.org/zap"
+)
+
+type fakeRevenueCatCustomerRepository struct {
+	customers map[string]string // app user id -> user id
+}
+
+func (r *fakeRevenueCatCustomerRepository) FindUserID(_ context.Context, appUserIDs []string) (string, error) {
+	for _, id := range appUserIDs {
+		if userID, ok := r.customers[id]; ok {
+			return userID, nil
+		}
+	}
+	return "", repositories.ErrNotFound
+}
+
+func (r *fakeRevenueCatCustomerRepository) Link(_ context.Context, p *repositories.LinkRevenueCatCustomerParams) error {
+	for _, id := range p.AppUserIDs {
+		if userID, ok := r.customers[id]; ok && userID != p.UserID {
+			return repositories.ErrRevenueCatCustomerLinked
+		}
+	}
+	for _, id := range p.AppUserIDs {
+		r.customers[id] = p.UserID
+	}
+	return nil
+}
+
+func Test_ResolveRevenueCatCustomer(t *testing.T) {
+	const anonymousID = "$RCAnonymousID:992ed7dce7d442838082445dfb1bacbe"
+
+	tests := []struct {
+		name       string
+		params     *ResolveRevenueCatCustomerParams
+		wantUserID string
+		wantLinked []string
+	}{
+		{
+			name:   "unknown customer",
+			params: &ResolveRevenueCatCustomerParams{EventType: typeInitialPurchase, AppUserID: ptr(anonymousID)},
+		},
+		{
+			name:       "linked app user id",
+			params:     &ResolveRevenueCatCustomerParams{EventType: typeRenewal, AppUserID: ptr("user_42")},
+			wantUserID: "user-1",
+		},
+		{
+			name: "alias merges anonymous id",
+			params: &ResolveRevenueCatCustomerParams{
+				EventType:         typeSubscriberAlias,
+				AppUserID:         ptr("user_42"),
+				OriginalAppUserID: ptr(anonymousID),
+				Aliases:           []string{anonymousID, "user_42"},
+			},
+			wantUserID: "user-1",
+			wantLinked: []string{anonymousID},
+		},
+		{
+			name: "transfer resolves destination",
+			params: &ResolveRevenueCatCustomerParams{
+				EventType:     typeTransfer,
+				AppUserID:     ptr(anonymousID),
+				TransferredTo: []string{"user_42"},
+			},
+			wantUserID: "user-1",
+		},
+		{
+			name: "aliases of different users are left as is",
+			params: &ResolveRevenueCatCustomerParams{
+				EventType: typeSubscriberAlias,
+				AppUserID: ptr("user_42"),
+				Aliases:   []string{"user_42", "user_43"},
+			},
+			wantUserID: "user-1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeRevenueCatCustomerRepository{customers: map[string]string{"user_42": "user-1", "user_43": "user-2"}}
+			usecase := NewResolveRevenueCatCustomerUsecase(repo, zap.NewNop())
+
+			userID, err := usecase.Perform(context.Background(), tt.params)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if valueOrEmpty(userID) != tt.wantUserID {
+				t.Errorf("expected user %q, got %q", tt.wantUserID, valueOrEmpty(userID))
+			}
+			for _, id := range tt.wantLinked {
+				if repo.customers[id] != tt.wantUserID {
+					t.Errorf("expected %s to be linked to %s, got %q", id, tt.wantUserID, repo.customers[id])
+				}
+			}
+			if repo.customers["user_43"] != "user-2" {
+				t.Errorf("expected customer of another user to stay linked to it")
+			}
+		})
+	}
+}
+
+func Test_CustomerAppUserIDs(t *testing.T) {
+	got := customerAppUserIDs(&ResolveRevenueCatCustomerParams{
+		EventType:         typeSubscriberAlias,
+		AppUserID:         ptr("b"),
+		OriginalAppUserID: ptr("a"),
+		Aliases:           []string{"a", "b", "", "c"},
+	})
+	if want := []string{"a", "b", "c"}; !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
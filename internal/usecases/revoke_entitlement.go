@@ -0,0 +1,67 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"athylps/internal/logging"
+	"athylps/internal/models"
+
+	"go.uber.org/zap"
+)
+
+type RevokeEntitlementParams struct {
+	UserID        string
+	EntitlementID string
+	RevokedBy     string // Admin who revokes the entitlement
+}
+
+// RevokeEntitlementUsecase expires an entitlement granted by GrantEntitlementUsecase.
+// Entitlements of store purchases can't be revoked, they follow the store.
+type RevokeEntitlementUsecase struct {
+	subscriptions manualSubscriptionRepository
+	logger        *zap.Logger
+	now           func() time.Time
+}
+
+func NewRevokeEntitlementUsecase(subscriptions manualSubscriptionRepository, logger *zap.Logger) *RevokeEntitlementUsecase {
+	return &RevokeEntitlementUsecase{
+		subscriptions: subscriptions,
+		logger:        logger,
+		now:           time.Now,
+	}
+}
+
+// Perform returns repositories.ErrNotFound if the entitlement has never been granted to the user.
+// Revoking an already revoked entitlement does nothing.
+func (u *RevokeEntitlementUsecase) Perform(ctx context.Context, params *RevokeEntitlementParams) (*models.Subscription, error) {
+	appUserID, productID := manualSubscriptionKey(params.UserID, params.EntitlementID)
+	grant, err := u.subscriptions.Get(ctx, appUserID, productID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get entitlement grant: %w", err)
+	}
+	if grant.Status == models.SubscriptionStatusExpired {
+		return grant, nil
+	}
+
+	now := u.now()
+	revoked := *grant
+	revoked.Status = models.SubscriptionStatusExpired
+	revoked.ExpiresAt = &now
+	revoked.LastEventType = typeManualRevoke
+	revoked.LastEventAt = &now
+	updated, err := u.subscriptions.Upsert(ctx, &revoked)
+	if err != nil {
+		return nil, fmt.Errorf("failed to revoke entitlement: %w", err)
+	}
+
+	logging.FromContext(ctx, u.logger).Info(
+		"revoked entitlement",
+		zap.String("user_id", params.UserID),
+		zap.String("entitlement_id", params.EntitlementID),
+		zap.String("revoked_by", params.RevokedBy),
+	)
+
+	return updated, nil
+}
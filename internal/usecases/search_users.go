@@ -0,0 +1,55 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"athylps/internal/models"
+	"athylps/internal/repositories"
+)
+
+const maxSearchedUsers = 100
+
+type SearchUsersParams struct {
+	// Id, email, Firebase uid or RevenueCat app user id of the user, the filter is ignored if set
+	Query  string
+	Filter repositories.ListUsersParams
+}
+
+type userSearcher interface {
+	FindUsers(ctx context.Context, query string) ([]*models.User, error)
+	ListUsers(ctx context.Context, p *repositories.ListUsersParams) ([]*models.User, error)
+}
+
+// SearchUsersUsecase finds users for support, deleted ones included when they are looked up directly.
+type SearchUsersUsecase struct {
+	users userSearcher
+}
+
+func NewSearchUsersUsecase(users userSearcher) *SearchUsersUsecase {
+	return &SearchUsersUsecase{
+		users: users,
+	}
+}
+
+// Perform looks users up by the query if it's set, otherwise lists users matching the filter,
+// the limit of the filter is clamped to [1, 100].
+func (u *SearchUsersUsecase) Perform(ctx context.Context, params *SearchUsersParams) ([]*models.User, error) {
+	if query := strings.TrimSpace(params.Query); query != "" {
+		users, err := u.users.FindUsers(ctx, query)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find users: %w", err)
+		}
+		return users, nil
+	}
+
+	filter := params.Filter
+	filter.Limit = max(1, min(filter.Limit, maxSearchedUsers))
+	users, err := u.users.ListUsers(ctx, &filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+
+	return users, nil
+}
@@ -0,0 +1,57 @@
+package usecases
+
+import (
+	"context"
+	"testing"
+
+	"athylps/internal/models"
+	"athylps/internal/repositories"
+)
+
+type fakeUserSearcher struct {
+	queries []string
+	filters []*repositories.ListUsersParams
+}
+
+func (s *fakeUserSearcher) FindUsers(_ context.Context, query string) ([]*models.User, error) {
+	s.queries = append(s.queries, query)
+	return []*models.User{{ID: "found"}}, nil
+}
+
+func (s *fakeUserSearcher) ListUsers(_ context.Context, p *repositories.ListUsersParams) ([]*models.User, error) {
+	s.filters = append(s.filters, p)
+	return []*models.User{{ID: "listed"}}, nil
+}
+
+func Test_SearchUsers(t *testing.T) {
+	ctx := context.Background()
+	users := &fakeUserSearcher{}
+	usecase := NewSearchUsersUsecase(users)
+
+	found, err := usecase.Perform(ctx, &SearchUsersParams{Query: " user@example.com ", Filter: repositories.ListUsersParams{Locale: ptr("ru")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 1 || found[0].ID != "found" || len(users.queries) != 1 || users.queries[0] != "user@example.com" {
+		t.Errorf("expected users to be found by the trimmed query, got %+v, queries %v", found, users.queries)
+	}
+	if len(users.filters) != 0 {
+		t.Errorf("expected the filter to be ignored with a query, got %+v", users.filters)
+	}
+
+	for _, tt := range []struct {
+		limit uint64
+		want  uint64
+	}{
+		{limit: 0, want: 1},
+		{limit: 20, want: 20},
+		{limit: 1000, want: maxSearchedUsers},
+	} {
+		if _, err := usecase.Perform(ctx, &SearchUsersParams{Query: "  ", Filter: repositories.ListUsersParams{Limit: tt.limit}}); err != nil {
+			t.Fatal(err)
+		}
+		if got := users.filters[len(users.filters)-1].Limit; got != tt.want {
+			t.Errorf("expected limit %d to be clamped to %d, got %d", tt.limit, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,221 @@
+package usecases
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"athylps/internal/models"
+	"athylps/internal/repositories"
+	"athylps/internal/services"
+
+	"go.uber.org/zap"
+)
+
+// DigestSchedule configures which digests are sent and when.
+type DigestSchedule struct {
+	Daily    bool
+	Weekly   bool
+	At       time.Duration // Offset from the local midnight
+	Location *time.Location
+	ChatID   string // The default notification chat if empty
+}
+
+type purchaseStatsRepository interface {
+	Stats(ctx context.Context, from time.Time, to time.Time) ([]*models.PurchaseStats, error)
+}
+
+type digestRunRepository interface {
+	Claim(ctx context.Context, kind string, periodStart time.Time) (bool, error)
+}
+
+type digestRenderer interface {
+	RenderDigest(locale string, data *services.DigestData) (string, error)
+}
+
+// SendDigestsUsecase sends revenue digests for the previous day and week once they are due.
+// It's meant to be run every minute or so, a digest missed while the app was down is sent on the next run.
+// Each period is claimed in the database together with enqueuing the digest, so replicas don't send it twice.
+type SendDigestsUsecase struct {
+	schedule  *DigestSchedule
+	tx        transactor
+	purchases purchaseStatsRepository
+	runs      digestRunRepository
+	outbox    notificationOutbox
+	templates digestRenderer
+	logger    *zap.Logger
+	now       func() time.Time
+	sent      map[string]time.Time // The last period sent by this instance, saves a claim query on every run
+}
+
+func NewSendDigestsUsecase(
+	schedule *DigestSchedule,
+	tx transactor,
+	purchases purchaseStatsRepository,
+	runs digestRunRepository,
+	outbox notificationOutbox,
+	templates digestRenderer,
+	logger *zap.Logger,
+) *SendDigestsUsecase {
+	return &SendDigestsUsecase{
+		schedule:  schedule,
+		tx:        tx,
+		purchases: purchases,
+		runs:      runs,
+		outbox:    outbox,
+		templates: templates,
+		logger:    logger,
+		now:       time.Now,
+		sent:      make(map[string]time.Time),
+	}
+}
+
+func (u *SendDigestsUsecase) Perform(ctx context.Context) error {
+	now := u.now().In(u.schedule.Location)
+
+	var errs []error
+	if u.schedule.Daily {
+		errs = append(errs, u.send(ctx, services.DigestDaily, now))
+	}
+	if u.schedule.Weekly {
+		errs = append(errs, u.send(ctx, services.DigestWeekly, now))
+	}
+
+	return errors.Join(errs...)
+}
+
+func (u *SendDigestsUsecase) send(ctx context.Context, kind string, now time.Time) error {
+	from, to := digestPeriod(kind, now, u.schedule.At)
+	if sent, ok := u.sent[kind]; ok && sent.Equal(from) {
+		return nil
+	}
+
+	claimed := false
+	err := u.tx.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		claimed, err = u.runs.Claim(ctx, kind, from)
+		if err != nil || !claimed {
+			return err
+		}
+
+		stats, err := u.purchases.Stats(ctx, from, to)
+		if err != nil {
+			return err
+		}
+
+		text, err := u.templates.RenderDigest("", buildDigestData(kind, from, to, stats))
+		if err != nil {
+			return err
+		}
+
+		return u.outbox.Enqueue(ctx, &repositories.EnqueueNotificationParams{
+			Target: models.NotificationTarget{Channel: models.NotificationChannelTelegram, Recipient: u.schedule.ChatID},
+			Text:   text,
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("failed to send %s digest: %w", kind, err)
+	}
+
+	u.sent[kind] = from
+	if claimed {
+		u.logger.Info("sent digest", zap.String("kind", kind), zap.Time("from", from), zap.Time("to", to))
+	}
+
+	return nil
+}
+
+// digestPeriod returns [from, to) of the latest period of the kind which digest is due at now.
+func digestPeriod(kind string, now time.Time, at time.Duration) (time.Time, time.Time) {
+	days := 1
+	if kind == services.DigestWeekly {
+		days = 7
+	}
+
+	from, _ := currentPeriod(kind, now)
+	from, to := from.AddDate(0, 0, -days), from
+	if now.Before(to.Add(at)) {
+		from, to = from.AddDate(0, 0, -days), from
+	}
+
+	return from, to
+}
+
+// currentPeriod returns [from, to) of the period of the kind now is in.
+// Days start at the local midnight, weeks start on Monday.
+func currentPeriod(kind string, now time.Time) (time.Time, time.Time) {
+	from := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	if kind == services.DigestWeekly {
+		from = from.AddDate(0, 0, -int((from.Weekday()+6)%7))
+		return from, from.AddDate(0, 0, 7)
+	}
+
+	return from, from.AddDate(0, 0, 1)
+}
+
+func buildDigestData(kind string, from time.Time, to time.Time, stats []*models.PurchaseStats) *services.DigestData {
+	data := &services.DigestData{
+		Kind:  kind,
+		From:  from,
+		Until: to.AddDate(0, 0, -1),
+	}
+
+	byStore := make(map[string]*services.DigestTotal)
+	byProduct := make(map[string]*services.DigestTotal)
+	byCountry := make(map[string]*services.DigestTotal)
+	for _, s := range stats {
+		switch s.EventType {
+		case typeInitialPurchase, typeNonRenewingPurchase:
+			addDigestTotal(&data.Purchases, s)
+		case typeRenewal:
+			addDigestTotal(&data.Renewals, s)
+		case typeCancellation:
+			data.Cancellations += s.Count
+			continue
+		case typeRefund:
+			addDigestTotal(&data.Refunds, s)
+			continue
+		default:
+			continue
+		}
+
+		data.Revenue += s.Revenue
+		addDigestBreakdown(byStore, s.Store, s)
+		addDigestBreakdown(byProduct, valueOrEmpty(s.ProductID), s)
+		addDigestBreakdown(byCountry, valueOrEmpty(s.CountryCode), s)
+	}
+
+	data.ByStore = sortedDigestTotals(byStore)
+	data.ByProduct = sortedDigestTotals(byProduct)
+	data.ByCountry = sortedDigestTotals(byCountry)
+
+	return data
+}
+
+func addDigestTotal(total *services.DigestTotal, s *models.PurchaseStats) {
+	total.Count += s.Count
+	total.Revenue += s.Revenue
+}
+
+func addDigestBreakdown(totals map[string]*services.DigestTotal, name string, s *models.PurchaseStats) {
+	total, ok := totals[name]
+	if !ok {
+		total = &services.DigestTotal{Name: name}
+		totals[name] = total
+	}
+	addDigestTotal(total, s)
+}
+
+func sortedDigestTotals(totals map[string]*services.DigestTotal) []services.DigestTotal {
+	sorted := make([]services.DigestTotal, 0, len(totals))
+	for _, total := range totals {
+		sorted = append(sorted, *total)
+	}
+	slices.SortFunc(sorted, func(a, b services.DigestTotal) int {
+		return cmp.Or(cmp.Compare(b.Revenue, a.Revenue), cmp.Compare(b.Count, a.Count), cmp.Compare(a.Name, b.Name))
+	})
+	return sorted
+}
@@ -0,0 +1,164 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"athylps/internal/models"
+	"athylps/internal/services"
+
+	"go.uber.org/zap"
+)
+
+type fakeDigestRuns struct {
+	claimed map[string]bool
+}
+
+func (r *fakeDigestRuns) Claim(_ context.Context, kind string, periodStart time.Time) (bool, error) {
+	key := kind + "/" + periodStart.Format(time.RFC3339)
+	if r.claimed[key] {
+		return false, nil
+	}
+	r.claimed[key] = true
+	return true, nil
+}
+
+type fakePurchaseStats struct {
+	stats    []*models.PurchaseStats
+	requests [][2]time.Time
+}
+
+func (s *fakePurchaseStats) Stats(_ context.Context, from time.Time, to time.Time) ([]*models.PurchaseStats, error) {
+	s.requests = append(s.requests, [2]time.Time{from, to})
+	return s.stats, nil
+}
+
+type fakeDigestRenderer struct{}
+
+func (fakeDigestRenderer) RenderDigest(_ string, data *services.DigestData) (string, error) {
+	return fmt.Sprintf("%s %s %.2f", data.Kind, data.From.Format(time.DateOnly), data.Revenue), nil
+}
+
+func Test_DigestPeriod(t *testing.T) {
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := 9 * time.Hour
+	date := func(day int, hour int) time.Time {
+		return time.Date(2025, 12, day, hour, 0, 0, 0, moscow)
+	}
+
+	tests := []struct {
+		name     string
+		kind     string
+		now      time.Time
+		wantFrom time.Time
+	}{
+		{name: "daily before time", kind: services.DigestDaily, now: date(4, 8), wantFrom: date(2, 0)},
+		{name: "daily at time", kind: services.DigestDaily, now: date(4, 9), wantFrom: date(3, 0)},
+		{name: "daily late", kind: services.DigestDaily, now: date(4, 23), wantFrom: date(3, 0)},
+		{name: "weekly on monday before time", kind: services.DigestWeekly, now: date(8, 8), wantFrom: date(24, 0).AddDate(0, -1, 0)},
+		{name: "weekly on monday", kind: services.DigestWeekly, now: date(8, 10), wantFrom: date(1, 0)},
+		{name: "weekly on sunday", kind: services.DigestWeekly, now: date(14, 20), wantFrom: date(1, 0)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from, to := digestPeriod(tt.kind, tt.now, at)
+			wantTo := tt.wantFrom.AddDate(0, 0, 1)
+			if tt.kind == services.DigestWeekly {
+				wantTo = tt.wantFrom.AddDate(0, 0, 7)
+			}
+			if !from.Equal(tt.wantFrom) || !to.Equal(wantTo) {
+				t.Errorf("expected [%s, %s), got [%s, %s)", tt.wantFrom, wantTo, from, to)
+			}
+		})
+	}
+}
+
+func Test_SendDigests_SendsOncePerPeriod(t *testing.T) {
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2025, 12, 8, 9, 0, 0, 0, moscow) // Monday
+	runs := &fakeDigestRuns{claimed: make(map[string]bool)}
+	outbox := &fakeEnqueueOutbox{}
+	stats := &fakePurchaseStats{stats: []*models.PurchaseStats{{EventType: typeInitialPurchase, Store: "APP_STORE", Count: 2, Revenue: 9.98}}}
+
+	// Two replicas share the database
+	replicas := make([]*SendDigestsUsecase, 2)
+	for i := range replicas {
+		replicas[i] = NewSendDigestsUsecase(
+			&DigestSchedule{Daily: true, Weekly: true, At: 9 * time.Hour, Location: moscow, ChatID: "digests"},
+			fakeTransactor{}, stats, runs, outbox, fakeDigestRenderer{}, zap.NewNop(),
+		)
+		replicas[i].now = func() time.Time { return now }
+	}
+
+	for range 2 {
+		for _, replica := range replicas {
+			if err := replica.Perform(context.Background()); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+	}
+
+	var texts []string
+	for _, p := range outbox.enqueued {
+		texts = append(texts, p.Text)
+		if p.Target.Channel != models.NotificationChannelTelegram || p.Target.Recipient != "digests" {
+			t.Errorf("expected digest to be sent to the digests chat, got %+v", p.Target)
+		}
+	}
+	if !slices.Equal(texts, []string{"daily 2025-12-07 9.98", "weekly 2025-12-01 9.98"}) {
+		t.Errorf("expected daily and weekly digests to be sent once, got %v", texts)
+	}
+	if len(stats.requests) != 2 {
+		t.Errorf("expected stats to be requested only for claimed digests, got %d requests", len(stats.requests))
+	}
+
+	// The next day only the daily digest is due
+	now = now.AddDate(0, 0, 1)
+	if err := replicas[0].Perform(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(outbox.enqueued) != 3 || outbox.enqueued[2].Text != "daily 2025-12-08 9.98" {
+		t.Errorf("expected the next daily digest, got %d digests", len(outbox.enqueued))
+	}
+}
+
+func Test_BuildDigestData(t *testing.T) {
+	from := time.Date(2025, 12, 1, 0, 0, 0, 0, time.UTC)
+	stats := []*models.PurchaseStats{
+		{EventType: typeInitialPurchase, Store: "APP_STORE", ProductID: ptr("premium"), CountryCode: ptr("RU"), Count: 2, Revenue: 10},
+		{EventType: typeNonRenewingPurchase, Store: "RU_STORE", ProductID: ptr("coins"), Count: 1, Revenue: 3},
+		{EventType: typeRenewal, Store: "APP_STORE", ProductID: ptr("premium"), CountryCode: ptr("SE"), Count: 3, Revenue: 15},
+		{EventType: typeCancellation, Store: "APP_STORE", ProductID: ptr("premium"), Count: 4},
+		{EventType: typeRefund, Store: "APP_STORE", ProductID: ptr("premium"), Count: 1, Revenue: 5},
+		{EventType: typeBillingIssue, Store: "APP_STORE", Count: 7},
+	}
+
+	data := buildDigestData(services.DigestWeekly, from, from.AddDate(0, 0, 7), stats)
+
+	if data.Revenue != 28 || data.Purchases != (services.DigestTotal{Count: 3, Revenue: 13}) ||
+		data.Renewals != (services.DigestTotal{Count: 3, Revenue: 15}) || data.Cancellations != 4 ||
+		data.Refunds != (services.DigestTotal{Count: 1, Revenue: 5}) {
+		t.Errorf("unexpected totals: %+v", data)
+	}
+	if !data.Until.Equal(time.Date(2025, 12, 7, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected the last day of the week, got %s", data.Until)
+	}
+	wantStores := []services.DigestTotal{{Name: "APP_STORE", Count: 5, Revenue: 25}, {Name: "RU_STORE", Count: 1, Revenue: 3}}
+	if !slices.Equal(data.ByStore, wantStores) {
+		t.Errorf("expected %v, got %v", wantStores, data.ByStore)
+	}
+	wantCountries := []services.DigestTotal{{Name: "SE", Count: 3, Revenue: 15}, {Name: "RU", Count: 2, Revenue: 10}, {Name: "", Count: 1, Revenue: 3}}
+	if !slices.Equal(data.ByCountry, wantCountries) {
+		t.Errorf("expected %v, got %v", wantCountries, data.ByCountry)
+	}
+}
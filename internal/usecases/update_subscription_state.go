@@ -0,0 +1,207 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"athylps/internal/logging"
+	"athylps/internal/models"
+	"athylps/internal/repositories"
+
+	"go.uber.org/zap"
+)
+
+var subscriptionEventTypes = []string{
+	typeInitialPurchase,
+	typeNonRenewingPurchase,
+	typeRenewal,
+	typeProductChange,
+	typeCancellation,
+	typeUncancellation,
+	typeBillingIssue,
+	typeSubscriptionPaused,
+	typeExpiration,
+	typeSubscriptionExtended,
+	typeRefund,
+}
+
+type UpdateSubscriptionStateParams struct {
+	EventType            string
+	AppUserID            string
+	UserID               *string // Our user the app user id (or the transfer destination) is linked to
+	ProductID            string
+	EntitlementIDs       []string
+	Store                string
+	Environment          *string
+	EventAt              *time.Time
+	PurchasedAt          *time.Time
+	ExpiresAt            *time.Time
+	GracePeriodExpiresAt *time.Time
+	TransferredFrom      []string
+	TransferredTo        []string
+}
+
+type subscriptionRepository interface {
+	Get(ctx context.Context, appUserID string, productID string) (*models.Subscription, error)
+	Upsert(ctx context.Context, s *models.Subscription) (*models.Subscription, error)
+	Transfer(ctx context.Context, fromAppUserIDs []string, toAppUserID string, toUserID *string) (int64, error)
+}
+
+// UpdateSubscriptionStateUsecase folds store events into the current state of a subscription.
+type UpdateSubscriptionStateUsecase struct {
+	subscriptions subscriptionRepository
+	logger        *zap.Logger
+}
+
+func NewUpdateSubscriptionStateUsecase(subscriptions subscriptionRepository, logger *zap.Logger) *UpdateSubscriptionStateUsecase {
+	return &UpdateSubscriptionStateUsecase{
+		subscriptions: subscriptions,
+		logger:        logger,
+	}
+}
+
+func (u *UpdateSubscriptionStateUsecase) Perform(ctx context.Context, params *UpdateSubscriptionStateParams) error {
+	if params.EventType == typeTransfer {
+		return u.transfer(ctx, params)
+	}
+
+	if !slices.Contains(subscriptionEventTypes, params.EventType) {
+		return nil
+	}
+
+	if params.EventType == typeNonRenewingPurchase && len(params.EntitlementIDs) == 0 {
+		// Consumables don't grant anything that has to be tracked
+		return nil
+	}
+
+	if params.AppUserID == "" || params.ProductID == "" {
+		logging.FromContext(ctx, u.logger).Warn("subscription event without app user id or product id", zap.String("event_type", params.EventType))
+		return nil
+	}
+
+	current, err := u.subscriptions.Get(ctx, params.AppUserID, params.ProductID)
+	if err != nil && !errors.Is(err, repositories.ErrNotFound) {
+		return fmt.Errorf("failed to load subscription state: %w", err)
+	}
+
+	next, ok := applySubscriptionEvent(current, params)
+	if !ok {
+		logging.FromContext(ctx, u.logger).Info(
+			"ignoring stale subscription event",
+			zap.String("event_type", params.EventType),
+			zap.String("app_user_id", params.AppUserID),
+			zap.String("product_id", params.ProductID),
+		)
+		return nil
+	}
+
+	if _, err := u.subscriptions.Upsert(ctx, next); err != nil {
+		return fmt.Errorf("failed to save subscription state: %w", err)
+	}
+
+	return nil
+}
+
+func (u *UpdateSubscriptionStateUsecase) transfer(ctx context.Context, params *UpdateSubscriptionStateParams) error {
+	if len(params.TransferredFrom) == 0 || len(params.TransferredTo) == 0 {
+		logging.FromContext(ctx, u.logger).Warn("transfer event without source or destination app user ids")
+		return nil
+	}
+
+	transferred, err := u.subscriptions.Transfer(ctx, params.TransferredFrom, params.TransferredTo[0], params.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to transfer subscriptions: %w", err)
+	}
+
+	logging.FromContext(ctx, u.logger).Info(
+		"transferred subscriptions",
+		zap.Strings("from", params.TransferredFrom),
+		zap.String("to", params.TransferredTo[0]),
+		zap.Int64("count", transferred),
+	)
+
+	return nil
+}
+
+// applySubscriptionEvent returns the state of the subscription after the event.
+// It returns false if the event is older than the current state and must be ignored.
+//
+// Events are ordered by the subscription period they belong to (expires at), and then by the event time,
+// so a late delivered renewal or expiration of a previous period doesn't override the current period.
+func applySubscriptionEvent(current *models.Subscription, e *UpdateSubscriptionStateParams) (*models.Subscription, bool) {
+	if current != nil && isStaleSubscriptionEvent(current, e) {
+		return nil, false
+	}
+
+	next := &models.Subscription{
+		AppUserID:   e.AppUserID,
+		ProductID:   e.ProductID,
+		Store:       e.Store,
+		Environment: e.Environment,
+		Status:      models.SubscriptionStatusActive,
+	}
+	if current != nil {
+		copied := *current
+		next = &copied
+	}
+
+	next.LastEventType = e.EventType
+	if e.UserID != nil {
+		next.UserID = e.UserID
+	}
+	if e.EventAt != nil {
+		next.LastEventAt = e.EventAt
+	}
+	if e.PurchasedAt != nil {
+		next.PurchasedAt = e.PurchasedAt
+	}
+	if e.ExpiresAt != nil {
+		next.ExpiresAt = e.ExpiresAt
+	}
+	if len(e.EntitlementIDs) > 0 {
+		next.EntitlementIDs = e.EntitlementIDs
+	}
+
+	switch e.EventType {
+	case typeInitialPurchase, typeNonRenewingPurchase, typeRenewal, typeUncancellation, typeSubscriptionExtended:
+		next.Status = models.SubscriptionStatusActive
+		next.CancelledAt = nil
+		next.GracePeriodExpiresAt = nil
+	case typeCancellation:
+		next.Status = models.SubscriptionStatusCancelled
+		next.CancelledAt = e.EventAt
+	case typeBillingIssue:
+		next.Status = models.SubscriptionStatusGracePeriod
+		next.GracePeriodExpiresAt = e.GracePeriodExpiresAt
+	case typeSubscriptionPaused:
+		next.Status = models.SubscriptionStatusPaused
+	case typeExpiration, typeRefund:
+		next.Status = models.SubscriptionStatusExpired
+		next.GracePeriodExpiresAt = nil
+	case typeProductChange:
+		// The new product is tracked by its own purchase or renewal event,
+		// the current one keeps its state until then.
+	}
+
+	return next, true
+}
+
+func isStaleSubscriptionEvent(current *models.Subscription, e *UpdateSubscriptionStateParams) bool {
+	if e.ExpiresAt != nil && current.ExpiresAt != nil {
+		if e.ExpiresAt.Before(*current.ExpiresAt) {
+			return true
+		}
+		if e.ExpiresAt.After(*current.ExpiresAt) {
+			return false
+		}
+	}
+
+	if e.EventAt != nil && current.LastEventAt != nil {
+		return e.EventAt.Before(*current.LastEventAt)
+	}
+
+	return false
+}
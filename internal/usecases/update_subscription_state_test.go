@@ -0,0 +1,240 @@
+package usecases
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"athylps/internal/models"
+	"athylps/internal/repositories"
+
+	"go.uber.org/zap"
+)
+
+type fakeSubscriptionRepository struct {
+	subscriptions map[string]*models.Subscription
+}
+
+func newFakeSubscriptionRepository() *fakeSubscriptionRepository {
+	return &fakeSubscriptionRepository{subscriptions: map[string]*models.Subscription{}}
+}
+
+func (r *fakeSubscriptionRepository) Get(_ context.Context, appUserID string, productID string) (*models.Subscription, error) {
+	s, ok := r.subscriptions[appUserID+"/"+productID]
+	if !ok {
+		return nil, repositories.ErrNotFound
+	}
+	copied := *s
+	return &copied, nil
+}
+
+func (r *fakeSubscriptionRepository) Upsert(_ context.Context, s *models.Subscription) (*models.Subscription, error) {
+	r.subscriptions[s.AppUserID+"/"+s.ProductID] = s
+	return s, nil
+}
+
+func (r *fakeSubscriptionRepository) Transfer(_ context.Context, from []string, to string, toUserID *string) (int64, error) {
+	var transferred int64
+	for key, s := range r.subscriptions {
+		for _, f := range from {
+			if s.AppUserID == f {
+				delete(r.subscriptions, key)
+				s.AppUserID = to
+				s.UserID = toUserID
+				r.subscriptions[to+"/"+s.ProductID] = s
+				transferred++
+			}
+		}
+	}
+	return transferred, nil
+}
+
+var subscriptionTestStart = time.Date(2025, 11, 1, 12, 0, 0, 0, time.UTC)
+
+func subscriptionEvent(eventType string, day int, expiresDay int) *UpdateSubscriptionStateParams {
+	eventAt := subscriptionTestStart.AddDate(0, 0, day)
+	expiresAt := subscriptionTestStart.AddDate(0, 0, expiresDay)
+	return &UpdateSubscriptionStateParams{
+		EventType: eventType,
+		AppUserID: "user",
+		ProductID: "premium_monthly",
+		Store:     "APP_STORE",
+		EventAt:   &eventAt,
+		ExpiresAt: &expiresAt,
+	}
+}
+
+func Test_UpdateSubscriptionState_Transitions(t *testing.T) {
+	graceExpiresAt := subscriptionTestStart.AddDate(0, 0, 37)
+	billingIssue := subscriptionEvent(typeBillingIssue, 30, 30)
+	billingIssue.GracePeriodExpiresAt = &graceExpiresAt
+
+	tests := []struct {
+		name   string
+		events []*UpdateSubscriptionStateParams
+		want   models.SubscriptionStatus
+	}{
+		{
+			name:   "initial purchase",
+			events: []*UpdateSubscriptionStateParams{subscriptionEvent(typeInitialPurchase, 0, 30)},
+			want:   models.SubscriptionStatusActive,
+		},
+		{
+			name: "cancelled but still active",
+			events: []*UpdateSubscriptionStateParams{
+				subscriptionEvent(typeInitialPurchase, 0, 30),
+				subscriptionEvent(typeCancellation, 5, 30),
+			},
+			want: models.SubscriptionStatusCancelled,
+		},
+		{
+			name: "uncancelled",
+			events: []*UpdateSubscriptionStateParams{
+				subscriptionEvent(typeInitialPurchase, 0, 30),
+				subscriptionEvent(typeCancellation, 5, 30),
+				subscriptionEvent(typeUncancellation, 6, 30),
+			},
+			want: models.SubscriptionStatusActive,
+		},
+		{
+			name: "billing issue",
+			events: []*UpdateSubscriptionStateParams{
+				subscriptionEvent(typeInitialPurchase, 0, 30),
+				billingIssue,
+			},
+			want: models.SubscriptionStatusGracePeriod,
+		},
+		{
+			name: "paused",
+			events: []*UpdateSubscriptionStateParams{
+				subscriptionEvent(typeInitialPurchase, 0, 30),
+				subscriptionEvent(typeSubscriptionPaused, 10, 30),
+			},
+			want: models.SubscriptionStatusPaused,
+		},
+		{
+			name: "expired",
+			events: []*UpdateSubscriptionStateParams{
+				subscriptionEvent(typeInitialPurchase, 0, 30),
+				subscriptionEvent(typeExpiration, 30, 30),
+			},
+			want: models.SubscriptionStatusExpired,
+		},
+		{
+			name: "extended after expiration",
+			events: []*UpdateSubscriptionStateParams{
+				subscriptionEvent(typeInitialPurchase, 0, 30),
+				subscriptionEvent(typeExpiration, 30, 30),
+				subscriptionEvent(typeSubscriptionExtended, 31, 60),
+			},
+			want: models.SubscriptionStatusActive,
+		},
+		{
+			name: "late expiration of previous period",
+			events: []*UpdateSubscriptionStateParams{
+				subscriptionEvent(typeInitialPurchase, 0, 30),
+				subscriptionEvent(typeRenewal, 30, 60),
+				subscriptionEvent(typeExpiration, 30, 30),
+			},
+			want: models.SubscriptionStatusActive,
+		},
+		{
+			name: "late renewal of previous period",
+			events: []*UpdateSubscriptionStateParams{
+				subscriptionEvent(typeRenewal, 60, 90),
+				subscriptionEvent(typeCancellation, 70, 90),
+				subscriptionEvent(typeRenewal, 30, 60),
+			},
+			want: models.SubscriptionStatusCancelled,
+		},
+		{
+			name: "late uncancellation within the same period",
+			events: []*UpdateSubscriptionStateParams{
+				subscriptionEvent(typeInitialPurchase, 0, 30),
+				subscriptionEvent(typeCancellation, 10, 30),
+				subscriptionEvent(typeUncancellation, 5, 30),
+			},
+			want: models.SubscriptionStatusCancelled,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newFakeSubscriptionRepository()
+			usecase := NewUpdateSubscriptionStateUsecase(repo, zap.NewNop())
+
+			for _, e := range tt.events {
+				if err := usecase.Perform(context.Background(), e); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+
+			s, err := repo.Get(context.Background(), "user", "premium_monthly")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if s.Status != tt.want {
+				t.Errorf("expected status %s, got %s", tt.want, s.Status)
+			}
+		})
+	}
+}
+
+func Test_UpdateSubscriptionState_Transfer(t *testing.T) {
+	repo := newFakeSubscriptionRepository()
+	usecase := NewUpdateSubscriptionStateUsecase(repo, zap.NewNop())
+
+	purchase := subscriptionEvent(typeInitialPurchase, 0, 30)
+	purchase.AppUserID = "$RCAnonymousID:1"
+	if err := usecase.Perform(context.Background(), purchase); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	userID := "0199a1b2-0000-7000-8000-000000000001"
+	err := usecase.Perform(context.Background(), &UpdateSubscriptionStateParams{
+		EventType:       typeTransfer,
+		UserID:          &userID,
+		TransferredFrom: []string{"$RCAnonymousID:1"},
+		TransferredTo:   []string{"user"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	transferred, err := repo.Get(context.Background(), "user", "premium_monthly")
+	if err != nil {
+		t.Fatalf("expected subscription to be transferred: %v", err)
+	}
+	if transferred.UserID == nil || *transferred.UserID != userID {
+		t.Errorf("expected subscription to move to user %s, got %v", userID, transferred.UserID)
+	}
+	if _, err := repo.Get(context.Background(), "$RCAnonymousID:1", "premium_monthly"); err == nil {
+		t.Errorf("expected subscription to be removed from the previous app user")
+	}
+}
+
+func Test_UpdateSubscriptionState_NonRenewingPurchases(t *testing.T) {
+	repo := newFakeSubscriptionRepository()
+	usecase := NewUpdateSubscriptionStateUsecase(repo, zap.NewNop())
+
+	events := []*UpdateSubscriptionStateParams{
+		{EventType: typeNonRenewingPurchase, AppUserID: "user", ProductID: "premium_lifetime", EntitlementIDs: []string{"premium"}},
+		{EventType: typeNonRenewingPurchase, AppUserID: "user", ProductID: "coins_100"},
+	}
+	for _, e := range events {
+		if err := usecase.Perform(context.Background(), e); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	lifetime, err := repo.Get(context.Background(), "user", "premium_lifetime")
+	if err != nil {
+		t.Fatalf("expected purchase granting entitlements to be tracked: %v", err)
+	}
+	if lifetime.Status != models.SubscriptionStatusActive || lifetime.ExpiresAt != nil {
+		t.Errorf("expected active purchase without expiration, got %+v", lifetime)
+	}
+	if _, err := repo.Get(context.Background(), "user", "coins_100"); err == nil {
+		t.Errorf("expected consumable not to be tracked")
+	}
+}
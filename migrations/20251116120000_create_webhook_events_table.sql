@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS webhook_events(
+    id uuid DEFAULT uuidv7() PRIMARY KEY,
+    provider text NOT NULL,
+    event_id text NOT NULL,
+    event_type text NOT NULL,
+    environment text DEFAULT null,
+    payload jsonb NOT NULL,
+    received_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    processed_at TIMESTAMPTZ DEFAULT null,
+    CONSTRAINT webhook_events_provider_event_id_key UNIQUE (provider, event_id)
+);
+
+CREATE INDEX IF NOT EXISTS webhook_events_received_at_idx ON webhook_events (received_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE webhook_events;
+-- +goose StatementEnd
@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS subscriptions(
+    id uuid DEFAULT uuidv7() PRIMARY KEY,
+    app_user_id text NOT NULL,
+    product_id text NOT NULL,
+    store text NOT NULL,
+    environment text DEFAULT null,
+    status text NOT NULL,
+    purchased_at TIMESTAMPTZ DEFAULT null,
+    expires_at TIMESTAMPTZ DEFAULT null,
+    grace_period_expires_at TIMESTAMPTZ DEFAULT null,
+    cancelled_at TIMESTAMPTZ DEFAULT null,
+    last_event_type text NOT NULL,
+    last_event_at TIMESTAMPTZ DEFAULT null,
+    created_at TIMESTAMPTZ DEFAULT now(),
+    updated_at TIMESTAMPTZ DEFAULT now(),
+    CONSTRAINT subscriptions_app_user_id_product_id_key UNIQUE (app_user_id, product_id)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE subscriptions;
+-- +goose StatementEnd
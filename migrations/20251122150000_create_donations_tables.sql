@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS donationalerts_tokens(
+    id smallint DEFAULT 1 PRIMARY KEY CHECK (id = 1),
+    access_token text NOT NULL,
+    refresh_token text NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT now(),
+    updated_at TIMESTAMPTZ DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS donations(
+    id uuid DEFAULT uuidv7() PRIMARY KEY,
+    external_id bigint NOT NULL UNIQUE,
+    username text DEFAULT null,
+    message text DEFAULT null,
+    amount numeric(12, 2) NOT NULL,
+    currency text NOT NULL,
+    donated_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT now()
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE donations;
+DROP TABLE donationalerts_tokens;
+-- +goose StatementEnd
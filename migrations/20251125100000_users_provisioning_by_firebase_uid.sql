@@ -0,0 +1,12 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Firebase users signed in with phone or anonymously don't have an email
+ALTER TABLE users ALTER COLUMN email DROP NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS users_firebase_uid_key ON users (firebase_uid);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS users_firebase_uid_key;
+ALTER TABLE users ALTER COLUMN email SET NOT NULL;
+-- +goose StatementEnd
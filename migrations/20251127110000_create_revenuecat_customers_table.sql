@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Every RevenueCat app user id (including anonymous aliases) known to belong to one of our users
+CREATE TABLE IF NOT EXISTS revenuecat_customers(
+    app_user_id text PRIMARY KEY,
+    user_id uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS revenuecat_customers_user_id_idx ON revenuecat_customers (user_id);
+
+ALTER TABLE subscriptions ADD COLUMN user_id uuid DEFAULT null REFERENCES users(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS subscriptions_user_id_idx ON subscriptions (user_id);
+
+ALTER TABLE webhook_events ADD COLUMN user_id uuid DEFAULT null REFERENCES users(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS webhook_events_user_id_idx ON webhook_events (user_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE webhook_events DROP COLUMN user_id;
+ALTER TABLE subscriptions DROP COLUMN user_id;
+DROP TABLE revenuecat_customers;
+-- +goose StatementEnd
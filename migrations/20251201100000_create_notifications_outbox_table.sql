@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Notifications are written here in the same transaction as the change they are about
+-- and delivered by the background dispatcher
+CREATE TABLE IF NOT EXISTS notifications_outbox(
+    id uuid DEFAULT uuidv7() PRIMARY KEY,
+    text text NOT NULL,
+    status text NOT NULL DEFAULT 'pending',
+    attempts integer NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_error text DEFAULT null,
+    created_at TIMESTAMPTZ DEFAULT now(),
+    sent_at TIMESTAMPTZ DEFAULT null
+);
+
+CREATE INDEX IF NOT EXISTS notifications_outbox_pending_idx ON notifications_outbox (next_attempt_at) WHERE status = 'pending';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE notifications_outbox;
+-- +goose StatementEnd
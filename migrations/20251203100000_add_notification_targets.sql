@@ -0,0 +1,14 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Notifications written before routing was introduced go to the default Telegram chat (empty recipient)
+ALTER TABLE notifications_outbox
+    ADD COLUMN channel text NOT NULL DEFAULT 'telegram',
+    ADD COLUMN recipient text NOT NULL DEFAULT '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE notifications_outbox
+    DROP COLUMN channel,
+    DROP COLUMN recipient;
+-- +goose StatementEnd
@@ -0,0 +1,34 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Normalized purchase related webhook events, the source of revenue digests and stats.
+-- Events received before this migration are only in webhook_events and are not counted.
+CREATE TABLE IF NOT EXISTS purchase_events(
+    id uuid DEFAULT uuidv7() PRIMARY KEY,
+    webhook_event_id uuid NOT NULL UNIQUE REFERENCES webhook_events (id) ON DELETE CASCADE,
+    event_type text NOT NULL,
+    store text NOT NULL,
+    environment text DEFAULT null,
+    product_id text DEFAULT null,
+    country_code text DEFAULT null,
+    price numeric(12, 2) DEFAULT null, -- USD
+    user_id uuid DEFAULT null REFERENCES users (id) ON DELETE SET NULL,
+    occurred_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS purchase_events_occurred_at_idx ON purchase_events (occurred_at);
+
+-- A digest period is claimed by inserting a row, so only one replica sends it
+CREATE TABLE IF NOT EXISTS digest_runs(
+    kind text NOT NULL,
+    period_start TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT now(),
+    PRIMARY KEY (kind, period_start)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE digest_runs;
+DROP TABLE purchase_events;
+-- +goose StatementEnd
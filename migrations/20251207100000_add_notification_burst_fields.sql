@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Purchase notifications keep the event they are about, so a burst of them can be collapsed into a summary
+ALTER TABLE notifications_outbox
+    ADD COLUMN event_type text DEFAULT null,
+    ADD COLUMN price numeric(12, 2) DEFAULT null, -- USD
+    ADD COLUMN locale text NOT NULL DEFAULT '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE notifications_outbox
+    DROP COLUMN event_type,
+    DROP COLUMN price,
+    DROP COLUMN locale;
+-- +goose StatementEnd
@@ -0,0 +1,12 @@
+-- +goose Up
+-- +goose StatementBegin
+-- W3C traceparent of the request that enqueued the notification, delivery continues its trace
+ALTER TABLE notifications_outbox
+    ADD COLUMN traceparent text NOT NULL DEFAULT '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE notifications_outbox
+    DROP COLUMN traceparent;
+-- +goose StatementEnd
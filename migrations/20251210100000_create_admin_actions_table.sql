@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Audit log of mutating admin API calls with the admin who made them
+CREATE TABLE IF NOT EXISTS admin_actions(
+    id uuid DEFAULT uuidv7() PRIMARY KEY,
+    admin_uid text NOT NULL, -- Firebase uid
+    method text NOT NULL,
+    route text NOT NULL, -- e.g. /admin/v1/users/{id}/entitlements
+    path text NOT NULL,
+    request jsonb DEFAULT null, -- Body of the request if it's JSON
+    status integer NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS admin_actions_created_at_idx ON admin_actions (created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE admin_actions;
+-- +goose StatementEnd
@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Time personal data of a user who has deleted the account is purged after, null for users deleted by admins
+ALTER TABLE users
+    ADD COLUMN purge_after TIMESTAMPTZ DEFAULT null;
+
+CREATE INDEX IF NOT EXISTS users_purge_after_idx ON users (purge_after) WHERE purge_after IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS users_purge_after_idx;
+ALTER TABLE users
+    DROP COLUMN purge_after;
+-- +goose StatementEnd
@@ -0,0 +1,14 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Price in the currency of the purchase, stores like RuStore report only it and price is converted from it
+ALTER TABLE purchase_events
+    ADD COLUMN price_in_purchased_currency numeric(12, 2) DEFAULT null,
+    ADD COLUMN currency text DEFAULT null;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE purchase_events
+    DROP COLUMN price_in_purchased_currency,
+    DROP COLUMN currency;
+-- +goose StatementEnd
@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Payloads are stored byte for byte as the provider sent them, jsonb reorders keys, drops whitespace
+-- and duplicate keys. Events received before this migration keep their normalized payload.
+ALTER TABLE webhook_events
+    ALTER COLUMN payload TYPE bytea USING convert_to(payload::text, 'UTF8');
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE webhook_events
+    ALTER COLUMN payload TYPE jsonb USING convert_from(payload, 'UTF8')::jsonb;
+-- +goose StatementEnd